@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog/v2"
+)
+
+// acceptedRegression records that a maintainer accepted a regression of up to
+// Ratio for a benchmark, e.g. because it is the expected cost of a new
+// feature.
+type acceptedRegression struct {
+	// Benchmark is the unique name of the benchmark.
+	Benchmark string    `yaml:"benchmark" json:"benchmark"`
+	Ratio     float64   `yaml:"ratio" json:"ratio"`
+	Author    string    `yaml:"author" json:"author"`
+	PR        int       `yaml:"pr,omitempty" json:"pr,omitempty"`
+	Expires   time.Time `yaml:"expires" json:"expires"`
+}
+
+var (
+	acceptedRegressionsPath string
+	acceptAuthor            string
+	acceptPR                int
+	acceptTTL               time.Duration
+)
+
+func loadAcceptedRegressions(path string) ([]acceptedRegression, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read accepted regressions: %w", err)
+	}
+	var accepted []acceptedRegression
+	if err := yaml.UnmarshalStrict(data, &accepted); err != nil {
+		return nil, fmt.Errorf("unable to parse accepted regressions '%s': %w", path, err)
+	}
+	return accepted, nil
+}
+
+func saveAcceptedRegressions(path string, accepted []acceptedRegression) error {
+	data, err := yaml.Marshal(accepted)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("unable to write accepted regressions: %w", err)
+	}
+	return nil
+}
+
+// acceptRegressions raises the threshold of the regressed results which are
+// covered by an accepted regression which has not expired, so that they are no
+// longer reported as regressions. The accepted regressions which were applied
+// are returned.
+func acceptRegressions(results []result, accepted []acceptedRegression, now time.Time) []acceptedRegression {
+	var applied []acceptedRegression
+	for i := range results {
+		if !isRegression(results[i]) {
+			continue
+		}
+		for _, a := range accepted {
+			if a.Benchmark != results[i].UniqueName || now.After(a.Expires) {
+				continue
+			}
+			r := results[i]
+			r.Threshold = a.Ratio
+			if isRegression(r) {
+				continue
+			}
+			klog.InfoS("Regression was accepted", "benchmark", a.Benchmark, "ratio", a.Ratio, "author", a.Author, "pr", a.PR, "expires", a.Expires)
+			results[i] = r
+			applied = append(applied, a)
+			break
+		}
+	}
+	return applied
+}
+
+func addAcceptFlags(fs *flag.FlagSet) {
+	fs.StringVar(&acceptAuthor, "author", "", "name of the maintainer accepting the regression")
+	fs.IntVar(&acceptPR, "pr", 0, "`number` of the pull request introducing the regression")
+	fs.DurationVar(&acceptTTL, "ttl", 30*24*time.Hour, "`duration` after which the accepted regression expires")
+}
+
+// runAccept records an accepted regression for a benchmark in the file given
+// by --accepted-regressions, replacing any previous entry for the same
+// benchmark and dropping expired entries.
+func runAccept(fs *flag.FlagSet) error {
+	if fs.NArg() != 2 {
+		return fmt.Errorf("expected a benchmark name and a ratio, e.g. 'BenchmarkFoo 15%%'")
+	}
+	if acceptedRegressionsPath == "" {
+		return fmt.Errorf("--accepted-regressions is required")
+	}
+	if acceptAuthor == "" {
+		return fmt.Errorf("--author is required")
+	}
+	var ratio ratioValue
+	if err := ratio.Set(fs.Arg(1)); err != nil {
+		return err
+	}
+	if ratio <= 0 {
+		return fmt.Errorf("the accepted ratio must be positive")
+	}
+	accepted, err := loadAcceptedRegressions(acceptedRegressionsPath)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	entry := acceptedRegression{
+		Benchmark: fs.Arg(0),
+		Ratio:     float64(ratio),
+		Author:    acceptAuthor,
+		PR:        acceptPR,
+		Expires:   now.Add(acceptTTL).UTC().Truncate(time.Second),
+	}
+	updated := []acceptedRegression{}
+	for _, a := range accepted {
+		if a.Benchmark == entry.Benchmark || now.After(a.Expires) {
+			continue
+		}
+		updated = append(updated, a)
+	}
+	updated = append(updated, entry)
+	if err := saveAcceptedRegressions(acceptedRegressionsPath, updated); err != nil {
+		return err
+	}
+	klog.InfoS("Recorded accepted regression", "benchmark", entry.Benchmark, "ratio", entry.Ratio, "expires", entry.Expires)
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptRegressions(t *testing.T) {
+	now := time.Date(2021, 10, 14, 0, 0, 0, 0, time.UTC)
+	contention := false
+	newResult := func(name string, ratio float64) result {
+		return result{
+			Benchmark:    Benchmark{UniqueName: name, BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}},
+			RatioNsPerOp: ratio,
+		}
+	}
+	accepted := []acceptedRegression{
+		{Benchmark: "BenchmarkFoo", Ratio: 0.2, Expires: now.Add(time.Hour)},
+		{Benchmark: "BenchmarkBar", Ratio: 0.2, Expires: now.Add(-time.Hour)},
+		{Benchmark: "BenchmarkBaz", Ratio: 0.2, Expires: now.Add(time.Hour)},
+	}
+
+	for _, tc := range []struct {
+		name       string
+		result     result
+		regression bool
+	}{
+		{"accepted", newResult("BenchmarkFoo", 0.15), false},
+		{"expired", newResult("BenchmarkBar", 0.15), true},
+		{"above accepted ratio", newResult("BenchmarkBaz", 0.25), true},
+		{"not accepted", newResult("BenchmarkQux", 0.15), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			results := []result{tc.result}
+			applied := acceptRegressions(results, accepted, now)
+			assert.Equal(t, tc.regression, isRegression(results[0]))
+			assert.Equal(t, !tc.regression, len(applied) == 1)
+		})
+	}
+}
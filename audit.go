@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var auditLogPath, auditEndpoint string
+
+// auditEntry records a gating decision, so that it can later be reviewed why
+// a regression was allowed to merge.
+type auditEntry struct {
+	Time  time.Time `json:"time"`
+	RunID string    `json:"runID"`
+	// Meta is the metadata passed with --meta.
+	Meta map[string]string `json:"meta,omitempty"`
+	// Actor is the user who triggered the run, if known.
+	Actor       string               `json:"actor,omitempty"`
+	Head        auditRef             `json:"head"`
+	Comparisons []auditComparison    `json:"comparisons"`
+	Accepted    []acceptedRegression `json:"accepted,omitempty"`
+	Verdict     string               `json:"verdict"`
+	// Partial is true if the deadline was reached before all benchmarks
+	// were run.
+	Partial bool `json:"partial,omitempty"`
+}
+
+type auditRef struct {
+	Name   string `json:"name"`
+	Commit string `json:"commit,omitempty"`
+}
+
+type auditComparison struct {
+	Base   auditRef `json:"base"`
+	Policy string   `json:"policy,omitempty"`
+	// Regressions describes each regressed benchmark, e.g.
+	// "BenchmarkFoo: ns/op +25.00%".
+	Regressions []string `json:"regressions,omitempty"`
+	Failed      bool     `json:"failed"`
+}
+
+// comparison is the outcome of the comparison of head with one ref.
+type comparison struct {
+	base    *refResults
+	results []result
+	failed  bool
+}
+
+func auditActor() string {
+	for _, env := range []string{"GITHUB_ACTOR", "USER", "USERNAME"} {
+		if actor := os.Getenv(env); actor != "" {
+			return actor
+		}
+	}
+	return ""
+}
+
+func newAuditEntry(head *refResults, comparisons []comparison, accepted []acceptedRegression) *auditEntry {
+	entry := &auditEntry{
+		Time:     time.Now().UTC(),
+		RunID:    runID,
+		Meta:     runMetadata(),
+		Actor:    auditActor(),
+		Head:     auditRef{Name: head.name, Commit: head.run.Commit},
+		Accepted: accepted,
+	}
+	var all []result
+	failed := false
+	for _, c := range comparisons {
+		if c.base == nil {
+			continue
+		}
+		ac := auditComparison{Base: auditRef{Name: c.base.name, Commit: c.base.run.Commit}, Policy: c.base.policy, Failed: c.failed}
+		for _, r := range c.results {
+			if isRegression(r) {
+				ac.Regressions = append(ac.Regressions, fmt.Sprintf("%s: %s", r.UniqueName, regressionSummary(r)))
+			}
+		}
+		entry.Comparisons = append(entry.Comparisons, ac)
+		all = append(all, c.results...)
+		failed = failed || c.failed
+	}
+	entry.Verdict, _, _ = resultVerdict(all, failed)
+	return entry
+}
+
+// recordAudit appends the entry to the audit log and posts it to the audit
+// endpoint, if they are configured. Failing to post the entry is only logged,
+// so that an unavailable endpoint does not block merges.
+func recordAudit(entry *auditEntry) error {
+	if auditLogPath == "" && auditEndpoint == "" {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if auditLogPath != "" {
+		f, err := os.OpenFile(auditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("unable to open audit log: %w", err)
+		}
+		_, err = f.Write(append(data, '\n'))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("unable to write audit log: %w", err)
+		}
+	}
+	if auditEndpoint != "" {
+		if err := postAudit(auditEndpoint, data); err != nil {
+			klog.ErrorS(err, "Failed to send audit entry", "endpoint", auditEndpoint)
+		}
+	}
+	return nil
+}
+
+func postAudit(endpoint string, data []byte) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
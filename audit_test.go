@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuditEntry(t *testing.T) {
+	defer func(id string) { runID = id }(runID)
+	runID = "run"
+	os.Setenv("GITHUB_ACTOR", "octocat")
+	defer os.Unsetenv("GITHUB_ACTOR")
+	contention := false
+	newResult := func(name string, ratio float64) result {
+		return result{
+			Benchmark:    Benchmark{UniqueName: name, BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}},
+			RatioNsPerOp: ratio,
+		}
+	}
+	head := &refResults{name: "HEAD", run: &RunResult{Commit: "abc"}}
+	base := &refResults{name: "main", run: &RunResult{Commit: "def"}, policy: policyWarn}
+	accepted := []acceptedRegression{{Benchmark: "BenchmarkBar", Ratio: 0.3, Author: "alice"}}
+
+	entry := newAuditEntry(head, []comparison{
+		{base, []result{newResult("BenchmarkFoo", 0.25), newResult("BenchmarkBar", 0.05)}, false},
+		{nil, nil, false},
+	}, accepted)
+	assert.Equal(t, "run", entry.RunID)
+	assert.Equal(t, "octocat", entry.Actor)
+	assert.Equal(t, auditRef{Name: "HEAD", Commit: "abc"}, entry.Head)
+	assert.Equal(t, []auditComparison{{
+		Base:        auditRef{Name: "main", Commit: "def"},
+		Policy:      policyWarn,
+		Regressions: []string{"BenchmarkFoo: ns/op +25.00%"},
+	}}, entry.Comparisons)
+	assert.Equal(t, accepted, entry.Accepted)
+	assert.Equal(t, "WARN", entry.Verdict)
+}
+
+func TestRecordAudit(t *testing.T) {
+	defer func(path, endpoint string) { auditLogPath, auditEndpoint = path, endpoint }(auditLogPath, auditEndpoint)
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		posted = append(posted, string(data))
+	}))
+	defer server.Close()
+
+	auditLogPath = filepath.Join(dir, "audit.jsonl")
+	auditEndpoint = server.URL
+	entry := &auditEntry{Time: time.Date(2021, 10, 14, 0, 0, 0, 0, time.UTC), RunID: "run", Verdict: "PASS"}
+	require.NoError(t, recordAudit(entry))
+	require.NoError(t, recordAudit(entry))
+
+	data, err := ioutil.ReadFile(auditLogPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var decoded auditEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &decoded))
+	assert.Equal(t, *entry, decoded)
+	assert.Equal(t, lines, posted)
+}
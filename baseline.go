@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/tools/benchmark/parse"
+	"k8s.io/klog/v2"
+)
+
+// currentSchemaVersion is the version of the format used to save results.
+// Version 1 was the unversioned format, which serialized parse.Benchmark
+// fields as is. Bump it whenever the format changes in an incompatible way,
+// and add the corresponding migration.
+const currentSchemaVersion = 2
+
+// migrations[i] converts a decoded document from schema version i+1 to i+2.
+var migrations = []func(doc map[string]interface{}) error{
+	migrateV1ToV2,
+}
+
+// baseline is a set of benchmark results saved to a file, which later runs can
+// be compared against.
+type baseline struct {
+	SchemaVersion int `json:"schemaVersion"`
+	// RunID identifies the run which saved the baseline.
+	RunID string `json:"runID,omitempty"`
+	// Meta is the metadata of the run which saved the baseline.
+	Meta map[string]string `json:"meta,omitempty"`
+	// Benchci is the version of benchci which saved the baseline.
+	Benchci versionInfo `json:"benchci"`
+	// Platform is the platform on which the results were collected.
+	Platform platformInfo `json:"platform"`
+	Results  Set          `json:"results"`
+}
+
+// storedResult is the serialized form of a benchResult.
+type storedResult struct {
+	Name              string   `json:"name"`
+	Iterations        int      `json:"iterations"`
+	NsPerOp           float64  `json:"nsPerOp"`
+	AllocedBytesPerOp uint64   `json:"allocedBytesPerOp"`
+	AllocsPerOp       uint64   `json:"allocsPerOp"`
+	MBPerS            float64  `json:"mbPerS"`
+	Measured          int      `json:"measured"`
+	ContentionNs      *float64 `json:"contentionNs,omitempty"`
+	PeakHeapBytes     *float64 `json:"peakHeapBytes,omitempty"`
+	// Metrics are the custom metrics, by unit.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// Samples are only stored when the benchmark was run more than once.
+	Samples []storedSample `json:"samples,omitempty"`
+}
+
+// storedSample is the serialized form of one run of a benchmark.
+type storedSample struct {
+	Iterations        int     `json:"iterations"`
+	NsPerOp           float64 `json:"nsPerOp"`
+	AllocedBytesPerOp uint64  `json:"allocedBytesPerOp"`
+	AllocsPerOp       uint64  `json:"allocsPerOp"`
+	MBPerS            float64 `json:"mbPerS"`
+	Measured          int     `json:"measured"`
+}
+
+func (r *benchResult) MarshalJSON() ([]byte, error) {
+	s := storedResult{
+		Name:              r.Name,
+		Iterations:        r.N,
+		NsPerOp:           r.NsPerOp,
+		AllocedBytesPerOp: r.AllocedBytesPerOp,
+		AllocsPerOp:       r.AllocsPerOp,
+		MBPerS:            r.MBPerS,
+		Measured:          r.Measured,
+		Metrics:           r.Metrics,
+	}
+	if r.ContentionMeasured {
+		s.ContentionNs = &r.ContentionNs
+	}
+	if r.MemStatsMeasured {
+		s.PeakHeapBytes = &r.PeakHeapBytes
+	}
+	for _, sample := range r.Samples {
+		s.Samples = append(s.Samples, storedSample{
+			Iterations:        sample.N,
+			NsPerOp:           sample.NsPerOp,
+			AllocedBytesPerOp: sample.AllocedBytesPerOp,
+			AllocsPerOp:       sample.AllocsPerOp,
+			MBPerS:            sample.MBPerS,
+			Measured:          sample.Measured,
+		})
+	}
+	return json.Marshal(s)
+}
+
+func (r *benchResult) UnmarshalJSON(data []byte) error {
+	var s storedResult
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	r.Benchmark = &parse.Benchmark{
+		Name:              s.Name,
+		N:                 s.Iterations,
+		NsPerOp:           s.NsPerOp,
+		AllocedBytesPerOp: s.AllocedBytesPerOp,
+		AllocsPerOp:       s.AllocsPerOp,
+		MBPerS:            s.MBPerS,
+		Measured:          s.Measured,
+	}
+	r.Metrics = s.Metrics
+	if s.ContentionNs != nil {
+		r.ContentionNs = *s.ContentionNs
+		r.ContentionMeasured = true
+	}
+	if s.PeakHeapBytes != nil {
+		r.PeakHeapBytes = *s.PeakHeapBytes
+		r.MemStatsMeasured = true
+	}
+	for _, sample := range s.Samples {
+		r.Samples = append(r.Samples, &parse.Benchmark{
+			Name:              s.Name,
+			N:                 sample.Iterations,
+			NsPerOp:           sample.NsPerOp,
+			AllocedBytesPerOp: sample.AllocedBytesPerOp,
+			AllocsPerOp:       sample.AllocsPerOp,
+			MBPerS:            sample.MBPerS,
+			Measured:          sample.Measured,
+		})
+	}
+	return nil
+}
+
+func migrateV1ToV2(doc map[string]interface{}) error {
+	results, _ := doc["results"].(map[string]interface{})
+	for name, r := range results {
+		old, ok := r.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid result for '%s'", name)
+		}
+		result := map[string]interface{}{
+			"name":              old["Name"],
+			"iterations":        old["N"],
+			"nsPerOp":           old["NsPerOp"],
+			"allocedBytesPerOp": old["AllocedBytesPerOp"],
+			"allocsPerOp":       old["AllocsPerOp"],
+			"mbPerS":            old["MBPerS"],
+			"measured":          old["Measured"],
+		}
+		if measured, _ := old["ContentionMeasured"].(bool); measured {
+			result["contentionNs"] = old["ContentionNs"]
+		}
+		results[name] = result
+	}
+	return nil
+}
+
+// migrate upgrades a decoded document to the current schema version.
+func migrate(doc map[string]interface{}) error {
+	version := 1
+	if v, ok := doc["schemaVersion"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 1 {
+			return fmt.Errorf("invalid schema version %v", v)
+		}
+		version = int(f)
+	}
+	if version > currentSchemaVersion {
+		return fmt.Errorf("schema version %d is not supported by this version of benchci (latest supported: %d)", version, currentSchemaVersion)
+	}
+	for ; version < currentSchemaVersion; version++ {
+		if err := migrations[version-1](doc); err != nil {
+			return fmt.Errorf("unable to migrate from schema version %d: %w", version, err)
+		}
+	}
+	doc["schemaVersion"] = currentSchemaVersion
+	return nil
+}
+
+func decodeBaseline(data []byte) (*baseline, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if err := migrate(doc); err != nil {
+		return nil, err
+	}
+	// Round-trip through JSON to decode the migrated document.
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	b := &baseline{}
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func loadBaseline(path string) (*baseline, error) {
+	var data []byte
+	var err error
+	if isOCIReference(path) {
+		if data, err = pullOCIArtifact(path, baselineArtifactType); err != nil {
+			return nil, fmt.Errorf("unable to pull baseline: %w", err)
+		}
+	} else if isRemoteBaseline(path) {
+		cacheDir := baselineCacheDir
+		if cacheDir == "" {
+			if cacheDir, err = defaultBaselineCacheDir(); err != nil {
+				return nil, fmt.Errorf("unable to determine the baseline cache directory: %w", err)
+			}
+		}
+		if data, err = fetchBaseline(path, cacheDir); err != nil {
+			return nil, err
+		}
+	} else if data, err = ioutil.ReadFile(path); err != nil {
+		return nil, fmt.Errorf("unable to read baseline: %w", err)
+	}
+	b, err := decodeBaseline(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse baseline '%s': %w", path, err)
+	}
+	return b, nil
+}
+
+// writeFileAtomic writes data to a temporary file which is then renamed, so
+// that concurrent readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	return err
+}
+
+// encodeBaseline serializes a baseline for the current run.
+func encodeBaseline(b *baseline) ([]byte, error) {
+	b.SchemaVersion = currentSchemaVersion
+	b.RunID = runID
+	b.Meta = runMetadata()
+	return json.MarshalIndent(b, "", "  ")
+}
+
+func saveBaseline(path string, b *baseline) error {
+	data, err := encodeBaseline(b)
+	if err != nil {
+		return err
+	}
+	if isOCIReference(path) {
+		if _, err := pushOCIArtifact(path, baselineArtifactType, baselineArtifactType, "baseline.json", data); err != nil {
+			return fmt.Errorf("unable to push baseline: %w", err)
+		}
+		return nil
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("unable to write baseline: %w", err)
+	}
+	return nil
+}
+
+// improvedBaseline returns the baseline updated with the current results if at
+// least one benchmark improved significantly and none regressed (which is
+// possible with the "warn" policy), or nil otherwise. Results which are only
+// present in the old baseline are preserved.
+func improvedBaseline(old *baseline, set Set, entries []Benchmark) *baseline {
+	improved := false
+	for _, benchmark := range entries {
+		headBench, ok := set[benchmark.UniqueName]
+		if !ok {
+			continue
+		}
+		baseBench, ok := old.Results[benchmark.UniqueName]
+		if !ok {
+			continue
+		}
+		result := newResult(benchmark, headBench, baseBench)
+		if isRegression(result) {
+			return nil
+		}
+		if isImprovement(result) {
+			klog.InfoS("Benchmark improved on the baseline", "benchmark", benchmark.UniqueName)
+			improved = true
+		}
+	}
+	if !improved {
+		return nil
+	}
+	results := Set{}
+	for name, r := range old.Results {
+		results[name] = r
+	}
+	for name, r := range set {
+		results[name] = r
+	}
+	return &baseline{Benchci: currentVersion(), Platform: currentPlatform(), Results: results}
+}
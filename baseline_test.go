@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestDecodeBaseline(t *testing.T) {
+	v1 := `{
+  "benchci": {"version": "v0.2.0", "commit": "abc", "date": "2021-10-14"},
+  "results": {
+    "BenchmarkFoo": {"Name": "BenchmarkFoo-4", "N": 1000, "NsPerOp": 839.4, "AllocedBytesPerOp": 112, "AllocsPerOp": 1, "MBPerS": 0, "Measured": 13, "Ord": 0, "ContentionNs": 0, "ContentionMeasured": false},
+    "BenchmarkBar": {"Name": "BenchmarkBar-4", "N": 10, "NsPerOp": 10, "AllocedBytesPerOp": 0, "AllocsPerOp": 0, "MBPerS": 0, "Measured": 1, "Ord": 1, "ContentionNs": 2000, "ContentionMeasured": true}
+  }
+}`
+	b, err := decodeBaseline([]byte(v1))
+	require.NoError(t, err)
+	assert.Equal(t, currentSchemaVersion, b.SchemaVersion)
+	assert.Equal(t, "v0.2.0", b.Benchci.Version)
+	assert.Equal(t, &benchResult{
+		Benchmark: &parse.Benchmark{Name: "BenchmarkFoo-4", N: 1000, NsPerOp: 839.4, AllocedBytesPerOp: 112, AllocsPerOp: 1, Measured: 13},
+	}, b.Results["BenchmarkFoo"])
+	assert.Equal(t, &benchResult{
+		Benchmark:          &parse.Benchmark{Name: "BenchmarkBar-4", N: 10, NsPerOp: 10, Measured: 1},
+		ContentionNs:       2000,
+		ContentionMeasured: true,
+	}, b.Results["BenchmarkBar"])
+
+	_, err = decodeBaseline([]byte(`{"schemaVersion": 100, "results": {}}`))
+	assert.Error(t, err)
+}
+
+func TestImprovedBaseline(t *testing.T) {
+	contention := false
+	entry := func(name string) Benchmark {
+		return Benchmark{Name: name, UniqueName: name, BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}}
+	}
+	result := func(nsPerOp float64) *benchResult {
+		return &benchResult{Benchmark: &parse.Benchmark{NsPerOp: nsPerOp}}
+	}
+	old := &baseline{Results: Set{"BenchmarkFoo": result(100), "BenchmarkBar": result(100), "BenchmarkOld": result(100)}}
+	entries := []Benchmark{entry("BenchmarkFoo"), entry("BenchmarkBar")}
+
+	for _, tc := range []struct {
+		name     string
+		set      Set
+		expected bool
+	}{
+		{"improvement", Set{"BenchmarkFoo": result(80), "BenchmarkBar": result(105)}, true},
+		{"within threshold", Set{"BenchmarkFoo": result(95), "BenchmarkBar": result(105)}, false},
+		{"improvement and regression", Set{"BenchmarkFoo": result(80), "BenchmarkBar": result(120)}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			updated := improvedBaseline(old, tc.set, entries)
+			if !tc.expected {
+				assert.Nil(t, updated)
+				return
+			}
+			require.NotNil(t, updated)
+			assert.Equal(t, 80.0, updated.Results["BenchmarkFoo"].NsPerOp)
+			assert.Equal(t, 105.0, updated.Results["BenchmarkBar"].NsPerOp)
+			assert.Equal(t, 100.0, updated.Results["BenchmarkOld"].NsPerOp)
+		})
+	}
+}
+
+func TestBaselineSamples(t *testing.T) {
+	samples := []*parse.Benchmark{
+		{Name: "BenchmarkFoo-4", N: 100, NsPerOp: 120, Measured: parse.NsPerOp},
+		{Name: "BenchmarkFoo-4", N: 100, NsPerOp: 100, Measured: parse.NsPerOp},
+		{Name: "BenchmarkFoo-4", N: 200, NsPerOp: 110, Measured: parse.NsPerOp},
+	}
+	data, err := json.Marshal(&baseline{SchemaVersion: currentSchemaVersion, Results: Set{"BenchmarkFoo": newBenchResult(samples), "BenchmarkBar": newBenchResult(samples[:1])}})
+	require.NoError(t, err)
+	b, err := decodeBaseline(data)
+	require.NoError(t, err)
+	assert.Equal(t, samples, b.Results["BenchmarkFoo"].Samples)
+	assert.Equal(t, samples, b.Results["BenchmarkFoo"].samples())
+	assert.Equal(t, 110.0, b.Results["BenchmarkFoo"].NsPerOp)
+	assert.Nil(t, b.Results["BenchmarkBar"].Samples)
+	assert.Equal(t, samples[:1], b.Results["BenchmarkBar"].samples())
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/src-d/go-git.v4"
+)
+
+// branchName overrides the branch used to select the baseline, which is
+// detected by default.
+var branchName string
+
+// currentBranch returns the branch whose baseline should be used. For a pull
+// request, this is the branch it targets, so that backports are compared with
+// the baseline of their release branch rather than with the one of main.
+func currentBranch() (string, error) {
+	if branchName != "" {
+		return branchName, nil
+	}
+	if base := os.Getenv("GITHUB_BASE_REF"); base != "" {
+		return base, nil
+	}
+	if os.Getenv("GITHUB_REF_TYPE") == "branch" && os.Getenv("GITHUB_REF_NAME") != "" {
+		return os.Getenv("GITHUB_REF_NAME"), nil
+	}
+	r, err := git.PlainOpen(".")
+	if err != nil {
+		return "", fmt.Errorf("unable to open the git repository to detect the branch, use --branch: %w", err)
+	}
+	head, err := r.Head()
+	if err != nil {
+		return "", fmt.Errorf("unable to get HEAD to detect the branch, use --branch: %w", err)
+	}
+	if !head.Name().IsBranch() {
+		return "", fmt.Errorf("HEAD is detached, use --branch to select the baseline")
+	}
+	return head.Name().Short(), nil
+}
+
+// expandBaselinePath expands the {{.Branch}} variable in the path of a
+// baseline, e.g. "baselines/{{.Branch}}.json" or
+// "oci://ghcr.io/antrea-io/benchci-baselines:{{.Branch}}". The branch is
+// sanitized, "release/1.12" becoming "release_1.12", so that it can be used in
+// file names and tags.
+func expandBaselinePath(path string) (string, error) {
+	if !strings.Contains(path, "{{") {
+		return path, nil
+	}
+	tmpl, err := template.New("baseline").Option("missingkey=error").Parse(path)
+	if err != nil {
+		return "", fmt.Errorf("invalid baseline path '%s': %w", path, err)
+	}
+	branch, err := currentBranch()
+	if err != nil {
+		return "", err
+	}
+	var expanded strings.Builder
+	if err := tmpl.Execute(&expanded, struct{ Branch string }{sanitizePathElem(branch)}); err != nil {
+		return "", fmt.Errorf("invalid baseline path '%s': %w", path, err)
+	}
+	return expanded.String(), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandBaselinePath(t *testing.T) {
+	defer func(b string) { branchName = b }(branchName)
+	for _, name := range []string{"GITHUB_BASE_REF", "GITHUB_REF_TYPE", "GITHUB_REF_NAME"} {
+		defer func(name, value string) { os.Setenv(name, value) }(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+
+	branchName = ""
+	path, err := expandBaselinePath("baselines/main.json")
+	require.NoError(t, err)
+	assert.Equal(t, "baselines/main.json", path)
+
+	os.Setenv("GITHUB_REF_TYPE", "branch")
+	os.Setenv("GITHUB_REF_NAME", "release-1.12")
+	path, err = expandBaselinePath("baselines/{{.Branch}}.json")
+	require.NoError(t, err)
+	assert.Equal(t, "baselines/release-1.12.json", path)
+
+	// pull requests use the baseline of the branch they target
+	os.Setenv("GITHUB_BASE_REF", "release-1.13")
+	path, err = expandBaselinePath("oci://ghcr.io/antrea-io/benchci-baselines:{{.Branch}}")
+	require.NoError(t, err)
+	assert.Equal(t, "oci://ghcr.io/antrea-io/benchci-baselines:release-1.13", path)
+
+	branchName = "release/1.12"
+	path, err = expandBaselinePath("baselines/{{.Branch}}.json")
+	require.NoError(t, err)
+	assert.Equal(t, "baselines/release_1.12.json", path)
+
+	_, err = expandBaselinePath("baselines/{{.Tag}}.json")
+	assert.Error(t, err)
+	_, err = expandBaselinePath("baselines/{{.Branch}.json")
+	assert.Error(t, err)
+}
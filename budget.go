@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ratioValue is a flag.Value for ratios, which can be given either as a
+// percentage ("5%") or as a ratio ("0.05").
+type ratioValue float64
+
+func (r *ratioValue) String() string {
+	if r == nil || *r == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(100*float64(*r), 'f', -1, 64) + "%"
+}
+
+func (r *ratioValue) Set(s string) error {
+	s = strings.TrimSpace(s)
+	percent := strings.HasSuffix(s, "%")
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+	if err != nil {
+		return fmt.Errorf("invalid ratio '%s'", s)
+	}
+	if percent {
+		v /= 100
+	}
+	*r = ratioValue(v)
+	return nil
+}
+
+var totalBudget ratioValue
+
+// comparedRatios returns the ratios of all the metrics compared for a
+// benchmark.
+func comparedRatios(result result) []float64 {
+	comparedScore := whichScoreToCompare(result.Compare)
+	var ratios []float64
+	if comparedScore.nsPerOp {
+		ratios = append(ratios, result.RatioNsPerOp)
+	}
+	if comparedScore.allocedBytesPerOp {
+		ratios = append(ratios, result.RatioAllocedBytesPerOp)
+	}
+	if comparedScore.allocsPerOp {
+		ratios = append(ratios, result.RatioAllocsPerOp)
+	}
+	// the throughput is higher-is-better, its worsening is the change of the
+	// time per byte, as for custom metrics
+	if comparedScore.mbPerS && result.RatioMBPerS > -1 {
+		ratios = append(ratios, 1/(1+result.RatioMBPerS)-1)
+	}
+	if comparedScore.contention && *result.Contention {
+		ratios = append(ratios, result.RatioContention)
+	}
+	if comparedScore.heap && *result.MemStats {
+		ratios = append(ratios, result.RatioPeakHeap)
+	}
+	for _, unit := range comparedScore.custom {
+		if ratio, ok := result.RatioMetrics[unit]; ok {
+			ratios = append(ratios, worsening(unit, ratio))
+		}
+	}
+	return ratios
+}
+
+// aggregateRatio returns the weighted geometric mean of the compared ratios
+// across all benchmarks, as a ratio (e.g. 0.05 for a 5% aggregate slowdown).
+func aggregateRatio(results []result) float64 {
+	var sum, totalWeight float64
+	for _, result := range results {
+		weight := result.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		for _, ratio := range comparedRatios(result) {
+			// A ratio of -1 means the metric dropped to 0, which cannot be
+			// part of a geometric mean.
+			if ratio <= -1 {
+				continue
+			}
+			sum += weight * math.Log1p(ratio)
+			totalWeight += weight
+		}
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+	return math.Expm1(sum / totalWeight)
+}
+
+// showAggregate prints the aggregate ratio and returns true if it exceeds the
+// total budget.
+func showAggregate(w io.Writer, results []result, compareWith string) bool {
+	ratio := aggregateRatio(results)
+	exceeded := ratio > float64(totalBudget)
+	verdict := "within budget"
+	if exceeded {
+		verdict = "budget exceeded"
+	}
+	fmt.Fprintf(w, "Aggregate change compared with %s: %s (budget: %s, %s)\n\n", compareWith, signedRatio(ratio), totalBudget.String(), verdict)
+	return exceeded
+}
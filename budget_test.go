@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatioValue(t *testing.T) {
+	var r ratioValue
+	require.NoError(t, r.Set("5%"))
+	assert.InDelta(t, 0.05, float64(r), 1e-9)
+	require.NoError(t, r.Set("0.1"))
+	assert.InDelta(t, 0.1, float64(r), 1e-9)
+	assert.Equal(t, "10%", r.String())
+	assert.Error(t, r.Set("ten"))
+}
+
+func TestAggregateRatio(t *testing.T) {
+	config := BenchmarkConfiguration{Compare: "ns/op", Contention: new(bool)}
+	results := []result{
+		{Benchmark: Benchmark{BenchmarkConfiguration: config}, RatioNsPerOp: 0.21},
+		{Benchmark: Benchmark{BenchmarkConfiguration: config}, RatioNsPerOp: -0.1},
+		{Benchmark: Benchmark{BenchmarkConfiguration: config, Weight: 2}, RatioNsPerOp: 0},
+	}
+	// (1.21 * 0.9 * 1 * 1) ^ (1/4) - 1
+	assert.InDelta(t, 0.0215, aggregateRatio(results), 1e-4)
+	assert.Equal(t, 0.0, aggregateRatio(nil))
+
+	// doubling the throughput halves the time per byte, which offsets a
+	// doubling of ns/op
+	throughput := BenchmarkConfiguration{Compare: "ns/op,MB/s", Contention: new(bool)}
+	results = []result{
+		{Benchmark: Benchmark{BenchmarkConfiguration: throughput}, RatioNsPerOp: 1, RatioMBPerS: 1},
+	}
+	assert.InDelta(t, 0, aggregateRatio(results), 1e-9)
+	// (1.21 / 1.1) ^ (1/2) - 1
+	results[0].RatioNsPerOp, results[0].RatioMBPerS = 0.21, 0.1
+	assert.InDelta(t, 0.0488, aggregateRatio(results), 1e-4)
+}
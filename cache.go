@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+)
+
+var (
+	resultCacheDir string
+	noCache        bool
+)
+
+// cachedRun is a run of a commit saved in the result cache, with the
+// fingerprint of the settings it was run with.
+type cachedRun struct {
+	Fingerprint string     `json:"fingerprint"`
+	Run         *RunResult `json:"run"`
+}
+
+// defaultResultCacheDir returns the directory in which the results of the
+// base ref and of the latest release are cached when -result-cache is not
+// set.
+func defaultResultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "benchci", "results"), nil
+}
+
+// cacheFingerprint identifies everything besides the source of a commit which
+// affects its results: the benchmarks with their settings, the files carried
+// from HEAD, the hooks, the toolchain, the go version of the ref and the
+// machine. A cached run is only used if it was run with the same fingerprint,
+// so that changing the configuration or upgrading Go invalidates the cache.
+func cacheFingerprint(tagVersion, goVersion string) (string, error) {
+	data, err := json.Marshal(struct {
+		Command    string
+		Benchmarks []Benchmark
+		CarryFiles []string
+		CarryHash  string
+		Build      []CommandStep
+		Setup      []CommandStep
+		Teardown   []CommandStep
+		ReleaseEnv *ReleaseEnv
+		Toolchain  string
+		TagVersion string
+		GoVersion  string
+		Seed       int64
+		Platform   platformInfo
+	}{
+		Command:    benchmarks.Command,
+		Benchmarks: benchmarks.Benchmarks,
+		CarryFiles: benchmarks.CarryFiles,
+		CarryHash:  carriedHash,
+		Build:      benchmarks.Build,
+		Setup:      benchmarks.Setup,
+		Teardown:   benchmarks.Teardown,
+		ReleaseEnv: benchmarks.ReleaseEnv,
+		Toolchain:  toolchain,
+		TagVersion: tagVersion,
+		GoVersion:  goVersion,
+		Seed:       seed,
+		Platform:   currentPlatform(),
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cacheEnabled returns false with -no-cache, or when flamegraphs or traces are
+// collected since they are not cached.
+func cacheEnabled() bool {
+	if noCache {
+		return false
+	}
+	for _, benchmark := range benchmarks.Benchmarks {
+		if *benchmark.Flamegraph || *benchmark.Trace {
+			return false
+		}
+	}
+	return true
+}
+
+func resultCachePath(commit string) (string, error) {
+	dir := resultCacheDir
+	if dir == "" {
+		var err error
+		if dir, err = defaultResultCacheDir(); err != nil {
+			return "", fmt.Errorf("unable to determine the result cache directory: %w", err)
+		}
+	}
+	return filepath.Join(dir, commit+".json"), nil
+}
+
+// refGoVersion returns the output of "go version" in the environment the
+// benchmarks of a ref are run with, see refEnv.
+func refGoVersion(dir string, release bool) (string, error) {
+	env, cleanup, err := refEnv(dir, release)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+	return goVersion(goCommand(&benchmarks.BenchmarkConfiguration), dir, env), nil
+}
+
+// loadCachedRun returns the cached run of a commit, or nil if there is none or
+// if it was run with other settings or another go version.
+func loadCachedRun(ref, commit, tagVersion, goVersion string) *RunResult {
+	if !cacheEnabled() {
+		return nil
+	}
+	path, err := resultCachePath(commit)
+	if err != nil {
+		klog.ErrorS(err, "Failed to read the result cache")
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			klog.ErrorS(err, "Failed to read the result cache", "path", path)
+		}
+		return nil
+	}
+	fingerprint, err := cacheFingerprint(tagVersion, goVersion)
+	if err != nil {
+		klog.ErrorS(err, "Failed to compute the fingerprint of the configuration")
+		return nil
+	}
+	var cached cachedRun
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Run == nil {
+		klog.InfoS("Ignoring invalid cached results", "path", path, "err", err)
+		return nil
+	}
+	if cached.Fingerprint != fingerprint {
+		klog.InfoS("Ignoring cached results, the configuration or the go version changed", "ref", ref, "commit", commit)
+		return nil
+	}
+	klog.InfoS("Using cached results", "ref", ref, "commit", commit, "path", path)
+	cached.Run.Ref = ref
+	return cached.Run
+}
+
+// saveCachedRun saves the run of a commit in the cache, unless some benchmarks
+// failed or were not run, which may not happen next time. Like the other
+// reporters, a failure is only logged.
+func saveCachedRun(run *RunResult, tagVersion string) {
+	if !cacheEnabled() || run.Partial || len(run.Errors) > 0 {
+		return
+	}
+	path, err := resultCachePath(run.Commit)
+	if err != nil {
+		klog.ErrorS(err, "Failed to save results to the cache")
+		return
+	}
+	fingerprint, err := cacheFingerprint(tagVersion, run.GoVersion)
+	if err != nil {
+		klog.ErrorS(err, "Failed to compute the fingerprint of the configuration")
+		return
+	}
+	data, err := json.Marshal(cachedRun{Fingerprint: fingerprint, Run: run})
+	if err == nil {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			err = writeFileAtomic(path, data)
+		}
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to save results to the cache", "path", path)
+		return
+	}
+	klog.InfoS("Saved results to the cache", "ref", run.Ref, "commit", run.Commit, "path", path)
+}
+
+// replayProgress reports the cached results of a run as if they had just been
+// measured, so that the events are the same whether a run is cached or not.
+func replayProgress(run *RunResult, progress func(Benchmark, *benchResult)) {
+	if progress == nil {
+		return
+	}
+	for _, benchmark := range benchmarks.Benchmarks {
+		if r, ok := run.Results[benchmark.UniqueName]; ok {
+			progress(benchmark, r)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestResultCache(t *testing.T) {
+	defer func(b BenchmarkList, dir string, disabled bool) {
+		*benchmarks, resultCacheDir, noCache = b, dir, disabled
+	}(*benchmarks, resultCacheDir, noCache)
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	resultCacheDir, noCache = dir, false
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo"}}}
+	require.NoError(t, updateBenchmarks())
+
+	run := newRunResult("main", "aaaa")
+	run.GoVersion = "go1.22.5"
+	run.Results["BenchmarkFoo"] = &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", NsPerOp: 100}}
+	assert.Nil(t, loadCachedRun("main", "aaaa", "", "go1.22.5"))
+	saveCachedRun(run, "")
+	assert.FileExists(t, filepath.Join(dir, "aaaa.json"))
+
+	cached := loadCachedRun("HEAD~1", "aaaa", "", "go1.22.5")
+	require.NotNil(t, cached)
+	assert.Equal(t, "HEAD~1", cached.Ref)
+	assert.Equal(t, 100.0, cached.Results["BenchmarkFoo"].NsPerOp)
+	var replayed []string
+	replayProgress(cached, func(benchmark Benchmark, r *benchResult) {
+		replayed = append(replayed, benchmark.UniqueName)
+	})
+	assert.Equal(t, []string{"BenchmarkFoo"}, replayed)
+
+	// the cache is keyed by commit and by the configuration
+	assert.Nil(t, loadCachedRun("main", "bbbb", "", "go1.22.5"))
+	assert.Nil(t, loadCachedRun("v1.2.0", "aaaa", "v1.2.0", "go1.22.5"))
+	benchtime := benchmarks.Benchmarks[0].Benchtime
+	benchmarks.Benchmarks[0].Benchtime = "100x"
+	assert.Nil(t, loadCachedRun("main", "aaaa", "", "go1.22.5"))
+	benchmarks.Benchmarks[0].Benchtime = benchtime
+	// e.g. after Go was upgraded on the runner
+	assert.Nil(t, loadCachedRun("main", "aaaa", "", "go1.23.0"))
+	noCache = true
+	assert.Nil(t, loadCachedRun("main", "aaaa", "", "go1.22.5"))
+	noCache = false
+	require.NotNil(t, loadCachedRun("main", "aaaa", "", "go1.22.5"))
+
+	// incomplete runs are not cached
+	failed := newRunResult("main", "cccc")
+	failed.fail("BenchmarkFoo", assert.AnError)
+	saveCachedRun(failed, "")
+	assert.Nil(t, loadCachedRun("main", "cccc", "", "go1.22.5"))
+}
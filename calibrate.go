@@ -0,0 +1,190 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/yaml.v2"
+	"k8s.io/klog/v2"
+)
+
+var (
+	calibrateRuns   int
+	calibrateFactor float64
+)
+
+func addCalibrateFlags(fs *flag.FlagSet) {
+	fs.IntVar(&calibrateRuns, "runs", 10, "`number` of times the whole suite is run")
+	fs.Float64Var(&calibrateFactor, "factor", 3, "recommended thresholds are this `multiple` of the coefficient of variation of ns/op")
+}
+
+// noise is the variation of the ns/op of a benchmark entry over the runs of
+// the suite.
+type noise struct {
+	benchmark Benchmark
+	values    []float64
+}
+
+func (n noise) cv() float64 {
+	m := mean(n.values)
+	if m == 0 {
+		return 0
+	}
+	return stddev(n.values) / m
+}
+
+// recommendedThreshold returns factor times the coefficient of variation,
+// rounded up to a whole percent so that it is readable in the configuration.
+func recommendedThreshold(cv, factor float64) float64 {
+	return math.Max(math.Ceil(cv*factor*100-1e-9)/100, 0.01)
+}
+
+// configuredName returns the unique name of the configured benchmark of an
+// entry, which differs for the entries expanded from a sweep or from a list of
+// cpu values.
+func configuredName(benchmark Benchmark) string {
+	switch {
+	case benchmark.sweepOf != "":
+		return benchmark.sweepOf
+	case benchmark.cpuOf != "":
+		return benchmark.cpuOf
+	default:
+		return benchmark.UniqueName
+	}
+}
+
+// recommendedEntry identifies a configured benchmark with its recommended
+// threshold, in the format of the configuration.
+type recommendedEntry struct {
+	Name       string  `yaml:"name"`
+	Package    string  `yaml:"package,omitempty"`
+	UniqueName string  `yaml:"uniqueName,omitempty"`
+	Threshold  float64 `yaml:"threshold"`
+}
+
+// showRecommendations writes the noise of each benchmark entry with its
+// recommended threshold, then the thresholds of the configured benchmarks as
+// YAML. The threshold of a configured benchmark is the largest one of its
+// entries.
+func showRecommendations(w io.Writer, noises []noise, factor float64) {
+	fmt.Fprintln(w, "\nRecommended thresholds")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 22))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"Name", "Runs", "Mean", "CV", "Threshold", "Recommended"})
+	var entries []*recommendedEntry
+	byName := map[string]*recommendedEntry{}
+	for _, n := range noises {
+		cv := n.cv()
+		recommended := recommendedThreshold(cv, factor)
+		table.Append([]string{n.benchmark.UniqueName, strconv.Itoa(len(n.values)), numbers.float(mean(n.values)) + " ns/op",
+			fmt.Sprintf("±%s", numbers.percent(cv)), numbers.percent(n.benchmark.Threshold), numbers.percent(recommended)})
+		name := configuredName(n.benchmark)
+		entry, ok := byName[name]
+		if !ok {
+			entry = &recommendedEntry{Name: n.benchmark.Name, Package: n.benchmark.Package}
+			if name != n.benchmark.Name {
+				entry.UniqueName = name
+			}
+			byName[name] = entry
+			entries = append(entries, entry)
+		}
+		entry.Threshold = math.Max(entry.Threshold, recommended)
+	}
+	table.Render()
+
+	data, err := yaml.Marshal(entries)
+	if err != nil {
+		klog.ErrorS(err, "Failed to format the recommended thresholds")
+		return
+	}
+	fmt.Fprintf(w, "\nThresholds of %s times the coefficient of variation, for the benchmarks of the configuration:\n\n", strconv.FormatFloat(factor, 'f', -1, 64))
+	w.Write(data)
+	fmt.Fprintln(w)
+}
+
+// runCalibrate runs the whole suite several times at HEAD and recommends a
+// threshold for each benchmark from how much its ns/op varies between runs,
+// so that noise is not reported as regressions.
+func runCalibrate() error {
+	if outputFormat != outputText {
+		return fmt.Errorf("calibrate only supports the %s output format", outputText)
+	}
+	if calibrateRuns < 2 {
+		return fmt.Errorf("--runs must be at least 2")
+	}
+	if calibrateFactor <= 0 {
+		return fmt.Errorf("--factor must be positive")
+	}
+	if err := parseBenchmarks(); err != nil {
+		return err
+	}
+	r, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("unable to open the git repository: %w", err)
+	}
+	head, err := r.ResolveRevision(plumbing.Revision("HEAD"))
+	if err != nil {
+		return fmt.Errorf("unable to resolve HEAD: %w", err)
+	}
+	if err := discoverBenchmarks(""); err != nil {
+		return err
+	}
+	if err := updateBenchmarks(); err != nil {
+		return err
+	}
+	if err := prepareArtifactsDir(); err != nil {
+		return err
+	}
+
+	checkouts, err := newWorktrees(".")
+	if err != nil {
+		return err
+	}
+	defer checkouts.remove()
+	dir, _, err := checkouts.checkout(*head)
+	if err != nil {
+		return err
+	}
+	runs := make([]*RunResult, 0, calibrateRuns)
+	for i := 0; i < calibrateRuns; i++ {
+		klog.InfoS("Run Benchmark", "commitHash", head, "dir", dir, "run", i+1, "runs", calibrateRuns)
+		run, err := runBenchmarks(dir, "HEAD", head.String(), "", nil)
+		if err != nil {
+			return fmt.Errorf("failed to run a benchmark: %w", err)
+		}
+		runs = append(runs, run)
+	}
+
+	var noises []noise
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" || benchmark.Calibration {
+			continue
+		}
+		n := noise{benchmark: benchmark}
+		for _, run := range runs {
+			if b, ok := run.Results[benchmark.UniqueName]; ok {
+				n.values = append(n.values, b.NsPerOp)
+			}
+		}
+		if len(n.values) < 2 {
+			klog.InfoS("Not enough results to measure the noise of benchmark", "benchmark", benchmark.UniqueName, "results", len(n.values))
+			continue
+		}
+		noises = append(noises, n)
+	}
+	if len(noises) == 0 {
+		return fmt.Errorf("no benchmark has enough results to recommend a threshold")
+	}
+	showRecommendations(report, noises, calibrateFactor)
+	return nil
+}
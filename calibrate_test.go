@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecommendedThreshold(t *testing.T) {
+	assert.Equal(t, 0.07, recommendedThreshold(0.0201, 3))
+	assert.Equal(t, 0.06, recommendedThreshold(0.02, 3))
+	assert.Equal(t, 0.01, recommendedThreshold(0, 3))
+}
+
+func TestShowRecommendations(t *testing.T) {
+	foo := Benchmark{Name: "BenchmarkFoo", Package: "antrea.io/antrea/pkg/foo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1}}
+	bar1 := Benchmark{Name: "BenchmarkBar", Package: "antrea.io/antrea/pkg/bar", UniqueName: "BenchmarkBar/size=1", sweepOf: "BenchmarkBar"}
+	bar2 := Benchmark{Name: "BenchmarkBar", Package: "antrea.io/antrea/pkg/bar", UniqueName: "BenchmarkBar/size=2", sweepOf: "BenchmarkBar"}
+	noises := []noise{
+		{foo, []float64{100, 100, 100, 100}},
+		{bar1, []float64{98, 100, 102}},
+		{bar2, []float64{90, 100, 110}},
+	}
+	assert.InDelta(t, 0.1, noises[2].cv(), 1e-9)
+
+	var b bytes.Buffer
+	showRecommendations(&b, noises, 3)
+	assert.Contains(t, b.String(), "BenchmarkBar/size=2")
+	assert.Contains(t, b.String(), `- name: BenchmarkFoo
+  package: antrea.io/antrea/pkg/foo
+  threshold: 0.01
+- name: BenchmarkBar
+  package: antrea.io/antrea/pkg/bar
+  threshold: 0.3
+`)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"k8s.io/klog/v2"
+)
+
+// calibrationBenchmark returns the unique name of the calibration benchmark,
+// or an empty string if there is none.
+func calibrationBenchmark() string {
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Calibration && benchmark.Skip == "" {
+			return benchmark.UniqueName
+		}
+	}
+	return ""
+}
+
+// calibrationScale returns the factor by which the ns/op values of head are
+// multiplied to compensate for the speed difference between the machines (or
+// runs) which produced head and other, as measured by the calibration
+// benchmark. It returns false if the calibration benchmark is not configured
+// or not available for both refs.
+func calibrationScale(head, other Set) (float64, bool) {
+	name := calibrationBenchmark()
+	if name == "" {
+		return 1, false
+	}
+	headBench, headOk := head[name]
+	otherBench, otherOk := other[name]
+	if !headOk || !otherOk || headBench.NsPerOp == 0 || otherBench.NsPerOp == 0 {
+		klog.InfoS("Calibration benchmark is missing, results are not normalized", "benchmark", name)
+		return 1, false
+	}
+	return otherBench.NsPerOp / headBench.NsPerOp, true
+}
+
+// scaleNsPerOp returns a copy of a result with ns/op multiplied by scale and
+// MB/s, which is inversely proportional to the duration, divided by scale.
+func scaleNsPerOp(b *benchResult, scale float64) *benchResult {
+	scaled := *b
+	benchmark := *b.Benchmark
+	benchmark.NsPerOp *= scale
+	benchmark.MBPerS /= scale
+	scaled.Benchmark = &benchmark
+	scaled.Samples = nil
+	for _, sample := range b.Samples {
+		s := *sample
+		s.NsPerOp *= scale
+		s.MBPerS /= scale
+		scaled.Samples = append(scaled.Samples, &s)
+	}
+	return &scaled
+}
+
+func showCalibration(w io.Writer, scale float64, name string) {
+	fmt.Fprintf(w, "\nns/op ratios with %s are normalized with calibration benchmark %s, which changed by %s\n", name, calibrationBenchmark(), signedRatio(1/scale-1))
+}
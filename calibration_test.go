@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestCalibrationScale(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	newSet := func(calibration, foo float64) Set {
+		return Set{
+			"calibration": {Benchmark: &parse.Benchmark{NsPerOp: calibration}},
+			"foo":         {Benchmark: &parse.Benchmark{NsPerOp: foo}},
+		}
+	}
+	head, base := newSet(120, 240), newSet(100, 200)
+	head["foo"].MBPerS, base["foo"].MBPerS = 50, 60
+
+	benchmarks.Benchmarks = []Benchmark{{UniqueName: "foo"}}
+	scale, ok := calibrationScale(head, base)
+	assert.False(t, ok)
+	assert.Equal(t, 1.0, scale)
+
+	benchmarks.Benchmarks = append(benchmarks.Benchmarks, Benchmark{UniqueName: "calibration", Calibration: true})
+	scale, ok = calibrationScale(head, base)
+	assert.True(t, ok)
+	scaled := scaleNsPerOp(head["foo"], scale)
+	assert.InDelta(t, 200, scaled.NsPerOp, 1e-9)
+	assert.Equal(t, 240.0, head["foo"].NsPerOp)
+	assert.InDelta(t, 60, scaled.MBPerS, 1e-9)
+	assert.Equal(t, 0.0, newResult(Benchmark{}, scaled, base["foo"]).RatioNsPerOp)
+	assert.InDelta(t, 0, newResult(Benchmark{}, scaled, base["foo"]).RatioMBPerS, 1e-9)
+
+	var b bytes.Buffer
+	showCalibration(&b, scale, "base")
+	assert.Equal(t, "\nns/op ratios with base are normalized with calibration benchmark calibration, which changed by +20.00%\n", b.String())
+
+	delete(base, "calibration")
+	_, ok = calibrationScale(head, base)
+	assert.False(t, ok)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"k8s.io/klog/v2"
+)
+
+// carriedFile is a file from HEAD which is copied to other checkouts.
+type carriedFile struct {
+	path     string
+	mode     os.FileMode
+	contents string
+}
+
+// carriedHash is the hash of the files carried from HEAD. It is part of the
+// cache fingerprint: the carried files are used by every ref, so changing them
+// in HEAD affects the results of the cached refs too.
+var carriedHash string
+
+// hashCarriedFiles returns a hash of the paths, modes and contents of files.
+func hashCarriedFiles(files []carriedFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	h := sha256.New()
+	for _, f := range files {
+		fmt.Fprintf(h, "%s %v %d\n", f.path, f.mode, len(f.contents))
+		h.Write([]byte(f.contents))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// matchesCarryPath returns true if file is one of the paths, or is in one of
+// the directories.
+func matchesCarryPath(file string, paths []string) bool {
+	for _, p := range paths {
+		p = strings.TrimSuffix(path.Clean(filepath.ToSlash(p)), "/")
+		if file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readCarriedFiles reads the files of a commit which match the carryFiles
+// paths of the configuration.
+func readCarriedFiles(r *git.Repository, commit plumbing.Hash) ([]carriedFile, error) {
+	if len(benchmarks.CarryFiles) == 0 {
+		return nil, nil
+	}
+	c, err := r.CommitObject(commit)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get commit %v: %w", commit, err)
+	}
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the tree of commit %v: %w", commit, err)
+	}
+	var files []carriedFile
+	err = tree.Files().ForEach(func(f *object.File) error {
+		if !matchesCarryPath(f.Name, benchmarks.CarryFiles) {
+			return nil
+		}
+		contents, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		mode, err := f.Mode.ToOSFileMode()
+		if err != nil {
+			return err
+		}
+		files = append(files, carriedFile{path: f.Name, mode: mode, contents: contents})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the files to carry: %w", err)
+	}
+	if len(files) == 0 {
+		klog.InfoS("No files to carry found in HEAD", "carryFiles", benchmarks.CarryFiles)
+	}
+	return files, nil
+}
+
+// writeCarriedFiles writes files carried from HEAD to the checkout in dir.
+func writeCarriedFiles(dir string, files []carriedFile) error {
+	for _, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f.path))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(p, []byte(f.contents), f.mode.Perm()); err != nil {
+			return fmt.Errorf("unable to carry '%s': %w", f.path, err)
+		}
+	}
+	if len(files) > 0 {
+		klog.InfoS("Carried files from HEAD", "count", len(files))
+	}
+	return nil
+}
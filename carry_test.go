@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchesCarryPath(t *testing.T) {
+	paths := []string{"testdata/", "config/fixture.yaml", "./pkg/foo/testdata"}
+	for file, expected := range map[string]bool{
+		"testdata/a.json":         true,
+		"testdata":                true,
+		"testdata2/a.json":        false,
+		"config/fixture.yaml":     true,
+		"config/other.yaml":       false,
+		"pkg/foo/testdata/a.json": true,
+		"pkg/bar/testdata/a.json": false,
+	} {
+		assert.Equal(t, expected, matchesCarryPath(file, paths), file)
+	}
+}
+
+func TestCarriedHashFingerprint(t *testing.T) {
+	defer func(h string) { carriedHash = h }(carriedHash)
+	fixture := []carriedFile{{path: "testdata/a.json", mode: 0644, contents: "{}"}}
+	carriedHash = hashCarriedFiles(fixture)
+	before, err := cacheFingerprint("", "go1.22.5")
+	require.NoError(t, err)
+
+	// the results cached with other fixtures are not used
+	fixture[0].contents = `{"size": 1000}`
+	carriedHash = hashCarriedFiles(fixture)
+	after, err := cacheFingerprint("", "go1.22.5")
+	require.NoError(t, err)
+	assert.NotEqual(t, before, after)
+
+	assert.Empty(t, hashCarriedFiles(nil))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// changePoint looks for the most likely step change in a history of values,
+// with a single binary segmentation: the split maximizing the weighted
+// squared difference of the means before and after it. It returns the index
+// of the first value after the change and the relative change of the mean,
+// or false if there are less than 3 values.
+func changePoint(values []float64) (int, float64, bool) {
+	n := len(values)
+	if n < 3 {
+		return 0, 0, false
+	}
+	best, bestScore := 0, -1.0
+	for k := 1; k < n; k++ {
+		d := mean(values[k:]) - mean(values[:k])
+		if score := float64(k*(n-k)) / float64(n) * d * d; score > bestScore {
+			best, bestScore = k, score
+		}
+	}
+	before := mean(values[:best])
+	if before == 0 {
+		return 0, 0, false
+	}
+	return best, (mean(values[best:]) - before) / before, true
+}
+
+// suspectedRange returns the range of values [first, last] during which the
+// mean shifted at the change point k: from the first value after the last one
+// at or below the mean before the change, to the first value at or above the
+// mean after it. A step change is attributed to a single value, while a
+// gradual drift spans several.
+func suspectedRange(values []float64, k int) (int, int) {
+	before, after := mean(values[:k]), mean(values[k:])
+	// there is at least one value at or below the mean before, and one at or
+	// above the mean after
+	j := k - 1
+	for values[j] > before {
+		j--
+	}
+	last := k
+	for values[last] < after {
+		last++
+	}
+	return j + 1, last
+}
+
+// creepingChange looks for a shift of the mean of values by more than
+// threshold, during which no single value increased by more than
+// stepThreshold. It returns the suspected range of the shift and the relative
+// change of the mean, or false if there is no such shift.
+func creepingChange(values []float64, threshold, stepThreshold float64) (int, int, float64, bool) {
+	k, ratio, ok := changePoint(values)
+	if !ok || ratio <= threshold {
+		return 0, 0, 0, false
+	}
+	first, last := suspectedRange(values, k)
+	for i := first; i <= last; i++ {
+		if (values[i]-values[i-1])/values[i-1] > stepThreshold {
+			return 0, 0, 0, false
+		}
+	}
+	return first, last, ratio, true
+}
+
+// showDrift reports the benchmarks whose ns/op shifted by more than their
+// threshold over the series, while no single commit of the suspected range
+// did, e.g. after several small slowdowns. It returns true if there is any.
+func showDrift(w io.Writer, steps []seriesStep) bool {
+	var drifts []string
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" || !whichScoreToCompare(benchmark.Compare).nsPerOp {
+			continue
+		}
+		// the history stops at the first missing result
+		var values []float64
+		for _, step := range steps {
+			cur, ok := step.results[benchmark.UniqueName]
+			if !ok {
+				break
+			}
+			values = append(values, cur.NsPerOp)
+		}
+		first, last, ratio, ok := creepingChange(values, benchmark.Threshold, benchmark.Threshold)
+		if !ok {
+			continue
+		}
+		commits := commitTitle(steps[first].commit)
+		if last > first {
+			commits = fmt.Sprintf("%s..%s", steps[first].commit.Hash.String()[:8], commitTitle(steps[last].commit))
+		}
+		drifts = append(drifts, fmt.Sprintf("%s: ns/op %s, suspected commits %s", benchmark.UniqueName, signedRatio(ratio), commits))
+	}
+	if len(drifts) == 0 {
+		return false
+	}
+	fmt.Fprintln(w, "\nDrift below the threshold of each commit")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 40))
+	for _, d := range drifts {
+		fmt.Fprintln(w, d)
+	}
+	fmt.Fprintln(w)
+	return true
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/benchmark/parse"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestChangePoint(t *testing.T) {
+	_, _, ok := changePoint([]float64{100, 200})
+	assert.False(t, ok)
+
+	values := []float64{100, 101, 99, 130, 131, 129}
+	k, ratio, ok := changePoint(values)
+	assert.True(t, ok)
+	assert.Equal(t, 3, k)
+	assert.InDelta(t, 0.3, ratio, 1e-9)
+	first, last := suspectedRange(values, k)
+	assert.Equal(t, []int{3, 3}, []int{first, last})
+
+	// a gradual drift is attributed to several commits
+	values = []float64{100, 100, 100, 106, 112, 118, 124, 124, 124}
+	k, ratio, ok = changePoint(values)
+	assert.True(t, ok)
+	assert.True(t, ratio > 0.15, ratio)
+	first, last = suspectedRange(values, k)
+	assert.Equal(t, []int{3, 6}, []int{first, last})
+}
+
+func TestShowDrift(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	contention := false
+	benchmarks.Benchmarks = []Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}},
+	}
+	var steps []seriesStep
+	for i, nsPerOp := range []float64{100, 100, 106, 112, 118, 118} {
+		hash := plumbing.NewHash(strings.Repeat(strconv.Itoa(i+1), 40))
+		steps = append(steps, seriesStep{&object.Commit{Hash: hash, Message: fmt.Sprintf("Change %d", i+1)}, Set{"BenchmarkFoo": &benchResult{Benchmark: &parse.Benchmark{NsPerOp: nsPerOp}}}})
+	}
+
+	var b bytes.Buffer
+	assert.False(t, showSeries(&b, steps))
+	b.Reset()
+	assert.True(t, showDrift(&b, steps))
+	assert.Contains(t, b.String(), "BenchmarkFoo: ns/op +13.73%, suspected commits 33333333..55555555 Change 5\n")
+
+	// a step change is already reported by showSeries
+	steps[2].results["BenchmarkFoo"].NsPerOp = 118
+	steps[3].results["BenchmarkFoo"].NsPerOp = 118
+	b.Reset()
+	assert.False(t, showDrift(&b, steps))
+}
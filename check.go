@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/benchmark/parse"
+	"k8s.io/klog/v2"
+)
+
+var cpuSuffix = regexp.MustCompile(`-\d+$`)
+
+// readResults parses benchmark results from the output of "go test -bench".
+// The samples of benchmarks which were run several times are combined.
+func readResults(r io.Reader) (Set, error) {
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+	parseSet, err := parse.ParseSet(bytes.NewReader(out))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse a result of benchmarks: %w", err)
+	}
+	custom := parseCustomMetrics(out)
+	set := Set{}
+	for name, s := range parseSet {
+		set[name] = newBenchResult(s)
+		set[name].Metrics = medianMetrics(custom[name])
+	}
+	return set, nil
+}
+
+// checkBenchmarks returns one benchmark entry for each result. When a
+// configuration file is provided, the configuration of the first entry whose
+// name matches the benchmark is used.
+func checkBenchmarks(set Set) ([]Benchmark, error) {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	entries := make([]Benchmark, 0, len(names))
+	for _, name := range names {
+		entry := Benchmark{Name: name, UniqueName: name}
+		for _, configured := range benchmarks.Benchmarks {
+			match, err := regexp.MatchString(configured.Name, cpuSuffix.ReplaceAllString(name, ""))
+			if err != nil {
+				return nil, fmt.Errorf("invalid benchmark name '%s': %w", configured.Name, err)
+			}
+			if match {
+				entry.BenchmarkConfiguration = configured.BenchmarkConfiguration
+				entry.Calibration = configured.Calibration
+				break
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+var (
+	baselinePath, saveBaselinePath string
+	updateBaselineOnImprovement    bool
+	allowCrossMachine              bool
+)
+
+func addCheckFlags(fs *flag.FlagSet) {
+	fs.StringVar(&baselinePath, "baseline", "", "`path`, http(s) URL or OCI reference (oci://<registry>/<repository>:<tag>) of the baseline to compare the results read from stdin with")
+	fs.StringVar(&baselineCacheDir, "baseline-cache", "", "`directory` where baselines fetched over HTTP are cached (benchci/baselines in the user cache directory by default)")
+	fs.StringVar(&saveBaselinePath, "save-baseline", "", "save the results read from stdin as a baseline to this `path` or OCI reference")
+	fs.BoolVar(&updateBaselineOnImprovement, "update-baseline-on-improvement", false, "replace the baseline with the results read from stdin when they are significantly better and nothing regressed")
+	fs.StringVar(&branchName, "branch", "", "`name` of the branch substituted for {{.Branch}} in baseline paths, detected from the CI environment or from HEAD by default")
+	fs.BoolVar(&allowCrossMachine, "allow-cross-machine", false, "compare with a baseline collected on a different machine, ns/op values are scaled if a calibration benchmark is configured")
+}
+
+// crossMachine are the differences between the machine of the baseline and
+// the current one, when they are compared with --allow-cross-machine. The
+// disclaimer is then part of every report format.
+var crossMachine []string
+
+// checkMachine refuses to compare with a baseline collected on a different
+// machine, unless --allow-cross-machine is set, in which case a disclaimer is
+// added to the report.
+func checkMachine(w io.Writer, saved, current platformInfo) error {
+	differences := platformDifferences(saved, current)
+	if len(differences) == 0 {
+		return nil
+	}
+	if !allowCrossMachine {
+		return fmt.Errorf("the baseline was collected on a different machine (%s), use --allow-cross-machine to compare anyway", strings.Join(differences, ", "))
+	}
+	crossMachine = differences
+	fmt.Fprintln(w, "WARNING: cross-machine comparison")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 33))
+	fmt.Fprintln(w, strings.Join(crossMachineDisclaimer(), "\n"))
+	fmt.Fprintln(w)
+	return nil
+}
+
+// crossMachineDisclaimer returns the sentences of the disclaimer of a
+// cross-machine comparison.
+func crossMachineDisclaimer() []string {
+	disclaimer := []string{fmt.Sprintf("The baseline was collected on a different machine (%s).", strings.Join(crossMachine, ", "))}
+	if name := calibrationBenchmark(); name != "" {
+		disclaimer = append(disclaimer, fmt.Sprintf("ns/op values are scaled with calibration benchmark %s, which only partially compensates for the difference.", name))
+	} else {
+		disclaimer = append(disclaimer, "No calibration benchmark is configured, ns/op values are compared as is.")
+	}
+	return append(disclaimer, "Treat the results below as indicative only.")
+}
+
+// runCheck compares benchmark results read from stdin with a baseline, which
+// provides the comparison and gating logic without running any benchmark.
+func runCheck() error {
+	if baselinePath == "" && saveBaselinePath == "" {
+		return fmt.Errorf("at least one of --baseline and --save-baseline is required")
+	}
+
+	var err error
+	if baselinePath, err = expandBaselinePath(baselinePath); err != nil {
+		return err
+	}
+	if saveBaselinePath, err = expandBaselinePath(saveBaselinePath); err != nil {
+		return err
+	}
+
+	if updateBaselineOnImprovement && isRemoteBaseline(baselinePath) {
+		return fmt.Errorf("--update-baseline-on-improvement requires a local baseline")
+	}
+
+	if configPath != "" {
+		if err := parseBenchmarks(); err != nil {
+			return err
+		}
+	}
+	set, err := readResults(os.Stdin)
+	if err != nil {
+		return err
+	}
+	if len(set) == 0 {
+		return fmt.Errorf("no benchmark results found in input")
+	}
+	if saveBaselinePath != "" {
+		if err := saveBaseline(saveBaselinePath, &baseline{Benchci: currentVersion(), Platform: currentPlatform(), Results: set}); err != nil {
+			return err
+		}
+		klog.InfoS("Saved baseline", "path", saveBaselinePath)
+	}
+	if baselinePath == "" {
+		return nil
+	}
+
+	b, err := loadBaseline(baselinePath)
+	if err != nil {
+		return err
+	}
+	if benchmarks.Benchmarks, err = checkBenchmarks(set); err != nil {
+		return err
+	}
+	if err := updateBenchmarks(); err != nil {
+		return err
+	}
+	if basePolicy, _, err = comparisonPolicies(); err != nil {
+		return err
+	}
+	// the warning must not corrupt other output formats
+	w := report
+	if outputFormat != outputText {
+		w = diagnostics
+	}
+	if err := checkMachine(w, b.Platform, currentPlatform()); err != nil {
+		return err
+	}
+
+	current := &RunResult{Ref: "current", Platform: currentPlatform(), Results: set}
+	saved := &RunResult{Ref: "baseline", Platform: b.Platform, Results: b.Results}
+	if err := compareResults(&refResults{name: "current", ref: "current", run: current}, &refResults{name: "baseline", ref: "baseline", run: saved, policy: basePolicy}, nil); err != nil {
+		return err
+	}
+	if !updateBaselineOnImprovement {
+		return nil
+	}
+	updated := improvedBaseline(b, set, benchmarks.Benchmarks)
+	if updated == nil {
+		klog.InfoS("No significant improvement, keeping the baseline", "path", baselinePath)
+		return nil
+	}
+	if err := saveBaseline(baselinePath, updated); err != nil {
+		return err
+	}
+	klog.InfoS("Updated baseline", "path", baselinePath)
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBenchmarks(t *testing.T) {
+	output := `goos: linux
+BenchmarkFoo-4   	  100000	       839.4 ns/op	     112 B/op	       1 allocs/op
+BenchmarkBar/small-4   	  200000	       420.1 ns/op
+PASS
+`
+	set, err := readResults(strings.NewReader(output))
+	require.NoError(t, err)
+	require.Len(t, set, 2)
+	assert.Equal(t, 839.4, set["BenchmarkFoo-4"].NsPerOp)
+
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	benchmarks.Benchmarks = []Benchmark{
+		{Name: "BenchmarkBar/small$", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.5}},
+		{Name: "BenchmarkBar", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.3}},
+	}
+	entries, err := checkBenchmarks(set)
+	require.NoError(t, err)
+	assert.Equal(t, []Benchmark{
+		{Name: "BenchmarkBar/small-4", UniqueName: "BenchmarkBar/small-4", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.5}},
+		{Name: "BenchmarkFoo-4", UniqueName: "BenchmarkFoo-4"},
+	}, entries)
+}
+
+func TestCheckMachine(t *testing.T) {
+	defer func(b []Benchmark, allow bool, differences []string) {
+		benchmarks.Benchmarks, allowCrossMachine, crossMachine = b, allow, differences
+	}(benchmarks.Benchmarks, allowCrossMachine, crossMachine)
+	benchmarks.Benchmarks = nil
+	saved := platformInfo{OS: "linux", Arch: "amd64", NumCPU: 8, CPUModel: "Intel Xeon"}
+	current := platformInfo{OS: "linux", Arch: "amd64", NumCPU: 4, CPUModel: "AMD EPYC"}
+
+	var b strings.Builder
+	allowCrossMachine = false
+	assert.NoError(t, checkMachine(&b, saved, saved))
+	assert.EqualError(t, checkMachine(&b, saved, current), "the baseline was collected on a different machine (8 CPUs != 4 CPUs, 'Intel Xeon' != 'AMD EPYC'), use --allow-cross-machine to compare anyway")
+	assert.Empty(t, b.String())
+
+	allowCrossMachine = true
+	assert.NoError(t, checkMachine(&b, saved, current))
+	assert.Contains(t, b.String(), "No calibration benchmark is configured")
+
+	// the disclaimer is also part of the JSON and Markdown reports
+	head := &refResults{name: "current", run: &RunResult{}}
+	disclaimer := "The baseline was collected on a different machine (8 CPUs != 4 CPUs, 'Intel Xeon' != 'AMD EPYC'). No calibration benchmark is configured, ns/op values are compared as is. Treat the results below as indicative only."
+	assert.Equal(t, disclaimer, newJSONReport(head, nil, nil, false, false).CrossMachine)
+	assert.Contains(t, markdownReport(head, nil, nil, nil, nil, nil, nil, false, false), "**Cross-machine comparison**: "+disclaimer+"\n")
+
+	b.Reset()
+	benchmarks.Benchmarks = []Benchmark{{UniqueName: "BenchmarkCalibration", Calibration: true}}
+	assert.NoError(t, checkMachine(&b, saved, current))
+	assert.Contains(t, b.String(), "scaled with calibration benchmark BenchmarkCalibration")
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// command is a benchci subcommand.
+type command struct {
+	name        string
+	description string
+	// usage describes the positional arguments, if any.
+	usage string
+	// addFlags registers the flags specific to the command.
+	addFlags func(fs *flag.FlagSet)
+	run      func(fs *flag.FlagSet) error
+}
+
+// flagGroup is used to organize flags in the help text.
+type flagGroup struct {
+	name  string
+	flags []string
+}
+
+var flagGroups = []flagGroup{
+	{"General flags", []string{"config", "version", "run-id", "meta", "offline"}},
+	{"Ref selection flags", []string{"base", "head", "compare-release", "apply-patch"}},
+	{"Benchmark configuration flags", []string{"profile", "confirm-profile", "benchtime", "count", "seed", "cpu", "avoid-smt-siblings", "parallel-refs", "jobs", "toolchain", "no-cache", "result-cache", "timeout", "benchmem", "threshold", "epsilon", "alpha", "significance", "compare"}},
+	{"Gating flags", []string{"release-threshold", "touched-threshold", "warn-threshold", "warn-exit-code", "fail-on", "base-policy", "release-policy", "total-budget", "fail-on-deprecated", "accepted-regressions", "audit-log", "audit-endpoint", "deadline", "partial-policy", "min-coverage"}},
+	{"Profiling flags", []string{"artifacts-dir", "flamegraph", "trace", "contention", "memstats"}},
+	{"Output flags", []string{"only-regression", "output-format", "markdown-file", "color", "layout", "precision", "thousands-separator", "events", "csv", "csv-delimiter", "csv-decimal-separator", "csv-header", "textfile", "datadog", "cloudevents-sink", "release-asset", "file-issues", "github-comment", "github-pr", "gitlab-note", "gitlab-status", "upload-report", "history", "trend-window", "trend-threshold"}},
+}
+
+func commands() []command {
+	return []command{
+		{
+			name:        "diff-dirs",
+			description: "Run the configured benchmarks in two existing source trees and compare them, without using git",
+			addFlags:    addDiffDirsFlags,
+			run:         func(*flag.FlagSet) error { return runDiffDirs() },
+		},
+		{
+			name:        "remote-compare",
+			description: "Fetch two commits of a remote repository in a temporary directory and compare them, without a local checkout",
+			addFlags:    addRemoteCompareFlags,
+			run:         func(*flag.FlagSet) error { return runRemoteCompare() },
+		},
+		{
+			name:        "check",
+			description: "Compare benchmark results read from stdin with a baseline",
+			addFlags:    addCheckFlags,
+			run:         func(*flag.FlagSet) error { return runCheck() },
+		},
+		{
+			name:        "accept",
+			description: "Record an accepted regression for a benchmark, which stops being reported until it expires",
+			usage:       "<benchmark> <ratio>",
+			addFlags:    addAcceptFlags,
+			run:         runAccept,
+		},
+		{
+			name:        "rerun",
+			description: "Compare HEAD with the base ref and the latest release again on behalf of a user, with rate limits",
+			usage:       "[benchmark]",
+			addFlags:    addRerunFlags,
+			run:         runRerun,
+		},
+		{
+			name:        "series",
+			description: "Benchmark every commit of a series and report per-commit changes",
+			addFlags:    addSeriesFlags,
+			run:         func(*flag.FlagSet) error { return runSeries() },
+		},
+		{
+			name:        "history",
+			description: "Show the trend of a benchmark over the last commits recorded with -history",
+			addFlags:    addHistoryFlags,
+			run:         runHistory,
+		},
+		{
+			name:        "stress",
+			description: "Run one benchmark many times at a single ref and show the distribution of its results, to choose its threshold",
+			addFlags:    addStressFlags,
+			run:         func(*flag.FlagSet) error { return runStress() },
+		},
+		{
+			name:        "calibrate",
+			description: "Run the whole suite several times at HEAD and recommend a threshold for each benchmark from its noise",
+			addFlags:    addCalibrateFlags,
+			run:         func(*flag.FlagSet) error { return runCalibrate() },
+		},
+		{
+			name:        "doctor",
+			description: "Check the git repository, the Go toolchain, the machine and the configuration before running benchmarks",
+			run:         func(*flag.FlagSet) error { return runDoctor() },
+		},
+		{
+			name:        "completion",
+			description: "Generate a completion script for bash, zsh or fish",
+			usage:       "bash|zsh|fish",
+			run:         runCompletion,
+		},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commands() {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// newCommandFlagSet returns the FlagSet for a subcommand, which includes all
+// the top-level flags (benchmark configuration, config path, ...).
+func newCommandFlagSet(c command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ExitOnError)
+	flag.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	if c.addFlags != nil {
+		c.addFlags(fs)
+	}
+	fs.Usage = func() { commandUsage(fs.Output(), c, fs) }
+	return fs
+}
+
+// parseFlags parses the command-line flags and sets up logging. It exits after
+// printing the version if it was requested.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	_ = fs.Parse(args)
+	if showVersion {
+		fmt.Println(currentVersion())
+		os.Exit(0)
+	}
+	if runID == "" {
+		var err error
+		if runID, err = newRunID(); err != nil {
+			return err
+		}
+	}
+	if err := setupLogging(); err != nil {
+		return err
+	}
+	if err := validateLayout(); err != nil {
+		return err
+	}
+	if err := validateOutputFormat(); err != nil {
+		return err
+	}
+	if err := validatePartialPolicy(); err != nil {
+		return err
+	}
+	if err := validateDatadog(); err != nil {
+		return err
+	}
+	if err := validateReleaseAsset(); err != nil {
+		return err
+	}
+	if err := validateFileIssues(); err != nil {
+		return err
+	}
+	if err := validateGitHubComment(); err != nil {
+		return err
+	}
+	if err := validateGitLab(); err != nil {
+		return err
+	}
+	if err := validateUploadReport(); err != nil {
+		return err
+	}
+	if err := validateHeadCandidates(); err != nil {
+		return err
+	}
+	if err := validateConfirmProfile(); err != nil {
+		return err
+	}
+	if err := validateParallelRefs(); err != nil {
+		return err
+	}
+	if err := validateJobs(); err != nil {
+		return err
+	}
+	if err := validateNumberFormat(); err != nil {
+		return err
+	}
+	if err := validateCSV(); err != nil {
+		return err
+	}
+	if err := validateToolchain(); err != nil {
+		return err
+	}
+	if err := setupOffline(); err != nil {
+		return err
+	}
+	klog.V(2).InfoS("Starting benchci", "runID", runID)
+	return setupColor()
+}
+
+// execute runs the subcommand selected by the first argument, or compares the
+// current HEAD with the base ref and the latest release if there is none.
+func execute(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		flag.CommandLine.Usage = func() { rootUsage(flag.CommandLine.Output()) }
+		if err := parseFlags(flag.CommandLine, args); err != nil {
+			return err
+		}
+		return withUploadedReport(run)
+	}
+	c, ok := findCommand(args[0])
+	if !ok {
+		rootUsage(os.Stderr)
+		return fmt.Errorf("unknown command '%s'", args[0])
+	}
+	fs := newCommandFlagSet(c)
+	if err := parseFlags(fs, args[1:]); err != nil {
+		return err
+	}
+	return withUploadedReport(func() error { return c.run(fs) })
+}
+
+// commandOnlyFlagSet returns a FlagSet with only the flags which are specific
+// to a command.
+func commandOnlyFlagSet(c command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	if c.addFlags != nil {
+		c.addFlags(fs)
+	}
+	return fs
+}
+
+// commandFlags returns the names of the flags which are specific to a command.
+func commandFlags(c command) []string {
+	var names []string
+	commandOnlyFlagSet(c).VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+	})
+	return names
+}
+
+// groupedFlags returns the top-level flags organized in groups. Flags which
+// were not assigned to a group are returned in an extra group.
+func groupedFlags() []flagGroup {
+	grouped := map[string]bool{}
+	for _, g := range flagGroups {
+		for _, name := range g.flags {
+			grouped[name] = true
+		}
+	}
+	var others []string
+	flag.VisitAll(func(f *flag.Flag) {
+		if !grouped[f.Name] {
+			others = append(others, f.Name)
+		}
+	})
+	groups := flagGroups
+	if len(others) > 0 {
+		sort.Strings(others)
+		groups = append(groups[:len(groups):len(groups)], flagGroup{"Other flags", others})
+	}
+	return groups
+}
+
+func printFlags(w io.Writer, fs *flag.FlagSet, names []string) {
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+		typeName, usage := flag.UnquoteUsage(f)
+		line := "  -" + f.Name
+		if typeName != "" {
+			line += " " + typeName
+		}
+		fmt.Fprintf(w, "%s\n    \t%s", line, strings.ReplaceAll(usage, "\n", "\n    \t"))
+		if f.DefValue != "" && f.DefValue != "false" {
+			fmt.Fprintf(w, " (default %s)", f.DefValue)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func printFlagGroups(w io.Writer, fs *flag.FlagSet) {
+	for _, g := range groupedFlags() {
+		fmt.Fprintf(w, "\n%s:\n", g.name)
+		printFlags(w, fs, g.flags)
+	}
+}
+
+func rootUsage(w io.Writer) {
+	fmt.Fprintf(w, "Run Go benchmarks as part of CI.\n\n")
+	fmt.Fprintf(w, "Usage:\n")
+	fmt.Fprintf(w, "  benchci [flags]            compare HEAD with the base ref and the latest release\n")
+	fmt.Fprintf(w, "  benchci <command> [flags]\n")
+	fmt.Fprintf(w, "\nCommands:\n")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.description)
+	}
+	printFlagGroups(w, flag.CommandLine)
+	fmt.Fprintf(w, "\nUse \"benchci <command> -h\" for more information about a command.\n")
+}
+
+func commandUsage(w io.Writer, c command, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "%s.\n\n", c.description)
+	fmt.Fprintf(w, "Usage:\n  benchci %s [flags]", c.name)
+	if c.usage != "" {
+		fmt.Fprintf(w, " %s", c.usage)
+	}
+	fmt.Fprintln(w)
+	if names := commandFlags(c); len(names) > 0 {
+		fmt.Fprintf(w, "\nCommand flags:\n")
+		printFlags(w, fs, names)
+	}
+	printFlagGroups(w, fs)
+}
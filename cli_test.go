@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagsDocumented(t *testing.T) {
+	grouped := map[string]bool{}
+	for _, g := range flagGroups {
+		for _, name := range g.flags {
+			assert.NotNil(t, flag.Lookup(name), "flag %s is in a group but does not exist", name)
+			grouped[name] = true
+		}
+	}
+	flag.VisitAll(func(f *flag.Flag) {
+		// Flags registered by the testing package.
+		if strings.HasPrefix(f.Name, "test.") {
+			return
+		}
+		assert.NotEmpty(t, f.Usage, "flag %s has no help text", f.Name)
+		assert.True(t, grouped[f.Name], "flag %s does not belong to any group", f.Name)
+	})
+	for _, c := range commands() {
+		assert.NotEmpty(t, c.description, "command %s has no description", c.name)
+		commandOnlyFlagSet(c).VisitAll(func(f *flag.Flag) {
+			assert.NotEmpty(t, f.Usage, "flag %s of command %s has no help text", f.Name, c.name)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var cloudEventsSink string
+
+const runCompletedEventType = "io.antrea.benchci.run.completed"
+
+// cloudEventRequest returns a request carrying a CloudEvent in the binary
+// content mode of the HTTP protocol binding: the attributes of the event are
+// sent as ce- headers and the body is the summary of the run.
+func cloudEventRequest(sink string, entry *auditEntry) (*http.Request, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, sink, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	source := "benchci"
+	if repo := repositoryName(); repo != "" {
+		source += "/" + repo
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("ce-specversion", "1.0")
+	req.Header.Set("ce-id", entry.RunID)
+	req.Header.Set("ce-source", source)
+	req.Header.Set("ce-type", runCompletedEventType)
+	req.Header.Set("ce-time", entry.Time.Format(time.RFC3339Nano))
+	if entry.Head.Commit != "" {
+		req.Header.Set("ce-subject", entry.Head.Commit)
+	}
+	return req, nil
+}
+
+// emitCloudEvent sends the summary of the run to the CloudEvents sink, if
+// one is configured. Like the audit endpoint, failing to send it is only
+// logged.
+func emitCloudEvent(entry *auditEntry) {
+	if cloudEventsSink == "" {
+		return
+	}
+	if err := sendCloudEvent(cloudEventsSink, entry); err != nil {
+		klog.ErrorS(err, "Failed to send CloudEvent", "sink", cloudEventsSink)
+	}
+}
+
+func sendCloudEvent(sink string, entry *auditEntry) error {
+	req, err := cloudEventRequest(sink, entry)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
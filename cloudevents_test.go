@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendCloudEvent(t *testing.T) {
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+	entry := &auditEntry{
+		Time:    time.Date(2021, 10, 14, 0, 0, 0, 0, time.UTC),
+		RunID:   "run",
+		Head:    auditRef{Name: "HEAD", Commit: "abc"},
+		Verdict: "PASS",
+	}
+	var header http.Header
+	var body []byte
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	require.NoError(t, sendCloudEvent(server.URL, entry))
+	assert.Equal(t, "1.0", header.Get("ce-specversion"))
+	assert.Equal(t, "run", header.Get("ce-id"))
+	assert.Equal(t, "benchci/antrea-io/antrea", header.Get("ce-source"))
+	assert.Equal(t, runCompletedEventType, header.Get("ce-type"))
+	assert.Equal(t, "2021-10-14T00:00:00Z", header.Get("ce-time"))
+	assert.Equal(t, "abc", header.Get("ce-subject"))
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	var decoded auditEntry
+	require.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, *entry, decoded)
+
+	status = http.StatusBadRequest
+	assert.Error(t, sendCloudEvent(server.URL, entry))
+}
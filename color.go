@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+var (
+	colorMode string
+	useColor  bool
+)
+
+// setupColor decides whether ANSI colors are used in tables. In auto mode,
+// colors are used when stdout is a terminal which supports them, and in CI
+// jobs, whose logs render them even though stdout is not a terminal.
+func setupColor() error {
+	switch colorMode {
+	case "always":
+		useColor = true
+	case "never":
+		useColor = false
+	case "auto":
+		useColor = os.Getenv("NO_COLOR") == "" && (isCI() || isTerminal(os.Stdout) && enableVirtualTerminal(os.Stdout))
+	default:
+		return fmt.Errorf("unsupported color mode '%s'", colorMode)
+	}
+	return nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// isCI returns true when running in a CI job, as told by the CI variable set
+// by GitHub Actions, GitLab CI and most other CI systems.
+func isCI() bool {
+	ci := os.Getenv("CI")
+	return ci != "" && ci != "false" && ci != "0"
+}
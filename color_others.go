@@ -0,0 +1,10 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "os"
+
+func enableVirtualTerminal(f *os.File) bool {
+	return true
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupColor(t *testing.T) {
+	defer func(mode string, color bool) { colorMode, useColor = mode, color }(colorMode, useColor)
+	for _, k := range []string{"CI", "NO_COLOR"} {
+		defer os.Setenv(k, os.Getenv(k))
+	}
+	os.Unsetenv("NO_COLOR")
+
+	// the output of go test is not a terminal
+	colorMode = "auto"
+	os.Setenv("CI", "")
+	require.NoError(t, setupColor())
+	assert.False(t, useColor)
+	// CI logs are colored
+	os.Setenv("CI", "true")
+	require.NoError(t, setupColor())
+	assert.True(t, useColor)
+	os.Setenv("NO_COLOR", "1")
+	require.NoError(t, setupColor())
+	assert.False(t, useColor)
+
+	colorMode = "always"
+	require.NoError(t, setupColor())
+	assert.True(t, useColor)
+	colorMode = "rainbow"
+	assert.EqualError(t, setupColor(), "unsupported color mode 'rainbow'")
+}
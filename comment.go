@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// commentMarker identifies the comment posted by benchci on a pull request,
+// so that it is updated by the next runs instead of adding a new one.
+const commentMarker = "<!-- benchci-report -->"
+
+var (
+	githubComment bool
+	// githubPR is the number of the pull request to comment on, detected
+	// from the GitHub Actions environment if 0.
+	githubPR int
+)
+
+var pullRequestRef = regexp.MustCompile(`^refs/pull/(\d+)/`)
+
+// detectPullRequest returns the number of the pull request which triggered
+// the GitHub Actions workflow, from its event or from GITHUB_REF, or 0.
+func detectPullRequest() int {
+	if path := os.Getenv("GITHUB_EVENT_PATH"); path != "" {
+		if data, err := ioutil.ReadFile(path); err == nil {
+			var event struct {
+				PullRequest *struct {
+					Number int `json:"number"`
+				} `json:"pull_request"`
+				Issue *struct {
+					Number      int              `json:"number"`
+					PullRequest *json.RawMessage `json:"pull_request"`
+				} `json:"issue"`
+			}
+			if err := json.Unmarshal(data, &event); err == nil {
+				switch {
+				case event.PullRequest != nil:
+					return event.PullRequest.Number
+				case event.Issue != nil && event.Issue.PullRequest != nil:
+					// a comment on a pull request, e.g. to re-run benchmarks
+					return event.Issue.Number
+				}
+			}
+		}
+	}
+	if m := pullRequestRef.FindStringSubmatch(os.Getenv("GITHUB_REF")); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	return 0
+}
+
+// validateGitHubComment fails early if the report cannot be posted, rather
+// than after running the benchmarks.
+func validateGitHubComment() error {
+	if !githubComment {
+		return nil
+	}
+	if os.Getenv("GITHUB_TOKEN") == "" || os.Getenv("GITHUB_REPOSITORY") == "" {
+		return fmt.Errorf("GITHUB_TOKEN and GITHUB_REPOSITORY must be set to comment on a pull request")
+	}
+	if githubPR == 0 {
+		githubPR = detectPullRequest()
+	}
+	if githubPR == 0 {
+		return fmt.Errorf("unable to detect the pull request to comment on, set -github-pr")
+	}
+	return nil
+}
+
+// findComment returns the ID of the comment of a pull request with the
+// marker, or 0 if there is none.
+func findComment(apiURL, repository string, pr int) (int64, error) {
+	for page := 1; ; page++ {
+		query := url.Values{"per_page": {"100"}, "page": {strconv.Itoa(page)}}
+		resp, err := githubRequest(http.MethodGet, fmt.Sprintf("%s/repos/%s/issues/%d/comments?%s", apiURL, repository, pr, query.Encode()), "", nil)
+		if err != nil {
+			return 0, fmt.Errorf("unable to list comments: %w", err)
+		}
+		var comments []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&comments)
+		resp.Body.Close()
+		if err != nil {
+			return 0, fmt.Errorf("unable to decode comments: %w", err)
+		}
+		for _, comment := range comments {
+			if strings.Contains(comment.Body, commentMarker) {
+				return comment.ID, nil
+			}
+		}
+		if len(comments) < 100 {
+			return 0, nil
+		}
+	}
+}
+
+// postGitHubComment posts the Markdown report as a comment of the pull
+// request, or updates the comment of a previous run so that there is a single
+// up-to-date comment.
+func postGitHubComment(apiURL, markdown string) error {
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	id, err := findComment(apiURL, repository, githubPR)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(map[string]string{"body": markdown + "\n" + commentMarker + "\n"})
+	if err != nil {
+		return err
+	}
+	method, endpoint := http.MethodPost, fmt.Sprintf("%s/repos/%s/issues/%d/comments", apiURL, repository, githubPR)
+	if id != 0 {
+		method, endpoint = http.MethodPatch, fmt.Sprintf("%s/repos/%s/issues/comments/%d", apiURL, repository, id)
+	}
+	resp, err := githubRequest(method, endpoint, "application/json", data)
+	if err != nil {
+		return fmt.Errorf("unable to post the comment: %w", err)
+	}
+	defer resp.Body.Close()
+	var comment struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&comment); err != nil {
+		return fmt.Errorf("unable to decode the comment: %w", err)
+	}
+	klog.InfoS("Posted the report on the pull request", "pr", githubPR, "updated", id != 0, "url", comment.HTMLURL)
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectPullRequest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	defer os.Unsetenv("GITHUB_EVENT_PATH")
+	defer os.Unsetenv("GITHUB_REF")
+
+	assert.Equal(t, 0, detectPullRequest())
+	os.Setenv("GITHUB_REF", "refs/pull/1234/merge")
+	assert.Equal(t, 1234, detectPullRequest())
+
+	path := filepath.Join(dir, "event.json")
+	os.Setenv("GITHUB_EVENT_PATH", path)
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"pull_request": {"number": 42}}`), 0644))
+	assert.Equal(t, 42, detectPullRequest())
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"issue": {"number": 43, "pull_request": {}}}`), 0644))
+	assert.Equal(t, 43, detectPullRequest())
+	// a comment on an issue
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"issue": {"number": 44}}`), 0644))
+	assert.Equal(t, 1234, detectPullRequest())
+}
+
+func TestValidateGitHubComment(t *testing.T) {
+	defer func(enabled bool, pr int) { githubComment, githubPR = enabled, pr }(githubComment, githubPR)
+	githubComment, githubPR = true, 0
+	os.Setenv("GITHUB_TOKEN", "secret")
+	defer os.Unsetenv("GITHUB_TOKEN")
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+	assert.Error(t, validateGitHubComment())
+	os.Setenv("GITHUB_REF", "refs/pull/1234/merge")
+	defer os.Unsetenv("GITHUB_REF")
+	assert.NoError(t, validateGitHubComment())
+	assert.Equal(t, 1234, githubPR)
+}
+
+func TestPostGitHubComment(t *testing.T) {
+	defer func(pr int) { githubPR = pr }(githubPR)
+	githubPR = 1234
+	os.Setenv("GITHUB_TOKEN", "secret")
+	defer os.Unsetenv("GITHUB_TOKEN")
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+
+	var existing string
+	var posted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		var comment struct {
+			Body string `json:"body"`
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/antrea-io/antrea/issues/1234/comments":
+			fmt.Fprintf(w, `[{"id": 1, "body": "LGTM"}%s]`, existing)
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/antrea-io/antrea/issues/1234/comments":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&comment))
+			posted = append(posted, "POST "+comment.Body)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"html_url": "https://github.com/antrea-io/antrea/pull/1234#issuecomment-2"}`)
+		case r.Method == http.MethodPatch && r.URL.Path == "/repos/antrea-io/antrea/issues/comments/2":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&comment))
+			posted = append(posted, "PATCH "+comment.Body)
+			fmt.Fprint(w, `{"html_url": "https://github.com/antrea-io/antrea/pull/1234#issuecomment-2"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	require.NoError(t, postGitHubComment(server.URL, "Report 1\n"))
+	existing = `, {"id": 2, "body": "Report 1\n<!-- benchci-report -->\n"}`
+	require.NoError(t, postGitHubComment(server.URL, "Report 2\n"))
+	assert.Equal(t, []string{
+		"POST Report 1\n\n<!-- benchci-report -->\n",
+		"PATCH Report 2\n\n<!-- benchci-report -->\n",
+	}, posted)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type completionFlag struct {
+	name        string
+	description string
+}
+
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		_, usage := flag.UnquoteUsage(f)
+		flags = append(flags, completionFlag{f.Name, usage})
+	})
+	return flags
+}
+
+func topLevelCompletionFlags() []completionFlag {
+	return completionFlags(flag.CommandLine)
+}
+
+func commandCompletionFlags(c command) []completionFlag {
+	return completionFlags(commandOnlyFlagSet(c))
+}
+
+func flagNames(flags []completionFlag) string {
+	names := make([]string, 0, len(flags))
+	for _, f := range flags {
+		names = append(names, "--"+f.name)
+	}
+	return strings.Join(names, " ")
+}
+
+func writeBashCompletion(w io.Writer) {
+	var names []string
+	for _, c := range commands() {
+		names = append(names, c.name)
+	}
+	global := flagNames(topLevelCompletionFlags())
+	fmt.Fprintf(w, "# bash completion for benchci\n")
+	fmt.Fprintf(w, "_benchci() {\n")
+	fmt.Fprintf(w, "  local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(w, "  if [[ ${COMP_CWORD} -eq 1 && ${cur} != -* ]]; then\n")
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W \"%s\" -- \"${cur}\"))\n", strings.Join(names, " "))
+	fmt.Fprintf(w, "    return\n")
+	fmt.Fprintf(w, "  fi\n")
+	fmt.Fprintf(w, "  local opts=\"%s\"\n", global)
+	fmt.Fprintf(w, "  case \"${COMP_WORDS[1]}\" in\n")
+	for _, c := range commands() {
+		if c.name == "completion" {
+			fmt.Fprintf(w, "    completion) COMPREPLY=($(compgen -W \"bash zsh fish\" -- \"${cur}\")); return ;;\n")
+			continue
+		}
+		if flags := commandCompletionFlags(c); len(flags) > 0 {
+			fmt.Fprintf(w, "    %s) opts=\"${opts} %s\" ;;\n", c.name, flagNames(flags))
+		}
+	}
+	fmt.Fprintf(w, "  esac\n")
+	fmt.Fprintf(w, "  if [[ ${cur} == -* ]]; then\n")
+	fmt.Fprintf(w, "    COMPREPLY=($(compgen -W \"${opts}\" -- \"${cur}\"))\n")
+	fmt.Fprintf(w, "  fi\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -o default -F _benchci benchci\n")
+}
+
+func zshEscape(s string) string {
+	return strings.NewReplacer("'", "'\\''", ":", "\\:", "[", "\\[", "]", "\\]").Replace(s)
+}
+
+func writeZshFlags(w io.Writer, indent string, flags []completionFlag) {
+	for _, f := range flags {
+		fmt.Fprintf(w, "%s'--%s[%s]'\n", indent, f.name, zshEscape(f.description))
+	}
+}
+
+func writeZshCompletion(w io.Writer) {
+	fmt.Fprintf(w, "#compdef benchci\n\n")
+	fmt.Fprintf(w, "_benchci() {\n")
+	fmt.Fprintf(w, "  local -a commands opts\n")
+	fmt.Fprintf(w, "  commands=(\n")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "    '%s:%s'\n", c.name, zshEscape(c.description))
+	}
+	fmt.Fprintf(w, "  )\n")
+	fmt.Fprintf(w, "  if (( CURRENT == 2 )) && [[ ${words[CURRENT]} != -* ]]; then\n")
+	fmt.Fprintf(w, "    _describe 'command' commands\n")
+	fmt.Fprintf(w, "    return\n")
+	fmt.Fprintf(w, "  fi\n")
+	fmt.Fprintf(w, "  opts=(\n")
+	writeZshFlags(w, "    ", topLevelCompletionFlags())
+	fmt.Fprintf(w, "  )\n")
+	fmt.Fprintf(w, "  case ${words[2]} in\n")
+	for _, c := range commands() {
+		if c.name == "completion" {
+			fmt.Fprintf(w, "    completion) _values 'shell' bash zsh fish; return ;;\n")
+			continue
+		}
+		if flags := commandCompletionFlags(c); len(flags) > 0 {
+			fmt.Fprintf(w, "    %s) opts+=(\n", c.name)
+			writeZshFlags(w, "      ", flags)
+			fmt.Fprintf(w, "    ) ;;\n")
+		}
+	}
+	fmt.Fprintf(w, "  esac\n")
+	fmt.Fprintf(w, "  _arguments -s $opts '*:file:_files'\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "compdef _benchci benchci\n")
+}
+
+func fishEscape(s string) string {
+	return strings.ReplaceAll(s, "'", "\\'")
+}
+
+func writeFishCompletion(w io.Writer) {
+	fmt.Fprintf(w, "# fish completion for benchci\n")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "complete -c benchci -f -n '__fish_use_subcommand' -a %s -d '%s'\n", c.name, fishEscape(c.description))
+	}
+	for _, f := range topLevelCompletionFlags() {
+		fmt.Fprintf(w, "complete -c benchci -l %s -d '%s'\n", f.name, fishEscape(f.description))
+	}
+	for _, c := range commands() {
+		if c.name == "completion" {
+			fmt.Fprintf(w, "complete -c benchci -f -n '__fish_seen_subcommand_from completion' -a 'bash zsh fish'\n")
+			continue
+		}
+		for _, f := range commandCompletionFlags(c) {
+			fmt.Fprintf(w, "complete -c benchci -n '__fish_seen_subcommand_from %s' -l %s -d '%s'\n", c.name, f.name, fishEscape(f.description))
+		}
+	}
+}
+
+// runCompletion prints a completion script for the requested shell.
+func runCompletion(fs *flag.FlagSet) error {
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected exactly one shell name (bash, zsh or fish)")
+	}
+	switch shell := fs.Arg(0); shell {
+	case "bash":
+		writeBashCompletion(os.Stdout)
+	case "zsh":
+		writeZshCompletion(os.Stdout)
+	case "fish":
+		writeFishCompletion(os.Stdout)
+	default:
+		return fmt.Errorf("unsupported shell '%s'", shell)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import "fmt"
+
+// screenMargin is the fraction of the threshold above which a benchmark is
+// re-run for confirmation: a quick screen run is noisy, and may underestimate
+// a regression as much as overestimate it.
+const screenMargin = 0.5
+
+// confirmProfile is the profile used to re-run the benchmarks which may have
+// regressed, enabling two-phase gating: all the benchmarks are run first with
+// the regular settings as a quick screen, e.g. a short benchtime.
+var confirmProfile string
+
+func validateConfirmProfile() error {
+	if confirmProfile == "" {
+		return nil
+	}
+	if len(headCandidates) > 0 {
+		return fmt.Errorf("-confirm-profile cannot be used with -head")
+	}
+	if applyPatch != "" {
+		return fmt.Errorf("-confirm-profile cannot be used with -apply-patch")
+	}
+	return nil
+}
+
+// screenRegression returns true if a benchmark may have regressed compared
+// with any of the bases in the screen run.
+func screenRegression(benchmark Benchmark, head *refResults, bases []*refResults) bool {
+	headBench, ok := head.run.Results[benchmark.UniqueName]
+	if !ok {
+		return false
+	}
+	for _, base := range bases {
+		if base == nil {
+			continue
+		}
+		baseBench, ok := base.run.Results[benchmark.UniqueName]
+		if !ok {
+			continue
+		}
+		screened := benchmark
+		if base.latestRelease && screened.ReleaseThreshold != 0 {
+			screened.Threshold = screened.ReleaseThreshold
+		}
+		screened.Threshold *= screenMargin
+		if isRegression(newResult(screened, headBench, baseBench)) {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmationBenchmarks returns the benchmarks to re-run for confirmation,
+// with the settings of the confirmation profile, which override the settings
+// of the benchmarks.
+func confirmationBenchmarks(head *refResults, bases ...*refResults) []Benchmark {
+	profileConfiguration := benchmarks.Profiles[confirmProfile]
+	var confirm []Benchmark
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" || benchmark.Calibration || !screenRegression(benchmark, head, bases) {
+			continue
+		}
+		configuration := profileConfiguration
+		// the entries were already expanded for each GOMAXPROCS value
+		configuration.Cpu = benchmark.Cpu
+		benchmark.BenchmarkConfiguration = *configuration.applyDefaults(&benchmark.BenchmarkConfiguration)
+		confirm = append(confirm, benchmark)
+	}
+	return confirm
+}
+
+// mergeConfirmation replaces the screen results of a run with the results of
+// the confirmation run.
+func mergeConfirmation(run, confirmation *RunResult) {
+	for name, result := range confirmation.Results {
+		run.Results[name] = result
+		delete(run.Errors, name)
+	}
+	for name, reason := range confirmation.Errors {
+		run.Errors[name] = reason
+		delete(run.Results, name)
+	}
+	run.Partial = run.Partial || confirmation.Partial
+}
+
+// replaceBenchmarks replaces the entries which were confirmed, so that their
+// settings are the ones used for the comparison.
+func replaceBenchmarks(entries, confirmed []Benchmark) []Benchmark {
+	byName := make(map[string]Benchmark, len(confirmed))
+	for _, benchmark := range confirmed {
+		byName[benchmark.UniqueName] = benchmark
+	}
+	replaced := make([]Benchmark, 0, len(entries))
+	for _, benchmark := range entries {
+		if c, ok := byName[benchmark.UniqueName]; ok {
+			benchmark = c
+		}
+		replaced = append(replaced, benchmark)
+	}
+	return replaced
+}
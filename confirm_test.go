@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestConfirmationBenchmarks(t *testing.T) {
+	defer func(b BenchmarkList, p string) { *benchmarks, confirmProfile = b, p }(*benchmarks, confirmProfile)
+	disabled := false
+	configuration := BenchmarkConfiguration{Benchtime: "100ms", Count: 1, Threshold: 0.1, Compare: "ns/op", Cpu: "2", Contention: &disabled, MemStats: &disabled}
+	entry := func(name string) Benchmark {
+		return Benchmark{Name: name, UniqueName: name, BenchmarkConfiguration: configuration}
+	}
+	*benchmarks = BenchmarkList{
+		Benchmarks: []Benchmark{entry("BenchmarkFoo"), entry("BenchmarkBar"), entry("BenchmarkBaz"), entry("BenchmarkQux")},
+		Profiles:   map[string]BenchmarkConfiguration{"confirm": {Benchtime: "10s", Count: 10, Cpu: "8"}},
+	}
+	confirmProfile = "confirm"
+	nsPerOp := func(v float64) *benchResult {
+		return &benchResult{Benchmark: &parse.Benchmark{NsPerOp: v}}
+	}
+	head := &refResults{name: "HEAD", run: &RunResult{Results: Set{
+		"BenchmarkFoo": nsPerOp(120),
+		// within the screen margin
+		"BenchmarkBar": nsPerOp(106),
+		"BenchmarkBaz": nsPerOp(101),
+		"BenchmarkQux": nsPerOp(120),
+	}}}
+	base := &refResults{name: "main", run: &RunResult{Results: Set{"BenchmarkFoo": nsPerOp(100), "BenchmarkBar": nsPerOp(100), "BenchmarkBaz": nsPerOp(100)}}}
+
+	confirmed := confirmationBenchmarks(head, base, nil)
+	assert.Len(t, confirmed, 2)
+	assert.Equal(t, "BenchmarkFoo", confirmed[0].UniqueName)
+	assert.Equal(t, "BenchmarkBar", confirmed[1].UniqueName)
+	assert.Equal(t, "10s", confirmed[0].Benchtime)
+	assert.Equal(t, 10, confirmed[0].Count)
+	assert.Equal(t, "2", confirmed[0].Cpu)
+	assert.Equal(t, 0.1, confirmed[0].Threshold)
+
+	replaced := replaceBenchmarks(benchmarks.Benchmarks, confirmed)
+	assert.Len(t, replaced, 4)
+	assert.Equal(t, "10s", replaced[0].Benchtime)
+	assert.Equal(t, "100ms", replaced[2].Benchtime)
+}
+
+func TestMergeConfirmation(t *testing.T) {
+	foo, bar := &benchResult{Benchmark: &parse.Benchmark{NsPerOp: 100}}, &benchResult{Benchmark: &parse.Benchmark{NsPerOp: 200}}
+	run := newRunResult("HEAD", "")
+	run.Results["BenchmarkFoo"] = foo
+	run.Results["BenchmarkBar"] = foo
+	run.fail("BenchmarkBaz", errors.New("timeout"))
+	confirmation := newRunResult("HEAD", "")
+	confirmation.Results["BenchmarkFoo"] = bar
+	confirmation.fail("BenchmarkBar", errors.New("timeout"))
+	mergeConfirmation(run, confirmation)
+	assert.Equal(t, Set{"BenchmarkFoo": bar}, run.Results)
+	assert.Equal(t, map[string]string{"BenchmarkBar": "timeout", "BenchmarkBaz": "timeout"}, run.Errors)
+}
+
+func TestValidateConfirmProfile(t *testing.T) {
+	defer func(p, a string, h stringsValue) { confirmProfile, applyPatch, headCandidates = p, a, h }(confirmProfile, applyPatch, headCandidates)
+	confirmProfile, applyPatch, headCandidates = "confirm", "", nil
+	assert.NoError(t, validateConfirmProfile())
+	headCandidates = stringsValue{"feature-a"}
+	assert.Error(t, validateConfirmProfile())
+	headCandidates, applyPatch = nil, "fix.patch"
+	assert.Error(t, validateConfirmProfile())
+}
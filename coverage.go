@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// minCoverage is the minimum ratio of the benchmarks compared with the base
+// ref, so that a suite which silently stops being compared fails the run.
+var minCoverage ratioValue
+
+// benchmarkCoverage returns the number of benchmarks compared with the base ref
+// and the number of configured benchmarks, without the calibration benchmark
+// which is never compared. The benchmarks filtered out of the run are not
+// counted, but the skipped ones are.
+func benchmarkCoverage(ratios []result) (int, int) {
+	configured := 0
+	for _, benchmark := range benchmarks.Benchmarks {
+		if !benchmark.Calibration {
+			configured++
+		}
+	}
+	return len(ratios), configured
+}
+
+// belowMinCoverage returns true if -min-coverage is set and less than this
+// ratio of the configured benchmarks were compared.
+func belowMinCoverage(compared, configured int) bool {
+	return minCoverage > 0 && configured > 0 && float64(compared)/float64(configured) < float64(minCoverage)
+}
+
+// showCoverage writes how many benchmarks were compared, when some were not.
+func showCoverage(w io.Writer, compared, configured int) {
+	if compared == configured || configured == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Coverage: %d of %d benchmarks compared with the base ref (%s)", compared, configured, numbers.percent(float64(compared)/float64(configured)))
+	if minCoverage > 0 {
+		fmt.Fprintf(w, ", minimum %s", numbers.percent(float64(minCoverage)))
+	}
+	fmt.Fprint(w, "\n\n")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestBelowMinCoverage(t *testing.T) {
+	defer func(c ratioValue) { minCoverage = c }(minCoverage)
+	minCoverage = 0
+	assert.False(t, belowMinCoverage(0, 10))
+	minCoverage = 0.9
+	assert.False(t, belowMinCoverage(9, 10))
+	assert.True(t, belowMinCoverage(8, 10))
+	assert.False(t, belowMinCoverage(0, 0))
+}
+
+func TestMinCoverage(t *testing.T) {
+	defer func(b BenchmarkList, f, id string, w io.Writer, c ratioValue) {
+		*benchmarks, outputFormat, runID, report, minCoverage = b, f, id, w, c
+	}(*benchmarks, outputFormat, runID, report, minCoverage)
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo"},
+		{Name: "BenchmarkBar", UniqueName: "BenchmarkBar", Skip: "flaky"},
+		{Name: "BenchmarkBaz", UniqueName: "BenchmarkBaz"},
+		{Name: "BenchmarkCalibration", UniqueName: "BenchmarkCalibration", Calibration: true},
+	}}
+	require.NoError(t, updateBenchmarks())
+	outputFormat, runID = outputJSON, "run"
+	var out bytes.Buffer
+	report = &out
+
+	result := func(nsPerOp float64) *benchResult {
+		return &benchResult{Benchmark: &parse.Benchmark{N: 10, NsPerOp: nsPerOp}}
+	}
+	head := &refResults{name: "HEAD", ref: "HEAD", run: &RunResult{Results: Set{
+		"BenchmarkFoo": result(100), "BenchmarkBaz": result(100), "BenchmarkCalibration": result(100),
+	}}}
+	base := &refResults{name: "main", ref: "main", policy: policyFail, run: &RunResult{Results: Set{
+		"BenchmarkFoo": result(100), "BenchmarkCalibration": result(100),
+	}, Errors: map[string]string{"BenchmarkBaz": "exit status 1"}}}
+
+	minCoverage = 0.3
+	require.NoError(t, compareResults(head, base, nil))
+	var r jsonReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &r), out.String())
+	assert.Equal(t, jsonCoverage{Compared: 1, Configured: 3}, r.Coverage)
+	assert.Equal(t, "PASS", r.Verdict)
+
+	minCoverage = 0.9
+	out.Reset()
+	assert.EqualError(t, compareResults(head, base, nil), "only 1 of 3 benchmarks were compared with main, below the minimum coverage of 90.00%")
+	require.NoError(t, json.Unmarshal(out.Bytes(), &r), out.String())
+	assert.Equal(t, "FAIL", r.Verdict)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// cpuUniqueName returns the unique name of the entry running a benchmark with
+// one of the cpu values, e.g. "BenchmarkFoo[cpu=4]".
+func cpuUniqueName(uniqueName, cpu string) string {
+	return fmt.Sprintf("%s[cpu=%s]", uniqueName, cpu)
+}
+
+// expandCPUs replaces each benchmark with a list of cpu values (e.g.
+// "1,4,16") by one entry per value, so that each value is compared on its own
+// and the speedup curve can be computed.
+func expandCPUs(entries []Benchmark) ([]Benchmark, error) {
+	expanded := make([]Benchmark, 0, len(entries))
+	for _, benchmark := range entries {
+		if benchmark.cpuOf != "" || !strings.Contains(benchmark.Cpu, ",") {
+			expanded = append(expanded, benchmark)
+			continue
+		}
+		values := strings.Split(benchmark.Cpu, ",")
+		seen := map[string]bool{}
+		for _, v := range values {
+			if n, err := strconv.Atoi(v); err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid cpu list '%s' for '%s'", benchmark.Cpu, benchmark.UniqueName)
+			}
+			if seen[v] {
+				return nil, fmt.Errorf("duplicate cpu value '%s' for '%s'", v, benchmark.UniqueName)
+			}
+			seen[v] = true
+		}
+		for i, v := range values {
+			b := benchmark
+			b.cpuOf = benchmark.UniqueName
+			b.Cpu = v
+			b.UniqueName = cpuUniqueName(benchmark.UniqueName, v)
+			b.variant = benchmark.variant || i > 0
+			expanded = append(expanded, b)
+		}
+	}
+	return expanded, nil
+}
+
+// speedups returns the speedup of each cpu value compared with the first one,
+// i.e. ns/op with the first value divided by ns/op with the other value.
+func speedups(entries []Benchmark, set Set) map[string]float64 {
+	first, ok := set[entries[0].UniqueName]
+	if !ok || first.NsPerOp == 0 {
+		return nil
+	}
+	speedups := map[string]float64{}
+	for _, entry := range entries[1:] {
+		if r, ok := set[entry.UniqueName]; ok && r.NsPerOp != 0 {
+			speedups[entry.Cpu] = first.NsPerOp / r.NsPerOp
+		}
+	}
+	return speedups
+}
+
+// cpuLists returns the expanded entries of each benchmark with a list of cpu
+// values, in configuration order.
+func cpuLists(entries []Benchmark) (names []string, byName map[string][]Benchmark) {
+	byName = map[string][]Benchmark{}
+	for _, entry := range entries {
+		if entry.cpuOf == "" || entry.Skip != "" {
+			continue
+		}
+		if _, ok := byName[entry.cpuOf]; !ok {
+			names = append(names, entry.cpuOf)
+		}
+		byName[entry.cpuOf] = append(byName[entry.cpuOf], entry)
+	}
+	return names, byName
+}
+
+// showCPUScaling compares the speedup curves of head with the ones of base,
+// and returns true if the speedup for a cpu value dropped by more than the
+// threshold, even if ns/op with the first cpu value is unchanged.
+func showCPUScaling(w io.Writer, head, base *refResults) bool {
+	names, byName := cpuLists(benchmarks.Benchmarks)
+	if len(names) == 0 {
+		return false
+	}
+	fmt.Fprintf(w, "\nCPU scaling compared with %s\n", base.name)
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 26+len(base.name)))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetRowLine(true)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Name", "CPUs", base.name + " speedup", head.name + " speedup", "Delta"})
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+
+	regression := false
+	for _, name := range names {
+		entries := byName[name]
+		baseSpeedups, headSpeedups := speedups(entries, base.run.Results), speedups(entries, head.run.Results)
+		for _, entry := range entries[1:] {
+			cpus := fmt.Sprintf("%s vs %s", entry.Cpu, entries[0].Cpu)
+			baseSpeedup, baseOK := baseSpeedups[entry.Cpu]
+			headSpeedup, headOK := headSpeedups[entry.Cpu]
+			if !baseOK || !headOK {
+				table.Append([]string{name, cpus, "-", "-", "-"})
+				continue
+			}
+			// a drop of the speedup is a regression, hence the sign
+			ratio := (baseSpeedup - headSpeedup) / baseSpeedup
+			color := tablewriter.Colors{}
+			if ratio > entry.Threshold || ratio < -entry.Threshold {
+				regression = regression || ratio > entry.Threshold
+				color = generateColor(ratio)
+			}
+			table.Rich([]string{name, cpus, numbers.float(baseSpeedup) + "x", numbers.float(headSpeedup) + "x", signedRatio(-ratio)},
+				[]tablewriter.Colors{{}, {}, {}, {}, color})
+		}
+	}
+	table.Render()
+	fmt.Fprintln(w)
+	return regression
+}
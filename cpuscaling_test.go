@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestExpandCPUs(t *testing.T) {
+	expanded, err := expandCPUs([]Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1,4"}},
+		{Name: "BenchmarkBar", UniqueName: "BenchmarkBar", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "2"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, expanded, 3)
+	assert.Equal(t, "BenchmarkFoo[cpu=1]", expanded[0].UniqueName)
+	assert.Equal(t, "1", expanded[0].Cpu)
+	assert.False(t, expanded[0].variant)
+	assert.Equal(t, "BenchmarkFoo[cpu=4]", expanded[1].UniqueName)
+	assert.Equal(t, "4", expanded[1].Cpu)
+	assert.True(t, expanded[1].variant)
+	assert.Equal(t, "BenchmarkBar", expanded[2].UniqueName)
+
+	again, err := expandCPUs(expanded)
+	require.NoError(t, err)
+	assert.Equal(t, expanded, again)
+
+	_, err = expandCPUs([]Benchmark{{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1,x"}}})
+	assert.Error(t, err)
+	_, err = expandCPUs([]Benchmark{{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1,1"}}})
+	assert.Error(t, err)
+}
+
+func TestExpandSweepsAndCPUs(t *testing.T) {
+	expanded, err := expandSweeps([]Benchmark{{
+		Name:                   "BenchmarkSort",
+		UniqueName:             "BenchmarkSort",
+		Sweep:                  &Sweep{Env: "SIZE", Values: []string{"100", "1000"}},
+		BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1,4"},
+	}})
+	require.NoError(t, err)
+	expanded, err = expandCPUs(expanded)
+	require.NoError(t, err)
+	var names []string
+	for _, b := range expanded {
+		names = append(names, b.UniqueName)
+	}
+	assert.Equal(t, []string{"BenchmarkSort[100][cpu=1]", "BenchmarkSort[100][cpu=4]", "BenchmarkSort[1000][cpu=1]", "BenchmarkSort[1000][cpu=4]"}, names)
+	assert.False(t, expanded[0].variant)
+	assert.True(t, expanded[2].variant)
+
+	sweepNames, bySweep := sweeps(expanded)
+	assert.Equal(t, []string{"BenchmarkSort[cpu=1]", "BenchmarkSort[cpu=4]"}, sweepNames)
+	assert.Len(t, bySweep["BenchmarkSort[cpu=4]"], 2)
+	cpuNames, byName := cpuLists(expanded)
+	assert.Equal(t, []string{"BenchmarkSort[100]", "BenchmarkSort[1000]"}, cpuNames)
+	assert.Len(t, byName["BenchmarkSort[100]"], 2)
+}
+
+func TestShowCPUScaling(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	var err error
+	benchmarks.Benchmarks, err = expandCPUs([]Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1,16", Threshold: 0.1}},
+	})
+	require.NoError(t, err)
+	ref := func(name string, one, sixteen float64) *refResults {
+		return &refResults{name: name, run: &RunResult{Results: Set{
+			"BenchmarkFoo[cpu=1]":  &benchResult{Benchmark: &parse.Benchmark{NsPerOp: one}},
+			"BenchmarkFoo[cpu=16]": &benchResult{Benchmark: &parse.Benchmark{NsPerOp: sixteen}},
+		}}}
+	}
+	base := ref("main", 1600, 200)
+
+	var b bytes.Buffer
+	// same single-CPU ns/op, but the speedup dropped from 8x to 4x
+	assert.True(t, showCPUScaling(&b, ref("HEAD", 1600, 400), base))
+	assert.Contains(t, b.String(), "CPU scaling compared with main")
+	assert.Contains(t, b.String(), "8.00x")
+	assert.Contains(t, b.String(), "4.00x")
+	assert.Contains(t, b.String(), "-50.00%")
+
+	b.Reset()
+	assert.False(t, showCPUScaling(&b, ref("HEAD", 1500, 190), base))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	csvPath             string
+	csvDelimiter        string
+	csvDecimalSeparator string
+	csvHeader           bool
+)
+
+var csvDelimiters = map[string]rune{"comma": ',', "semicolon": ';', "tab": '\t'}
+
+func validateCSV() error {
+	if _, ok := csvDelimiters[csvDelimiter]; !ok {
+		return fmt.Errorf("unsupported CSV delimiter '%s': must be comma, semicolon or tab", csvDelimiter)
+	}
+	if csvDecimalSeparator != "." && csvDecimalSeparator != "," {
+		return fmt.Errorf("unsupported CSV decimal separator '%s': must be '.' or ','", csvDecimalSeparator)
+	}
+	return nil
+}
+
+// csvNumber formats a value for spreadsheets: with all its digits, no
+// thousands separator, and the configured decimal separator.
+func csvNumber(v float64) string {
+	return strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", csvDecimalSeparator, 1)
+}
+
+// exportCSV formats the results of each ref with one row per benchmark and
+// ref.
+func exportCSV(refs ...*refResults) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = csvDelimiters[csvDelimiter]
+	if csvHeader {
+		if err := w.Write(append([]string{"benchmark", "ref", "commit", "ns/op", "B/op", "allocs/op", "MB/s"}, runMeta.keys()...)); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range refs {
+		if r == nil {
+			continue
+		}
+		names := make([]string, 0, len(r.run.Results))
+		for name := range r.run.Results {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b := r.run.Results[name]
+			record := []string{name, r.name, r.run.Commit, csvNumber(b.NsPerOp), csvNumber(float64(b.AllocedBytesPerOp)), csvNumber(float64(b.AllocsPerOp)), csvNumber(b.MBPerS)}
+			for _, k := range runMeta.keys() {
+				record = append(record, runMeta[k])
+			}
+			if err := w.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// writeCSV exports the results to the CSV file, if one is configured.
+func writeCSV(refs ...*refResults) error {
+	if csvPath == "" {
+		return nil
+	}
+	data, err := exportCSV(refs...)
+	if err != nil {
+		return fmt.Errorf("unable to export results to CSV: %w", err)
+	}
+	if err := writeFileAtomic(csvPath, data); err != nil {
+		return fmt.Errorf("unable to write CSV export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestExportCSV(t *testing.T) {
+	defer func(delimiter, decimal string, header bool) {
+		csvDelimiter, csvDecimalSeparator, csvHeader = delimiter, decimal, header
+	}(csvDelimiter, csvDecimalSeparator, csvHeader)
+	head := &refResults{name: "HEAD", run: &RunResult{Commit: "abc", Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{NsPerOp: 1234.5, AllocedBytesPerOp: 64, AllocsPerOp: 2}},
+	}}}
+	base := &refResults{name: "main", run: &RunResult{Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{NsPerOp: 1000, AllocedBytesPerOp: 64, AllocsPerOp: 2, MBPerS: 1.5}},
+	}}}
+
+	for _, tc := range []struct {
+		name      string
+		delimiter string
+		decimal   string
+		header    bool
+		expected  string
+	}{
+		{"default", "comma", ".", true, "benchmark,ref,commit,ns/op,B/op,allocs/op,MB/s\nBenchmarkFoo,HEAD,abc,1234.5,64,2,0\nBenchmarkFoo,main,,1000,64,2,1.5\n"},
+		{"european", "semicolon", ",", true, "benchmark;ref;commit;ns/op;B/op;allocs/op;MB/s\nBenchmarkFoo;HEAD;abc;1234,5;64;2;0\nBenchmarkFoo;main;;1000;64;2;1,5\n"},
+		{"decimal comma with comma delimiter", "comma", ",", false, "BenchmarkFoo,HEAD,abc,\"1234,5\",64,2,0\nBenchmarkFoo,main,,1000,64,2,\"1,5\"\n"},
+		{"tab", "tab", ".", false, "BenchmarkFoo\tHEAD\tabc\t1234.5\t64\t2\t0\nBenchmarkFoo\tmain\t\t1000\t64\t2\t1.5\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			csvDelimiter, csvDecimalSeparator, csvHeader = tc.delimiter, tc.decimal, tc.header
+			require.NoError(t, validateCSV())
+			data, err := exportCSV(head, base, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(data))
+		})
+	}
+
+	csvDelimiter = "pipe"
+	assert.Error(t, validateCSV())
+	csvDelimiter, csvDecimalSeparator = "comma", "'"
+	assert.Error(t, validateCSV())
+}
+
+func TestWriteCSV(t *testing.T) {
+	defer func(path string) { csvPath = path }(csvPath)
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	head := &refResults{name: "HEAD", run: &RunResult{Results: Set{}}}
+	csvPath = ""
+	require.NoError(t, writeCSV(head))
+	csvPath = filepath.Join(dir, "results.csv")
+	require.NoError(t, writeCSV(head))
+	_, err = os.Stat(csvPath)
+	assert.NoError(t, err)
+}
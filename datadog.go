@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var datadogEnabled bool
+
+// datadogSeries is a metric of the Datadog v1 series API.
+type datadogSeries struct {
+	Metric string       `json:"metric"`
+	Points [][2]float64 `json:"points"`
+	Type   string       `json:"type"`
+	Tags   []string     `json:"tags"`
+}
+
+// datadogEvent is an event of the Datadog v1 events API.
+type datadogEvent struct {
+	Title          string   `json:"title"`
+	Text           string   `json:"text"`
+	AlertType      string   `json:"alert_type"`
+	DateHappened   int64    `json:"date_happened"`
+	SourceTypeName string   `json:"source_type_name"`
+	Tags           []string `json:"tags"`
+}
+
+// validateDatadog fails early if Datadog reporting is enabled without an API
+// key, rather than after running the benchmarks.
+func validateDatadog() error {
+	if datadogEnabled && os.Getenv("DD_API_KEY") == "" {
+		return fmt.Errorf("DD_API_KEY must be set to report to Datadog")
+	}
+	return nil
+}
+
+// datadogURL returns the URL of the Datadog API, which depends on the site
+// the organization is hosted on.
+func datadogURL() string {
+	site := os.Getenv("DD_SITE")
+	if site == "" {
+		site = "datadoghq.com"
+	}
+	return "https://api." + site
+}
+
+// repositoryName returns the name of the repository the results are tagged
+// with.
+func repositoryName() string {
+	if repository := os.Getenv("GITHUB_REPOSITORY"); repository != "" {
+		return repository
+	}
+	if dir, err := os.Getwd(); err == nil {
+		return filepath.Base(dir)
+	}
+	return ""
+}
+
+// datadogMetrics returns the series for the results of head and the ratios
+// compared with each base, and an event for each regression.
+func datadogMetrics(head *refResults, comparisons []comparison, now time.Time) ([]datadogSeries, []datadogEvent) {
+	repo := repositoryName()
+	// the repository and the metadata of the run are attached to everything
+	withBaseTags := func(tags []string) []string {
+		base := []string{"repo:" + repo}
+		for _, k := range runMeta.keys() {
+			base = append(base, k+":"+runMeta[k])
+		}
+		return append(base, tags...)
+	}
+	ts := float64(now.Unix())
+	var series []datadogSeries
+	gauge := func(metric string, value float64, tags ...string) {
+		series = append(series, datadogSeries{Metric: metric, Points: [][2]float64{{ts, value}}, Type: "gauge", Tags: withBaseTags(tags)})
+	}
+	names := make([]string, 0, len(head.run.Results))
+	for name := range head.run.Results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b := head.run.Results[name]
+		tags := []string{"ref:" + head.name, "benchmark:" + name}
+		gauge("benchci.ns_per_op", b.NsPerOp, tags...)
+		gauge("benchci.alloced_bytes_per_op", float64(b.AllocedBytesPerOp), tags...)
+		gauge("benchci.allocs_per_op", float64(b.AllocsPerOp), tags...)
+	}
+	var events []datadogEvent
+	for _, c := range comparisons {
+		if c.base == nil {
+			continue
+		}
+		for _, result := range c.results {
+			tags := []string{"ref:" + head.name, "base:" + c.base.name, "benchmark:" + result.UniqueName}
+			gauge("benchci.ratio", result.RatioNsPerOp, append(tags, "metric:ns/op")...)
+			gauge("benchci.ratio", result.RatioAllocedBytesPerOp, append(tags, "metric:B/op")...)
+			comparedScore := whichScoreToCompare(result.Compare)
+			if comparedScore.allocsPerOp {
+				gauge("benchci.ratio", result.RatioAllocsPerOp, append(tags, "metric:allocs/op")...)
+			}
+			if comparedScore.mbPerS {
+				gauge("benchci.ratio", result.RatioMBPerS, append(tags, "metric:MB/s")...)
+			}
+			if !isRegression(result) {
+				continue
+			}
+			alertType := "error"
+			if c.base.policy == policyWarn {
+				alertType = "warning"
+			}
+			events = append(events, datadogEvent{
+				Title:          fmt.Sprintf("%s regressed compared with %s", result.UniqueName, c.base.name),
+				Text:           fmt.Sprintf("%s: %s (threshold %s, run %s)", result.UniqueName, regressionSummary(result), numbers.percent(result.Threshold), runID),
+				AlertType:      alertType,
+				DateHappened:   now.Unix(),
+				SourceTypeName: "benchci",
+				Tags:           withBaseTags(tags),
+			})
+		}
+	}
+	return series, events
+}
+
+func postDatadog(url, apiKey string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("DD-API-KEY", apiKey)
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// reportDatadog submits the metrics and the regression events to Datadog.
+// Like the audit endpoint, failing to submit them is only logged.
+func reportDatadog(baseURL string, head *refResults, comparisons []comparison) error {
+	if !datadogEnabled {
+		return nil
+	}
+	if err := validateDatadog(); err != nil {
+		return err
+	}
+	apiKey := os.Getenv("DD_API_KEY")
+	series, events := datadogMetrics(head, comparisons, time.Now())
+	if err := postDatadog(baseURL+"/api/v1/series", apiKey, map[string][]datadogSeries{"series": series}); err != nil {
+		klog.ErrorS(err, "Failed to submit metrics to Datadog")
+	}
+	for _, event := range events {
+		if err := postDatadog(baseURL+"/api/v1/events", apiKey, event); err != nil {
+			klog.ErrorS(err, "Failed to submit event to Datadog", "title", event.Title)
+		}
+	}
+	return nil
+}
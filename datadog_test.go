@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestDatadogMetrics(t *testing.T) {
+	defer func(id string) { runID = id }(runID)
+	runID = "run"
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+	head := &refResults{name: "HEAD", run: &RunResult{Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{NsPerOp: 125, AllocedBytesPerOp: 64, AllocsPerOp: 2}},
+	}}}
+	base := &refResults{name: "main", policy: policyWarn}
+	contention := false
+	results := []result{{
+		Benchmark:    Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}},
+		RatioNsPerOp: 0.25,
+	}}
+	now := time.Unix(1634169600, 0)
+
+	series, events := datadogMetrics(head, []comparison{{base, results, false}, {nil, nil, false}}, now)
+	require.Len(t, series, 5)
+	assert.Equal(t, datadogSeries{
+		Metric: "benchci.ns_per_op",
+		Points: [][2]float64{{1634169600, 125}},
+		Type:   "gauge",
+		Tags:   []string{"repo:antrea-io/antrea", "ref:HEAD", "benchmark:BenchmarkFoo"},
+	}, series[0])
+	assert.Equal(t, datadogSeries{
+		Metric: "benchci.ratio",
+		Points: [][2]float64{{1634169600, 0.25}},
+		Type:   "gauge",
+		Tags:   []string{"repo:antrea-io/antrea", "ref:HEAD", "base:main", "benchmark:BenchmarkFoo", "metric:ns/op"},
+	}, series[3])
+	assert.Equal(t, []datadogEvent{{
+		Title:          "BenchmarkFoo regressed compared with main",
+		Text:           "BenchmarkFoo: ns/op +25.00% (threshold 10.00%, run run)",
+		AlertType:      "warning",
+		DateHappened:   1634169600,
+		SourceTypeName: "benchci",
+		Tags:           []string{"repo:antrea-io/antrea", "ref:HEAD", "base:main", "benchmark:BenchmarkFoo"},
+	}}, events)
+}
+
+func TestReportDatadog(t *testing.T) {
+	defer func(enabled bool) { datadogEnabled = enabled }(datadogEnabled)
+	requests := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("DD-API-KEY"))
+		data, _ := ioutil.ReadAll(r.Body)
+		assert.True(t, json.Valid(data))
+		requests[r.URL.Path]++
+		if r.URL.Path == "/api/v1/events" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+	head := &refResults{name: "HEAD", run: &RunResult{Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{NsPerOp: 125}},
+	}}}
+	contention := false
+	results := []result{{
+		Benchmark:    Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}},
+		RatioNsPerOp: 0.25,
+	}}
+	comparisons := []comparison{{&refResults{name: "main"}, results, true}}
+
+	datadogEnabled = false
+	require.NoError(t, reportDatadog(server.URL, head, comparisons))
+	assert.Empty(t, requests)
+
+	datadogEnabled = true
+	os.Unsetenv("DD_API_KEY")
+	assert.Error(t, reportDatadog(server.URL, head, comparisons))
+
+	os.Setenv("DD_API_KEY", "secret")
+	defer os.Unsetenv("DD_API_KEY")
+	// A rejected event is only logged.
+	require.NoError(t, reportDatadog(server.URL, head, comparisons))
+	assert.Equal(t, map[string]int{"/api/v1/series": 1, "/api/v1/events": 1}, requests)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// policyGate handles the regressions found in partial results like in
+// complete ones.
+const policyGate = "gate"
+
+var (
+	// startTime is used to compute the deadline of the run.
+	startTime     = time.Now()
+	deadline      time.Duration
+	partialPolicy string
+)
+
+var errDeadline = fmt.Errorf("not run, the deadline was reached")
+
+func validatePartialPolicy() error {
+	switch partialPolicy {
+	case policyGate, policyFail, policyWarn:
+		return nil
+	default:
+		return fmt.Errorf("invalid partial policy '%s', must be %s, %s or %s", partialPolicy, policyGate, policyFail, policyWarn)
+	}
+}
+
+// deadlineReached returns true if no new benchmark should be started.
+func deadlineReached(now time.Time) bool {
+	return deadline > 0 && now.Sub(startTime) >= deadline
+}
+
+// isPartial returns true if some benchmarks were not run for one of the refs
+// because of the deadline.
+func isPartial(refs ...*refResults) bool {
+	for _, r := range refs {
+		if r != nil && r.run.Partial {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadlineReached(t *testing.T) {
+	defer func(d time.Duration) { deadline = d }(deadline)
+	deadline = 0
+	assert.False(t, deadlineReached(startTime.Add(24*time.Hour)))
+	deadline = time.Hour
+	assert.False(t, deadlineReached(startTime.Add(59*time.Minute)))
+	assert.True(t, deadlineReached(startTime.Add(time.Hour)))
+}
+
+func TestIsPartial(t *testing.T) {
+	complete := &refResults{run: &RunResult{}}
+	partial := &refResults{run: &RunResult{Partial: true}}
+	assert.False(t, isPartial(complete, complete, nil))
+	assert.True(t, isPartial(complete, partial, nil))
+}
+
+func TestValidatePartialPolicy(t *testing.T) {
+	defer func(p string) { partialPolicy = p }(partialPolicy)
+	for _, policy := range []string{policyGate, policyFail, policyWarn} {
+		partialPolicy = policy
+		assert.NoError(t, validatePartialPolicy())
+	}
+	partialPolicy = "ignore"
+	assert.EqualError(t, validatePartialPolicy(), "invalid partial policy 'ignore', must be gate, fail or warn")
+}
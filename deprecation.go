@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+	"k8s.io/klog/v2"
+)
+
+var failOnDeprecated bool
+
+// deprecatedBenchmarks returns the benchmarks which are past their
+// deprecation version, given the current version.
+func deprecatedBenchmarks(version string) []Benchmark {
+	current, err := semver.Make(trimTagVersion(version))
+	if err != nil {
+		return nil
+	}
+	var deprecated []Benchmark
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.DeprecatedAfter == "" {
+			continue
+		}
+		deprecatedAfter, err := semver.Make(trimTagVersion(strings.TrimSpace(benchmark.DeprecatedAfter)))
+		if err != nil {
+			klog.InfoS("Invalid deprecation version", "benchmark", benchmark.UniqueName, "deprecatedAfter", benchmark.DeprecatedAfter)
+			continue
+		}
+		if current.GT(deprecatedAfter) {
+			deprecated = append(deprecated, benchmark)
+		}
+	}
+	return deprecated
+}
+
+// nearestVersionTag returns the name of the semver tag closest to commit in
+// its history, like "git describe", so that a release branch is not compared
+// with the tags of newer releases. The highest version wins when several tags
+// point to the same commit.
+func nearestVersionTag(r *git.Repository, commit plumbing.Hash) (string, error) {
+	tagRefs, err := r.Tags()
+	if err != nil {
+		return "", err
+	}
+	type versionTag struct {
+		name    string
+		version semver.Version
+	}
+	tags := map[plumbing.Hash]versionTag{}
+	err = tagRefs.ForEach(func(tagRef *plumbing.Reference) error {
+		name := tagRef.Name().Short()
+		v, err := semver.Make(trimTagVersion(name))
+		if err != nil {
+			return nil
+		}
+		hash := tagRef.Hash()
+		// annotated tags point to a tag object rather than to the commit
+		if tag, err := r.TagObject(hash); err == nil {
+			c, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			hash = c.Hash
+		}
+		if t, ok := tags[hash]; !ok || v.GT(t.version) {
+			tags[hash] = versionTag{name, v}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	commits, err := r.Log(&git.LogOptions{From: commit, Order: git.LogOrderBSF})
+	if err != nil {
+		return "", fmt.Errorf("unable to get the history of %v: %w", commit, err)
+	}
+	var name string
+	err = commits.ForEach(func(c *object.Commit) error {
+		if t, ok := tags[c.Hash]; ok {
+			name = t.name
+			return storer.ErrStop
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("no version tag is reachable from %v", commit)
+	}
+	return name, nil
+}
+
+// checkDeprecations warns about benchmarks which should be removed or updated
+// because HEAD is past their deprecation version, and fails if requested. The
+// current version is the nearest version tag in the history of HEAD.
+func checkDeprecations(r *git.Repository, head plumbing.Hash) error {
+	version, err := nearestVersionTag(r, head)
+	if err != nil {
+		klog.InfoS("Unable to determine the current version, skipping deprecation checks", "err", err)
+		return nil
+	}
+	deprecated := deprecatedBenchmarks(version)
+	for _, benchmark := range deprecated {
+		klog.Warningf("Benchmark %s is deprecated after %s (current version is %s), it should be removed or updated",
+			benchmark.UniqueName, benchmark.DeprecatedAfter, version)
+	}
+	if failOnDeprecated && len(deprecated) > 0 {
+		return fmt.Errorf("%d benchmarks are deprecated as of %s", len(deprecated), version)
+	}
+	return nil
+}
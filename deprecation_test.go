@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestDeprecatedBenchmarks(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	benchmarks.Benchmarks = []Benchmark{
+		{UniqueName: "a", DeprecatedAfter: "v1.14.0"},
+		{UniqueName: "b", DeprecatedAfter: "v1.13.2"},
+		{UniqueName: "c"},
+	}
+	names := func(version string) []string {
+		var names []string
+		for _, b := range deprecatedBenchmarks(version) {
+			names = append(names, b.UniqueName)
+		}
+		return names
+	}
+	assert.Empty(t, names("v1.13.0"))
+	assert.Equal(t, []string{"b"}, names("v1.14.0"))
+	assert.Equal(t, []string{"a", "b"}, names("v1.15.0"))
+	assert.Empty(t, names("main"))
+}
+
+func TestNearestVersionTag(t *testing.T) {
+	repository, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(repository)
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repository
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(message string) plumbing.Hash {
+		git("commit", "-q", "--allow-empty", "-m", message)
+		return plumbing.NewHash(git("rev-parse", "HEAD"))
+	}
+	git("init", "-q")
+	initial := commit("initial")
+	commit("v1.0.0")
+	git("tag", "-a", "-m", "v1.0.0", "v1.0.0")
+	git("checkout", "-q", "-b", "release-1.0")
+	fix := commit("fix")
+	git("tag", "nightly")
+	git("checkout", "-q", "-")
+	commit("v1.1.0")
+	git("tag", "v1.1.0")
+	feature := commit("feature")
+	r, err := gogit.PlainOpen(repository)
+	require.NoError(t, err)
+
+	tag, err := nearestVersionTag(r, feature)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.1.0", tag)
+	// the tags of newer releases are not reachable from a release branch
+	tag, err = nearestVersionTag(r, fix)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.0.0", tag)
+	_, err = nearestVersionTag(r, initial)
+	assert.Error(t, err)
+}
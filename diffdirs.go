@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/klog/v2"
+)
+
+var oldDir, newDir string
+
+func addDiffDirsFlags(fs *flag.FlagSet) {
+	fs.StringVar(&oldDir, "old", "", "source tree used as the comparison `base`")
+	fs.StringVar(&newDir, "new", "", "source `tree` compared with the base")
+}
+
+// runDiffDirs runs the configured benchmarks in two existing source trees and
+// compares the results. Git is not used at all, which makes it possible to
+// compare trees prepared by other tools.
+func runDiffDirs() error {
+	if oldDir == "" || newDir == "" {
+		return fmt.Errorf("both --old and --new are required")
+	}
+	for _, dir := range []string{oldDir, newDir} {
+		if info, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("invalid source tree: %w", err)
+		} else if !info.IsDir() {
+			return fmt.Errorf("invalid source tree: %s is not a directory", dir)
+		}
+	}
+
+	return compareTrees(sourceTree{name: "old", dir: oldDir}, sourceTree{name: "new", dir: newDir})
+}
+
+// sourceTree is a directory with the sources of a version to benchmark, and
+// the commit it was checked out from, if any.
+type sourceTree struct {
+	name, dir, commit string
+}
+
+// compareTrees runs the configured benchmarks in two source trees and
+// compares the results of newTree with the ones of oldTree.
+func compareTrees(oldTree, newTree sourceTree) error {
+	if err := parseBenchmarks(); err != nil {
+		return err
+	}
+	if err := discoverBenchmarks(newTree.dir); err != nil {
+		return err
+	}
+	if err := updateBenchmarks(); err != nil {
+		return err
+	}
+	if err := checkOverlaps(newTree.dir); err != nil {
+		return err
+	}
+	var err error
+	if basePolicy, _, err = comparisonPolicies(); err != nil {
+		return err
+	}
+	if err := prepareArtifactsDir(); err != nil {
+		return err
+	}
+
+	stream, err := newStreamer(diagnostics)
+	if err != nil {
+		return err
+	}
+	defer stream.close()
+
+	klog.InfoS("Run Benchmark", "dir", oldTree.dir, "commitHash", oldTree.commit)
+	oldRun, err := runBenchmarks(oldTree.dir, oldTree.name, oldTree.commit, "", stream.progress(oldTree.name, false))
+	if err != nil {
+		return fmt.Errorf("failed to run a benchmark: %w", err)
+	}
+	old := &refResults{name: oldTree.name, ref: oldTree.name, run: oldRun, policy: basePolicy}
+	stream.bases = append(stream.bases, old)
+	klog.InfoS("Run Benchmark", "dir", newTree.dir, "commitHash", newTree.commit)
+	newRun, err := runBenchmarks(newTree.dir, newTree.name, newTree.commit, "", stream.progress(newTree.name, true))
+	if err != nil {
+		return fmt.Errorf("failed to run a benchmark: %w", err)
+	}
+
+	return compareResults(&refResults{name: newTree.name, ref: newTree.name, dir: newTree.dir, run: newRun}, old, nil)
+}
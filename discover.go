@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// discoveredBenchmark is a benchmark function found by "go test -list".
+type discoveredBenchmark struct {
+	name string
+	pkg  string
+}
+
+// parseTestList parses the output of "go test -list" for one or more
+// packages. The names listed for a package are printed before the "ok" line
+// for that package.
+func parseTestList(r io.Reader) []discoveredBenchmark {
+	var found, pending []discoveredBenchmark
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 1 && strings.HasPrefix(fields[0], "Benchmark"):
+			pending = append(pending, discoveredBenchmark{name: fields[0]})
+		case len(fields) >= 2 && fields[0] == "ok":
+			for _, b := range pending {
+				b.pkg = fields[1]
+				found = append(found, b)
+			}
+			pending = nil
+		}
+	}
+	return found
+}
+
+func compileAll(exprs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex '%s': %w", expr, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func matchAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterDiscovered applies the include and exclude rules of a discovery
+// section. All benchmarks are included if there is no include rule.
+func filterDiscovered(found []discoveredBenchmark, d Discovery) ([]discoveredBenchmark, error) {
+	include, err := compileAll(d.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := compileAll(d.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	var filtered []discoveredBenchmark
+	for _, b := range found {
+		if len(include) > 0 && !matchAny(include, b.name) {
+			continue
+		}
+		if matchAny(exclude, b.name) {
+			continue
+		}
+		filtered = append(filtered, b)
+	}
+	return filtered, nil
+}
+
+func listBenchmarks(cmdStr, dir, packages string) ([]discoveredBenchmark, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(cmdStr, "test", "-list", "^Benchmark", packages)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	klog.InfoS("Discovering benchmarks", "command", cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		klog.InfoS("Exec command error", "err", stderr.String())
+		return nil, fmt.Errorf("failed to run '%s' command: %w", cmd, err)
+	}
+	return parseTestList(bytes.NewReader(out)), nil
+}
+
+// discoverBenchmarks expands the discovery sections of the configuration to
+// benchmark entries, by listing the benchmark functions of the source tree in
+// dir. Benchmarks which are already configured explicitly (same package and
+// name) are left alone, so that their configuration can be customized.
+func discoverBenchmarks(dir string) error {
+	configured := map[string]bool{}
+	for _, benchmark := range benchmarks.Benchmarks {
+		configured[benchmark.Package+"."+benchmarkFunc(benchmark.Name)] = true
+	}
+	for _, d := range benchmarks.Discover {
+		configuration := d.BenchmarkConfiguration
+		found, err := listBenchmarks(goCommand(configuration.applyDefaults(&benchmarks.BenchmarkConfiguration)), dir, d.Packages)
+		if err != nil {
+			return err
+		}
+		if found, err = filterDiscovered(found, d); err != nil {
+			return fmt.Errorf("invalid discovery rules for '%s': %w", d.Packages, err)
+		}
+		for _, b := range found {
+			if configured[b.pkg+"."+b.name] {
+				continue
+			}
+			configured[b.pkg+"."+b.name] = true
+			benchmarks.Benchmarks = append(benchmarks.Benchmarks, Benchmark{
+				Name:                   "^" + b.name + "$",
+				Package:                b.pkg,
+				UniqueName:             b.pkg + "." + b.name,
+				BenchmarkConfiguration: d.BenchmarkConfiguration,
+			})
+		}
+		klog.InfoS("Discovered benchmarks", "packages", d.Packages, "count", len(found))
+	}
+	return nil
+}
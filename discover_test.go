@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestList(t *testing.T) {
+	out := `BenchmarkFoo
+BenchmarkBar
+ok  	example.com/foo	0.004s
+?   	example.com/foo/cmd	[no test files]
+BenchmarkBaz
+ok  	example.com/foo/bar	0.003s
+`
+	assert.Equal(t, []discoveredBenchmark{
+		{name: "BenchmarkFoo", pkg: "example.com/foo"},
+		{name: "BenchmarkBar", pkg: "example.com/foo"},
+		{name: "BenchmarkBaz", pkg: "example.com/foo/bar"},
+	}, parseTestList(strings.NewReader(out)))
+}
+
+func TestFilterDiscovered(t *testing.T) {
+	found := []discoveredBenchmark{{name: "BenchmarkInitSmall"}, {name: "BenchmarkInitLarge"}, {name: "BenchmarkSync"}}
+	for _, tc := range []struct {
+		name     string
+		d        Discovery
+		expected []string
+	}{
+		{"all", Discovery{}, []string{"BenchmarkInitSmall", "BenchmarkInitLarge", "BenchmarkSync"}},
+		{"include", Discovery{Include: []string{"^BenchmarkInit"}}, []string{"BenchmarkInitSmall", "BenchmarkInitLarge"}},
+		{"include and exclude", Discovery{Include: []string{"^BenchmarkInit"}, Exclude: []string{"Large"}}, []string{"BenchmarkInitSmall"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			filtered, err := filterDiscovered(found, tc.d)
+			require.NoError(t, err)
+			var names []string
+			for _, b := range filtered {
+				names = append(names, b.name)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+
+	_, err := filterDiscovered(found, Discovery{Exclude: []string{"("}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+type checkStatus string
+
+const (
+	checkPass checkStatus = "PASS"
+	checkWarn checkStatus = "WARN"
+	checkFail checkStatus = "FAIL"
+)
+
+type doctorCheck struct {
+	name   string
+	status checkStatus
+	detail string
+}
+
+// validateConfig returns the problems found in the benchmark configuration.
+func validateConfig() []string {
+	var problems []string
+	uniqueNames := map[string]bool{}
+	calibrations := 0
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Calibration {
+			calibrations++
+		}
+		if benchmark.Name == "" {
+			problems = append(problems, "benchmark with no name")
+			continue
+		}
+		if _, err := regexp.Compile(benchmark.Name); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid name for '%s': %v", benchmark.Name, err))
+		}
+		if benchmark.Package == "" && !isTarget(&benchmark) {
+			problems = append(problems, fmt.Sprintf("no package for '%s'", benchmark.Name))
+		}
+		if uniqueNames[benchmark.UniqueName] {
+			problems = append(problems, fmt.Sprintf("duplicate unique name '%s'", benchmark.UniqueName))
+		}
+		uniqueNames[benchmark.UniqueName] = true
+		if requirement := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(benchmark.VersionRequirement), ">=")); requirement != "" {
+			if _, err := semver.Make(trimTagVersion(requirement)); err != nil {
+				problems = append(problems, fmt.Sprintf("invalid version requirement for '%s': %v", benchmark.UniqueName, err))
+			}
+		}
+		if deprecatedAfter := strings.TrimSpace(benchmark.DeprecatedAfter); deprecatedAfter != "" {
+			if _, err := semver.Make(trimTagVersion(deprecatedAfter)); err != nil {
+				problems = append(problems, fmt.Sprintf("invalid deprecatedAfter for '%s': %v", benchmark.UniqueName, err))
+			}
+		}
+		for _, filter := range benchmark.OutputFilters {
+			if _, err := regexp.Compile(filter); err != nil {
+				problems = append(problems, fmt.Sprintf("invalid output filter for '%s': %v", benchmark.UniqueName, err))
+			}
+		}
+		if _, err := limitsExec(benchmark.Limits); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid limits for '%s': %v", benchmark.UniqueName, err))
+		}
+		if err := validateTarget(&benchmark); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid benchmark '%s': %v", benchmark.UniqueName, err))
+		}
+		if len(benchmark.Commands) > 0 {
+			if _, err := outputStep(benchmark.Commands); err != nil {
+				problems = append(problems, fmt.Sprintf("invalid commands for '%s': %v", benchmark.UniqueName, err))
+			}
+			for _, step := range benchmark.Commands {
+				if _, err := expandArgs(step.Args, refVars{}); err != nil {
+					problems = append(problems, fmt.Sprintf("invalid commands for '%s': %v", benchmark.UniqueName, err))
+				}
+			}
+		}
+		if benchmark.NumaNode != nil && *benchmark.NumaNode < 0 {
+			problems = append(problems, fmt.Sprintf("invalid NUMA node for '%s': %d", benchmark.UniqueName, *benchmark.NumaNode))
+		}
+		if benchmark.Threshold <= 0 {
+			problems = append(problems, fmt.Sprintf("threshold for '%s' should be positive", benchmark.UniqueName))
+		}
+		units := make([]string, 0, len(benchmark.MetricThresholds))
+		for unit := range benchmark.MetricThresholds {
+			units = append(units, unit)
+		}
+		sort.Strings(units)
+		for _, unit := range units {
+			if benchmark.MetricThresholds[unit] <= 0 {
+				problems = append(problems, fmt.Sprintf("threshold of %s for '%s' should be positive", unit, benchmark.UniqueName))
+			}
+		}
+	}
+	if calibrations > 1 {
+		problems = append(problems, fmt.Sprintf("%d calibration benchmarks, at most one is supported", calibrations))
+	}
+	for _, hook := range append(append(append([]CommandStep{}, benchmarks.Build...), benchmarks.Setup...), benchmarks.Teardown...) {
+		if len(hook.Args) == 0 {
+			problems = append(problems, "hook with no args")
+		} else if _, err := expandArgs(hook.Args, refVars{}); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid hook: %v", err))
+		}
+	}
+	for _, d := range benchmarks.Discover {
+		if d.Packages == "" {
+			problems = append(problems, "discovery rule with no packages")
+		}
+		if _, err := compileAll(append(append([]string{}, d.Include...), d.Exclude...)); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid discovery rule for '%s': %v", d.Packages, err))
+		}
+	}
+	return problems
+}
+
+// significanceWarnings returns the benchmarks with significance whose count is
+// too low for the statistical test to ever reach their alpha, in which case
+// the option has no effect.
+func significanceWarnings() []string {
+	var warnings []string
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Significance == nil || !*benchmark.Significance {
+			continue
+		}
+		level := alpha(result{Benchmark: benchmark})
+		if needed := minSignificanceCount(level); benchmark.Count < needed {
+			warnings = append(warnings, fmt.Sprintf("significance has no effect for '%s' with a count of %d, at least %d runs are needed at level %s", benchmark.UniqueName, benchmark.Count, needed, strconv.FormatFloat(level, 'g', -1, 64)))
+		}
+	}
+	return warnings
+}
+
+func checkConfig() doctorCheck {
+	c := doctorCheck{name: "Configuration"}
+	if configPath == "" {
+		c.status, c.detail = checkFail, "no configuration file, use -config"
+		return c
+	}
+	if err := parseBenchmarks(); err != nil {
+		c.status, c.detail = checkFail, err.Error()
+		return c
+	}
+	if err := updateBenchmarks(); err != nil {
+		c.status, c.detail = checkFail, err.Error()
+		return c
+	}
+	problems := validateConfig()
+	if _, _, err := comparisonPolicies(); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) > 0 {
+		c.status, c.detail = checkFail, strings.Join(problems, "; ")
+		return c
+	}
+	skipped := 0
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" {
+			skipped++
+		}
+	}
+	c.status, c.detail = checkPass, fmt.Sprintf("%d benchmarks (%d skipped)", len(benchmarks.Benchmarks), skipped)
+	if warnings := significanceWarnings(); len(warnings) > 0 {
+		c.status = checkWarn
+		c.detail += ": " + strings.Join(warnings, "; ")
+	}
+	return c
+}
+
+func checkGit() []doctorCheck {
+	repository := doctorCheck{name: "Git repository"}
+	r, err := git.PlainOpen(".")
+	if err != nil {
+		repository.status, repository.detail = checkFail, err.Error()
+		return []doctorCheck{repository}
+	}
+	repository.status = checkPass
+	checks := []doctorCheck{repository}
+
+	tree := doctorCheck{name: "Clean working tree", status: checkPass}
+	if w, err := r.Worktree(); err != nil {
+		tree.status, tree.detail = checkFail, err.Error()
+	} else if s, err := w.Status(); err != nil {
+		tree.status, tree.detail = checkFail, err.Error()
+	} else if !s.IsClean() {
+		tree.status, tree.detail = checkFail, "commit all changes before running"
+	}
+	checks = append(checks, tree)
+
+	base := doctorCheck{name: fmt.Sprintf("Base ref %s", baseRef), status: checkPass}
+	if hash, err := r.ResolveRevision(plumbing.Revision(baseRef)); err != nil {
+		base.status, base.detail = checkFail, err.Error()
+	} else {
+		base.detail = hash.String()
+	}
+	checks = append(checks, base)
+
+	tags := doctorCheck{name: "Release tags", status: checkPass}
+	if tag, err := getLatestRelease(r); err != nil {
+		tags.status, tags.detail = checkWarn, err.Error()
+		if compareLatestVersion {
+			tags.status = checkFail
+		}
+	} else {
+		tags.detail = fmt.Sprintf("latest release is %s", tag.Name().Short())
+	}
+	checks = append(checks, tags)
+	return checks
+}
+
+func checkGo() doctorCheck {
+	c := doctorCheck{name: "Go toolchain"}
+	cmdStr := goCommand(&benchmarks.BenchmarkConfiguration)
+	if cmdStr == "" {
+		cmdStr = "go"
+	}
+	out, err := exec.Command(cmdStr, "version").Output()
+	if err != nil {
+		c.status, c.detail = checkFail, fmt.Sprintf("unable to run '%s version': %v", cmdStr, err)
+		return c
+	}
+	c.status, c.detail = checkPass, strings.TrimSpace(string(out))
+	return c
+}
+
+func readSysFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	return strings.TrimSpace(string(data)), err
+}
+
+func checkCPUGovernor() doctorCheck {
+	c := doctorCheck{name: "CPU frequency governor"}
+	if runtime.GOOS != "linux" {
+		c.status, c.detail = checkWarn, fmt.Sprintf("cannot be checked on %s", runtime.GOOS)
+		return c
+	}
+	paths, _ := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_governor")
+	if len(paths) == 0 {
+		c.status, c.detail = checkWarn, "cpufreq is not available"
+		return c
+	}
+	governors := map[string]bool{}
+	for _, path := range paths {
+		if governor, err := readSysFile(path); err == nil {
+			governors[governor] = true
+		}
+	}
+	var names []string
+	for governor := range governors {
+		names = append(names, governor)
+	}
+	sort.Strings(names)
+	c.detail = strings.Join(names, ", ")
+	if len(names) == 1 && names[0] == "performance" {
+		c.status = checkPass
+	} else {
+		c.status = checkWarn
+		c.detail += ` (use "performance" for stable results)`
+	}
+	return c
+}
+
+func checkSMT() doctorCheck {
+	c := doctorCheck{name: "Simultaneous multithreading"}
+	if runtime.GOOS != "linux" {
+		c.status, c.detail = checkWarn, fmt.Sprintf("cannot be checked on %s", runtime.GOOS)
+		return c
+	}
+	active, err := readSysFile("/sys/devices/system/cpu/smt/active")
+	switch {
+	case err != nil:
+		c.status, c.detail = checkWarn, "SMT state is not available"
+	case active == "0":
+		c.status, c.detail = checkPass, "disabled"
+	case avoidsSMTSiblings():
+		c.status, c.detail = checkPass, "enabled, benchmarks are pinned to one CPU per core"
+	default:
+		c.status, c.detail = checkWarn, "enabled, sibling threads can make results noisy (use -avoid-smt-siblings)"
+	}
+	if topology, err := readSMTTopology("/sys"); err == nil {
+		cores, threads := topology.cores()
+		c.detail += fmt.Sprintf(", %d cores with %d threads each", cores, threads)
+	}
+	return c
+}
+
+func printChecks(w io.Writer, checks []doctorCheck) {
+	for _, c := range checks {
+		if c.detail == "" {
+			fmt.Fprintf(w, "[%s] %s\n", c.status, c.name)
+		} else {
+			fmt.Fprintf(w, "[%s] %s: %s\n", c.status, c.name, c.detail)
+		}
+	}
+}
+
+// runDoctor checks the environment and the configuration, to catch problems
+// before running benchmarks.
+func runDoctor() error {
+	checks := []doctorCheck{checkConfig()}
+	checks = append(checks, checkGit()...)
+	checks = append(checks, checkGo(), checkCPUGovernor(), checkSMT(), checkLimits(), checkNUMA())
+	printChecks(os.Stdout, checks)
+
+	failed := 0
+	for _, c := range checks {
+		if c.status == checkFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateConfig(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	config := BenchmarkConfiguration{Threshold: 0.2}
+	benchmarks.Benchmarks = []Benchmark{
+		{Name: "BenchmarkFoo", Package: "foo", UniqueName: "foo", BenchmarkConfiguration: config},
+		{Name: "BenchmarkFoo(", Package: "foo", UniqueName: "foo", BenchmarkConfiguration: config},
+		{Name: "BenchmarkBar", UniqueName: "bar", VersionRequirement: ">=v1.x", BenchmarkConfiguration: config},
+	}
+	assert.Equal(t, []string{
+		"invalid name for 'BenchmarkFoo(': error parsing regexp: missing closing ): `BenchmarkFoo(`",
+		"duplicate unique name 'foo'",
+		"no package for 'BenchmarkBar'",
+		"invalid version requirement for 'bar': No Major.Minor.Patch elements found",
+	}, validateConfig())
+}
+
+func TestSignificanceWarnings(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	significance := true
+	benchmarks.Benchmarks = []Benchmark{
+		{UniqueName: "foo", BenchmarkConfiguration: BenchmarkConfiguration{Count: 3, Significance: &significance}},
+		{UniqueName: "bar", BenchmarkConfiguration: BenchmarkConfiguration{Count: 4, Significance: &significance}},
+		{UniqueName: "baz", BenchmarkConfiguration: BenchmarkConfiguration{Count: 1}},
+	}
+	assert.Equal(t, []string{
+		"significance has no effect for 'foo' with a count of 3, at least 4 runs are needed at level 0.05",
+	}, significanceWarnings())
+}
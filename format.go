@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// numberFormat controls how numbers are formatted in reports, so that all the
+// outputs are consistent. Numbers are never formatted in scientific notation.
+type numberFormat struct {
+	// precision is the number of decimal places.
+	precision int
+	// thousandsSeparator is inserted between groups of 3 digits of the
+	// integer part, e.g. "," or " ", none if empty.
+	thousandsSeparator string
+}
+
+var numbers = numberFormat{precision: 2}
+
+func validateNumberFormat() error {
+	if numbers.precision < 0 || numbers.precision > 9 {
+		return fmt.Errorf("invalid precision %d: must be between 0 and 9", numbers.precision)
+	}
+	return nil
+}
+
+// group inserts the thousands separator in the integer part of a formatted
+// number.
+func (f numberFormat) group(s string) string {
+	if f.thousandsSeparator == "" {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	integer, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		integer, fraction = s[:i], s[i:]
+	}
+	var b strings.Builder
+	for i, c := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(f.thousandsSeparator)
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String() + fraction
+}
+
+// float formats a value with the configured precision.
+func (f numberFormat) float(v float64) string {
+	return f.group(strconv.FormatFloat(v, 'f', f.precision, 64))
+}
+
+// uint formats an integer value, e.g. a number of bytes.
+func (f numberFormat) uint(v uint64) string {
+	return f.group(strconv.FormatUint(v, 10))
+}
+
+// percent formats a ratio as a percentage, e.g. "25.00%" for 0.25.
+func (f numberFormat) percent(ratio float64) string {
+	return f.float(100*ratio) + "%"
+}
+
+// signedPercent formats a ratio as a percentage with its sign, e.g. "+25.00%"
+// for 0.25. Changes which round to zero are shown as "+0.00%".
+func (f numberFormat) signedPercent(ratio float64) string {
+	s := f.percent(ratio)
+	if strings.Trim(s, "-0.,%"+f.thousandsSeparator) == "" {
+		return "+" + strings.TrimPrefix(s, "-")
+	}
+	if !strings.HasPrefix(s, "-") {
+		s = "+" + s
+	}
+	return s
+}
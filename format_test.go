@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		format        numberFormat
+		value         float64
+		float         string
+		percent       string
+		signedPercent string
+	}{
+		{"default", numberFormat{precision: 2}, 1234567.891, "1234567.89", "123456789.10%", "+123456789.10%"},
+		{"separator", numberFormat{precision: 2, thousandsSeparator: ","}, 1234567.891, "1,234,567.89", "123,456,789.10%", "+123,456,789.10%"},
+		{"no decimals", numberFormat{precision: 0, thousandsSeparator: " "}, -1234.5, "-1 234", "-123 450%", "-123 450%"},
+		{"small", numberFormat{precision: 2, thousandsSeparator: ","}, 0.25, "0.25", "25.00%", "+25.00%"},
+		{"large", numberFormat{precision: 1}, 1e15, "1000000000000000.0", "100000000000000000.0%", "+100000000000000000.0%"},
+		{"rounds to zero", numberFormat{precision: 2}, -0.00001, "-0.00", "-0.00%", "+0.00%"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.float, tc.format.float(tc.value))
+			assert.Equal(t, tc.percent, tc.format.percent(tc.value))
+			assert.Equal(t, tc.signedPercent, tc.format.signedPercent(tc.value))
+		})
+	}
+	assert.Equal(t, "12,345", numberFormat{thousandsSeparator: ","}.uint(12345))
+	assert.Equal(t, "123", numberFormat{thousandsSeparator: ","}.uint(123))
+}
+
+func TestValidateNumberFormat(t *testing.T) {
+	defer func(f numberFormat) { numbers = f }(numbers)
+	numbers.precision = 3
+	assert.NoError(t, validateNumberFormat())
+	numbers.precision = -1
+	assert.Error(t, validateNumberFormat())
+}
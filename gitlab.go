@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// gitlabStatusName is the name of the commit status set by benchci.
+const gitlabStatusName = "benchci"
+
+var (
+	gitlabNote   bool
+	gitlabStatus bool
+)
+
+// validateGitLab fails early if the results cannot be posted to GitLab, rather
+// than after running the benchmarks. The CI_* variables are set by GitLab CI,
+// and GITLAB_TOKEN is a token with the api scope, since the job token cannot
+// post notes.
+func validateGitLab() error {
+	if !gitlabNote && !gitlabStatus {
+		return nil
+	}
+	required := []string{"GITLAB_TOKEN", "CI_API_V4_URL", "CI_PROJECT_ID"}
+	if gitlabNote {
+		required = append(required, "CI_MERGE_REQUEST_IID")
+	}
+	if gitlabStatus {
+		required = append(required, "CI_COMMIT_SHA")
+	}
+	var missing []string
+	for _, name := range required {
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s must be set to post results to GitLab", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func gitlabRequest(method, url string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("PRIVATE-TOKEN", os.Getenv("GITLAB_TOKEN"))
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: unexpected status: %s", method, url, resp.Status)
+	}
+	return resp, nil
+}
+
+// gitlabProjectURL returns the API URL of the project of the pipeline.
+func gitlabProjectURL() string {
+	return fmt.Sprintf("%s/projects/%s", strings.TrimSuffix(os.Getenv("CI_API_V4_URL"), "/"), url.PathEscape(os.Getenv("CI_PROJECT_ID")))
+}
+
+// findGitLabNote returns the ID of the note of a merge request with the
+// marker, or 0 if there is none.
+func findGitLabNote(notesURL string) (int64, error) {
+	for page := 1; ; page++ {
+		query := url.Values{"per_page": {"100"}, "page": {strconv.Itoa(page)}}
+		resp, err := gitlabRequest(http.MethodGet, notesURL+"?"+query.Encode(), nil)
+		if err != nil {
+			return 0, fmt.Errorf("unable to list notes: %w", err)
+		}
+		var notes []struct {
+			ID   int64  `json:"id"`
+			Body string `json:"body"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&notes)
+		resp.Body.Close()
+		if err != nil {
+			return 0, fmt.Errorf("unable to decode notes: %w", err)
+		}
+		for _, note := range notes {
+			if strings.Contains(note.Body, commentMarker) {
+				return note.ID, nil
+			}
+		}
+		if len(notes) < 100 {
+			return 0, nil
+		}
+	}
+}
+
+// postGitLabNote posts the Markdown report as a note of the merge request, or
+// updates the note of a previous run so that there is a single up-to-date
+// note.
+func postGitLabNote(projectURL, markdown string) error {
+	notesURL := fmt.Sprintf("%s/merge_requests/%s/notes", projectURL, url.PathEscape(os.Getenv("CI_MERGE_REQUEST_IID")))
+	id, err := findGitLabNote(notesURL)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(map[string]string{"body": markdown + "\n" + commentMarker + "\n"})
+	if err != nil {
+		return err
+	}
+	method, endpoint := http.MethodPost, notesURL
+	if id != 0 {
+		method, endpoint = http.MethodPut, fmt.Sprintf("%s/%d", notesURL, id)
+	}
+	resp, err := gitlabRequest(method, endpoint, data)
+	if err != nil {
+		return fmt.Errorf("unable to post the note: %w", err)
+	}
+	resp.Body.Close()
+	klog.InfoS("Posted the report on the merge request", "mergeRequest", os.Getenv("CI_MERGE_REQUEST_IID"), "updated", id != 0)
+	return nil
+}
+
+// setGitLabStatus sets the benchci status of the commit of the pipeline, with
+// the verdict line as description and a link to the job.
+func setGitLabStatus(projectURL string, results []result, failed bool) error {
+	verdict, regressions, improvements := resultVerdict(results, failed)
+	state := "success"
+	if verdict == "FAIL" {
+		state = "failed"
+	}
+	status := map[string]string{
+		"state":       state,
+		"name":        gitlabStatusName,
+		"description": fmt.Sprintf("%s: regressions=%d improvements=%d compared=%d", verdict, regressions, improvements, len(results)),
+	}
+	if jobURL := os.Getenv("CI_JOB_URL"); jobURL != "" {
+		status["target_url"] = jobURL
+	}
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	resp, err := gitlabRequest(http.MethodPost, fmt.Sprintf("%s/statuses/%s", projectURL, os.Getenv("CI_COMMIT_SHA")), data)
+	if err != nil {
+		return fmt.Errorf("unable to set the commit status: %w", err)
+	}
+	resp.Body.Close()
+	klog.InfoS("Set the commit status", "commit", os.Getenv("CI_COMMIT_SHA"), "state", state)
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setGitLabEnv(t *testing.T, env map[string]string) {
+	for name, value := range env {
+		os.Setenv(name, value)
+		t.Cleanup(func(name string) func() { return func() { os.Unsetenv(name) } }(name))
+	}
+}
+
+func TestValidateGitLab(t *testing.T) {
+	defer func(n, s bool) { gitlabNote, gitlabStatus = n, s }(gitlabNote, gitlabStatus)
+	gitlabNote, gitlabStatus = false, false
+	assert.NoError(t, validateGitLab())
+	gitlabNote = true
+	setGitLabEnv(t, map[string]string{"GITLAB_TOKEN": "secret", "CI_API_V4_URL": "https://gitlab.com/api/v4", "CI_PROJECT_ID": "42"})
+	assert.EqualError(t, validateGitLab(), "CI_MERGE_REQUEST_IID must be set to post results to GitLab")
+	setGitLabEnv(t, map[string]string{"CI_MERGE_REQUEST_IID": "7"})
+	assert.NoError(t, validateGitLab())
+	gitlabStatus = true
+	assert.Error(t, validateGitLab())
+}
+
+func TestGitLab(t *testing.T) {
+	var existing string
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("PRIVATE-TOKEN"))
+		var body map[string]string
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/projects/42/merge_requests/7/notes":
+			fmt.Fprintf(w, `[{"id": 1, "body": "LGTM"}%s]`, existing)
+			return
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v4/projects/42/merge_requests/7/notes",
+			r.Method == http.MethodPut && r.URL.Path == "/api/v4/projects/42/merge_requests/7/notes/2":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			requests = append(requests, r.Method+" "+body["body"])
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v4/projects/42/statuses/abc":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			requests = append(requests, fmt.Sprintf("status %s %s %s %s", body["name"], body["state"], body["description"], body["target_url"]))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+	setGitLabEnv(t, map[string]string{
+		"GITLAB_TOKEN":         "secret",
+		"CI_API_V4_URL":        server.URL + "/api/v4/",
+		"CI_PROJECT_ID":        "42",
+		"CI_MERGE_REQUEST_IID": "7",
+		"CI_COMMIT_SHA":        "abc",
+		"CI_JOB_URL":           "https://gitlab.com/antrea/antrea/-/jobs/1",
+	})
+	projectURL := gitlabProjectURL()
+	assert.Equal(t, server.URL+"/api/v4/projects/42", projectURL)
+
+	require.NoError(t, postGitLabNote(projectURL, "Report 1\n"))
+	existing = `, {"id": 2, "body": "Report 1\n<!-- benchci-report -->\n"}`
+	require.NoError(t, postGitLabNote(projectURL, "Report 2\n"))
+
+	disabled := false
+	regressed := result{
+		Benchmark:    Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &disabled, MemStats: &disabled}},
+		RatioNsPerOp: 0.25,
+		PNsPerOp:     math.NaN(),
+	}
+	require.NoError(t, setGitLabStatus(projectURL, []result{regressed}, true))
+	assert.Equal(t, []string{
+		"POST Report 1\n\n<!-- benchci-report -->\n",
+		"PUT Report 2\n\n<!-- benchci-report -->\n",
+		"status benchci failed FAIL: regressions=1 improvements=0 compared=1 https://gitlab.com/antrea/antrea/-/jobs/1",
+	}, requests)
+}
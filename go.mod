@@ -1,11 +1,13 @@
-module github.com/antoninbas/benchci
+module antrea.io/benchci
 
 go 1.16
 
 require (
 	github.com/blang/semver/v4 v4.0.0
+	github.com/go-logr/logr v1.0.0
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/stretchr/testify v1.3.0
+	golang.org/x/sys v0.0.0-20210510120138-977fb7262007
 	golang.org/x/tools v0.1.5
 	gopkg.in/src-d/go-git.v4 v4.13.1
 	gopkg.in/yaml.v2 v2.4.0
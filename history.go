@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"k8s.io/klog/v2"
+)
+
+var (
+	historyPath      string
+	historyBenchmark string
+	historyBranch    string
+	historyLast      int
+)
+
+func addHistoryFlags(fs *flag.FlagSet) {
+	fs.StringVar(&historyBenchmark, "benchmark", "", "unique `name` of the benchmark whose trend is shown")
+	fs.StringVar(&historyBranch, "branch", "", "only show the runs of this `branch`")
+	fs.IntVar(&historyLast, "last", 20, "`number` of most recent commits shown")
+}
+
+// historyEntry records the results of HEAD for one run, so that the trend of
+// a benchmark can be followed over commits.
+type historyEntry struct {
+	Time      time.Time         `json:"time"`
+	RunID     string            `json:"runID"`
+	Commit    string            `json:"commit"`
+	Branch    string            `json:"branch,omitempty"`
+	Meta      map[string]string `json:"meta,omitempty"`
+	Platform  platformInfo      `json:"platform"`
+	GoVersion string            `json:"goVersion,omitempty"`
+	Seed      int64             `json:"seed,omitempty"`
+	Results   Set               `json:"results"`
+}
+
+// historyStore stores the history of the results. Only JSON Lines files are
+// supported, other backends (e.g. a database) can implement it.
+type historyStore interface {
+	add(entry *historyEntry) error
+	// entries returns all the entries, oldest first.
+	entries() ([]*historyEntry, error)
+}
+
+// historyFile is a history stored as a JSON Lines file, one entry per run.
+type historyFile struct {
+	path string
+}
+
+func openHistory(path string) (historyStore, error) {
+	if strings.Contains(path, "://") {
+		return nil, fmt.Errorf("unsupported history store '%s', only paths of JSON Lines files are supported", path)
+	}
+	return historyFile{path: path}, nil
+}
+
+func (h historyFile) add(entry *historyEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open history: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write history: %w", err)
+	}
+	return nil
+}
+
+func (h historyFile) entries() ([]*historyEntry, error) {
+	f, err := os.Open(h.path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open history: %w", err)
+	}
+	defer f.Close()
+	var entries []*historyEntry
+	scanner := bufio.NewScanner(f)
+	// an entry holds the results of all the benchmarks
+	scanner.Buffer(nil, 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("invalid history entry at line %d: %w", line, err)
+		}
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read history: %w", err)
+	}
+	return entries, nil
+}
+
+// recordHistory adds the results of HEAD to the history, if -history is set.
+// Like the other reporters, a failure is only logged.
+func recordHistory(run *RunResult) {
+	if historyPath == "" {
+		return
+	}
+	store, err := openHistory(historyPath)
+	if err != nil {
+		klog.ErrorS(err, "Failed to open history")
+		return
+	}
+	branch, err := currentBranch()
+	if err != nil {
+		klog.InfoS("Unable to detect the branch, it is not recorded in the history", "err", err)
+	}
+	entry := &historyEntry{
+		Time:      time.Now().UTC(),
+		RunID:     runID,
+		Commit:    run.Commit,
+		Branch:    branch,
+		Meta:      runMetadata(),
+		Platform:  run.Platform,
+		GoVersion: run.GoVersion,
+		Seed:      run.Seed,
+		Results:   run.Results,
+	}
+	if err := store.add(entry); err != nil {
+		klog.ErrorS(err, "Failed to record history", "path", historyPath)
+		return
+	}
+	klog.InfoS("Recorded results in history", "path", historyPath, "commit", run.Commit, "branch", branch)
+}
+
+// benchmarkTrend returns the entries with a result for the benchmark, at most
+// one per commit (the most recent run), for the last commits, oldest first.
+func benchmarkTrend(entries []*historyEntry, benchmark, branch string, last int) []*historyEntry {
+	seen := map[string]bool{}
+	var trend []*historyEntry
+	for i := len(entries) - 1; i >= 0 && (last <= 0 || len(trend) < last); i-- {
+		entry := entries[i]
+		if _, ok := entry.Results[benchmark]; !ok || seen[entry.Commit] || (branch != "" && entry.Branch != branch) {
+			continue
+		}
+		seen[entry.Commit] = true
+		trend = append(trend, entry)
+	}
+	for i, j := 0, len(trend)-1; i < j; i, j = i+1, j-1 {
+		trend[i], trend[j] = trend[j], trend[i]
+	}
+	return trend
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+// showTrend writes the results of a benchmark over commits, with the change
+// from one commit to the next, and the most likely step change of ns/op.
+func showTrend(w io.Writer, benchmark string, trend []*historyEntry) {
+	fmt.Fprintf(w, "\nTrend of %s\n", benchmark)
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 9+len(benchmark)))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Commit", "Time", "Branch", "NsPerOp", "AllocedBytesPerOp", "Delta NsPerOp"})
+	values := make([]float64, 0, len(trend))
+	for i, entry := range trend {
+		cur := entry.Results[benchmark]
+		row := []string{shortCommit(entry.Commit), entry.Time.Format("2006-01-02 15:04"), entry.Branch, numbers.float(cur.NsPerOp) + " ns/op", numbers.uint(cur.AllocedBytesPerOp) + " B/op", "-"}
+		colors := make([]tablewriter.Colors, len(row))
+		if i > 0 {
+			if prev := trend[i-1].Results[benchmark]; prev.NsPerOp != 0 {
+				ratio := (cur.NsPerOp - prev.NsPerOp) / prev.NsPerOp
+				row[5], colors[5] = signedRatio(ratio), generateColor(ratio)
+			}
+		}
+		table.Rich(row, colors)
+		values = append(values, cur.NsPerOp)
+	}
+	table.Render()
+
+	if k, ratio, ok := changePoint(values); ok {
+		fmt.Fprintf(w, "\nLargest step change of ns/op: %s at commit %s\n", signedRatio(ratio), shortCommit(trend[k].Commit))
+	}
+	fmt.Fprintln(w)
+}
+
+// runHistory shows the trend of a benchmark over the last commits recorded in
+// the history.
+func runHistory(*flag.FlagSet) error {
+	if historyPath == "" {
+		return fmt.Errorf("--history is required")
+	}
+	if historyBenchmark == "" {
+		return fmt.Errorf("--benchmark is required")
+	}
+	store, err := openHistory(historyPath)
+	if err != nil {
+		return err
+	}
+	entries, err := store.entries()
+	if err != nil {
+		return err
+	}
+	trend := benchmarkTrend(entries, historyBenchmark, historyBranch, historyLast)
+	if len(trend) == 0 {
+		return fmt.Errorf("no results for benchmark '%s' in the history", historyBenchmark)
+	}
+	showTrend(report, historyBenchmark, trend)
+	return nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestHistoryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := openHistory(filepath.Join(dir, "history.jsonl"))
+	require.NoError(t, err)
+	start := time.Date(2021, 10, 14, 12, 0, 0, 0, time.UTC)
+	for i, commit := range []string{"aaa", "bbb"} {
+		require.NoError(t, store.add(&historyEntry{
+			Time:    start.Add(time.Duration(i) * time.Hour),
+			Commit:  commit,
+			Branch:  "main",
+			Results: Set{"BenchmarkFoo": &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkFoo-4", NsPerOp: float64(100 + i)}}},
+		}))
+	}
+	entries, err := store.entries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "bbb", entries[1].Commit)
+	assert.Equal(t, start.Add(time.Hour), entries[1].Time)
+	assert.Equal(t, 101.0, entries[1].Results["BenchmarkFoo"].NsPerOp)
+
+	_, err = openHistory("sqlite://benchci.db")
+	assert.Error(t, err)
+}
+
+func TestBenchmarkTrend(t *testing.T) {
+	entry := func(commit, branch string, nsPerOp float64) *historyEntry {
+		return &historyEntry{Commit: commit, Branch: branch, Results: Set{"BenchmarkFoo": &benchResult{Benchmark: &parse.Benchmark{NsPerOp: nsPerOp}}}}
+	}
+	entries := []*historyEntry{
+		entry("aaa", "main", 100),
+		entry("bbb", "main", 101),
+		entry("ccc", "feature", 120),
+		{Commit: "ddd", Branch: "main", Results: Set{}},
+		entry("bbb", "main", 102),
+		entry("eee", "main", 150),
+	}
+
+	commits := func(trend []*historyEntry) []string {
+		var commits []string
+		for _, e := range trend {
+			commits = append(commits, e.Commit)
+		}
+		return commits
+	}
+	trend := benchmarkTrend(entries, "BenchmarkFoo", "", 0)
+	assert.Equal(t, []string{"aaa", "ccc", "bbb", "eee"}, commits(trend))
+	// the most recent run of a commit is used
+	assert.Equal(t, 102.0, trend[2].Results["BenchmarkFoo"].NsPerOp)
+	assert.Equal(t, []string{"bbb", "eee"}, commits(benchmarkTrend(entries, "BenchmarkFoo", "main", 2)))
+	assert.Empty(t, benchmarkTrend(entries, "BenchmarkBar", "", 0))
+}
+
+func TestShowTrend(t *testing.T) {
+	entry := func(commit string, nsPerOp float64) *historyEntry {
+		return &historyEntry{Commit: commit, Results: Set{"BenchmarkFoo": &benchResult{Benchmark: &parse.Benchmark{NsPerOp: nsPerOp}}}}
+	}
+	trend := []*historyEntry{
+		entry("1111111111111111111111111111111111111111", 100),
+		entry("2222222222222222222222222222222222222222", 100),
+		entry("3333333333333333333333333333333333333333", 150),
+		entry("4444444444444444444444444444444444444444", 150),
+	}
+
+	var b bytes.Buffer
+	showTrend(&b, "BenchmarkFoo", trend)
+	assert.Contains(t, b.String(), "Trend of BenchmarkFoo")
+	assert.Contains(t, b.String(), "+50.00%")
+	assert.Contains(t, b.String(), "Largest step change of ns/op: +50.00% at commit 33333333\n")
+}
+
+func TestTrendAlerts(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	defer func(w int, th ratioValue) { trendWindow, trendThreshold = w, th }(trendWindow, trendThreshold)
+	benchmarks.Benchmarks = []Benchmark{
+		{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op"}},
+		{UniqueName: "BenchmarkBar", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op"}},
+	}
+	var entries []*historyEntry
+	// BenchmarkFoo creeps up by 4% per commit, BenchmarkBar jumps once
+	for i, foo := range []float64{100, 100, 100, 104, 108, 112, 116, 116, 116} {
+		bar := 100.0
+		if i >= 5 {
+			bar = 130
+		}
+		entries = append(entries, &historyEntry{Commit: fmt.Sprintf("%040d", i), Branch: "main", Results: Set{
+			"BenchmarkFoo": &benchResult{Benchmark: &parse.Benchmark{NsPerOp: foo}},
+			"BenchmarkBar": &benchResult{Benchmark: &parse.Benchmark{NsPerOp: bar}},
+		}})
+	}
+
+	trendWindow = 10
+	alerts := trendAlerts(entries, "main")
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "BenchmarkFoo", alerts[0].benchmark)
+	assert.Equal(t, 9, alerts[0].commits)
+	assert.Equal(t, fmt.Sprintf("%040d", 3), alerts[0].first)
+
+	var b bytes.Buffer
+	showTrendAlerts(&b, alerts)
+	assert.Contains(t, b.String(), "Trend alerts")
+	assert.Contains(t, b.String(), "00000000..00000000")
+	assert.Equal(t, []jsonTrendAlert{{Benchmark: "BenchmarkFoo", Commits: 9, Ratio: alerts[0].ratio, First: alerts[0].first, Last: alerts[0].last}}, newJSONTrendAlerts(alerts))
+	markdown := markdownReport(&refResults{name: "HEAD", run: &RunResult{}}, nil, nil, nil, nil, nil, alerts, false, false)
+	assert.Contains(t, markdown, "<summary>Trend alerts (1)</summary>")
+	assert.Contains(t, markdown, "| BenchmarkFoo |       9 | +12.48%       | 00000000..00000000 |")
+
+	// the drift is below the trend threshold
+	trendThreshold = 0.5
+	assert.Empty(t, trendAlerts(entries, "main"))
+	trendThreshold = 0
+	assert.Empty(t, trendAlerts(entries, "feature"))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"k8s.io/klog/v2"
+)
+
+// refVars are the variables which can be used in the args of hooks and
+// commands, e.g. "{{.Ref}}".
+type refVars struct {
+	Ref       string
+	CommitSHA string
+	IsTag     bool
+	// ArtifactsDir is a directory for the artifacts of the ref.
+	ArtifactsDir string
+	// env is added to the environment of the commands run for the ref.
+	env []string
+	// cpus is the list of CPUs the commands run for the ref are pinned to,
+	// with -parallel-refs.
+	cpus string
+}
+
+func newRefVars(ref, commit string, isTag bool) refVars {
+	vars := refVars{Ref: ref, CommitSHA: commit, IsTag: isTag}
+	if artifactsDir != "" {
+		vars.ArtifactsDir = filepath.Join(artifactsDir, sanitizePathElem(ref))
+	}
+	return vars
+}
+
+// expandArgs expands the templates in the args of a step.
+func expandArgs(args []string, vars refVars) ([]string, error) {
+	expanded := make([]string, 0, len(args))
+	for _, arg := range args {
+		t, err := template.New("arg").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template '%s': %w", arg, err)
+		}
+		var b bytes.Buffer
+		if err := t.Execute(&b, vars); err != nil {
+			return nil, fmt.Errorf("unable to expand '%s': %w", arg, err)
+		}
+		expanded = append(expanded, b.String())
+	}
+	return expanded, nil
+}
+
+// usesHooksOrCommands returns true if commands are run for any ref, in which
+// case an artifacts directory is provided to them.
+func usesHooksOrCommands() bool {
+	if len(benchmarks.Build) > 0 || len(benchmarks.Setup) > 0 || len(benchmarks.Teardown) > 0 {
+		return true
+	}
+	for _, benchmark := range benchmarks.Benchmarks {
+		if len(benchmark.Commands) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// runHooks runs setup or teardown hooks in order, stopping at the first
+// failure.
+func runHooks(kind, dir string, vars refVars, hooks []CommandStep) error {
+	for i, hook := range hooks {
+		args, err := expandArgs(hook.Args, vars)
+		if err != nil {
+			return fmt.Errorf("invalid %s hook: %w", kind, err)
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("%s hook %s has no args", kind, stepName(i, hook))
+		}
+		cmd := execCommand(dir, vars, args)
+		cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+		klog.InfoS("Running hook", "kind", kind, "ref", vars.Ref, "command", cmd)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %s failed for %s: %w", kind, stepName(i, hook), vars.Ref, err)
+		}
+	}
+	return nil
+}
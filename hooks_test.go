@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandArgs(t *testing.T) {
+	vars := refVars{Ref: "v1.2.0", CommitSHA: "abc", IsTag: true, ArtifactsDir: "/tmp/artifacts/v1.2.0"}
+	args, err := expandArgs([]string{"kind", "create", "cluster", "--name=bench-{{.Ref}}", "{{if .IsTag}}release{{else}}dev{{end}}", "{{.ArtifactsDir}}/{{.CommitSHA}}"}, vars)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"kind", "create", "cluster", "--name=bench-v1.2.0", "release", "/tmp/artifacts/v1.2.0/abc"}, args)
+
+	_, err = expandArgs([]string{"{{.Branch}}"}, vars)
+	assert.Error(t, err)
+	_, err = expandArgs([]string{"{{.Ref"}, vars)
+	assert.Error(t, err)
+}
+
+func TestRunHooks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	vars := refVars{Ref: "HEAD", CommitSHA: "abc"}
+
+	hooks := []CommandStep{
+		{Args: []string{"sh", "-c", "echo {{.Ref}} $BENCHCI_COMMIT > setup.txt"}},
+	}
+	require.NoError(t, runHooks("setup", dir, vars, hooks))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "setup.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "HEAD abc\n", string(data))
+
+	hooks = append(hooks, CommandStep{Name: "cleanup", Args: []string{"false"}})
+	assert.EqualError(t, runHooks("teardown", dir, vars, hooks), "teardown hook cleanup failed for HEAD: exit status 1")
+}
+
+func TestBuildHooks(t *testing.T) {
+	defer func(b BenchmarkList) { *benchmarks = b }(*benchmarks)
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	*benchmarks = BenchmarkList{
+		Build: []CommandStep{{Args: []string{"sh", "-c", "sleep 0.1; echo image-{{.Ref}} > build.txt"}}},
+		Setup: []CommandStep{{Args: []string{"sh", "-c", "cat build.txt > setup.txt"}}},
+	}
+	run, err := runBenchmarks(dir, "HEAD", "abc", "", nil)
+	require.NoError(t, err)
+	assert.True(t, run.BuildDuration >= 100*time.Millisecond, run.BuildDuration)
+	assert.True(t, !run.End.Before(run.Start))
+	data, err := ioutil.ReadFile(filepath.Join(dir, "setup.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "image-HEAD\n", string(data))
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// issueLabel is set on all the filed issues, to find the open ones.
+	issueLabel = "benchci-regression"
+
+	defaultIssueTitle = "Performance regression in {{.Benchmark}}"
+	defaultIssueBody  = `{{.Benchmark}} regressed compared with {{.Base}}: {{.Summary}}.
+
+{{with .Description}}{{.}}
+
+{{end}}{{.Table}}
+Suspect commits: {{.Commits}}
+
+{{with .Owner}}cc {{.}}
+
+{{end}}<sub>Run ID: {{.RunID}}</sub>
+`
+)
+
+var fileIssues bool
+
+// issueMarkerPattern matches the hidden marker identifying the benchmark of an
+// issue, which is more reliable than the title since it can be customized.
+var issueMarkerPattern = regexp.MustCompile(`<!-- benchci-regression: (.+?) -->`)
+
+func issueMarker(name string) string {
+	return fmt.Sprintf("<!-- benchci-regression: %s -->", name)
+}
+
+// issueData is the data available to the issue templates.
+type issueData struct {
+	Benchmark string
+	Base      string
+	// Summary describes the compared metrics, e.g. "ns/op +25.00%".
+	Summary string
+	// Description and Link are the ones of the benchmark, if any.
+	Description string
+	Link        string
+	// Owner is the owner of the benchmark, to mention, if any.
+	Owner string
+	// Table is the row of the comparison table, in Markdown.
+	Table string
+	// Commits is the range of commits which may have caused the regression,
+	// as a compare link when running in GitHub Actions.
+	Commits string
+	RunID   string
+}
+
+// validateFileIssues fails early if issues cannot be filed, rather than after
+// running the benchmarks.
+func validateFileIssues() error {
+	if !fileIssues {
+		return nil
+	}
+	if os.Getenv("GITHUB_TOKEN") == "" || os.Getenv("GITHUB_REPOSITORY") == "" {
+		return fmt.Errorf("GITHUB_TOKEN and GITHUB_REPOSITORY must be set to file issues")
+	}
+	return nil
+}
+
+// suspectCommits returns the range of commits between base and head.
+func suspectCommits(head, base *refResults) string {
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	if repository != "" && head.run.Commit != "" && base.run.Commit != "" {
+		server := os.Getenv("GITHUB_SERVER_URL")
+		if server == "" {
+			server = "https://github.com"
+		}
+		return fmt.Sprintf("%s/%s/compare/%s...%s", server, repository, base.run.Commit, head.run.Commit)
+	}
+	return fmt.Sprintf("%s..%s", base.name, head.name)
+}
+
+// openIssueBenchmarks returns the benchmarks which already have an open issue.
+// Only the 100 most recent issues are considered, which is plenty unless
+// regressions are never fixed.
+func openIssueBenchmarks(apiURL, repository string) (map[string]bool, error) {
+	query := url.Values{"state": {"open"}, "labels": {issueLabel}, "per_page": {"100"}}
+	resp, err := githubRequest(http.MethodGet, fmt.Sprintf("%s/repos/%s/issues?%s", apiURL, repository, query.Encode()), "", nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list open issues: %w", err)
+	}
+	defer resp.Body.Close()
+	var issues []struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&issues); err != nil {
+		return nil, fmt.Errorf("unable to decode open issues: %w", err)
+	}
+	benchmarks := map[string]bool{}
+	for _, issue := range issues {
+		if m := issueMarkerPattern.FindStringSubmatch(issue.Body); m != nil {
+			benchmarks[m[1]] = true
+		}
+	}
+	return benchmarks, nil
+}
+
+func createIssue(apiURL, repository, title, body string, labels []string) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{"title": title, "body": body, "labels": labels})
+	if err != nil {
+		return "", err
+	}
+	resp, err := githubRequest(http.MethodPost, fmt.Sprintf("%s/repos/%s/issues", apiURL, repository), "application/json", data)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var issue struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
+		return "", fmt.Errorf("unable to decode the created issue: %w", err)
+	}
+	return issue.HTMLURL, nil
+}
+
+// fileRegressionIssues opens a GitHub issue for each regressed benchmark, unless
+// there is already an open one for it. A failure to file an issue is logged,
+// and the other benchmarks are still processed.
+func fileRegressionIssues(apiURL string, head *refResults, comparisons []comparison) error {
+	issueTemplate := IssueTemplate{Title: defaultIssueTitle, Body: defaultIssueBody}
+	if benchmarks.Issues != nil {
+		if benchmarks.Issues.Title != "" {
+			issueTemplate.Title = benchmarks.Issues.Title
+		}
+		if benchmarks.Issues.Body != "" {
+			issueTemplate.Body = benchmarks.Issues.Body
+		}
+		issueTemplate.Labels = benchmarks.Issues.Labels
+	}
+	titleTemplate, err := template.New("title").Parse(issueTemplate.Title)
+	if err != nil {
+		return fmt.Errorf("invalid issue title template: %w", err)
+	}
+	bodyTemplate, err := template.New("body").Parse(issueTemplate.Body)
+	if err != nil {
+		return fmt.Errorf("invalid issue body template: %w", err)
+	}
+	labels := append([]string{issueLabel}, issueTemplate.Labels...)
+
+	repository := os.Getenv("GITHUB_REPOSITORY")
+	existing, err := openIssueBenchmarks(apiURL, repository)
+	if err != nil {
+		return err
+	}
+	for _, c := range comparisons {
+		if c.base == nil {
+			continue
+		}
+		for _, r := range c.results {
+			// the same benchmark may regress compared with both the base and
+			// the latest release
+			if !isRegression(r) || existing[r.UniqueName] {
+				continue
+			}
+			existing[r.UniqueName] = true
+			results := []result{r}
+			row, _ := ratioRow(r, comparedCustomUnits(results), signedRatio)
+			row[0] = markdownName(r.Benchmark)
+			var table bytes.Buffer
+			markdownTable(&table, ratioHeaders(results), [][]string{row})
+			data := issueData{
+				Benchmark:   r.UniqueName,
+				Base:        c.base.name,
+				Summary:     regressionSummary(r),
+				Description: r.Description,
+				Link:        r.Link,
+				Owner:       r.Owner,
+				Table:       table.String(),
+				Commits:     suspectCommits(head, c.base),
+				RunID:       runID,
+			}
+			var title, body strings.Builder
+			if err := titleTemplate.Execute(&title, data); err != nil {
+				klog.ErrorS(err, "Failed to render the issue title", "benchmark", r.UniqueName)
+				continue
+			}
+			if err := bodyTemplate.Execute(&body, data); err != nil {
+				klog.ErrorS(err, "Failed to render the issue body", "benchmark", r.UniqueName)
+				continue
+			}
+			fmt.Fprintf(&body, "\n%s\n", issueMarker(r.UniqueName))
+			issueURL, err := createIssue(apiURL, repository, strings.TrimSpace(title.String()), body.String(), labels)
+			if err != nil {
+				klog.ErrorS(err, "Failed to file a regression issue", "benchmark", r.UniqueName)
+				continue
+			}
+			klog.InfoS("Filed regression issue", "benchmark", r.UniqueName, "url", issueURL)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRegressionIssues(t *testing.T) {
+	defer func(b BenchmarkList, id string) { *benchmarks, runID = b, id }(*benchmarks, runID)
+	*benchmarks = BenchmarkList{Issues: &IssueTemplate{Title: "{{.Benchmark}} is slower than {{.Base}}", Labels: []string{"performance"}}}
+	runID = "run"
+	os.Setenv("GITHUB_TOKEN", "secret")
+	defer os.Unsetenv("GITHUB_TOKEN")
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+	os.Setenv("GITHUB_SERVER_URL", "https://github.com")
+	defer os.Unsetenv("GITHUB_SERVER_URL")
+
+	type issue struct {
+		Title  string   `json:"title"`
+		Body   string   `json:"body"`
+		Labels []string `json:"labels"`
+	}
+	var created []issue
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/antrea-io/antrea/issues":
+			assert.Equal(t, "benchci-regression", r.URL.Query().Get("labels"))
+			assert.Equal(t, "open", r.URL.Query().Get("state"))
+			fmt.Fprint(w, `[{"body": "BenchmarkBar regressed\n<!-- benchci-regression: BenchmarkBar -->"}]`)
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/antrea-io/antrea/issues":
+			var i issue
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&i))
+			created = append(created, i)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"html_url": "https://github.com/antrea-io/antrea/issues/%d"}`, len(created))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	disabled := false
+	regressed := func(name string) result {
+		return result{
+			Benchmark:    Benchmark{Name: name, UniqueName: name, BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &disabled, MemStats: &disabled}},
+			RatioNsPerOp: 0.25,
+			PNsPerOp:     math.NaN(),
+		}
+	}
+	unchanged := regressed("BenchmarkBaz")
+	unchanged.RatioNsPerOp = 0.01
+	head := &refResults{name: "HEAD", run: &RunResult{Commit: "bbbb"}}
+	base := &refResults{name: "main", run: &RunResult{Commit: "aaaa"}}
+	release := &refResults{name: "v1.4.0", run: &RunResult{}}
+	comparisons := []comparison{
+		{base, []result{regressed("BenchmarkFoo"), regressed("BenchmarkBar"), unchanged}, true},
+		{release, []result{regressed("BenchmarkFoo")}, true},
+	}
+	require.NoError(t, fileRegressionIssues(server.URL, head, comparisons))
+
+	// BenchmarkBar already has an open issue, and BenchmarkFoo is only
+	// filed once.
+	require.Len(t, created, 1)
+	assert.Equal(t, "BenchmarkFoo is slower than main", created[0].Title)
+	assert.Equal(t, []string{"benchci-regression", "performance"}, created[0].Labels)
+	assert.Equal(t, `BenchmarkFoo regressed compared with main: ns/op +25.00%.
+
+|     Name     | NsPerOp | AllocedBytesPerOp |
+|--------------|---------|-------------------|
+| BenchmarkFoo | +25.00% | -                 |
+
+Suspect commits: https://github.com/antrea-io/antrea/compare/aaaa...bbbb
+
+<sub>Run ID: run</sub>
+
+<!-- benchci-regression: BenchmarkFoo -->
+`, created[0].Body)
+
+	benchmarks.Issues.Body = "{{.Unknown}}"
+	created = nil
+	require.NoError(t, fileRegressionIssues(server.URL, head, comparisons))
+	assert.Empty(t, created)
+}
+
+func TestSuspectCommits(t *testing.T) {
+	os.Unsetenv("GITHUB_REPOSITORY")
+	head := &refResults{name: "HEAD", run: &RunResult{Commit: "bbbb"}}
+	base := &refResults{name: "main", run: &RunResult{Commit: "aaaa"}}
+	assert.Equal(t, "main..HEAD", suspectCommits(head, base))
+}
+
+func TestValidateFileIssues(t *testing.T) {
+	defer func(enabled bool) { fileIssues = enabled }(fileIssues)
+	fileIssues = false
+	assert.NoError(t, validateFileIssues())
+	fileIssues = true
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+	assert.Error(t, validateFileIssues())
+	os.Setenv("GITHUB_TOKEN", "secret")
+	defer os.Unsetenv("GITHUB_TOKEN")
+	assert.NoError(t, validateFileIssues())
+}
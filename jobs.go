@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"k8s.io/klog/v2"
+)
+
+// jobs is the number of benchmark entries run concurrently for each ref, each
+// of them pinned to its own cores.
+var jobs int
+
+func validateJobs() error {
+	if jobs < 1 {
+		return fmt.Errorf("invalid -jobs %d: must be at least 1", jobs)
+	}
+	if jobs > 1 && runtime.GOOS != "linux" {
+		return fmt.Errorf("-jobs is not supported on %s", runtime.GOOS)
+	}
+	return nil
+}
+
+// jobCPUs splits the CPUs of a ref between the jobs: the CPUs of its pass with
+// -parallel-refs, or else one CPU per core of the machine.
+func jobCPUs(sysfs, cpus string) ([][]int, error) {
+	var available []int
+	if cpus != "" {
+		var err error
+		if available, err = parseCPUList(cpus); err != nil {
+			return nil, err
+		}
+	} else {
+		topology, err := readSMTTopology(sysfs)
+		if err != nil {
+			return nil, err
+		}
+		available = topology.coreCPUs()
+	}
+	return splitCPUs(available, jobs, "-jobs")
+}
+
+// runEntries calls runEntry for each benchmark entry, in order, or with -jobs
+// from as many goroutines as jobs, each of them with the variables of the ref
+// pinned to the CPUs of the job.
+func runEntries(entries int, vars refVars, runEntry func(int, refVars)) error {
+	if jobs <= 1 {
+		for i := 0; i < entries; i++ {
+			runEntry(i, vars)
+		}
+		return nil
+	}
+	sets, err := jobCPUs("/sys", vars.cpus)
+	if err != nil {
+		return err
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for _, set := range sets {
+		jobVars := vars
+		jobVars.cpus = formatCPUList(set)
+		klog.InfoS("Starting benchmark job", "ref", vars.Ref, "cpus", jobVars.cpus)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				runEntry(i, jobVars)
+			}
+		}()
+	}
+	for i := 0; i < entries; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return nil
+}
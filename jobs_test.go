@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobCPUs(t *testing.T) {
+	defer func(b BenchmarkList, n int) { *benchmarks, jobs = b, n }(*benchmarks, jobs)
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{
+		{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1"}},
+	}}
+	sysfs := writeSysfs(t)
+	defer os.RemoveAll(sysfs)
+	jobs = 2
+	sets, err := jobCPUs(sysfs, "")
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 1}, {2, 3}}, sets)
+	// the CPUs of the pass with -parallel-refs
+	sets, err = jobCPUs(sysfs, "4,5,6")
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{4}, {5}}, sets)
+
+	benchmarks.Benchmarks[0].Cpu = "2"
+	_, err = jobCPUs(sysfs, "4,5,6")
+	assert.EqualError(t, err, "-jobs needs 4 cores for 2 sets of up to 2 CPUs, but only 3 are available")
+}
+
+func TestRunEntries(t *testing.T) {
+	defer func(b BenchmarkList, n int) { *benchmarks, jobs = b, n }(*benchmarks, jobs)
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{
+		{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1"}},
+	}}
+	var mu sync.Mutex
+	var entries []int
+	cpus := map[string]bool{}
+	runEntry := func(i int, vars refVars) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, i)
+		cpus[vars.cpus] = true
+	}
+
+	jobs = 1
+	require.NoError(t, runEntries(5, refVars{Ref: "HEAD"}, runEntry))
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, entries)
+	assert.Equal(t, map[string]bool{"": true}, cpus)
+
+	jobs = 2
+	entries, cpus = nil, map[string]bool{}
+	require.NoError(t, runEntries(5, refVars{Ref: "HEAD", cpus: "0-3"}, runEntry))
+	sort.Ints(entries)
+	assert.Equal(t, []int{0, 1, 2, 3, 4}, entries)
+	for set := range cpus {
+		assert.Contains(t, []string{"0,1", "2,3"}, set)
+	}
+
+	jobs = 3
+	assert.Error(t, runEntries(5, refVars{Ref: "HEAD", cpus: "0,1"}, runEntry))
+}
+
+func TestValidateJobs(t *testing.T) {
+	defer func(n int) { jobs = n }(jobs)
+	jobs = 0
+	assert.Error(t, validateJobs())
+	jobs = 1
+	assert.NoError(t, validateJobs())
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	// layoutWide shows one row per benchmark and one column per (metric, ref).
+	layoutWide = "wide"
+	// layoutTransposed shows one column per benchmark and one row per (ref,
+	// metric), which is more readable with few benchmarks and many refs.
+	layoutTransposed = "transposed"
+	// layoutCompact shows one row per benchmark and one column per metric,
+	// with the values for all refs in the same cell.
+	layoutCompact = "compact"
+)
+
+var layout string
+
+func validateLayout() error {
+	switch layout {
+	case layoutWide, layoutTransposed, layoutCompact:
+		return nil
+	default:
+		return fmt.Errorf("unsupported layout '%s', must be one of %s, %s or %s", layout, layoutWide, layoutTransposed, layoutCompact)
+	}
+}
+
+// resultGroup holds the result rows of a benchmark, as returned by
+// generateRow, one per ref.
+type resultGroup [][]string
+
+func (g resultGroup) name() string {
+	return g[0][0]
+}
+
+// cell returns the value of the metric with the given index for a ref.
+func (g resultGroup) cell(ref string, metric int) string {
+	for _, row := range g {
+		if row[1] == ref {
+			return strings.TrimSpace(row[2+metric])
+		}
+	}
+	return "-"
+}
+
+func metricHeaders() []string {
+	headers := []string{"NsPerOp", "AllocedBytesPerOp"}
+	if allocsEnabled() {
+		headers = append(headers, "AllocsPerOp")
+	}
+	if throughputEnabled() {
+		headers = append(headers, "MBPerS")
+	}
+	if contentionEnabled() {
+		headers = append(headers, "Contention")
+	}
+	if memStatsEnabled() {
+		headers = append(headers, "PeakHeap")
+	}
+	return headers
+}
+
+// groupRefs returns all the refs found in the groups, in order of appearance.
+func groupRefs(groups []resultGroup) []string {
+	var refs []string
+	seen := map[string]bool{}
+	for _, g := range groups {
+		for _, row := range g {
+			if !seen[row[1]] {
+				seen[row[1]] = true
+				refs = append(refs, row[1])
+			}
+		}
+	}
+	return refs
+}
+
+func showResult(w io.Writer, groups []resultGroup) {
+	fmt.Fprintln(w, "\nResult")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 6))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetRowLine(true)
+	table.SetAutoWrapText(false)
+	metrics := metricHeaders()
+	refs := groupRefs(groups)
+
+	switch layout {
+	case layoutTransposed:
+		headers := []string{"Commit", "Metric"}
+		for _, g := range groups {
+			headers = append(headers, g.name())
+		}
+		table.SetHeader(headers)
+		table.SetAutoMergeCellsByColumnIndex([]int{0})
+		for _, ref := range refs {
+			for i, metric := range metrics {
+				row := []string{ref, metric}
+				for _, g := range groups {
+					row = append(row, g.cell(ref, i))
+				}
+				table.Append(row)
+			}
+		}
+	case layoutCompact:
+		headers := []string{"Name"}
+		for _, metric := range metrics {
+			headers = append(headers, fmt.Sprintf("%s (%s)", metric, strings.Join(refs, " / ")))
+		}
+		table.SetHeader(headers)
+		for _, g := range groups {
+			row := []string{g.name()}
+			for i := range metrics {
+				values := make([]string, 0, len(refs))
+				for _, ref := range refs {
+					values = append(values, g.cell(ref, i))
+				}
+				row = append(row, strings.Join(values, " / "))
+			}
+			table.Append(row)
+		}
+	default:
+		headers := []string{"Name"}
+		for _, metric := range metrics {
+			for _, ref := range refs {
+				headers = append(headers, fmt.Sprintf("%s (%s)", metric, ref))
+			}
+		}
+		table.SetHeader(headers)
+		for _, g := range groups {
+			row := []string{g.name()}
+			for i := range metrics {
+				for _, ref := range refs {
+					row = append(row, g.cell(ref, i))
+				}
+			}
+			table.Append(row)
+		}
+	}
+	table.Render()
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultGroup(t *testing.T) {
+	groups := []resultGroup{
+		{{"BenchmarkFoo-4", "HEAD", " 10.00 ns/op", " 0 B/op"}, {"BenchmarkFoo-4", "main", " 12.00 ns/op", " 8 B/op"}},
+		{{"BenchmarkBar-4", "HEAD", " 5.00 ns/op", " 0 B/op"}, {"BenchmarkBar", "main", "-", "-"}, {"BenchmarkBar-4", "v1.2.0", " 4.00 ns/op", " 0 B/op"}},
+	}
+	assert.Equal(t, []string{"HEAD", "main", "v1.2.0"}, groupRefs(groups))
+	assert.Equal(t, "BenchmarkBar-4", groups[1].name())
+	assert.Equal(t, "12.00 ns/op", groups[0].cell("main", 0))
+	assert.Equal(t, "8 B/op", groups[0].cell("main", 1))
+	assert.Equal(t, "-", groups[0].cell("v1.2.0", 0))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// memoryUnits are the suffixes accepted for memory limits, as in Kubernetes
+// resource quantities.
+var memoryUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"Ki", 1 << 10},
+	{"Mi", 1 << 20},
+	{"Gi", 1 << 30},
+	{"Ti", 1 << 40},
+	{"k", 1000},
+	{"K", 1000},
+	{"M", 1000 * 1000},
+	{"G", 1000 * 1000 * 1000},
+	{"T", 1000 * 1000 * 1000 * 1000},
+}
+
+// parseCPULimit parses a CPU limit, in cores ("2", "0.5") or millicores
+// ("500m").
+func parseCPULimit(s string) (float64, error) {
+	value, factor := s, 1.0
+	if strings.HasSuffix(s, "m") {
+		value, factor = strings.TrimSuffix(s, "m"), 0.001
+	}
+	cpu, err := strconv.ParseFloat(value, 64)
+	if err != nil || cpu <= 0 {
+		return 0, fmt.Errorf("invalid CPU limit '%s'", s)
+	}
+	return cpu * factor, nil
+}
+
+// parseMemoryLimit parses a memory limit in bytes, with an optional unit
+// suffix ("1Gi", "512M").
+func parseMemoryLimit(s string) (int64, error) {
+	value, factor := s, int64(1)
+	for _, unit := range memoryUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			value, factor = strings.TrimSuffix(s, unit.suffix), unit.factor
+			break
+		}
+	}
+	memory, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || memory <= 0 {
+		return 0, fmt.Errorf("invalid memory limit '%s'", s)
+	}
+	return memory * factor, nil
+}
+
+// limitsExec returns the command used to run the test binary in a transient
+// systemd scope, i.e. a dedicated cgroup, with the given limits. It returns
+// an empty string if there are no limits.
+func limitsExec(limits *ResourceLimits) (string, error) {
+	if limits == nil || (limits.CPU == "" && limits.Memory == "") {
+		return "", nil
+	}
+	args := []string{"systemd-run", "--user", "--scope", "--quiet"}
+	if limits.CPU != "" {
+		cpu, err := parseCPULimit(limits.CPU)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, fmt.Sprintf("-pCPUQuota=%d%%", int(cpu*100)))
+	}
+	if limits.Memory != "" {
+		memory, err := parseMemoryLimit(limits.Memory)
+		if err != nil {
+			return "", err
+		}
+		// Disable swap so that going over the limit gets the process killed,
+		// as in a pod.
+		args = append(args, fmt.Sprintf("-pMemoryMax=%d", memory), "-pMemorySwapMax=0")
+	}
+	return strings.Join(args, " "), nil
+}
+
+func limitsEnabled() bool {
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Limits != nil && benchmark.Skip == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func checkLimits() doctorCheck {
+	c := doctorCheck{name: "Resource limits"}
+	if !limitsEnabled() {
+		c.status, c.detail = checkPass, "not used"
+		return c
+	}
+	if runtime.GOOS != "linux" {
+		c.status, c.detail = checkFail, fmt.Sprintf("not supported on %s", runtime.GOOS)
+		return c
+	}
+	if err := exec.Command("systemd-run", "--user", "--scope", "--quiet", "-pCPUQuota=100%", "-pMemoryMax=1G", "true").Run(); err != nil {
+		c.status, c.detail = checkFail, fmt.Sprintf("unable to create a systemd scope: %v", err)
+		return c
+	}
+	c.status = checkPass
+	return c
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseCPULimit(t *testing.T) {
+	for _, tc := range []struct {
+		limit   string
+		cpu     float64
+		invalid bool
+	}{
+		{limit: "2", cpu: 2},
+		{limit: "0.5", cpu: 0.5},
+		{limit: "250m", cpu: 0.25},
+		{limit: "0", invalid: true},
+		{limit: "two", invalid: true},
+	} {
+		cpu, err := parseCPULimit(tc.limit)
+		if tc.invalid {
+			assert.Error(t, err, tc.limit)
+			continue
+		}
+		assert.NoError(t, err, tc.limit)
+		assert.InDelta(t, tc.cpu, cpu, 1e-9, tc.limit)
+	}
+}
+
+func TestParseMemoryLimit(t *testing.T) {
+	for _, tc := range []struct {
+		limit   string
+		memory  int64
+		invalid bool
+	}{
+		{limit: "1048576", memory: 1 << 20},
+		{limit: "1Gi", memory: 1 << 30},
+		{limit: "512Mi", memory: 512 << 20},
+		{limit: "2G", memory: 2000000000},
+		{limit: "1.5Gi", invalid: true},
+		{limit: "-1Mi", invalid: true},
+	} {
+		memory, err := parseMemoryLimit(tc.limit)
+		if tc.invalid {
+			assert.Error(t, err, tc.limit)
+			continue
+		}
+		assert.NoError(t, err, tc.limit)
+		assert.Equal(t, tc.memory, memory, tc.limit)
+	}
+}
+
+func TestLimitsExec(t *testing.T) {
+	cmd, err := limitsExec(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "", cmd)
+
+	cmd, err = limitsExec(&ResourceLimits{CPU: "2", Memory: "1Gi"})
+	assert.NoError(t, err)
+	assert.Equal(t, "systemd-run --user --scope --quiet -pCPUQuota=200% -pMemoryMax=1073741824 -pMemorySwapMax=0", cmd)
+
+	cmd, err = limitsExec(&ResourceLimits{CPU: "500m"})
+	assert.NoError(t, err)
+	assert.Equal(t, "systemd-run --user --scope --quiet -pCPUQuota=50%", cmd)
+
+	_, err = limitsExec(&ResourceLimits{Memory: "lots"})
+	assert.EqualError(t, err, "invalid memory limit 'lots'")
+}
+
+func TestResourceLimitsYAML(t *testing.T) {
+	var c BenchmarkConfiguration
+	assert.NoError(t, yaml.Unmarshal([]byte("limits:\n  cpu: 2\n  memory: 1Gi\n"), &c))
+	assert.Equal(t, &ResourceLimits{CPU: "2", Memory: "1Gi"}, c.Limits)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// lockFile takes an exclusive lock on a lock file next to path, waiting for
+// other processes to release it, so that concurrent jobs do not lose each
+// other's updates of path. The lock is released by calling the returned
+// function. A separate file is locked since path is replaced atomically.
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the lock file of '%s': %w", path, err)
+	}
+	if err := lockExclusive(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("unable to lock '%s': %w", path, err)
+	}
+	return func() {
+		unlock(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+)
+
+var logFormat string
+
+func init() {
+	// klog flags are registered in their own group, which also includes the
+	// log format.
+	names := []string{"log-format"}
+	flag.StringVar(&logFormat, "log-format", "text", "log `format`, one of text or json")
+	fs := flag.NewFlagSet("klog", flag.ExitOnError)
+	klog.InitFlags(fs)
+	fs.VisitAll(func(f *flag.Flag) {
+		flag.Var(f.Value, f.Name, f.Usage)
+		names = append(names, f.Name)
+	})
+	flagGroups = append(flagGroups, flagGroup{"Logging flags", names})
+}
+
+// setupLogging configures klog according to the log format.
+func setupLogging() error {
+	switch logFormat {
+	case "text":
+	case "json":
+		keysAndValues := []interface{}{"runID", runID}
+		if meta := runMetadata(); meta != nil {
+			keysAndValues = append(keysAndValues, "meta", meta)
+		}
+		klog.SetLogger(logr.New(&jsonLogSink{w: os.Stderr, mu: &sync.Mutex{}, keysAndValues: keysAndValues}))
+	default:
+		return fmt.Errorf("unsupported log format '%s'", logFormat)
+	}
+	return nil
+}
+
+// jsonLogSink is a logr.LogSink which writes one JSON object per log entry,
+// so that logs can be shipped to structured log pipelines. klog verbosity
+// flags still apply.
+type jsonLogSink struct {
+	w             io.Writer
+	mu            *sync.Mutex
+	name          string
+	keysAndValues []interface{}
+}
+
+func (s *jsonLogSink) Init(logr.RuntimeInfo) {}
+
+func (s *jsonLogSink) Enabled(level int) bool {
+	return klog.V(klog.Level(level)).Enabled()
+}
+
+func (s *jsonLogSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.write("info", level, nil, msg, keysAndValues)
+}
+
+func (s *jsonLogSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.write("error", 0, err, msg, keysAndValues)
+}
+
+func (s *jsonLogSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	c := *s
+	c.keysAndValues = append(append([]interface{}{}, s.keysAndValues...), keysAndValues...)
+	return &c
+}
+
+func (s *jsonLogSink) WithName(name string) logr.LogSink {
+	c := *s
+	if c.name != "" {
+		name = c.name + "." + name
+	}
+	c.name = name
+	return &c
+}
+
+// jsonValue converts a logged value to something which can be serialized.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if _, err := json.Marshal(v); err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return v
+}
+
+func (s *jsonLogSink) write(level string, v int, err error, msg string, keysAndValues []interface{}) {
+	entry := map[string]interface{}{
+		"ts":    time.Now().UTC().Format(time.RFC3339Nano),
+		"level": level,
+		"msg":   msg,
+	}
+	if level == "info" {
+		entry["v"] = v
+	}
+	if s.name != "" {
+		entry["logger"] = s.name
+	}
+	if err != nil {
+		entry["err"] = err.Error()
+	}
+	kvs := append(append([]interface{}{}, s.keysAndValues...), keysAndValues...)
+	for i := 0; i < len(kvs); i += 2 {
+		key := fmt.Sprint(kvs[i])
+		if i+1 < len(kvs) {
+			entry[key] = jsonValue(kvs[i+1])
+		} else {
+			entry[key] = "(MISSING)"
+		}
+	}
+	data, jsonErr := json.Marshal(entry)
+	if jsonErr != nil {
+		data = []byte(fmt.Sprintf(`{"level":"error","msg":"unable to serialize log entry","err":%q}`, jsonErr.Error()))
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, _ = s.w.Write(append(data, '\n'))
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestJSONLogSink(t *testing.T) {
+	var b bytes.Buffer
+	logger := logr.New(&jsonLogSink{w: &b, mu: &sync.Mutex{}, keysAndValues: []interface{}{"runID", "run"}})
+	entries := func() []map[string]interface{} {
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+			var entry map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+			assert.NotEmpty(t, entry["ts"])
+			delete(entry, "ts")
+			entries = append(entries, entry)
+		}
+		b.Reset()
+		return entries
+	}
+
+	logger.Info("Run Benchmark", "commitHash", plumbing.NewHash("0123456789abcdef0123456789abcdef01234567"), "count", 3)
+	logger.WithName("cache").WithName("git").WithValues("ref", "main").Error(errors.New("not found"), "Failed to load", "cause", errors.New("corrupted"))
+	// the value of the last key is missing, and channels cannot be
+	// serialized
+	logger.Info("Odd", "benchmark", "BenchmarkFoo", "dangling")
+	logger.Info("Unserializable", "ch", make(chan int))
+	logged := entries()
+	require.Len(t, logged, 4)
+	assert.Equal(t, []map[string]interface{}{
+		{"level": "info", "v": 0.0, "msg": "Run Benchmark", "runID": "run", "commitHash": "0123456789abcdef0123456789abcdef01234567", "count": 3.0},
+		{"level": "error", "msg": "Failed to load", "logger": "cache.git", "err": "not found", "runID": "run", "ref": "main", "cause": "corrupted"},
+		{"level": "info", "v": 0.0, "msg": "Odd", "runID": "run", "benchmark": "BenchmarkFoo", "dangling": "(MISSING)"},
+	}, logged[:3])
+	assert.IsType(t, "", logged[3]["ch"])
+}
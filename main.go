@@ -2,14 +2,21 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/blang/semver/v4"
 	"github.com/olekukonko/tablewriter"
@@ -29,11 +36,33 @@ type result struct {
 	Benchmark
 	RatioNsPerOp           float64
 	RatioAllocedBytesPerOp float64
+	RatioAllocsPerOp       float64
+	// RatioMBPerS is the relative change of the throughput, a decrease is
+	// a regression.
+	RatioMBPerS     float64
+	RatioContention float64
+	RatioPeakHeap   float64
+	// PNsPerOp, PAllocedBytesPerOp, PAllocsPerOp and PMBPerS are the
+	// p-values of the statistical test of the samples, NaN if there are not
+	// enough samples.
+	PNsPerOp           float64
+	PAllocedBytesPerOp float64
+	PAllocsPerOp       float64
+	PMBPerS            float64
+	// RatioMetrics are the relative changes of the compared custom metrics,
+	// by unit.
+	RatioMetrics map[string]float64
 }
 
 type comparedScore struct {
 	nsPerOp           bool
 	allocedBytesPerOp bool
+	allocsPerOp       bool
+	mbPerS            bool
+	contention        bool
+	heap              bool
+	// custom are the units of the compared custom metrics.
+	custom []string
 }
 
 var (
@@ -43,31 +72,163 @@ var (
 	baseRef              string
 	onlyRegression       bool
 	compareLatestVersion bool
+	artifactsDir         string
+	profile              string
+	// report receives the report, while logs and other diagnostics go to
+	// stderr, so that the report can be redirected to a file.
+	report io.Writer = os.Stdout
+	// diagnostics receives warnings and annotations meant for humans or for
+	// the CI system, but which are not part of the report.
+	diagnostics io.Writer = os.Stderr
 )
 
-type Set map[string]*parse.Benchmark
+// benchResult is the parsed result of a benchmark, along with the auxiliary
+// metrics collected by benchci.
+type benchResult struct {
+	// Benchmark combines the samples, see combineSamples.
+	*parse.Benchmark
+	// Samples are the results of each run when the benchmark was run more
+	// than once (-count), nil otherwise.
+	Samples []*parse.Benchmark
+	// ContentionNs is the total time spent blocked on synchronization
+	// primitives, only set when contention profiling is enabled.
+	ContentionNs       float64
+	ContentionMeasured bool
+	// PeakHeapBytes is collected from the GC trace, only when memstats is
+	// enabled.
+	PeakHeapBytes    float64
+	MemStatsMeasured bool
+	// Metrics are the custom metrics reported with b.ReportMetric, by unit.
+	Metrics map[string]float64
+}
+
+type Set map[string]*benchResult
+
+func newBenchResult(samples []*parse.Benchmark) *benchResult {
+	r := &benchResult{Benchmark: combineSamples(samples)}
+	if len(samples) > 1 {
+		r.Samples = samples
+	}
+	return r
+}
+
+// samples returns the results of each run of the benchmark.
+func (r *benchResult) samples() []*parse.Benchmark {
+	if len(r.Samples) == 0 {
+		return []*parse.Benchmark{r.Benchmark}
+	}
+	return r.Samples
+}
 
 func init() {
 	flagConfiguration.Benchmem = new(bool)
-	flag.StringVar(&flagConfiguration.Benchtime, "benchtime", "1s", "")
-	flag.Float64Var(&flagConfiguration.Threshold, "threshold", 0.2, "")
-	flag.StringVar(&flagConfiguration.Compare, "compare", "ns/op,B/op", "")
-	flag.StringVar(&flagConfiguration.Cpu, "cpu", "4", "")
-	flag.StringVar(&flagConfiguration.Timeout, "timeout", "10m", "")
-	flag.BoolVar(flagConfiguration.Benchmem, "benchmem", true, "")
-	flag.StringVar(&configPath, "config", "", "")
-	flag.StringVar(&baseRef, "base", "HEAD~1", "")
+	flagConfiguration.Flamegraph = new(bool)
+	flagConfiguration.Trace = new(bool)
+	flagConfiguration.Contention = new(bool)
+	flagConfiguration.MemStats = new(bool)
+	flagConfiguration.AvoidSMTSiblings = new(bool)
+	flagConfiguration.Significance = new(bool)
+	flag.StringVar(&flagConfiguration.Benchtime, "benchtime", "1s", "default `duration` (or number of iterations, e.g. 100x) of each benchmark")
+	flag.Float64Var(&flagConfiguration.Threshold, "threshold", 0.2, "default `ratio` above which a slowdown is reported as a regression")
+	flag.Float64Var(&flagConfiguration.ReleaseThreshold, "release-threshold", 0, "default `ratio` above which a slowdown compared with the latest release is reported as a regression, -threshold is used if 0")
+	flag.Float64Var(&flagConfiguration.WarnThreshold, "warn-threshold", 0, "default `ratio` above which a slowdown which is not a regression is reported as a warning, must be below -threshold (0 to disable)")
+	flag.IntVar(&warnExitCode, "warn-exit-code", 2, "exit `code` of a run with warnings but no failure, 0 to succeed")
+	flag.Float64Var(&flagConfiguration.TouchedThreshold, "touched-threshold", 0, "default `ratio` above which a slowdown compared with the base ref is reported as a regression for benchmarks whose package was modified, if stricter than -threshold")
+	flag.StringVar(&basePolicy, "base-policy", policyFail, "`policy` in case of regression compared with the base ref: fail or warn")
+	flag.StringVar(&releasePolicy, "release-policy", policyFail, "`policy` in case of regression compared with the latest release: fail or warn")
+	flag.StringVar(&failOn, "fail-on", "", "comparisons in which a regression fails the run, instead of -base-policy and -release-policy: none (report only), regression, regression-vs-base or regression-vs-release")
+	flag.Float64Var(&flagConfiguration.Epsilon, "epsilon", 0, "default `ratio` around the threshold within which ns/op and B/op changes are only reported if they are statistically significant, 0 to disable")
+	flag.Float64Var(&flagConfiguration.Alpha, "alpha", defaultAlpha, "default significance `level` of the Mann-Whitney U test of the samples")
+	flag.BoolVar(flagConfiguration.Significance, "significance", false, "only report changes of ns/op and B/op which are statistically significant, when benchmarks are run several times")
+	flag.StringVar(&flagConfiguration.Compare, "compare", "ns/op,B/op", "default comma-separated `list` of metrics to compare")
+	flag.StringVar(&flagConfiguration.Cpu, "cpu", "4", "default comma-separated `list` of GOMAXPROCS values to run benchmarks with")
+	flag.Int64Var(&seed, "seed", 0, "`number` exported to the benchmarks of all refs as BENCHCI_SEED, so that benchmarks with randomized inputs are reproducible (not exported if 0)")
+	flag.IntVar(&flagConfiguration.Count, "count", 1, "default `number` of times each benchmark is run, the median of the samples is used")
+	flag.StringVar(&profile, "profile", "", "`name` of the profile from the configuration file to use")
+	flag.StringVar(&confirmProfile, "confirm-profile", "", "`name` of a profile used to re-run the benchmarks which may have regressed, the first run being a quick screen")
+	flag.BoolVar(&noCache, "no-cache", false, "always run the benchmarks of the base ref and of the latest release, instead of using the results cached for their commit")
+	flag.StringVar(&resultCacheDir, "result-cache", "", "`directory` where the results of the base ref and of the latest release are cached by commit (benchci/results in the user cache directory by default)")
+	flag.IntVar(&jobs, "jobs", 1, "`number` of benchmark entries run concurrently for each ref, each of them pinned to its own cores (Linux only)")
+	flag.BoolVar(&parallelRefs, "parallel-refs", false, "run the base ref, the latest release and HEAD concurrently, each of them pinned to its own cores (Linux only)")
+	flag.BoolVar(flagConfiguration.AvoidSMTSiblings, "avoid-smt-siblings", false, "pin benchmarks to one CPU per core, so that their threads do not run on SMT siblings")
+	flag.StringVar(&toolchain, "toolchain", "", "Go `toolchain` used for all refs (e.g. go1.22.5, downloaded on demand), or go.mod to use the one required by the go.mod of each ref (the local toolchain is used if empty)")
+	flag.StringVar(&flagConfiguration.Timeout, "timeout", "10m", "default `duration` after which a benchmark run is aborted")
+	flag.BoolVar(flagConfiguration.Benchmem, "benchmem", true, "collect memory allocation statistics by default")
+	flag.BoolVar(flagConfiguration.Flamegraph, "flamegraph", false, "collect CPU profiles and generate flamegraphs")
+	flag.BoolVar(flagConfiguration.Trace, "trace", false, "collect execution traces")
+	flag.BoolVar(flagConfiguration.Contention, "contention", false, "collect block and mutex profiles and report the total contention time")
+	flag.BoolVar(flagConfiguration.MemStats, "memstats", false, "trace garbage collections and report the peak heap size")
+	flag.StringVar(&runID, "run-id", "", "unique `id` of this run, included in logs, reports and saved results (generated if empty)")
+	flag.BoolVar(&offline, "offline", false, "guarantee that no network access is made, for isolated performance labs: features requiring it are rejected and the go command does not download modules")
+	flag.Var(runMeta, "meta", "`key=value` metadata attached to all outputs (report, saved results, audit log, events, CSV, textfile, Datadog), e.g. pr=1234, can be repeated")
+	flag.StringVar(&configPath, "config", "", "`path` to the YAML benchmark configuration")
+	flag.StringVar(&baseRef, "base", "HEAD~1", "git `revision` to compare HEAD with")
 	flag.BoolVar(&compareLatestVersion, "compare-release", true, "compare with latest release version")
-	flag.BoolVar(&onlyRegression, "only-regression", false, "")
+	flag.Var(&headCandidates, "head", "`ref` of a candidate compared with the base ref instead of HEAD, can be repeated to compare several candidates side by side")
+	flag.StringVar(&applyPatch, "apply-patch", "", "`path` of a patch which is applied to HEAD, HEAD with the patch is then compared with the base ref and with HEAD instead of the latest release")
+	flag.StringVar(&textfilePath, "textfile", "", "`path` of a file to which the results are written in the OpenMetrics text format, for the node_exporter textfile collector")
+	flag.BoolVar(&datadogEnabled, "datadog", false, "submit the results and the regressions to Datadog, using the DD_API_KEY and DD_SITE environment variables")
+	flag.StringVar(&cloudEventsSink, "cloudevents-sink", "", "`URL` to which a CloudEvent summarizing the verdict is sent at the end of the run")
+	flag.BoolVar(&releaseAsset, "release-asset", false, "when HEAD is a tag, upload its results to the GitHub Release of the tag, using the GITHUB_TOKEN and GITHUB_REPOSITORY environment variables")
+	flag.BoolVar(&fileIssues, "file-issues", false, "open a GitHub issue for each regressed benchmark without an open one, using the GITHUB_TOKEN and GITHUB_REPOSITORY environment variables")
+	flag.StringVar(&historyPath, "history", "", "`path` of a JSON lines file to which the results of HEAD are appended with its commit and branch, to follow trends with the history command")
+	flag.IntVar(&trendWindow, "trend-window", 10, "`number` of most recent commits of the -history of the branch in which creeping regressions are looked for, 0 to disable trend alerts")
+	flag.Var(&trendThreshold, "trend-threshold", "`ratio` (e.g. 5%) by which the ns/op of a benchmark may drift over -trend-window commits before a trend alert is shown, its threshold by default")
+	flag.BoolVar(&githubComment, "github-comment", false, "post the Markdown report as a comment of the pull request, or update the comment of a previous run, using the GITHUB_TOKEN and GITHUB_REPOSITORY environment variables")
+	flag.IntVar(&githubPR, "github-pr", 0, "`number` of the pull request commented with -github-comment, detected from the GitHub Actions event if not set")
+	flag.BoolVar(&gitlabNote, "gitlab-note", false, "post the Markdown report as a note of the GitLab merge request of the pipeline, or update the note of a previous run, using the GITLAB_TOKEN and CI_* environment variables")
+	flag.BoolVar(&gitlabStatus, "gitlab-status", false, "set the benchci status of the commit of the GitLab pipeline to the verdict, using the GITLAB_TOKEN and CI_* environment variables")
+	flag.StringVar(&uploadReport, "upload-report", "", "upload the report and print its URL, useful when CI logs are truncated: \"gist\" (using the GITHUB_TOKEN environment variable) or an OCI `reference` (oci://<registry>/<repository>:<tag>) to push it to a container registry")
+	flag.IntVar(&numbers.precision, "precision", 2, "`number` of decimal places of the values and percentages in the report")
+	flag.StringVar(&numbers.thousandsSeparator, "thousands-separator", "", "`separator` inserted between groups of 3 digits in the report, e.g. \",\" (none by default)")
+	flag.StringVar(&csvPath, "csv", "", "`path` of a file to which the results are exported in CSV format")
+	flag.StringVar(&csvDelimiter, "csv-delimiter", "comma", "`delimiter` of the CSV export: comma, semicolon or tab")
+	flag.StringVar(&csvDecimalSeparator, "csv-decimal-separator", ".", "decimal `separator` of the CSV export: \".\" or \",\"")
+	flag.BoolVar(&csvHeader, "csv-header", true, "include a header row in the CSV export")
+	flag.StringVar(&eventsPath, "events", "", "`path` of a file to which a JSON event is written as soon as each result is available (JSON lines)")
+	flag.BoolVar(&onlyRegression, "only-regression", false, "only show benchmarks which regressed")
+	flag.Var(&totalBudget, "total-budget", "if set, only fail when the weighted geometric mean of all compared metrics regresses by more than this `ratio` (e.g. 5%), instead of failing on individual regressions")
+	flag.StringVar(&outputFormat, "output-format", outputText, "`format` of the report: text (tables), json (a single JSON document with the results and the comparisons) or markdown (GitHub-flavored Markdown with collapsible sections, for pull request comments)")
+	flag.StringVar(&markdownFile, "markdown-file", "", "`path` of a file to which the report is also written as GitHub-flavored Markdown, whatever the output format")
+	flag.StringVar(&layout, "layout", layoutWide, "`layout` of the result table, one of wide (one row per benchmark, one column per ref and metric), transposed (one column per benchmark) or compact (one row per benchmark, one column per metric)")
+	flag.StringVar(&colorMode, "color", "auto", "whether to use colors in tables, one of auto, always or never")
+	flag.StringVar(&acceptedRegressionsPath, "accepted-regressions", "", "`path` of the file recording accepted regressions, which are not reported as regressions until they expire")
+	flag.StringVar(&auditLogPath, "audit-log", "", "`path` of a file to which an audit record of each gating decision is appended (JSON lines)")
+	flag.StringVar(&auditEndpoint, "audit-endpoint", "", "`URL` to which the audit record of each gating decision is posted as JSON")
+	flag.DurationVar(&deadline, "deadline", 0, "`duration` after which no new benchmark is started, the report is then marked as partial (no deadline if 0)")
+	flag.Var(&minCoverage, "min-coverage", "minimum `ratio` (e.g. 90%) of the configured benchmarks which must be compared with the base ref, the run fails if more were skipped or failed")
+	flag.StringVar(&partialPolicy, "partial-policy", policyGate, "`policy` for partial runs: gate (regressions fail the run as usual), warn (never fail) or fail (always fail)")
+	flag.BoolVar(&failOnDeprecated, "fail-on-deprecated", false, "fail when benchmarks are past their deprecatedAfter version")
+	flag.BoolVar(&showVersion, "version", false, "print the benchci version and exit")
+	flag.StringVar(&artifactsDir, "artifacts-dir", "", "`directory` where profiles and other artifacts are stored, a temporary directory is used if empty")
 }
 
 func main() {
-	flag.Parse()
-	if err := run(); err != nil {
+	if err := execute(os.Args[1:]); err != nil {
+		var exitErr *exitError
+		if errors.As(err, &exitErr) {
+			klog.Warning(err)
+			klog.Flush()
+			os.Exit(exitErr.code)
+		}
+		if logFormat == "json" {
+			// klog.Fatal would log the goroutine stacks as a single message.
+			klog.ErrorS(err, "benchci failed")
+			os.Exit(1)
+		}
 		klog.Fatal(err)
 	}
 }
 
+// goCommand returns the go command to use with a configuration: its goBinary
+// if set, or else the command of the list.
+func goCommand(c *BenchmarkConfiguration) string {
+	if c.GoBinary != "" {
+		return c.GoBinary
+	}
+	return benchmarks.Command
+}
+
 func parseBenchmarks() error {
 	data, err := ioutil.ReadFile(configPath)
 	if err != nil {
@@ -83,6 +244,15 @@ func (c *BenchmarkConfiguration) applyDefaults(d *BenchmarkConfiguration) *Bench
 	if c.Threshold == 0 {
 		c.Threshold = d.Threshold
 	}
+	if c.ReleaseThreshold == 0 {
+		c.ReleaseThreshold = d.ReleaseThreshold
+	}
+	if c.TouchedThreshold == 0 {
+		c.TouchedThreshold = d.TouchedThreshold
+	}
+	if c.WarnThreshold == 0 {
+		c.WarnThreshold = d.WarnThreshold
+	}
 	if c.Compare == "" {
 		c.Compare = d.Compare
 	}
@@ -95,17 +265,88 @@ func (c *BenchmarkConfiguration) applyDefaults(d *BenchmarkConfiguration) *Bench
 	if c.Benchmem == nil {
 		c.Benchmem = d.Benchmem
 	}
+	if c.Flamegraph == nil {
+		c.Flamegraph = d.Flamegraph
+	}
+	if c.Trace == nil {
+		c.Trace = d.Trace
+	}
+	if c.Contention == nil {
+		c.Contention = d.Contention
+	}
+	if c.MemStats == nil {
+		c.MemStats = d.MemStats
+	}
+	if c.OutputFilters == nil {
+		c.OutputFilters = d.OutputFilters
+	}
+	if c.Count == 0 {
+		c.Count = d.Count
+	}
+	if c.GoBinary == "" {
+		c.GoBinary = d.GoBinary
+	}
+	// thresholds are merged by unit, so that a benchmark can override a
+	// single one
+	for unit, threshold := range d.MetricThresholds {
+		if _, ok := c.MetricThresholds[unit]; ok {
+			continue
+		}
+		thresholds := make(map[string]float64, len(c.MetricThresholds)+1)
+		for u, t := range c.MetricThresholds {
+			thresholds[u] = t
+		}
+		thresholds[unit] = threshold
+		c.MetricThresholds = thresholds
+	}
+	if c.Epsilon == 0 {
+		c.Epsilon = d.Epsilon
+	}
+	if c.Alpha == 0 {
+		c.Alpha = d.Alpha
+	}
+	if c.Limits == nil {
+		c.Limits = d.Limits
+	}
+	if c.NumaNode == nil {
+		c.NumaNode = d.NumaNode
+	}
+	if c.AvoidSMTSiblings == nil {
+		c.AvoidSMTSiblings = d.AvoidSMTSiblings
+	}
+	if c.Significance == nil {
+		c.Significance = d.Significance
+	}
 	return c
 }
 
-func updateBenchmarks() {
+func updateBenchmarks() error {
+	if err := validateMetricDirections(); err != nil {
+		return err
+	}
+	var profileConfiguration BenchmarkConfiguration
+	if profile != "" {
+		var ok bool
+		if profileConfiguration, ok = benchmarks.Profiles[profile]; !ok {
+			return fmt.Errorf("unknown profile '%s'", profile)
+		}
+	}
 	for idx := range benchmarks.Benchmarks {
 		benchmark := &benchmarks.Benchmarks[idx]
 		if benchmark.UniqueName == "" {
 			benchmark.UniqueName = benchmark.Name
 		}
-		benchmark.applyDefaults(&benchmarks.BenchmarkConfiguration).applyDefaults(flagConfiguration)
+		benchmark.applyDefaults(&profileConfiguration).applyDefaults(&benchmarks.BenchmarkConfiguration).applyDefaults(flagConfiguration)
+		if err := validateScrape(benchmark); err != nil {
+			return fmt.Errorf("invalid scrape of '%s': %w", benchmark.UniqueName, err)
+		}
 	}
+	var err error
+	if benchmarks.Benchmarks, err = expandSweeps(benchmarks.Benchmarks); err != nil {
+		return err
+	}
+	benchmarks.Benchmarks, err = expandCPUs(benchmarks.Benchmarks)
+	return err
 }
 
 func versionRequired(required, tag string) bool {
@@ -127,35 +368,138 @@ func versionRequired(required, tag string) bool {
 	return tagVer.Equals(requiredVer)
 }
 
-func runBenchmarks(tagVersion string) (Set, error) {
-	set := Set{}
-	for i, benchmark := range benchmarks.Benchmarks {
+func runBenchmarks(dir, ref, commit, tagVersion string, progress func(Benchmark, *benchResult)) (*RunResult, error) {
+	return runPinnedBenchmarks(dir, ref, commit, tagVersion, "", progress)
+}
+
+// runPinnedBenchmarks runs the benchmarks of a ref, with all the commands
+// pinned to a list of CPUs if it is not empty.
+func runPinnedBenchmarks(dir, ref, commit, tagVersion, cpus string, progress func(Benchmark, *benchResult)) (*RunResult, error) {
+	run := newRunResult(ref, commit)
+	vars := newRefVars(ref, commit, tagVersion != "")
+	vars.cpus = cpus
+	env, cleanup, err := refEnv(dir, tagVersion != "")
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	vars.env = env
+	run.GoVersion = goVersion(goCommand(&benchmarks.BenchmarkConfiguration), dir, vars.env)
+	if len(vars.env) > 0 {
+		klog.InfoS("Using custom environment for ref", "ref", ref, "env", vars.env, "goVersion", run.GoVersion)
+	}
+	run.Seed = seed
+	vars.env = append(vars.env, seedEnv()...)
+	if vars.ArtifactsDir != "" {
+		if err := os.MkdirAll(vars.ArtifactsDir, 0755); err != nil {
+			return nil, fmt.Errorf("unable to create artifacts directory: %w", err)
+		}
+	}
+	if len(benchmarks.Build) > 0 {
+		buildStart := time.Now()
+		if err := runHooks("build", dir, vars, benchmarks.Build); err != nil {
+			return nil, err
+		}
+		run.BuildDuration = time.Since(buildStart).Round(time.Millisecond)
+		run.Start = time.Now().UTC()
+		klog.InfoS("Built ref", "ref", ref, "duration", run.BuildDuration)
+	}
+	if err := runHooks("setup", dir, vars, benchmarks.Setup); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := runHooks("teardown", dir, vars, benchmarks.Teardown); err != nil {
+			klog.ErrorS(err, "Teardown failed", "ref", ref)
+		}
+	}()
+	// with -jobs, entries are run concurrently and mu protects the run
+	var mu sync.Mutex
+	fail := func(name string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		run.fail(name, err)
+	}
+	runEntry := func(i int, vars refVars) {
+		benchmark := benchmarks.Benchmarks[i]
+		if benchmark.Skip != "" {
+			klog.InfoS("Benchmark is skipped", "benchmark", benchmark.UniqueName, "reason", benchmark.Skip)
+			return
+		}
 		if tagVersion != "" && !versionRequired(benchmark.VersionRequirement, tagVersion) {
 			klog.InfoS("Version required, skip test", "tagVersion", tagVersion, "versionRequirement", benchmark.VersionRequirement)
-			continue
+			return
 		}
-		parseSet, err := runBenchmark(benchmarks.Command, &benchmarks.Benchmarks[i])
+		if deadlineReached(time.Now()) {
+			mu.Lock()
+			run.Partial = true
+			mu.Unlock()
+			fail(benchmark.UniqueName, errDeadline)
+			return
+		}
+		var before, after map[string]float64
+		if benchmark.Scrape != nil {
+			var err error
+			if before, err = scrapeCounters(benchmark.Scrape); err != nil {
+				fail(benchmark.UniqueName, fmt.Errorf("%w, the endpoint must be served before the benchmark is run, e.g. by a setup hook", err))
+				return
+			}
+		}
+		parseSet, custom, stats, err := runBenchmark(goCommand(&benchmark.BenchmarkConfiguration), dir, vars, &benchmarks.Benchmarks[i])
 		if err != nil {
 			klog.InfoS("Parse result error", "parseSet", parseSet)
-			continue
+			fail(benchmark.UniqueName, err)
+			return
+		}
+		if benchmark.Scrape != nil {
+			if after, err = scrapeCounters(benchmark.Scrape); err != nil {
+				fail(benchmark.UniqueName, err)
+				return
+			}
 		}
 		if len(parseSet) != 1 {
 			klog.InfoS("expected exactly one benchmark result", "got", parseSet)
-			continue
-		}
-		if _, ok := set[benchmark.UniqueName]; ok {
-			klog.InfoS("more than one benchmark with unique name", "Name", benchmark.UniqueName)
-			continue
+			fail(benchmark.UniqueName, fmt.Errorf("expected exactly one benchmark result, got %d", len(parseSet)))
+			return
 		}
 		for name, s := range parseSet {
-			if len(s) != 1 {
-				klog.InfoS("expected exactly one benchmark result", "Name", name, "benchmark.UniqueName", benchmark.UniqueName)
-				continue
+			result := newBenchResult(s)
+			result.Metrics = medianMetrics(custom[name])
+			for unit, increase := range counterIncreases(before, after, benchmarkIterations(s)) {
+				if result.Metrics == nil {
+					result.Metrics = map[string]float64{}
+				}
+				result.Metrics[unit] = increase
+			}
+			if *benchmark.Contention {
+				contention, err := totalContention(goCommand(&benchmark.BenchmarkConfiguration), ref, benchmark.UniqueName)
+				if err != nil {
+					klog.ErrorS(err, "Failed to compute contention", "benchmark", benchmark.UniqueName, "ref", ref)
+				}
+				result.ContentionNs = contention
+				result.ContentionMeasured = err == nil
+			}
+			if stats != nil {
+				result.PeakHeapBytes = stats.peakHeapBytes
+				result.MemStatsMeasured = true
+			}
+			mu.Lock()
+			if _, ok := run.Results[benchmark.UniqueName]; ok {
+				mu.Unlock()
+				klog.InfoS("more than one benchmark with unique name", "Name", benchmark.UniqueName)
+				return
+			}
+			run.Results[benchmark.UniqueName] = result
+			mu.Unlock()
+			if progress != nil {
+				progress(benchmark, result)
 			}
-			set[benchmark.UniqueName] = s[0]
 		}
 	}
-	return set, nil
+	if err := runEntries(len(benchmarks.Benchmarks), vars, runEntry); err != nil {
+		return nil, err
+	}
+	run.End = time.Now().UTC()
+	return run, nil
 }
 
 func trimTagVersion(tagName string) string {
@@ -200,6 +544,9 @@ func run() error {
 	if err := parseBenchmarks(); err != nil {
 		return err
 	}
+	if err := discoverBenchmarks(""); err != nil {
+		return err
+	}
 
 	r, err := git.PlainOpen(".")
 	if err != nil {
@@ -230,135 +577,628 @@ func run() error {
 		return fmt.Errorf("the repository is dirty: commit all changes before running")
 	}
 
-	resetAndRunBenchmark := func(commit plumbing.Hash, ref string, isTag bool) (benchSet Set, err error) {
-		err = w.Reset(&git.ResetOptions{Commit: commit, Mode: git.HardReset})
+	candidates := make([]plumbing.Hash, 0, len(headCandidates))
+	for _, name := range headCandidates {
+		hash, err := r.ResolveRevision(plumbing.Revision(name))
 		if err != nil {
-			return nil, fmt.Errorf("failed to reset the worktree to a commit %v, ref %v: %w", commit, ref, err)
+			return fmt.Errorf("unable to resolve '%s': %w", name, err)
 		}
+		candidates = append(candidates, *hash)
+	}
 
-		klog.InfoS("Run Benchmark", "commitHash", commit, "Ref", ref)
+	carried, err := readCarriedFiles(r, head.Hash())
+	if err != nil {
+		return err
+	}
+	carriedHash = hashCarriedFiles(carried)
+
+	checkouts, err := newWorktrees(".")
+	if err != nil {
+		return err
+	}
+	defer checkouts.remove()
+
+	checkout := func(commit plumbing.Hash, ref string) (string, error) {
+		dir, created, err := checkouts.checkout(commit)
+		if err != nil {
+			return "", fmt.Errorf("failed to check out ref %v: %w", ref, err)
+		}
+		if created && commit != head.Hash() {
+			if err := writeCarriedFiles(dir, carried); err != nil {
+				return "", err
+			}
+		}
+		return dir, nil
+	}
+
+	checkoutAndRunBenchmark := func(commit plumbing.Hash, ref string, isTag bool, progress func(Benchmark, *benchResult)) (*RunResult, string, error) {
+		dir, err := checkout(commit, ref)
+		if err != nil {
+			return nil, "", err
+		}
+
+		klog.InfoS("Run Benchmark", "commitHash", commit, "Ref", ref, "dir", dir)
 		var tagVersion string
 		if isTag {
 			tagVersion = ref
 		}
-		benchSet, err = runBenchmarks(tagVersion)
+		run, err := runBenchmarks(dir, ref, commit.String(), tagVersion, progress)
 		if err != nil {
-			return nil, fmt.Errorf("failed to run a benchmark: %w", err)
+			return nil, "", fmt.Errorf("failed to run a benchmark: %w", err)
 		}
-		return
+		return run, dir, nil
 	}
 
-	defer func() {
-		_ = w.Reset(&git.ResetOptions{Commit: head.Hash(), Mode: git.HardReset})
-	}()
-	updateBenchmarks()
+	// the results of the base ref and of the latest release are cached, since
+	// they rarely change from one run to the next
+	runCachedBenchmark := func(commit plumbing.Hash, ref string, isTag bool, progress func(Benchmark, *benchResult)) (*RunResult, error) {
+		var tagVersion string
+		if isTag {
+			tagVersion = ref
+		}
+		// the go version is part of the key of the cache, the ref is checked
+		// out to know the toolchain it is built with
+		dir, err := checkout(commit, ref)
+		if err != nil {
+			return nil, err
+		}
+		version, err := refGoVersion(dir, isTag)
+		if err != nil {
+			return nil, err
+		}
+		if run := loadCachedRun(ref, commit.String(), tagVersion, version); run != nil {
+			replayProgress(run, progress)
+			return run, nil
+		}
+		run, _, err := checkoutAndRunBenchmark(commit, ref, isTag, progress)
+		if err != nil {
+			return nil, err
+		}
+		saveCachedRun(run, tagVersion)
+		return run, nil
+	}
 
-	// run benchmark of baseRef
-	prevSet, err := resetAndRunBenchmark(*prev, baseRef, false)
+	if err := updateBenchmarks(); err != nil {
+		return err
+	}
+	if _, ok := benchmarks.Profiles[confirmProfile]; confirmProfile != "" && !ok {
+		return fmt.Errorf("unknown confirmation profile '%s'", confirmProfile)
+	}
+	if rerunBenchmark != "" {
+		all := benchmarks.Benchmarks
+		if benchmarks.Benchmarks, err = selectBenchmark(all, rerunBenchmark); err != nil {
+			return err
+		}
+		filteredBenchmarks = filterExclusions(all, benchmarks.Benchmarks, fmt.Sprintf("re-run of %s only", rerunBenchmark))
+	}
+	if err := checkOverlaps(""); err != nil {
+		return err
+	}
+	if err := markTouchedBenchmarks(r, *prev, head.Hash()); err != nil {
+		return err
+	}
+	basePolicy, releasePolicy, err = comparisonPolicies()
 	if err != nil {
 		return err
 	}
+	if err := checkDeprecations(r, head.Hash()); err != nil {
+		return err
+	}
+	if err := prepareArtifactsDir(); err != nil {
+		return err
+	}
+
+	stream, err := newStreamer(diagnostics)
+	if err != nil {
+		return err
+	}
+	defer stream.close()
 
-	// run benchmark of latestReleaseVersion
-	var latestReleaseSet Set
 	var tagName string
 	var prevVersionTag *plumbing.Reference
-	if compareLatestVersion {
+	// with a patch, HEAD is used as the second comparison base instead
+	if compareLatestVersion && applyPatch == "" && len(candidates) == 0 {
 		prevVersionTag, err = getLatestRelease(r)
 		if err != nil {
 			return fmt.Errorf("failed to get latest release version: %w", err)
 		}
 		tagName = prevVersionTag.Name().String()
-		latestReleaseSet, err = resetAndRunBenchmark(prevVersionTag.Hash(), prevVersionTag.Name().Short(), true)
+	}
+	base := &refResults{name: baseRef, ref: baseRef, policy: basePolicy, diffBase: true}
+	var release *refResults
+	if prevVersionTag != nil {
+		release = &refResults{name: tagName, ref: prevVersionTag.Name().Short(), policy: releasePolicy, latestRelease: true}
+	}
+	var headRun *RunResult
+	var headDir string
+
+	if parallelRefs {
+		basePass := &refPass{commit: *prev, ref: baseRef, progress: stream.progress(baseRef, false)}
+		var releasePass *refPass
+		if release != nil {
+			releasePass = &refPass{commit: prevVersionTag.Hash(), ref: release.ref, tagVersion: release.ref, progress: stream.progress(tagName, false)}
+		}
+		// the results of HEAD cannot be compared as they are measured, the
+		// bases being run at the same time
+		headPass := &refPass{commit: head.Hash(), ref: "HEAD", progress: stream.progress("HEAD", true)}
+		var passes []*refPass
+		for _, p := range []*refPass{basePass, releasePass} {
+			if p == nil {
+				continue
+			}
+			if p.dir, err = checkout(p.commit, p.ref); err != nil {
+				return err
+			}
+			version, err := refGoVersion(p.dir, p.tagVersion != "")
+			if err != nil {
+				return err
+			}
+			if p.run = loadCachedRun(p.ref, p.commit.String(), p.tagVersion, version); p.run != nil {
+				replayProgress(p.run, p.progress)
+				continue
+			}
+			passes = append(passes, p)
+		}
+		passes = append(passes, headPass)
+		topology, err := readSMTTopology("/sys")
 		if err != nil {
 			return err
 		}
+		cpus, err := passCPUs(topology, len(passes))
+		if err != nil {
+			return err
+		}
+		for i, p := range passes {
+			if p.dir, err = checkout(p.commit, p.ref); err != nil {
+				return err
+			}
+			p.cpus = cpus[i]
+		}
+		if err := runPasses(passes); err != nil {
+			return err
+		}
+		for _, p := range passes[:len(passes)-1] {
+			saveCachedRun(p.run, p.tagVersion)
+		}
+		base.run = basePass.run
+		if releasePass != nil {
+			release.run = releasePass.run
+		}
+		headRun, headDir = headPass.run, headPass.dir
+	} else {
+		// run benchmark of baseRef
+		if base.run, err = runCachedBenchmark(*prev, baseRef, false, stream.progress(baseRef, false)); err != nil {
+			return err
+		}
+		stream.bases = append(stream.bases, base)
+
+		// with head candidates, each of them is compared with baseRef only
+		if len(candidates) > 0 {
+			candidateResults := make([]*refResults, 0, len(candidates))
+			for i, hash := range candidates {
+				name := headCandidates[i]
+				run, _, err := checkoutAndRunBenchmark(hash, name, false, stream.progress(name, true))
+				if err != nil {
+					return err
+				}
+				candidateResults = append(candidateResults, &refResults{name: name, ref: name, run: run})
+			}
+			return compareCandidates(base, candidateResults)
+		}
+
+		// run benchmark of latestReleaseVersion
+		if release != nil {
+			if release.run, err = runCachedBenchmark(prevVersionTag.Hash(), release.ref, true, stream.progress(tagName, false)); err != nil {
+				return err
+			}
+			stream.bases = append(stream.bases, release)
+		}
+
+		// run benchmark of HEAD
+		if headRun, headDir, err = checkoutAndRunBenchmark(head.Hash(), "HEAD", false, stream.progress("HEAD", true)); err != nil {
+			return err
+		}
 	}
 
-	// run benchmark of HEAD
-	headSet, err := resetAndRunBenchmark(head.Hash(), "HEAD", false)
-	if err != nil {
-		return err
+	if applyPatch != "" {
+		recordHistory(headRun)
+		headResults := &refResults{name: "HEAD", ref: "HEAD", run: headRun, policy: basePolicy}
+		stream.bases = append(stream.bases, headResults)
+		patchedRun, err := runPatchedBenchmarks(headDir, head.Hash().String(), stream.progress(patchedRef, true))
+		if err != nil {
+			return err
+		}
+		return compareResults(&refResults{name: patchedRef, ref: patchedRef, dir: headDir, run: patchedRun}, base, headResults)
+	}
+
+	headResults := &refResults{name: "HEAD", ref: "HEAD", dir: headDir, run: headRun}
+	if confirmProfile != "" {
+		if confirmed := confirmationBenchmarks(headResults, base, release); len(confirmed) > 0 {
+			names := make([]string, 0, len(confirmed))
+			for _, benchmark := range confirmed {
+				names = append(names, benchmark.UniqueName)
+			}
+			klog.InfoS("Confirming potential regressions", "profile", confirmProfile, "benchmarks", names)
+			screened := benchmarks.Benchmarks
+			benchmarks.Benchmarks = confirmed
+			type confirmation struct {
+				commit  plumbing.Hash
+				results *refResults
+				isTag   bool
+			}
+			// HEAD comes last, it is checked out for the report
+			confirmations := []confirmation{{*prev, base, false}}
+			if release != nil {
+				confirmations = append(confirmations, confirmation{prevVersionTag.Hash(), release, true})
+			}
+			confirmations = append(confirmations, confirmation{head.Hash(), headResults, false})
+			for _, c := range confirmations {
+				confirmationRun, _, err := checkoutAndRunBenchmark(c.commit, c.results.ref, c.isTag, nil)
+				if err != nil {
+					return err
+				}
+				mergeConfirmation(c.results.run, confirmationRun)
+			}
+			benchmarks.Benchmarks = replaceBenchmarks(screened, confirmed)
+		}
+	}
+
+	recordHistory(headRun)
+	publishReleaseResults(r, head.Hash(), headRun)
+	return compareResults(headResults, base, release)
+}
+
+// newResult computes the relative change of each metric between the base and
+// the head results of a benchmark.
+func newResult(benchmark Benchmark, headBench, baseBench *benchResult) result {
+	r := result{Benchmark: benchmark}
+	if baseBench.NsPerOp != 0 {
+		r.RatioNsPerOp = (headBench.NsPerOp - baseBench.NsPerOp) / baseBench.NsPerOp
+	}
+	if baseBench.AllocedBytesPerOp != 0 {
+		r.RatioAllocedBytesPerOp = (float64(headBench.AllocedBytesPerOp) - float64(baseBench.AllocedBytesPerOp)) / float64(baseBench.AllocedBytesPerOp)
+	}
+	if baseBench.AllocsPerOp != 0 {
+		r.RatioAllocsPerOp = (float64(headBench.AllocsPerOp) - float64(baseBench.AllocsPerOp)) / float64(baseBench.AllocsPerOp)
 	}
+	if baseBench.MBPerS != 0 {
+		r.RatioMBPerS = (headBench.MBPerS - baseBench.MBPerS) / baseBench.MBPerS
+	}
+	if baseBench.ContentionNs != 0 {
+		r.RatioContention = (headBench.ContentionNs - baseBench.ContentionNs) / baseBench.ContentionNs
+	}
+	if baseBench.PeakHeapBytes != 0 {
+		r.RatioPeakHeap = (headBench.PeakHeapBytes - baseBench.PeakHeapBytes) / baseBench.PeakHeapBytes
+	}
+	r.RatioMetrics = customRatios(whichScoreToCompare(benchmark.Compare).custom, headBench, baseBench)
+	pValues(&r, headBench, baseBench)
+	return r
+}
 
+// refResults holds the benchmark results for one ref.
+type refResults struct {
+	// name is used to identify the ref in reports.
+	name string
+	// ref is the ref which was benchmarked, it is used to locate artifacts.
+	ref string
+	// dir is the source tree which was benchmarked, if available.
+	dir string
+	run *RunResult
+	// policy is the policy applied in case of regression compared with this
+	// ref, it is only relevant for comparison bases.
+	policy string
+	// latestRelease is true for the latest release, which is compared using
+	// ReleaseThreshold.
+	latestRelease bool
+	// diffBase is true for the base ref of the changes of HEAD, which is
+	// compared using TouchedThreshold for the benchmarks of the packages
+	// modified by the changes.
+	diffBase bool
+}
+
+// compareResults compares the results of head with the results of base and
+// (optionally) release, and reports them. An error is returned if there is a
+// regression.
+func compareResults(head, base, release *refResults) error {
+	// the text report is only written with the text output format, the
+	// other formats are written at the end
+	w := report
+	if outputFormat != outputText {
+		w = ioutil.Discard
+	}
 	var ratios []result
-	var rows [][]string
+	var rows []resultGroup
 	var ratiosWithRelease []result
 
+	baseScale, baseCalibrated := calibrationScale(head.run.Results, base.run.Results)
+	releaseScale, releaseCalibrated := 1.0, false
+	if release != nil {
+		releaseScale, releaseCalibrated = calibrationScale(head.run.Results, release.run.Results)
+	}
+
 	for _, benchmark := range benchmarks.Benchmarks {
 		benchName := benchmark.UniqueName
-		headBench, ok := headSet[benchName]
+		if benchmark.Skip != "" {
+			continue
+		}
+		headBench, ok := head.run.Results[benchName]
 		if !ok {
-			klog.ErrorS(fmt.Errorf("missing benchmark '%s'", benchName), "missing benchmark", "benchName", benchName)
+			klog.ErrorS(fmt.Errorf("missing benchmark '%s'", benchName), "missing benchmark", "benchName", benchName, "reason", head.run.Errors[benchName])
 			continue
 		}
 
-		rows = append(rows, generateRow("HEAD", headBench))
+		group := resultGroup{generateRow(benchName, head.name, headBench)}
 
-		prevBench, ok := prevSet[benchName]
+		prevBench, ok := base.run.Results[benchName]
 		if !ok {
-			rows = append(rows, []string{benchName, baseRef, "-", "-"})
+			rows = append(rows, append(group, generateMissingRow(benchName, base.name)))
 			continue
 		}
 
-		getRationsPerOP := func(headBench, baseBench *parse.Benchmark) (ratioNsPerOp float64) {
-			if prevBench.NsPerOp != 0 {
-				ratioNsPerOp = (headBench.NsPerOp - baseBench.NsPerOp) / baseBench.NsPerOp
-			}
-			return
-		}
-
-		getRatioAllocedBytesPerOp := func(headBench, baseBench *parse.Benchmark) (ratioAllocedBytesPerOp float64) {
-			if prevBench.AllocedBytesPerOp != 0 {
-				ratioAllocedBytesPerOp = (float64(headBench.AllocedBytesPerOp) - float64(baseBench.AllocedBytesPerOp)) / float64(baseBench.AllocedBytesPerOp)
-			}
-			return
+		group = append(group, generateRow(benchName, base.name, prevBench))
+		if !benchmark.Calibration {
+			ratios = append(ratios, newResult(comparedBenchmark(benchmark, base), scaleNsPerOp(headBench, baseScale), prevBench))
 		}
 
-		rows = append(rows, generateRow(baseRef, prevBench))
-		ratios = append(ratios, result{
-			Benchmark:              benchmark,
-			RatioNsPerOp:           getRationsPerOP(headBench, prevBench),
-			RatioAllocedBytesPerOp: getRatioAllocedBytesPerOp(headBench, prevBench),
-		})
-
 		// get benchmark result of latestReleaseVersion
-		if latestReleaseSet == nil {
+		if release == nil {
+			rows = append(rows, group)
 			continue
 		}
-		if latestReleaseBench, ok := latestReleaseSet[benchName]; ok {
-			rows = append(rows, generateRow(tagName, latestReleaseBench))
-			ratiosWithRelease = append(ratiosWithRelease, result{
-				Benchmark:              benchmark,
-				RatioNsPerOp:           getRationsPerOP(headBench, latestReleaseBench),
-				RatioAllocedBytesPerOp: getRatioAllocedBytesPerOp(headBench, latestReleaseBench),
-			})
+		if latestReleaseBench, ok := release.run.Results[benchName]; ok {
+			group = append(group, generateRow(benchName, release.name, latestReleaseBench))
+			if !benchmark.Calibration {
+				ratiosWithRelease = append(ratiosWithRelease, newResult(comparedBenchmark(benchmark, release), scaleNsPerOp(headBench, releaseScale), latestReleaseBench))
+			}
+		}
+		rows = append(rows, group)
+	}
+
+	var applied []acceptedRegression
+	if acceptedRegressionsPath != "" {
+		accepted, err := loadAcceptedRegressions(acceptedRegressionsPath)
+		if err != nil {
+			return err
 		}
+		now := time.Now()
+		applied = append(acceptRegressions(ratios, accepted, now), acceptRegressions(ratiosWithRelease, accepted, now)...)
 	}
 
 	if !onlyRegression {
-		showResult(os.Stdout, rows)
+		showResult(w, rows)
+		showSampleStats(w, head, base, release)
 	}
 
-	regression := showRatio(os.Stdout, ratios, onlyRegression, baseRef)
+	if baseCalibrated {
+		showCalibration(w, baseScale, base.name)
+	}
+	regression := showRatio(w, ratios, onlyRegression, base.name)
+	if totalBudget > 0 {
+		// Individual regressions are tolerated as long as the aggregate stays within the budget.
+		regression = showAggregate(w, ratios, base.name)
+	}
+	// a sweep can scale worse even if no single size regressed
+	regression = showScaling(w, head, base) || regression
+	regression = showCPUScaling(w, head, base) || regression
 
 	var regressionWithLatestVersion bool
-	if latestReleaseSet != nil {
-		regressionWithLatestVersion = showRatio(os.Stdout, ratiosWithRelease, onlyRegression, tagName)
+	var tagName string
+	refs := []string{head.ref, base.ref}
+	if release != nil {
+		if releaseCalibrated {
+			showCalibration(w, releaseScale, release.name)
+		}
+		regressionWithLatestVersion = showRatio(w, ratiosWithRelease, onlyRegression, release.name)
+		if totalBudget > 0 {
+			regressionWithLatestVersion = showAggregate(w, ratiosWithRelease, release.name)
+		}
+		regressionWithLatestVersion = showScaling(w, head, release) || regressionWithLatestVersion
+		regressionWithLatestVersion = showCPUScaling(w, head, release) || regressionWithLatestVersion
+		tagName = release.name
+		refs = append(refs, release.ref)
+	}
+	flamegraphs := regressionFlamegraphs(append(ratios, ratiosWithRelease...), refs)
+	showFlamegraphs(w, flamegraphs)
+	var sources []benchmarkSource
+	if head.dir != "" {
+		sources = regressionLocations(diagnostics, append(ratios, ratiosWithRelease...), head.dir)
+	}
+	showSourceLocations(w, sources)
+	skipped := exclusions(head, base, release)
+	showSkipped(w, skipped)
+	showFailed(w, head, base, release)
+	alerts := historyTrendAlerts()
+	showTrendAlerts(w, alerts)
+	showRunInfo(w)
+
+	partial := isPartial(head, base, release)
+	if partial {
+		fmt.Fprintf(w, "Partial results: the deadline was reached before all benchmarks were run (partial policy: %s)\n\n", partialPolicy)
+	}
+	regressed := regression || regressionWithLatestVersion
+	regression = applyPolicy(diagnostics, regression, base)
+	if release != nil {
+		regressionWithLatestVersion = applyPolicy(diagnostics, regressionWithLatestVersion, release)
+	}
+	// regressions tolerated by the policy are warnings
+	demoted := regressed && !regression && !regressionWithLatestVersion
+	failedPartial := false
+	if partial {
+		switch partialPolicy {
+		case policyWarn:
+			regression, regressionWithLatestVersion = false, false
+		case policyFail:
+			failedPartial = true
+		}
+	}
+	compared, configured := benchmarkCoverage(ratios)
+	showCoverage(w, compared, configured)
+	failedCoverage := belowMinCoverage(compared, configured)
+	failed := regression || regressionWithLatestVersion || failedPartial || failedCoverage
+	// the verdict line is looked for in logs, it goes with the diagnostics
+	// when the report is a document
+	verdictWriter := w
+	if outputFormat != outputText {
+		verdictWriter = diagnostics
+	}
+	showVerdict(verdictWriter, append(ratios, ratiosWithRelease...), failed, partial)
+	comparisons := []comparison{{base, ratios, regression}, {release, ratiosWithRelease, regressionWithLatestVersion}}
+	if outputFormat == outputJSON {
+		jsonReport := newJSONReport(head, comparisons, skipped, failed, partial)
+		jsonReport.Coverage = jsonCoverage{Compared: compared, Configured: configured}
+		jsonReport.TrendAlerts = newJSONTrendAlerts(alerts)
+		if err := writeJSONReport(report, jsonReport); err != nil {
+			return err
+		}
+	}
+	if outputFormat == outputMarkdown || markdownFile != "" || githubComment || gitlabNote {
+		markdown := markdownReport(head, rows, comparisons, skipped, flamegraphs, sources, alerts, failed, partial)
+		if outputFormat == outputMarkdown {
+			fmt.Fprint(report, markdown)
+		}
+		if err := writeMarkdownFile(markdown); err != nil {
+			return err
+		}
+		if githubComment {
+			if err := postGitHubComment(githubAPIURL(), markdown); err != nil {
+				klog.ErrorS(err, "Failed to comment on the pull request", "pr", githubPR)
+			}
+		}
+		if gitlabNote {
+			if err := postGitLabNote(gitlabProjectURL(), markdown); err != nil {
+				klog.ErrorS(err, "Failed to post a note on the merge request")
+			}
+		}
+	}
+	if gitlabStatus {
+		if err := setGitLabStatus(gitlabProjectURL(), append(ratios, ratiosWithRelease...), failed); err != nil {
+			klog.ErrorS(err, "Failed to set the commit status")
+		}
+	}
+	if err := writeTextfile(head, comparisons, failed); err != nil {
+		return err
+	}
+	if err := writeCSV(head, base, release); err != nil {
+		return err
+	}
+	if err := reportDatadog(datadogURL(), head, comparisons); err != nil {
+		return err
+	}
+	entry := newAuditEntry(head, comparisons, applied)
+	entry.Partial = partial
+	if failedPartial || failedCoverage {
+		entry.Verdict = "FAIL"
+	}
+	if err := recordAudit(entry); err != nil {
+		return err
+	}
+	emitCloudEvent(entry)
+	if fileIssues {
+		if err := fileRegressionIssues(githubAPIURL(), head, comparisons); err != nil {
+			klog.ErrorS(err, "Failed to file regression issues")
+		}
+	}
+	if failedPartial {
+		return fmt.Errorf("the deadline was reached before all benchmarks were run")
+	}
+	if failedCoverage {
+		return fmt.Errorf("only %d of %d benchmarks were compared with %s, below the minimum coverage of %s", compared, configured, base.name, numbers.percent(float64(minCoverage)))
+	}
+	if regression && release == nil {
+		return fmt.Errorf("this commit makes benchmarks worse compared with %s", base.name)
 	}
 	if regression || regressionWithLatestVersion {
 		return fmt.Errorf("this commit makes benchmarks worse，compared with %s: %t, compared with %s: %t",
-			baseRef, regression, tagName, regressionWithLatestVersion)
+			base.name, regression, tagName, regressionWithLatestVersion)
+	}
+	if warnExitCode != 0 && !reportOnly() {
+		if demoted {
+			return &exitError{code: warnExitCode, err: fmt.Errorf("this commit makes benchmarks worse, the regressions are tolerated by the policy")}
+		}
+		if warnings := countWarnings(append(ratios, ratiosWithRelease...)); warnings > 0 {
+			return &exitError{code: warnExitCode, err: fmt.Errorf("%d benchmark comparisons exceed their warn threshold", warnings)}
+		}
 	}
 
 	return nil
 }
 
-func runBenchmark(cmdStr string, benchmark *Benchmark) (parse.Set, error) {
+// showVerdict prints a single line summarizing the comparisons, which is
+// always the last line of the report so that it can easily be found in logs.
+// The verdict is WARN when there are regressions which do not fail the run.
+// partial=true is added when the deadline was reached.
+func showVerdict(w io.Writer, results []result, failed, partial bool) {
+	verdict, regressions, improvements := resultVerdict(results, failed)
+	line := fmt.Sprintf("BENCHCI RESULT: regressions=%d improvements=%d compared=%d verdict=%s", regressions, improvements, len(results), verdict)
+	if partial {
+		line += " partial=true"
+	}
+	fmt.Fprintln(w, line)
+}
+
+func resultVerdict(results []result, failed bool) (string, int, int) {
+	var regressions, improvements int
+	for _, result := range results {
+		if isRegression(result) {
+			regressions++
+		} else if isImprovement(result) {
+			improvements++
+		}
+	}
+	verdict := "PASS"
+	if failed {
+		verdict = "FAIL"
+	} else if regressions > 0 || countWarnings(results) > 0 {
+		verdict = "WARN"
+	}
+	return verdict, regressions, improvements
+}
+
+func runBenchmark(cmdStr, dir string, vars refVars, benchmark *Benchmark) (parse.Set, map[string][]map[string]float64, *gcStats, error) {
+	var out []byte
+	var err error
+	switch {
+	case isTarget(benchmark):
+		out, err = runTarget(dir, vars, benchmark)
+	case len(benchmark.Commands) == 0:
+		out, err = runGoTest(cmdStr, dir, vars, benchmark)
+	default:
+		out, err = runPipeline(cmdStr, dir, vars, benchmark)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(benchmark.OutputFilters) > 0 {
+		if out, err = filterOutput(out, benchmark.OutputFilters); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	var stats *gcStats
+	if *benchmark.MemStats {
+		stats = parseGCTrace(out)
+	}
+	b := bytes.NewBuffer(out)
+	s, err := parse.ParseSet(b)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to parse a result of benchmarks: %w", err)
+	}
+	return s, parseCustomMetrics(out), stats, nil
+}
+
+// testBinaryArgs returns the custom flags of the test binary of a benchmark:
+// its args, then the value of its sweep so that it overrides them.
+func testBinaryArgs(benchmark *Benchmark) []string {
+	return append(append([]string{}, benchmark.Args...), sweepArgs(benchmark)...)
+}
+
+// runGoTest runs the benchmark with "go test" and returns its output. No
+// output is returned if the package has no test files.
+func runGoTest(cmdStr, dir string, vars refVars, benchmark *Benchmark) ([]byte, error) {
 	var stderr bytes.Buffer
 	args := []string{
 		"test",
-		"-run", "'^$'",
+		"-run", "^$",
 		"-bench", benchmark.Name,
 		"-benchtime", benchmark.Benchtime,
 		"-timeout", benchmark.Timeout,
@@ -368,47 +1208,302 @@ func runBenchmark(cmdStr string, benchmark *Benchmark) (parse.Set, error) {
 	if *benchmark.Benchmem {
 		args = append(args, "-benchmem")
 	}
+	if benchmark.Count > 1 {
+		args = append(args, "-count", strconv.Itoa(benchmark.Count))
+	}
+	execCmd, err := testExec(benchmark)
+	if err != nil {
+		return nil, err
+	}
+	if execCmd != "" {
+		args = append(args, "-exec", execCmd)
+	}
+	var cpuProfile, testBinary, trace string
+	if *benchmark.Flamegraph || *benchmark.Trace || *benchmark.Contention {
+		prefix := artifactPath(vars.Ref, benchmark.UniqueName)
+		if err := os.MkdirAll(filepath.Dir(prefix), 0755); err != nil {
+			return nil, fmt.Errorf("unable to create artifacts directory: %w", err)
+		}
+		// Keep the test binary out of the worktree, it is needed to symbolize
+		// profiles and traces.
+		testBinary = prefix + ".test"
+		args = append(args, "-o", testBinary)
+		if *benchmark.Flamegraph {
+			cpuProfile = prefix + ".cpu.pprof"
+			args = append(args, "-cpuprofile", cpuProfile)
+		}
+		if *benchmark.Trace {
+			trace = prefix + ".trace"
+			args = append(args, "-trace", trace)
+		}
+		if *benchmark.Contention {
+			args = append(args, "-blockprofile", prefix+".block.pprof", "-mutexprofile", prefix+".mutex.pprof")
+		}
+	}
 	args = append(args, benchmark.Package)
-	cmd := exec.Command(cmdStr, args...)
+	if binaryArgs := testBinaryArgs(benchmark); len(binaryArgs) > 0 {
+		args = append(append(args, "-args"), binaryArgs...)
+	}
+	args = pinArgs(vars, append([]string{cmdStr}, args...))
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
 	cmd.Stderr = &stderr
+	if env := append(append([]string{}, vars.env...), sweepEnv(benchmark)...); len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
+	// go test validates the timeout, it is only used here to kill hung
+	// processes
+	timeout, _ := time.ParseDuration(benchmark.Timeout)
 	klog.InfoS("Running benchmark", "command", cmd)
-	out, err := cmd.Output()
+	out, err := outputWithTimeout(cmd, timeout)
 	if err != nil {
 		if strings.HasSuffix(strings.TrimSpace(stderr.String()), "no packages to test") {
-			return parse.Set{}, nil
+			return nil, nil
 		}
 		klog.InfoS("Exec command output", "out", string(out))
 		klog.InfoS("Exec command error", "err", stderr.String())
 		return nil, fmt.Errorf("failed to run '%s' command: %w", cmd, err)
 	}
 
-	b := bytes.NewBuffer(out)
-	s, err := parse.ParseSet(b)
+	if trace != "" {
+		klog.InfoS("Collected execution trace", "benchmark", benchmark.UniqueName, "ref", vars.Ref, "path", trace)
+	}
+	if cpuProfile != "" {
+		title := fmt.Sprintf("%s (%s)", benchmark.UniqueName, vars.Ref)
+		if svg, err := generateFlamegraph(cmdStr, testBinary, cpuProfile, title); err != nil {
+			klog.ErrorS(err, "Failed to generate flamegraph", "benchmark", benchmark.UniqueName, "ref", vars.Ref)
+		} else {
+			klog.InfoS("Generated flamegraph", "benchmark", benchmark.UniqueName, "ref", vars.Ref, "path", svg)
+		}
+	}
+	return out, nil
+}
+
+// testExec returns the command wrapping the test binary, so that the limits,
+// the CPU placement and the GC tracing only apply to the benchmark and not to
+// the go command and the compiler.
+func testExec(benchmark *Benchmark) (string, error) {
+	limits, err := limitsExec(benchmark.Limits)
+	if err != nil {
+		return "", err
+	}
+	smt, err := smtExec("/sys", benchmark)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse a result of benchmarks: %w", err)
+		return "", err
 	}
-	return s, nil
+	wrappers := []string{limits, numaExec(benchmark.NumaNode), smt}
+	if *benchmark.MemStats {
+		wrappers = append(wrappers, "env GODEBUG=gctrace=1")
+	}
+	return strings.Join(strings.Fields(strings.Join(wrappers, " ")), " "), nil
 }
 
-func generateRow(ref string, b *parse.Benchmark) []string {
-	return []string{b.Name, ref, fmt.Sprintf(" %.2f ns/op", b.NsPerOp),
-		fmt.Sprintf(" %d B/op", b.AllocedBytesPerOp)}
+// filterOutput drops the lines of the output which match one of the filters.
+func filterOutput(out []byte, filters []string) ([]byte, error) {
+	res := make([]*regexp.Regexp, 0, len(filters))
+	for _, filter := range filters {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output filter '%s': %w", filter, err)
+		}
+		res = append(res, re)
+	}
+	var filtered bytes.Buffer
+	for _, line := range bytes.SplitAfter(out, []byte("\n")) {
+		drop := false
+		for _, re := range res {
+			if re.Match(bytes.TrimRight(line, "\r\n")) {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			filtered.Write(line)
+		}
+	}
+	return filtered.Bytes(), nil
+}
+
+// combineSamples returns the median of each metric when a benchmark was run
+// several times.
+func combineSamples(samples []*parse.Benchmark) *parse.Benchmark {
+	if len(samples) == 1 {
+		return samples[0]
+	}
+	medianValue := func(value func(b *parse.Benchmark) float64) float64 {
+		return median(sampleValues(samples, value))
+	}
+	combined := &parse.Benchmark{Name: samples[0].Name}
+	for _, b := range samples {
+		combined.N += b.N
+		combined.Measured |= b.Measured
+	}
+	combined.NsPerOp = medianValue(func(b *parse.Benchmark) float64 { return b.NsPerOp })
+	combined.AllocedBytesPerOp = uint64(medianValue(func(b *parse.Benchmark) float64 { return float64(b.AllocedBytesPerOp) }))
+	combined.AllocsPerOp = uint64(medianValue(func(b *parse.Benchmark) float64 { return float64(b.AllocsPerOp) }))
+	combined.MBPerS = medianValue(func(b *parse.Benchmark) float64 { return b.MBPerS })
+	return combined
 }
 
-func showResult(w io.Writer, rows [][]string) {
-	fmt.Fprintln(w, "\nResult")
+// generateRow returns the row of the result table for the result of a
+// benchmark at a ref, labelled with its unique name so that the entries of a
+// sweep or of a cpu list can be told apart.
+func generateRow(name, ref string, b *benchResult) []string {
+	row := []string{name, ref, " " + numbers.float(b.NsPerOp) + " ns/op",
+		" " + numbers.uint(b.AllocedBytesPerOp) + " B/op"}
+	if allocsEnabled() {
+		row = append(row, " "+numbers.uint(b.AllocsPerOp)+" allocs/op")
+	}
+	if throughputEnabled() {
+		row = append(row, " "+numbers.float(b.MBPerS)+" MB/s")
+	}
+	if contentionEnabled() {
+		if b.ContentionMeasured {
+			row = append(row, fmt.Sprintf(" %s", time.Duration(b.ContentionNs)))
+		} else {
+			row = append(row, "-")
+		}
+	}
+	if memStatsEnabled() {
+		if b.MemStatsMeasured {
+			row = append(row, fmt.Sprintf(" %s", formatBytes(b.PeakHeapBytes)))
+		} else {
+			row = append(row, "-")
+		}
+	}
+	return row
+}
+
+func generateMissingRow(name, ref string) []string {
+	row := []string{name, ref, "-", "-"}
+	if allocsEnabled() {
+		row = append(row, "-")
+	}
+	if throughputEnabled() {
+		row = append(row, "-")
+	}
+	if contentionEnabled() {
+		row = append(row, "-")
+	}
+	if memStatsEnabled() {
+		row = append(row, "-")
+	}
+	return row
+}
+
+// showFailed lists the benchmarks which have no result for a ref, along with
+// the reason.
+func showFailed(w io.Writer, refs ...*refResults) {
+	var rows [][]string
+	for _, r := range refs {
+		if r == nil {
+			continue
+		}
+		names := make([]string, 0, len(r.run.Errors))
+		for name := range r.run.Errors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			rows = append(rows, []string{name, r.name, r.run.Errors[name]})
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nFailed")
 	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 6))
 
 	table := tablewriter.NewWriter(w)
 	table.SetAutoFormatHeaders(false)
-	table.SetAlignment(tablewriter.ALIGN_CENTER)
-	headers := []string{"Name", "Commit", "NsPerOp", "AllocedBytesPerOp"}
-	table.SetHeader(headers)
-	table.SetAutoMergeCells(true)
-	table.SetRowLine(true)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"Name", "Ref", "Error"})
 	table.AppendBulk(rows)
 	table.Render()
+	fmt.Fprintln(w)
+}
+
+// ratioHeaders returns the headers of the ratio table, with a column for each
+// custom metric compared in results.
+func ratioHeaders(results []result) []string {
+	headers := []string{"Name", "NsPerOp", "AllocedBytesPerOp"}
+	if allocsEnabled() {
+		headers = append(headers, "AllocsPerOp")
+	}
+	if throughputEnabled() {
+		headers = append(headers, "MBPerS")
+	}
+	if contentionEnabled() {
+		headers = append(headers, "Contention")
+	}
+	if memStatsEnabled() {
+		headers = append(headers, "PeakHeap")
+	}
+	return append(headers, comparedCustomUnits(results)...)
+}
+
+// ratioRow returns the row of the ratio table for a result, with "-" for the
+// metrics which are not compared. Ratios are formatted with format.
+func ratioRow(result result, customUnits []string, format func(float64) string) ([]string, []tablewriter.Colors) {
+	comparedScore := whichScoreToCompare(result.Compare)
+	row := []string{result.UniqueName, format(result.RatioNsPerOp), format(result.RatioAllocedBytesPerOp)}
+	colors := []tablewriter.Colors{{}, generateColor(result.RatioNsPerOp), generateColor(result.RatioAllocedBytesPerOp)}
+	if !comparedScore.nsPerOp {
+		row[1] = "-"
+		colors[1] = tablewriter.Colors{}
+	}
+	if !comparedScore.allocedBytesPerOp {
+		row[2] = "-"
+		colors[2] = tablewriter.Colors{}
+	}
+	if allocsEnabled() {
+		if comparedScore.allocsPerOp {
+			row = append(row, format(result.RatioAllocsPerOp))
+			colors = append(colors, generateColor(result.RatioAllocsPerOp))
+		} else {
+			row = append(row, "-")
+			colors = append(colors, tablewriter.Colors{})
+		}
+	}
+	if throughputEnabled() {
+		if comparedScore.mbPerS {
+			// higher is better
+			row = append(row, format(result.RatioMBPerS))
+			colors = append(colors, generateColor(-result.RatioMBPerS))
+		} else {
+			row = append(row, "-")
+			colors = append(colors, tablewriter.Colors{})
+		}
+	}
+	if contentionEnabled() {
+		if comparedScore.contention && *result.Contention {
+			row = append(row, format(result.RatioContention))
+			colors = append(colors, generateColor(result.RatioContention))
+		} else {
+			row = append(row, "-")
+			colors = append(colors, tablewriter.Colors{})
+		}
+	}
+	if memStatsEnabled() {
+		if comparedScore.heap && *result.MemStats {
+			row = append(row, format(result.RatioPeakHeap))
+			colors = append(colors, generateColor(result.RatioPeakHeap))
+		} else {
+			row = append(row, "-")
+			colors = append(colors, tablewriter.Colors{})
+		}
+	}
+	for _, unit := range customUnits {
+		if ratio, ok := result.RatioMetrics[unit]; ok {
+			row = append(row, format(ratio))
+			colors = append(colors, generateColor(worsening(unit, ratio)))
+		} else {
+			row = append(row, "-")
+			colors = append(colors, tablewriter.Colors{})
+		}
+	}
+	return row, colors
 }
 
 func showRatio(w io.Writer, results []result, onlyRegression bool, compareWith string) bool {
@@ -416,30 +1511,20 @@ func showRatio(w io.Writer, results []result, onlyRegression bool, compareWith s
 	table.SetAutoFormatHeaders(false)
 	table.SetAlignment(tablewriter.ALIGN_CENTER)
 	table.SetRowLine(true)
-	headers := []string{"Name", "NsPerOp", "AllocedBytesPerOp"}
-	table.SetHeader(headers)
+	table.SetHeader(ratioHeaders(results))
+	customUnits := comparedCustomUnits(results)
 
 	var regression bool
 	for _, result := range results {
-		comparedScore := whichScoreToCompare(result.Compare)
-		if comparedScore.nsPerOp && result.Threshold < result.RatioNsPerOp {
+		warning := isWarning(result)
+		if isRegression(result) {
 			regression = true
-		} else if comparedScore.allocedBytesPerOp && result.Threshold < result.RatioAllocedBytesPerOp {
-			regression = true
-		} else {
-			if onlyRegression {
-				continue
-			}
-		}
-		row := []string{result.Name, generateRatioItem(result.RatioNsPerOp), generateRatioItem(result.RatioAllocedBytesPerOp)}
-		colors := []tablewriter.Colors{{}, generateColor(result.RatioNsPerOp), generateColor(result.RatioAllocedBytesPerOp)}
-		if !comparedScore.nsPerOp {
-			row[1] = "-"
-			colors[1] = tablewriter.Colors{}
+		} else if onlyRegression && !warning {
+			continue
 		}
-		if !comparedScore.allocedBytesPerOp {
-			row[2] = "-"
-			colors[2] = tablewriter.Colors{}
+		row, colors := ratioRow(result, customUnits, generateRatioItem)
+		if warning {
+			colors = warningColors(colors)
 		}
 		table.Rich(row, colors)
 	}
@@ -449,27 +1534,106 @@ func showRatio(w io.Writer, results []result, onlyRegression bool, compareWith s
 
 		table.Render()
 		fmt.Fprintln(w)
+		showNotes(w, results)
 	}
 	return regression
 }
 
-func generateRatioItem(ratio float64) string {
-	if -0.0001 < ratio && ratio < 0.0001 {
-		ratio = 0
+func isRegression(result result) bool {
+	comparedScore := whichScoreToCompare(result.Compare)
+	if comparedScore.nsPerOp && exceedsThreshold(result, result.RatioNsPerOp, result.PNsPerOp) {
+		return true
+	}
+	if comparedScore.allocedBytesPerOp && exceedsThreshold(result, result.RatioAllocedBytesPerOp, result.PAllocedBytesPerOp) {
+		return true
+	}
+	if comparedScore.allocsPerOp && exceedsThreshold(result, result.RatioAllocsPerOp, result.PAllocsPerOp) {
+		return true
+	}
+	// a decrease of the throughput is a regression
+	if comparedScore.mbPerS && exceedsThreshold(result, -result.RatioMBPerS, result.PMBPerS) {
+		return true
+	}
+	if comparedScore.contention && *result.Contention && result.Threshold < result.RatioContention {
+		return true
+	}
+	if comparedScore.heap && *result.MemStats && result.Threshold < result.RatioPeakHeap {
+		return true
+	}
+	for unit, ratio := range result.RatioMetrics {
+		if metricThreshold(result, unit) < worsening(unit, ratio) {
+			return true
+		}
+	}
+	return false
+}
+
+// isImprovement returns true if one of the compared scores improved by more
+// than the threshold.
+func isImprovement(result result) bool {
+	comparedScore := whichScoreToCompare(result.Compare)
+	if comparedScore.nsPerOp && belowThreshold(result, result.RatioNsPerOp, result.PNsPerOp) {
+		return true
+	}
+	if comparedScore.allocedBytesPerOp && belowThreshold(result, result.RatioAllocedBytesPerOp, result.PAllocedBytesPerOp) {
+		return true
+	}
+	if comparedScore.allocsPerOp && belowThreshold(result, result.RatioAllocsPerOp, result.PAllocsPerOp) {
+		return true
 	}
-	if 0 <= ratio {
-		return fmt.Sprintf("%.2f%%", 100*ratio)
+	if comparedScore.mbPerS && belowThreshold(result, -result.RatioMBPerS, result.PMBPerS) {
+		return true
 	}
-	return fmt.Sprintf("%.2f%%", -100*ratio)
+	if comparedScore.contention && *result.Contention && result.RatioContention < -result.Threshold {
+		return true
+	}
+	if comparedScore.heap && *result.MemStats && result.RatioPeakHeap < -result.Threshold {
+		return true
+	}
+	for unit, ratio := range result.RatioMetrics {
+		if worsening(unit, ratio) < -metricThreshold(result, unit) {
+			return true
+		}
+	}
+	return false
+}
+
+func generateRatioItem(ratio float64) string {
+	return numbers.percent(math.Abs(ratio))
 }
 
 func generateColor(ratio float64) tablewriter.Colors {
+	if !useColor {
+		return tablewriter.Colors{}
+	}
 	if ratio > 0 {
 		return tablewriter.Colors{tablewriter.Bold, tablewriter.FgHiRedColor}
 	}
 	return tablewriter.Colors{tablewriter.Bold, tablewriter.FgBlueColor}
 }
 
+// allocsEnabled returns true if allocs/op is compared for any benchmark, in
+// which case it is shown in the tables.
+func allocsEnabled() bool {
+	for _, benchmark := range benchmarks.Benchmarks {
+		if whichScoreToCompare(benchmark.Compare).allocsPerOp {
+			return true
+		}
+	}
+	return false
+}
+
+// throughputEnabled returns true if MB/s is compared for any benchmark, in
+// which case it is shown in the tables.
+func throughputEnabled() bool {
+	for _, benchmark := range benchmarks.Benchmarks {
+		if whichScoreToCompare(benchmark.Compare).mbPerS {
+			return true
+		}
+	}
+	return false
+}
+
 func whichScoreToCompare(c string) comparedScore {
 	var comparedScore comparedScore
 	for _, cc := range strings.Split(c, ",") {
@@ -478,6 +1642,18 @@ func whichScoreToCompare(c string) comparedScore {
 			comparedScore.nsPerOp = true
 		case "B/op":
 			comparedScore.allocedBytesPerOp = true
+		case "allocs/op":
+			comparedScore.allocsPerOp = true
+		case "MB/s":
+			comparedScore.mbPerS = true
+		case "contention":
+			comparedScore.contention = true
+		case "heap":
+			comparedScore.heap = true
+		default:
+			if cc != "" && !builtinUnits[cc] {
+				comparedScore.custom = append(comparedScore.custom, cc)
+			}
 		}
 	}
 	return comparedScore
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/benchmark/parse"
 )
 
 func TestVersionCompare(t *testing.T) {
@@ -62,3 +64,157 @@ func TestVersionCompare(t *testing.T) {
 		assert.Equal(t, tCase.expectedResult, versionRequired(tCase.versionRequirement, tCase.version), "version check result not match")
 	}
 }
+
+func TestFilterOutput(t *testing.T) {
+	out := "goos: linux\nI1014 10:00:00.000000    1 controller.go:42] Syncing\nBenchmarkFoo-4   \t    1000\t   1000 ns/op\nW1014 10:00:00.000000    1 controller.go:43] Slow\nPASS\n"
+	filtered, err := filterOutput([]byte(out), []string{`^[IWE]\d{4} `})
+	assert.NoError(t, err)
+	assert.Equal(t, "goos: linux\nBenchmarkFoo-4   \t    1000\t   1000 ns/op\nPASS\n", string(filtered))
+
+	_, err = filterOutput([]byte(out), []string{"("})
+	assert.Error(t, err)
+}
+
+func TestShowVerdict(t *testing.T) {
+	contention := false
+	newResult := func(ratio float64) result {
+		return result{
+			Benchmark:    Benchmark{BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}},
+			RatioNsPerOp: ratio,
+		}
+	}
+	results := []result{newResult(0.2), newResult(-0.2), newResult(-0.3), newResult(0.05)}
+
+	var b strings.Builder
+	showVerdict(&b, results, true, false)
+	assert.Equal(t, "BENCHCI RESULT: regressions=1 improvements=2 compared=4 verdict=FAIL\n", b.String())
+
+	b.Reset()
+	showVerdict(&b, results, false, false)
+	assert.Equal(t, "BENCHCI RESULT: regressions=1 improvements=2 compared=4 verdict=WARN\n", b.String())
+
+	b.Reset()
+	showVerdict(&b, results[1:], false, false)
+	assert.Equal(t, "BENCHCI RESULT: regressions=0 improvements=2 compared=3 verdict=PASS\n", b.String())
+
+	b.Reset()
+	showVerdict(&b, results[1:], false, true)
+	assert.Equal(t, "BENCHCI RESULT: regressions=0 improvements=2 compared=3 verdict=PASS partial=true\n", b.String())
+}
+
+func TestCombineSamples(t *testing.T) {
+	samples := []*parse.Benchmark{
+		{Name: "BenchmarkFoo-4", N: 100, NsPerOp: 120, AllocedBytesPerOp: 16, Measured: parse.NsPerOp},
+		{Name: "BenchmarkFoo-4", N: 100, NsPerOp: 100, AllocedBytesPerOp: 32, Measured: parse.NsPerOp | parse.AllocedBytesPerOp},
+		{Name: "BenchmarkFoo-4", N: 200, NsPerOp: 300, AllocedBytesPerOp: 16, Measured: parse.NsPerOp},
+	}
+	assert.Equal(t, &parse.Benchmark{Name: "BenchmarkFoo-4", N: 400, NsPerOp: 120, AllocedBytesPerOp: 16, Measured: parse.NsPerOp | parse.AllocedBytesPerOp}, combineSamples(samples))
+	assert.Equal(t, 110.0, combineSamples(samples[:2]).NsPerOp)
+	assert.True(t, samples[0] == combineSamples(samples[:1]))
+}
+
+func TestUpdateBenchmarksWithProfile(t *testing.T) {
+	defer func(b BenchmarkList, p string) { *benchmarks, profile = b, p }(*benchmarks, profile)
+	*benchmarks = BenchmarkList{
+		BenchmarkConfiguration: BenchmarkConfiguration{Benchtime: "1s"},
+		Profiles: map[string]BenchmarkConfiguration{
+			"nightly": {Benchtime: "10s", Count: 10},
+		},
+		Benchmarks: []Benchmark{
+			{Name: "BenchmarkFoo"},
+			{Name: "BenchmarkBar", BenchmarkConfiguration: BenchmarkConfiguration{Benchtime: "20x"}},
+		},
+	}
+	profile = "nightly"
+	assert.NoError(t, updateBenchmarks())
+	assert.Equal(t, "10s", benchmarks.Benchmarks[0].Benchtime)
+	assert.Equal(t, 10, benchmarks.Benchmarks[0].Count)
+	assert.Equal(t, "20x", benchmarks.Benchmarks[1].Benchtime)
+	assert.Equal(t, 10, benchmarks.Benchmarks[1].Count)
+
+	profile = "weekly"
+	assert.Error(t, updateBenchmarks())
+}
+
+func TestGoCommand(t *testing.T) {
+	defer func(b BenchmarkList, p string) { *benchmarks, profile = b, p }(*benchmarks, profile)
+	*benchmarks = BenchmarkList{
+		Command:                "go",
+		BenchmarkConfiguration: BenchmarkConfiguration{GoBinary: "/opt/go1.22.3/bin/go"},
+		Benchmarks: []Benchmark{
+			{Name: "BenchmarkFoo"},
+			{Name: "BenchmarkBar", BenchmarkConfiguration: BenchmarkConfiguration{GoBinary: "/opt/go1.21.13/bin/go"}},
+		},
+	}
+	profile = ""
+	assert.NoError(t, updateBenchmarks())
+	assert.Equal(t, "/opt/go1.22.3/bin/go", goCommand(&benchmarks.Benchmarks[0].BenchmarkConfiguration))
+	assert.Equal(t, "/opt/go1.21.13/bin/go", goCommand(&benchmarks.Benchmarks[1].BenchmarkConfiguration))
+	assert.Equal(t, "go", goCommand(&BenchmarkConfiguration{}))
+}
+
+func TestCompareAllocsPerOp(t *testing.T) {
+	defer func(b BenchmarkList) { *benchmarks = b }(*benchmarks)
+	disabled := false
+	benchmark := Benchmark{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{
+		Threshold: 0.1, Compare: "ns/op,allocs/op", Contention: &disabled, MemStats: &disabled,
+	}}
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{benchmark}}
+	head := &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", NsPerOp: 100, AllocsPerOp: 12}}
+	base := &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", NsPerOp: 100, AllocsPerOp: 10}}
+	r := newResult(benchmark, head, base)
+	assert.InDelta(t, 0.2, r.RatioAllocsPerOp, 1e-9)
+	assert.True(t, isRegression(r))
+	assert.Equal(t, "ns/op +0.00%, allocs/op +20.00%", regressionSummary(r))
+	assert.Equal(t, []string{"Name", "NsPerOp", "AllocedBytesPerOp", "AllocsPerOp"}, ratioHeaders([]result{r}))
+	row, _ := ratioRow(r, nil, signedRatio)
+	assert.Equal(t, []string{"BenchmarkFoo", "+0.00%", "-", "+20.00%"}, row)
+
+	r = newResult(benchmark, base, head)
+	assert.False(t, isRegression(r))
+	assert.True(t, isImprovement(r))
+
+	benchmarks.Benchmarks[0].Compare = "ns/op"
+	assert.Equal(t, []string{"Name", "NsPerOp", "AllocedBytesPerOp"}, ratioHeaders([]result{r}))
+}
+
+func TestCompareMBPerS(t *testing.T) {
+	defer func(b BenchmarkList) { *benchmarks = b }(*benchmarks)
+	disabled := false
+	benchmark := Benchmark{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{
+		Threshold: 0.1, Compare: "MB/s", Contention: &disabled, MemStats: &disabled,
+	}}
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{benchmark}}
+	head := &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", NsPerOp: 100, MBPerS: 80}}
+	base := &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", NsPerOp: 100, MBPerS: 100}}
+	// lower throughput is worse
+	r := newResult(benchmark, head, base)
+	assert.InDelta(t, -0.2, r.RatioMBPerS, 1e-9)
+	assert.True(t, isRegression(r))
+	assert.False(t, isImprovement(r))
+	assert.Equal(t, "MB/s -20.00%", regressionSummary(r))
+	assert.Equal(t, []string{"Name", "NsPerOp", "AllocedBytesPerOp", "MBPerS"}, ratioHeaders([]result{r}))
+	row, _ := ratioRow(r, nil, signedRatio)
+	assert.Equal(t, []string{"BenchmarkFoo", "-", "-", "-20.00%"}, row)
+	assert.Equal(t, []string{"BenchmarkFoo", "HEAD", " 100.00 ns/op", " 0 B/op", " 80.00 MB/s"}, generateRow("BenchmarkFoo", "HEAD", head))
+
+	r = newResult(benchmark, base, head)
+	assert.False(t, isRegression(r))
+	assert.True(t, isImprovement(r))
+}
+
+func TestTestExec(t *testing.T) {
+	node := 1
+	memStats, noMemStats := true, false
+	benchmark := &Benchmark{BenchmarkConfiguration: BenchmarkConfiguration{MemStats: &noMemStats}}
+	execCmd, err := testExec(benchmark)
+	assert.NoError(t, err)
+	assert.Equal(t, "", execCmd)
+
+	benchmark.MemStats = &memStats
+	benchmark.NumaNode = &node
+	benchmark.Limits = &ResourceLimits{CPU: "1"}
+	execCmd, err = testExec(benchmark)
+	assert.NoError(t, err)
+	assert.Equal(t, "systemd-run --user --scope --quiet -pCPUQuota=100% numactl --cpunodebind=1 --membind=1 env GODEBUG=gctrace=1", execCmd)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+var markdownFile string
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;")
+
+// markdownTable renders a GitHub-flavored Markdown table.
+func markdownTable(w io.Writer, header []string, rows [][]string) {
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAutoWrapText(false)
+	table.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	table.SetCenterSeparator("|")
+	escaped := make([]string, len(header))
+	for i, h := range header {
+		escaped[i] = markdownEscaper.Replace(h)
+	}
+	table.SetHeader(escaped)
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = markdownEscaper.Replace(strings.TrimSpace(c))
+		}
+		table.Append(cells)
+	}
+	table.Render()
+}
+
+// markdownSection writes a collapsible section, expanded if open is true.
+func markdownSection(w io.Writer, summary string, open bool, body func(io.Writer)) {
+	if open {
+		fmt.Fprintln(w, "<details open>")
+	} else {
+		fmt.Fprintln(w, "<details>")
+	}
+	fmt.Fprintf(w, "<summary>%s</summary>\n\n", markdownEscaper.Replace(summary))
+	body(w)
+	fmt.Fprint(w, "\n</details>\n\n")
+}
+
+// markdownReport renders the comparisons as GitHub-flavored Markdown, which
+// can be posted as is as a pull request comment. The verdict comes first, the
+// comparisons are expanded only if they have regressions, and the results and
+// the skipped benchmarks are collapsed.
+func markdownReport(head *refResults, groups []resultGroup, comparisons []comparison, skipped []exclusion, flamegraphs []flamegraphs, sources []benchmarkSource, alerts []trendAlert, failed, partial bool) string {
+	var w bytes.Buffer
+	var all []result
+	refs := []*refResults{head}
+	for _, c := range comparisons {
+		if c.base != nil {
+			all = append(all, c.results...)
+			refs = append(refs, c.base)
+		}
+	}
+	verdict, regressions, improvements := resultVerdict(all, failed)
+	fmt.Fprintf(&w, "### benchci: %s\n\n", verdict)
+	fmt.Fprintf(&w, "regressions=%d improvements=%d compared=%d\n\n", regressions, improvements, len(all))
+	if partial {
+		fmt.Fprintf(&w, "**Partial results**: the deadline was reached before all benchmarks were run (partial policy: %s).\n\n", partialPolicy)
+	}
+	if len(crossMachine) > 0 {
+		fmt.Fprintf(&w, "**Cross-machine comparison**: %s\n\n", markdownEscaper.Replace(strings.Join(crossMachineDisclaimer(), " ")))
+	}
+	if owners := regressionOwners(all); len(owners) > 0 {
+		fmt.Fprintf(&w, "cc %s\n\n", strings.Join(owners, " "))
+	}
+
+	for _, c := range comparisons {
+		if c.base == nil {
+			continue
+		}
+		customUnits := comparedCustomUnits(c.results)
+		var rows [][]string
+		regressed := 0
+		for _, result := range c.results {
+			status := ""
+			if isRegression(result) {
+				status = ":x: regression"
+				regressed++
+			} else if isWarning(result) {
+				status = ":warning: warning"
+			} else if onlyRegression {
+				continue
+			} else if isImprovement(result) {
+				status = ":white_check_mark: improvement"
+			}
+			// there are no colors, so the sign shows the direction
+			row, _ := ratioRow(result, customUnits, signedRatio)
+			row[0] = markdownName(result.Benchmark)
+			rows = append(rows, append(row, status))
+		}
+		if len(rows) == 0 {
+			continue
+		}
+		summary := fmt.Sprintf("Comparison with %s (regressions: %d)", c.base.name, regressed)
+		markdownSection(&w, summary, regressed > 0, func(w io.Writer) {
+			markdownTable(w, append(ratioHeaders(c.results), "Status"), rows)
+			markdownNotes(w, c.results)
+		})
+	}
+
+	if len(sources) > 0 {
+		rows := make([][]string, 0, len(sources))
+		for _, s := range sources {
+			source := "`" + s.location.String() + "`"
+			if link := githubLink(s.location); link != "" {
+				source = fmt.Sprintf("[%s](%s)", s.location, link)
+			}
+			rows = append(rows, []string{s.benchmark, source})
+		}
+		markdownSection(&w, "Source of regressed benchmarks", true, func(w io.Writer) {
+			markdownTable(w, []string{"Name", "Source"}, rows)
+		})
+	}
+	if len(flamegraphs) > 0 {
+		rows := make([][]string, 0, len(flamegraphs))
+		for _, f := range flamegraphs {
+			links := make([]string, 0, len(f.refs))
+			for i, ref := range f.refs {
+				links = append(links, fmt.Sprintf("[%s](%s)", markdownEscaper.Replace(ref), filepath.ToSlash(f.paths[i])))
+			}
+			rows = append(rows, []string{f.benchmark, strings.Join(links, " ")})
+		}
+		markdownSection(&w, "Flamegraphs of regressed benchmarks", false, func(w io.Writer) {
+			markdownTable(w, []string{"Name", "Flamegraphs"}, rows)
+		})
+	}
+
+	if len(groups) > 0 && !onlyRegression {
+		names := groupRefs(groups)
+		headers := []string{"Name"}
+		for _, metric := range metricHeaders() {
+			headers = append(headers, fmt.Sprintf("%s (%s)", metric, strings.Join(names, " / ")))
+		}
+		var rows [][]string
+		for _, g := range groups {
+			row := []string{g.name()}
+			for i := range metricHeaders() {
+				values := make([]string, 0, len(names))
+				for _, ref := range names {
+					values = append(values, g.cell(ref, i))
+				}
+				row = append(row, strings.Join(values, " / "))
+			}
+			rows = append(rows, row)
+		}
+		markdownSection(&w, "Results", false, func(w io.Writer) { markdownTable(w, headers, rows) })
+	}
+	if len(skipped) > 0 {
+		markdownSection(&w, fmt.Sprintf("Skipped (%d)", len(skipped)), false, func(w io.Writer) {
+			markdownTable(w, []string{"Name", "Ref", "Reason"}, exclusionRows(skipped))
+		})
+	}
+	var failedRows [][]string
+	for _, r := range refs {
+		names := make([]string, 0, len(r.run.Errors))
+		for name := range r.run.Errors {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			failedRows = append(failedRows, []string{name, r.name, r.run.Errors[name]})
+		}
+	}
+	if len(failedRows) > 0 {
+		markdownSection(&w, fmt.Sprintf("Failed (%d)", len(failedRows)), true, func(w io.Writer) {
+			markdownTable(w, []string{"Name", "Ref", "Reason"}, failedRows)
+		})
+	}
+	if len(alerts) > 0 {
+		rows := make([][]string, 0, len(alerts))
+		for _, alert := range alerts {
+			rows = append(rows, alert.row())
+		}
+		markdownSection(&w, fmt.Sprintf("Trend alerts (%d)", len(alerts)), false, func(w io.Writer) {
+			markdownTable(w, trendAlertHeaders, rows)
+		})
+	}
+
+	info := "Run ID: " + runID
+	if len(runMeta) > 0 {
+		info += ", " + strings.Join(runMeta.pairs(), ", ")
+	}
+	fmt.Fprintf(&w, "<sub>%s</sub>\n", markdownEscaper.Replace(info))
+	return w.String()
+}
+
+// writeMarkdownFile writes the Markdown report to the -markdown-file path,
+// if any, regardless of the output format.
+func writeMarkdownFile(report string) error {
+	if markdownFile == "" {
+		return nil
+	}
+	if err := writeFileAtomic(markdownFile, []byte(report)); err != nil {
+		return fmt.Errorf("unable to write Markdown report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestMarkdownTable(t *testing.T) {
+	var b bytes.Buffer
+	markdownTable(&b, []string{"Name", "Reason"}, [][]string{{"BenchmarkFoo", " a|b <c>"}})
+	assert.Equal(t, `|     Name     |     Reason     |
+|--------------|----------------|
+| BenchmarkFoo | a\|b &lt;c&gt; |
+`, b.String())
+}
+
+func TestMarkdownReport(t *testing.T) {
+	defer func(b BenchmarkList, f, m, id string, w, d io.Writer) {
+		*benchmarks, outputFormat, markdownFile, runID, report, diagnostics = b, f, m, id, w, d
+	}(*benchmarks, outputFormat, markdownFile, runID, report, diagnostics)
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", Owner: "@antrea-io/agent-maintainers @alice"},
+		{Name: "BenchmarkBar", UniqueName: "BenchmarkBar", Owner: "@antrea-io/controller-maintainers"},
+		{Name: "BenchmarkBaz", UniqueName: "BenchmarkBaz", Skip: "flaky"},
+	}}
+	require.NoError(t, updateBenchmarks())
+	outputFormat, markdownFile, runID = outputMarkdown, filepath.Join(dir, "report.md"), "run"
+	var out, diag bytes.Buffer
+	report, diagnostics = &out, &diag
+
+	head := &refResults{name: "HEAD", ref: "HEAD", run: &RunResult{Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", NsPerOp: 150, AllocedBytesPerOp: 64}},
+		"BenchmarkBar": {Benchmark: &parse.Benchmark{Name: "BenchmarkBar", NsPerOp: 50, AllocedBytesPerOp: 64}},
+	}}}
+	base := &refResults{name: "main", ref: "main", policy: policyFail, run: &RunResult{Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", NsPerOp: 100, AllocedBytesPerOp: 64}},
+		"BenchmarkBar": {Benchmark: &parse.Benchmark{Name: "BenchmarkBar", NsPerOp: 100, AllocedBytesPerOp: 64}},
+	}, Errors: map[string]string{"BenchmarkQux": "exit status 1"}}}
+	assert.Error(t, compareResults(head, base, nil))
+	assert.Equal(t, "BENCHCI RESULT: regressions=1 improvements=1 compared=2 verdict=FAIL\n", diag.String())
+
+	assert.Equal(t, `### benchci: FAIL
+
+regressions=1 improvements=1 compared=2
+
+cc @alice @antrea-io/agent-maintainers
+
+<details open>
+<summary>Comparison with main (regressions: 1)</summary>
+
+|     Name     | NsPerOp | AllocedBytesPerOp |             Status             |
+|--------------|---------|-------------------|--------------------------------|
+| BenchmarkFoo | +50.00% | +0.00%            | :x: regression                 |
+| BenchmarkBar | -50.00% | +0.00%            | :white_check_mark: improvement |
+
+</details>
+
+<details>
+<summary>Results</summary>
+
+|     Name     |    NsPerOp (HEAD / main)    | AllocedBytesPerOp (HEAD / main) |
+|--------------|-----------------------------|---------------------------------|
+| BenchmarkFoo | 150.00 ns/op / 100.00 ns/op | 64 B/op / 64 B/op               |
+| BenchmarkBar | 50.00 ns/op / 100.00 ns/op  | 64 B/op / 64 B/op               |
+
+</details>
+
+<details>
+<summary>Skipped (1)</summary>
+
+|     Name     | Ref | Reason |
+|--------------|-----|--------|
+| BenchmarkBaz | all | flaky  |
+
+</details>
+
+<details open>
+<summary>Failed (1)</summary>
+
+|     Name     | Ref  |    Reason     |
+|--------------|------|---------------|
+| BenchmarkQux | main | exit status 1 |
+
+</details>
+
+<sub>Run ID: run</sub>
+`, out.String())
+	data, err := ioutil.ReadFile(markdownFile)
+	require.NoError(t, err)
+	assert.Equal(t, out.String(), string(data))
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	for _, k := range []string{"GITHUB_REPOSITORY", "GITHUB_SHA", "GITHUB_SERVER_URL"} {
+		defer os.Setenv(k, os.Getenv(k))
+	}
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	os.Setenv("GITHUB_SHA", "abc")
+	os.Setenv("GITHUB_SERVER_URL", "")
+	head := &refResults{name: "HEAD", run: &RunResult{}}
+	flamegraphs := []flamegraphs{{benchmark: "BenchmarkFoo", refs: []string{"HEAD", "main"}, paths: []string{"artifacts/HEAD/BenchmarkFoo.cpu.svg", "artifacts/main/BenchmarkFoo.cpu.svg"}}}
+	sources := []benchmarkSource{{benchmark: "BenchmarkFoo", location: sourceLocation{file: "pkg/foo/foo_test.go", line: 7}}}
+
+	markdown := markdownReport(head, nil, nil, nil, flamegraphs, sources, nil, true, false)
+	assert.Contains(t, markdown, "<summary>Source of regressed benchmarks</summary>")
+	assert.Contains(t, markdown, "| BenchmarkFoo | [pkg/foo/foo_test.go:7](https://github.com/antrea-io/antrea/blob/abc/pkg/foo/foo_test.go#L7) |")
+	assert.Contains(t, markdown, "<summary>Flamegraphs of regressed benchmarks</summary>")
+	assert.Contains(t, markdown, "| BenchmarkFoo | [HEAD](artifacts/HEAD/BenchmarkFoo.cpu.svg) [main](artifacts/main/BenchmarkFoo.cpu.svg) |")
+
+	// outside of GitHub Actions, the location is not a link
+	os.Setenv("GITHUB_SHA", "")
+	markdown = markdownReport(head, nil, nil, nil, nil, sources, nil, true, false)
+	assert.Contains(t, markdown, "| BenchmarkFoo | `pkg/foo/foo_test.go:7` |")
+}
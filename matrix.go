@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// stringsValue is a flag which can be repeated.
+type stringsValue []string
+
+func (s *stringsValue) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsValue) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+var headCandidates stringsValue
+
+func validateHeadCandidates() error {
+	if len(headCandidates) > 0 && applyPatch != "" {
+		return fmt.Errorf("-head cannot be used with -apply-patch")
+	}
+	return nil
+}
+
+// candidateComparison is the comparison of one head candidate with the base.
+type candidateComparison struct {
+	head    *refResults
+	results []result
+}
+
+// compareCandidate computes the ratios of a candidate compared with the base.
+func compareCandidate(head, base *refResults) candidateComparison {
+	c := candidateComparison{head: head}
+	scale, _ := calibrationScale(head.run.Results, base.run.Results)
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" || benchmark.Calibration {
+			continue
+		}
+		headBench, ok := head.run.Results[benchmark.UniqueName]
+		if !ok {
+			continue
+		}
+		baseBench, ok := base.run.Results[benchmark.UniqueName]
+		if !ok {
+			continue
+		}
+		c.results = append(c.results, newResult(benchmark, scaleNsPerOp(headBench, scale), baseBench))
+	}
+	return c
+}
+
+func (c candidateComparison) find(uniqueName string) (result, bool) {
+	for _, r := range c.results {
+		if r.UniqueName == uniqueName {
+			return r, true
+		}
+	}
+	return result{}, false
+}
+
+// showMatrix prints, for each benchmark, the results of the base and of every
+// candidate side by side, along with the change compared with the base.
+func showMatrix(w io.Writer, base *refResults, comparisons []candidateComparison) {
+	fmt.Fprintln(w, "\nCandidates")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 10))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetRowLine(true)
+	table.SetAutoWrapText(false)
+	header := []string{"Name", "Metric", base.name}
+	for _, c := range comparisons {
+		header = append(header, c.head.name)
+	}
+	table.SetHeader(header)
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" {
+			continue
+		}
+		baseBench, ok := base.run.Results[benchmark.UniqueName]
+		if !ok {
+			continue
+		}
+		nsRow := []string{benchmark.UniqueName, "ns/op", numbers.float(baseBench.NsPerOp)}
+		bytesRow := []string{benchmark.UniqueName, "B/op", numbers.uint(baseBench.AllocedBytesPerOp)}
+		nsColors := make([]tablewriter.Colors, 3, 3+len(comparisons))
+		bytesColors := make([]tablewriter.Colors, 3, 3+len(comparisons))
+		for _, c := range comparisons {
+			headBench, ok := c.head.run.Results[benchmark.UniqueName]
+			if !ok {
+				nsRow, bytesRow = append(nsRow, "-"), append(bytesRow, "-")
+				nsColors, bytesColors = append(nsColors, nil), append(bytesColors, nil)
+				continue
+			}
+			nsCell, bytesCell := numbers.float(headBench.NsPerOp), numbers.uint(headBench.AllocedBytesPerOp)
+			var nsColor, bytesColor tablewriter.Colors
+			if r, ok := c.find(benchmark.UniqueName); ok {
+				nsCell += fmt.Sprintf(" (%s)", signedRatio(r.RatioNsPerOp))
+				bytesCell += fmt.Sprintf(" (%s)", signedRatio(r.RatioAllocedBytesPerOp))
+				nsColor, bytesColor = generateColor(r.RatioNsPerOp), generateColor(r.RatioAllocedBytesPerOp)
+			}
+			nsRow, bytesRow = append(nsRow, nsCell), append(bytesRow, bytesCell)
+			nsColors, bytesColors = append(nsColors, nsColor), append(bytesColors, bytesColor)
+		}
+		table.Rich(nsRow, nsColors)
+		table.Rich(bytesRow, bytesColors)
+	}
+	table.Render()
+	fmt.Fprintln(w)
+}
+
+// showCandidateVerdicts prints a summary line for each candidate, and returns
+// the names of the candidates which regressed compared with the base.
+func showCandidateVerdicts(w io.Writer, base *refResults, comparisons []candidateComparison) []string {
+	var regressed []string
+	for _, c := range comparisons {
+		regression := false
+		for _, r := range c.results {
+			regression = regression || isRegression(r)
+		}
+		if totalBudget > 0 {
+			regression = aggregateRatio(c.results) > float64(totalBudget)
+		}
+		regression = applyPolicy(diagnostics, regression, base)
+		verdict, regressions, improvements := resultVerdict(c.results, regression)
+		fmt.Fprintf(w, "%s: aggregate=%s regressions=%d improvements=%d compared=%d verdict=%s\n",
+			c.head.name, signedRatio(aggregateRatio(c.results)), regressions, improvements, len(c.results), verdict)
+		if regression {
+			regressed = append(regressed, c.head.name)
+		}
+	}
+	fmt.Fprintln(w)
+	return regressed
+}
+
+// compareCandidates compares several head candidates with the base, so that
+// alternative approaches can be evaluated in a single run. An error is
+// returned if any candidate makes benchmarks worse.
+func compareCandidates(base *refResults, candidates []*refResults) error {
+	comparisons := make([]candidateComparison, 0, len(candidates))
+	for _, candidate := range candidates {
+		comparisons = append(comparisons, compareCandidate(candidate, base))
+	}
+	showMatrix(report, base, comparisons)
+	showFailed(report, append([]*refResults{base}, candidates...)...)
+	showRunInfo(report)
+	regressed := showCandidateVerdicts(report, base, comparisons)
+	if len(regressed) > 0 {
+		return fmt.Errorf("some candidates make benchmarks worse compared with %s: %s", base.name, strings.Join(regressed, ", "))
+	}
+	return nil
+}
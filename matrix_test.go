@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestStringsValue(t *testing.T) {
+	var s stringsValue
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&s, "head", "")
+	assert.NoError(t, fs.Parse([]string{"-head=featureA", "-head", "featureB"}))
+	assert.Equal(t, stringsValue{"featureA", "featureB"}, s)
+	assert.Equal(t, "featureA,featureB", s.String())
+}
+
+func TestCompareCandidates(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	defer func(r io.Writer) { report = r }(report)
+	contention := false
+	benchmarks.Benchmarks = []Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}},
+	}
+	candidate := func(name string, nsPerOp float64) *refResults {
+		return &refResults{name: name, run: &RunResult{Results: Set{
+			"BenchmarkFoo": &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkFoo-4", NsPerOp: nsPerOp}},
+		}}}
+	}
+	base := candidate("main", 100)
+
+	var b bytes.Buffer
+	report = &b
+	err := compareCandidates(base, []*refResults{candidate("featureA", 80), candidate("featureB", 150)})
+	assert.EqualError(t, err, "some candidates make benchmarks worse compared with main: featureB")
+	assert.Contains(t, b.String(), "80.00 (-20.00%)")
+	assert.Contains(t, b.String(), "150.00 (+50.00%)")
+	assert.Contains(t, b.String(), "featureA: aggregate=-20.00% regressions=0 improvements=1 compared=1 verdict=PASS\n")
+	assert.Contains(t, b.String(), "featureB: aggregate=+50.00% regressions=1 improvements=0 compared=1 verdict=FAIL\n")
+
+	b.Reset()
+	assert.NoError(t, compareCandidates(base, []*refResults{candidate("featureA", 80)}))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strconv"
+)
+
+// gctraceLine matches the heap sizes (at GC start, at GC end, and live heap)
+// in the output of GODEBUG=gctrace=1.
+var gctraceLine = regexp.MustCompile(`^gc \d+ @.* (\d+)->(\d+)->(\d+) MB`)
+
+// gcStats are the garbage collection statistics of a benchmark process. The
+// number of GCs is not kept since it covers all the runs used to calibrate
+// b.N, and thus cannot be compared across refs.
+type gcStats struct {
+	peakHeapBytes float64
+}
+
+// parseGCTrace extracts GC statistics from the output of a benchmark run
+// with GODEBUG=gctrace=1. Heap sizes are only reported in MB by the runtime.
+func parseGCTrace(out []byte) *gcStats {
+	stats := &gcStats{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		m := gctraceLine.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		for _, s := range m[1:3] {
+			mb, _ := strconv.ParseFloat(s, 64)
+			if mb*1024*1024 > stats.peakHeapBytes {
+				stats.peakHeapBytes = mb * 1024 * 1024
+			}
+		}
+	}
+	return stats
+}
+
+func memStatsEnabled() bool {
+	for _, benchmark := range benchmarks.Benchmarks {
+		if *benchmark.MemStats {
+			return true
+		}
+	}
+	return false
+}
+
+// formatBytes formats a size in bytes with a binary unit.
+func formatBytes(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for ; b >= 1024 && i < len(units)-1; i++ {
+		b /= 1024
+	}
+	return strconv.FormatFloat(b, 'f', 1, 64) + " " + units[i]
+}
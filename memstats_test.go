@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGCTrace(t *testing.T) {
+	out := `gc 1 @0.000s 7%: 0.006+0.096+0.009 ms clock, 0.006+0/0.018/0.074+0.009 ms cpu, 0->0->0 MB, 4 MB goal, 0 MB stacks, 0 MB globals, 1 P (forced)
+goos: linux
+BenchmarkAlloc
+gc 2 @0.001s 14%: 0.003+0.11+0.004 ms clock, 0.003+0.097/0/0+0.004 ms cpu, 3->4->3 MB, 4 MB goal, 0 MB stacks, 0 MB globals, 1 P
+gc 3 @0.002s 20%: 0.003+0.17+0 ms clock, 0.003+0.16/0/0+0 ms cpu, 7->7->7 MB, 7 MB goal, 0 MB stacks, 0 MB globals, 1 P
+BenchmarkAlloc 	14753047	       113.7 ns/op
+PASS
+`
+	assert.Equal(t, &gcStats{peakHeapBytes: 7 * 1024 * 1024}, parseGCTrace([]byte(out)))
+	assert.Equal(t, &gcStats{}, parseGCTrace([]byte("PASS\n")))
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512.0 B", formatBytes(512))
+	assert.Equal(t, "7.0 MiB", formatBytes(7*1024*1024))
+	assert.Equal(t, "2048.0 GiB", formatBytes(2048*1024*1024*1024))
+}
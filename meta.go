@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// metaKeyPattern restricts keys to names which are valid Prometheus labels,
+// Datadog tags and CSV columns.
+var metaKeyPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedMetaKeys are the labels, tags and columns set by benchci itself in
+// the textfile, Datadog and CSV outputs, which metadata must not override.
+var reservedMetaKeys = map[string]bool{
+	"benchmark": true,
+	"ref":       true,
+	"base":      true,
+	"metric":    true,
+	"run_id":    true,
+	"repo":      true,
+	"commit":    true,
+}
+
+// metaValue is a repeatable key=value flag.
+type metaValue map[string]string
+
+func (m metaValue) String() string {
+	return strings.Join(m.pairs(), ",")
+}
+
+func (m metaValue) Set(v string) error {
+	parts := strings.SplitN(v, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("invalid metadata '%s': must be key=value", v)
+	}
+	if !metaKeyPattern.MatchString(parts[0]) {
+		return fmt.Errorf("invalid metadata key '%s': must only contain letters, digits and underscores", parts[0])
+	}
+	if reservedMetaKeys[parts[0]] {
+		return fmt.Errorf("invalid metadata key '%s': reserved for the labels of benchci", parts[0])
+	}
+	if _, ok := m[parts[0]]; ok {
+		return fmt.Errorf("duplicate metadata key '%s'", parts[0])
+	}
+	m[parts[0]] = parts[1]
+	return nil
+}
+
+// pairs returns the key=value pairs, sorted by key.
+func (m metaValue) pairs() []string {
+	pairs := make([]string, 0, len(m))
+	for _, k := range m.keys() {
+		pairs = append(pairs, k+"="+m[k])
+	}
+	return pairs
+}
+
+func (m metaValue) keys() []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// runMeta is attached to all the outputs of the run, so that external
+// orchestrators can slice stored results, e.g. by runner pool or PR number.
+var runMeta = metaValue{}
+
+// runMetadata returns the metadata of the run to embed in JSON outputs, nil
+// if there is none so that it is omitted.
+func runMetadata() map[string]string {
+	if len(runMeta) == 0 {
+		return nil
+	}
+	return runMeta
+}
+
+// showRunInfo prints the run ID and the metadata of the run.
+func showRunInfo(w io.Writer) {
+	fmt.Fprintf(w, "Run ID: %s\n", runID)
+	if len(runMeta) > 0 {
+		fmt.Fprintf(w, "Metadata: %s\n", strings.Join(runMeta.pairs(), ", "))
+	}
+	fmt.Fprintln(w)
+}
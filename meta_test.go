@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestMetaValue(t *testing.T) {
+	m := metaValue{}
+	require.NoError(t, m.Set("pr=1234"))
+	require.NoError(t, m.Set("pool=bare-metal=large"))
+	require.NoError(t, m.Set("trigger="))
+	assert.Equal(t, metaValue{"pr": "1234", "pool": "bare-metal=large", "trigger": ""}, m)
+	assert.Equal(t, "pool=bare-metal=large,pr=1234,trigger=", m.String())
+
+	assert.EqualError(t, m.Set("pr=1235"), "duplicate metadata key 'pr'")
+	assert.EqualError(t, m.Set("pr"), "invalid metadata 'pr': must be key=value")
+	assert.EqualError(t, m.Set("runner-pool=a"), "invalid metadata key 'runner-pool': must only contain letters, digits and underscores")
+	assert.Error(t, m.Set("1pr=a"))
+	assert.EqualError(t, m.Set("benchmark=a"), "invalid metadata key 'benchmark': reserved for the labels of benchci")
+	assert.EqualError(t, m.Set("run_id=a"), "invalid metadata key 'run_id': reserved for the labels of benchci")
+}
+
+func TestRunMetadata(t *testing.T) {
+	defer func(id string, m metaValue) { runID, runMeta = id, m }(runID, runMeta)
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	runID = "run"
+	runMeta = metaValue{}
+
+	var buf bytes.Buffer
+	showRunInfo(&buf)
+	assert.Equal(t, "Run ID: run\n\n", buf.String())
+	assert.Nil(t, runMetadata())
+
+	runMeta = metaValue{"pr": "1234", "pool": "large"}
+	buf.Reset()
+	showRunInfo(&buf)
+	assert.Equal(t, "Run ID: run\nMetadata: pool=large, pr=1234\n\n", buf.String())
+
+	data, err := encodeBaseline(&baseline{})
+	require.NoError(t, err)
+	var b baseline
+	require.NoError(t, json.Unmarshal(data, &b))
+	assert.Equal(t, map[string]string{"pr": "1234", "pool": "large"}, b.Meta)
+
+	head := &refResults{name: "HEAD", run: &RunResult{Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{NsPerOp: 125}},
+	}}}
+	assert.Equal(t, map[string]string{"pr": "1234", "pool": "large"}, newAuditEntry(head, nil, nil).Meta)
+
+	metrics := textfileMetrics(head, nil, false, time.Unix(1634169600, 0))
+	assert.Contains(t, metrics, "benchci_ns_per_op{benchmark=\"BenchmarkFoo\",ref=\"HEAD\",pool=\"large\",pr=\"1234\"} 125\n")
+	assert.Contains(t, metrics, "benchci_failed{pool=\"large\",pr=\"1234\"} 0\n")
+
+	series, _ := datadogMetrics(head, nil, time.Unix(1634169600, 0))
+	assert.Equal(t, []string{"repo:antrea-io/antrea", "pool:large", "pr:1234", "ref:HEAD", "benchmark:BenchmarkFoo"}, series[0].Tags)
+
+	data, err = exportCSV(head)
+	require.NoError(t, err)
+	assert.Equal(t, "benchmark,ref,commit,ns/op,B/op,allocs/op,MB/s,pool,pr\nBenchmarkFoo,HEAD,,125,0,0,0,large,1234", strings.TrimSpace(string(data)))
+}
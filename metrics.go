@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	lowerIsBetter  = "lower-better"
+	higherIsBetter = "higher-better"
+)
+
+// builtinUnits are the metrics parsed by golang.org/x/tools/benchmark/parse,
+// the other units are custom metrics reported with b.ReportMetric.
+var builtinUnits = map[string]bool{"ns/op": true, "B/op": true, "allocs/op": true, "MB/s": true}
+
+// parseCustomMetrics returns the custom metrics of each benchmark line of the
+// output of "go test -bench", by benchmark name, with one map per sample.
+func parseCustomMetrics(out []byte) map[string][]map[string]float64 {
+	metrics := map[string][]map[string]float64{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+		sample := map[string]float64{}
+		for i := 2; i+1 < len(fields); i += 2 {
+			if builtinUnits[fields[i+1]] {
+				continue
+			}
+			if v, err := strconv.ParseFloat(fields[i], 64); err == nil {
+				sample[fields[i+1]] = v
+			}
+		}
+		if len(sample) > 0 {
+			metrics[fields[0]] = append(metrics[fields[0]], sample)
+		}
+	}
+	return metrics
+}
+
+// medianMetrics combines the custom metrics of several samples, like
+// combineSamples does for the builtin metrics.
+func medianMetrics(samples []map[string]float64) map[string]float64 {
+	if len(samples) == 0 {
+		return nil
+	}
+	values := map[string][]float64{}
+	for _, sample := range samples {
+		for unit, v := range sample {
+			values[unit] = append(values[unit], v)
+		}
+	}
+	combined := map[string]float64{}
+	for unit, vs := range values {
+		combined[unit] = median(vs)
+	}
+	return combined
+}
+
+func validateMetricDirections() error {
+	for unit, direction := range benchmarks.MetricDirections {
+		if direction != lowerIsBetter && direction != higherIsBetter {
+			return fmt.Errorf("invalid direction '%s' for metric '%s': must be %s or %s", direction, unit, lowerIsBetter, higherIsBetter)
+		}
+	}
+	return nil
+}
+
+// higherBetter returns true if an increase of the metric is an improvement.
+// Lower is better for metrics which are not configured.
+func higherBetter(unit string) bool {
+	return benchmarks.MetricDirections[unit] == higherIsBetter
+}
+
+// worsening converts the relative change of a metric to a ratio which is
+// positive when the metric got worse, e.g. 0.25 when ns/op increased by 25%,
+// or when requests/s decreased by 20%, so that it can be compared with the
+// threshold and colored like the builtin metrics.
+func worsening(unit string, ratio float64) float64 {
+	if !higherBetter(unit) {
+		return ratio
+	}
+	if ratio <= -1 {
+		return 0
+	}
+	return 1/(1+ratio) - 1
+}
+
+// metricThreshold returns the threshold of a custom metric for a result.
+func metricThreshold(r result, unit string) float64 {
+	if threshold, ok := r.MetricThresholds[unit]; ok {
+		return threshold
+	}
+	return r.Threshold
+}
+
+// customRatios returns the relative change of each custom metric between the
+// base and the head results of a benchmark.
+func customRatios(units []string, headBench, baseBench *benchResult) map[string]float64 {
+	var ratios map[string]float64
+	for _, unit := range units {
+		head, headOK := headBench.Metrics[unit]
+		base, baseOK := baseBench.Metrics[unit]
+		if !headOK || !baseOK || base == 0 {
+			continue
+		}
+		if ratios == nil {
+			ratios = map[string]float64{}
+		}
+		ratios[unit] = (head - base) / base
+	}
+	return ratios
+}
+
+// comparedCustomUnits returns the custom metrics compared for any of the
+// results, sorted.
+func comparedCustomUnits(results []result) []string {
+	seen := map[string]bool{}
+	var units []string
+	for _, result := range results {
+		for _, unit := range whichScoreToCompare(result.Compare).custom {
+			if !seen[unit] {
+				seen[unit] = true
+				units = append(units, unit)
+			}
+		}
+	}
+	sort.Strings(units)
+	return units
+}
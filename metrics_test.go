@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+const customMetricsOutput = `goos: linux
+BenchmarkServe-4   	    1000	   1000000 ns/op	      1200 requests/s	    900000 p99-ns	     128 B/op	       2 allocs/op
+BenchmarkServe-4   	    1000	   1100000 ns/op	      1000 requests/s	    950000 p99-ns	     128 B/op	       2 allocs/op
+BenchmarkServe-4   	    1000	   1050000 ns/op	      1100 requests/s	   1000000 p99-ns	     128 B/op	       2 allocs/op
+BenchmarkPlain-4   	    1000	      1000 ns/op
+PASS
+`
+
+func TestParseCustomMetrics(t *testing.T) {
+	metrics := parseCustomMetrics([]byte(customMetricsOutput))
+	assert.Len(t, metrics, 1)
+	require.Len(t, metrics["BenchmarkServe-4"], 3)
+	assert.Equal(t, map[string]float64{"requests/s": 1200, "p99-ns": 900000}, metrics["BenchmarkServe-4"][0])
+	assert.Equal(t, map[string]float64{"requests/s": 1100, "p99-ns": 950000}, medianMetrics(metrics["BenchmarkServe-4"]))
+	assert.Nil(t, medianMetrics(nil))
+}
+
+func TestReadResultsCustomMetrics(t *testing.T) {
+	set, err := readResults(strings.NewReader(customMetricsOutput))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"requests/s": 1100, "p99-ns": 950000}, set["BenchmarkServe-4"].Metrics)
+	assert.Nil(t, set["BenchmarkPlain-4"].Metrics)
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	var decoded Set
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, set["BenchmarkServe-4"].Metrics, decoded["BenchmarkServe-4"].Metrics)
+}
+
+func TestMetricDirections(t *testing.T) {
+	defer func(d map[string]string) { benchmarks.MetricDirections = d }(benchmarks.MetricDirections)
+	benchmarks.MetricDirections = map[string]string{"requests/s": higherIsBetter, "p99-ns": lowerIsBetter}
+	require.NoError(t, validateMetricDirections())
+
+	contention, memStats := false, false
+	benchmark := Benchmark{UniqueName: "BenchmarkServe", BenchmarkConfiguration: BenchmarkConfiguration{
+		Threshold: 0.1, Compare: "requests/s,p99-ns", Contention: &contention, MemStats: &memStats,
+	}}
+	bench := func(requests, p99 float64) *benchResult {
+		return &benchResult{Benchmark: &parse.Benchmark{NsPerOp: 100}, Metrics: map[string]float64{"requests/s": requests, "p99-ns": p99}}
+	}
+	for _, tc := range []struct {
+		name        string
+		head        *benchResult
+		regression  bool
+		improvement bool
+	}{
+		{"fewer requests", bench(800, 1000), true, false},
+		{"more requests", bench(1200, 1000), false, true},
+		{"higher latency", bench(1000, 1200), true, false},
+		{"lower latency", bench(1000, 800), false, true},
+		{"unchanged", bench(1050, 1050), false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newResult(benchmark, tc.head, bench(1000, 1000))
+			assert.Equal(t, tc.regression, isRegression(r))
+			assert.Equal(t, tc.improvement, isImprovement(r))
+		})
+	}
+
+	r := newResult(benchmark, bench(800, 1000), bench(1000, 1000))
+	assert.Equal(t, "requests/s -20.00%, p99-ns +0.00%", regressionSummary(r))
+	var b bytes.Buffer
+	assert.True(t, showRatio(&b, []result{r}, false, "main"))
+	assert.Contains(t, b.String(), "requests/s")
+	assert.Contains(t, b.String(), "20.00%")
+
+	benchmarks.MetricDirections = map[string]string{"requests/s": "up"}
+	assert.Error(t, validateMetricDirections())
+}
+
+func TestMetricThresholds(t *testing.T) {
+	global := BenchmarkConfiguration{MetricThresholds: map[string]float64{"p99-ns": 0.3, "flows/op": 0.05}}
+	c := BenchmarkConfiguration{MetricThresholds: map[string]float64{"p99-ns": 0.5}}
+	c.applyDefaults(&global)
+	assert.Equal(t, map[string]float64{"p99-ns": 0.5, "flows/op": 0.05}, c.MetricThresholds)
+	assert.Equal(t, map[string]float64{"p99-ns": 0.3, "flows/op": 0.05}, global.MetricThresholds)
+
+	contention, memStats := false, false
+	c.Threshold, c.Compare, c.Contention, c.MemStats = 0.1, "p99-ns,flows/op,requests/s", &contention, &memStats
+	benchmark := Benchmark{UniqueName: "BenchmarkServe", BenchmarkConfiguration: c}
+	bench := func(p99, flows, requests float64) *benchResult {
+		return &benchResult{Benchmark: &parse.Benchmark{NsPerOp: 100}, Metrics: map[string]float64{"p99-ns": p99, "flows/op": flows, "requests/s": requests}}
+	}
+	base := bench(1000, 100, 1000)
+	assert.False(t, isRegression(newResult(benchmark, bench(1400, 100, 1000), base)))
+	assert.True(t, isRegression(newResult(benchmark, bench(1600, 100, 1000), base)))
+	assert.True(t, isRegression(newResult(benchmark, bench(1000, 106, 1000), base)))
+	assert.True(t, isImprovement(newResult(benchmark, bench(1000, 94, 1000), base)))
+	// the threshold of the benchmark is used for the other metrics
+	assert.True(t, isRegression(newResult(benchmark, bench(1000, 100, 1200), base)))
+}
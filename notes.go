@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// benchmarkNote returns what a benchmark measures and where to learn more
+// about it, e.g. "Time to compute the span of a group (https://...)", or an
+// empty string if it has neither a description nor a link.
+func benchmarkNote(benchmark Benchmark) string {
+	switch {
+	case benchmark.Description != "" && benchmark.Link != "":
+		return fmt.Sprintf("%s (%s)", benchmark.Description, benchmark.Link)
+	case benchmark.Link != "":
+		return benchmark.Link
+	default:
+		return benchmark.Description
+	}
+}
+
+// markdownName returns the name of a benchmark for a Markdown table, linked to
+// its link if any, with its description as a tooltip.
+func markdownName(benchmark Benchmark) string {
+	if benchmark.Link == "" {
+		return benchmark.UniqueName
+	}
+	if benchmark.Description == "" {
+		return fmt.Sprintf("[%s](%s)", benchmark.UniqueName, benchmark.Link)
+	}
+	return fmt.Sprintf("[%s](%s %q)", benchmark.UniqueName, benchmark.Link, benchmark.Description)
+}
+
+// regressionNotes returns the notes of the regressed benchmarks, by name, so
+// that readers of the report know what a regression means.
+func regressionNotes(results []result) [][2]string {
+	var notes [][2]string
+	for _, r := range results {
+		if note := benchmarkNote(r.Benchmark); note != "" && isRegression(r) {
+			notes = append(notes, [2]string{r.Name, note})
+		}
+	}
+	return notes
+}
+
+// showNotes writes the notes of the regressed benchmarks after a ratio table.
+func showNotes(w io.Writer, results []result) {
+	notes := regressionNotes(results)
+	if len(notes) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Notes:")
+	for _, note := range notes {
+		fmt.Fprintf(w, "  %s: %s\n", note[0], note[1])
+	}
+	fmt.Fprintln(w)
+}
+
+// markdownNotes writes the notes of the regressed benchmarks as a Markdown
+// list.
+func markdownNotes(w io.Writer, results []result) {
+	notes := regressionNotes(results)
+	if len(notes) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nNotes:")
+	for _, note := range notes {
+		fmt.Fprintf(w, "- **%s**: %s\n", markdownEscaper.Replace(note[0]), markdownEscaper.Replace(strings.TrimSpace(note[1])))
+	}
+}
+
+// regressionOwners returns the owners of the regressed benchmarks, sorted and
+// without duplicates. An owner may list several handles separated by spaces.
+func regressionOwners(results []result) []string {
+	seen := map[string]bool{}
+	var owners []string
+	for _, r := range results {
+		if r.Owner == "" || !isRegression(r) {
+			continue
+		}
+		for _, owner := range strings.Fields(r.Owner) {
+			if !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+	}
+	sort.Strings(owners)
+	return owners
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownName(t *testing.T) {
+	benchmark := Benchmark{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo"}
+	assert.Equal(t, "BenchmarkFoo", markdownName(benchmark))
+	benchmark.Description = `Time to compute the "span" of a group`
+	assert.Equal(t, "BenchmarkFoo", markdownName(benchmark))
+	benchmark.Link = "https://example.com/foo"
+	assert.Equal(t, `[BenchmarkFoo](https://example.com/foo "Time to compute the \"span\" of a group")`, markdownName(benchmark))
+	benchmark.Description = ""
+	assert.Equal(t, "[BenchmarkFoo](https://example.com/foo)", markdownName(benchmark))
+}
+
+func TestRegressionNotes(t *testing.T) {
+	disabled := false
+	newResult := func(name string, ratio float64, description, link string) result {
+		return result{
+			Benchmark: Benchmark{Name: name, UniqueName: name, Description: description, Link: link,
+				BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &disabled, MemStats: &disabled}},
+			RatioNsPerOp: ratio,
+			PNsPerOp:     math.NaN(),
+		}
+	}
+	results := []result{
+		newResult("BenchmarkFoo", 0.25, "Time to compute the span of a group", "https://example.com/foo"),
+		newResult("BenchmarkBar", 0.25, "", ""),
+		newResult("BenchmarkBaz", 0.01, "Unchanged", ""),
+		newResult("BenchmarkQux", 0.5, "", "https://example.com/qux"),
+	}
+	var b bytes.Buffer
+	showNotes(&b, results)
+	assert.Equal(t, `Notes:
+  BenchmarkFoo: Time to compute the span of a group (https://example.com/foo)
+  BenchmarkQux: https://example.com/qux
+
+`, b.String())
+
+	b.Reset()
+	markdownNotes(&b, results)
+	assert.Equal(t, `
+Notes:
+- **BenchmarkFoo**: Time to compute the span of a group (https://example.com/foo)
+- **BenchmarkQux**: https://example.com/qux
+`, b.String())
+
+	b.Reset()
+	showNotes(&b, results[1:3])
+	assert.Empty(t, b.String())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numaExec returns the command used to bind the test binary to the CPUs and
+// the memory of a NUMA node, or an empty string if node is nil.
+func numaExec(node *int) string {
+	if node == nil {
+		return ""
+	}
+	return fmt.Sprintf("numactl --cpunodebind=%d --membind=%d", *node, *node)
+}
+
+// numaNodes returns the NUMA nodes of the machine, as listed in sysfs.
+func numaNodes(sysfs string) []int {
+	paths, _ := filepath.Glob(filepath.Join(sysfs, "devices/system/node/node[0-9]*"))
+	var nodes []int
+	for _, path := range paths {
+		if node, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(path), "node")); err == nil {
+			nodes = append(nodes, node)
+		}
+	}
+	sort.Ints(nodes)
+	return nodes
+}
+
+func checkNUMA() doctorCheck {
+	c := doctorCheck{name: "NUMA placement"}
+	used := map[int]bool{}
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.NumaNode != nil && benchmark.Skip == "" {
+			used[*benchmark.NumaNode] = true
+		}
+	}
+	if len(used) == 0 {
+		c.status, c.detail = checkPass, "not used"
+		return c
+	}
+	if runtime.GOOS != "linux" {
+		c.status, c.detail = checkFail, fmt.Sprintf("not supported on %s", runtime.GOOS)
+		return c
+	}
+	if _, err := exec.LookPath("numactl"); err != nil {
+		c.status, c.detail = checkFail, "numactl is not installed"
+		return c
+	}
+	nodes := numaNodes("/sys")
+	available := map[int]bool{}
+	for _, node := range nodes {
+		available[node] = true
+	}
+	var missing []string
+	for node := range used {
+		if !available[node] {
+			missing = append(missing, strconv.Itoa(node))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		c.status, c.detail = checkFail, fmt.Sprintf("node %s not found, the machine has %d nodes", strings.Join(missing, ", "), len(nodes))
+		return c
+	}
+	c.status, c.detail = checkPass, fmt.Sprintf("%d nodes", len(nodes))
+	return c
+}
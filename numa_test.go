@@ -0,0 +1,28 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumaExec(t *testing.T) {
+	node := 0
+	assert.Equal(t, "", numaExec(nil))
+	assert.Equal(t, "numactl --cpunodebind=0 --membind=0", numaExec(&node))
+}
+
+func TestNumaNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"node1", "node0", "node10", "possible"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "devices/system/node", name), 0755))
+	}
+	assert.Equal(t, []int{0, 1, 10}, numaNodes(dir))
+	assert.Empty(t, numaNodes(filepath.Join(dir, "missing")))
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	ociScheme = "oci://"
+
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociEmptyMediaType    = "application/vnd.oci.empty.v1+json"
+
+	baselineArtifactType = "application/vnd.antrea.benchci.baseline.v1+json"
+	reportArtifactType   = "application/vnd.antrea.benchci.report.v1"
+	reportMediaType      = "text/plain"
+)
+
+// ociEmptyConfig is the config of artifacts, as recommended by the OCI image
+// spec for artifacts with no configuration.
+var ociEmptyConfig = []byte("{}")
+
+var ociReferencePattern = regexp.MustCompile(`^oci://([^/]+)/([a-z0-9._/-]+?)(?::([\w][\w.-]{0,127}))?$`)
+
+// ociReference is the location of an artifact in a container registry, e.g.
+// oci://ghcr.io/antrea-io/benchci-baselines:main.
+type ociReference struct {
+	registry   string
+	repository string
+	tag        string
+}
+
+func isOCIReference(s string) bool {
+	return strings.HasPrefix(s, ociScheme)
+}
+
+func parseOCIReference(s string) (ociReference, error) {
+	m := ociReferencePattern.FindStringSubmatch(s)
+	if m == nil {
+		return ociReference{}, fmt.Errorf("invalid OCI reference '%s': must be oci://<registry>/<repository>[:<tag>]", s)
+	}
+	ref := ociReference{registry: m[1], repository: m[2], tag: m[3]}
+	if ref.tag == "" {
+		ref.tag = "latest"
+	}
+	return ref, nil
+}
+
+func (r ociReference) String() string {
+	return fmt.Sprintf("%s%s/%s:%s", ociScheme, r.registry, r.repository, r.tag)
+}
+
+// url returns the URL of a path of the repository in the registry API. Plain
+// HTTP is used for registries running on the local machine, as ORAS does with
+// --plain-http.
+func (r ociReference) url(path string) string {
+	scheme := "https"
+	host := strings.Split(r.registry, ":")[0]
+	if host == "localhost" || host == "127.0.0.1" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, r.registry, r.repository, path)
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+func ociDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// registryCredentials returns the credentials for a registry, from the
+// BENCHCI_REGISTRY_USERNAME and BENCHCI_REGISTRY_PASSWORD environment
+// variables or else from the Docker configuration, so that a prior
+// "docker login" is enough.
+func registryCredentials(registry string) (string, string) {
+	if username := os.Getenv("BENCHCI_REGISTRY_USERNAME"); username != "" {
+		return username, os.Getenv("BENCHCI_REGISTRY_PASSWORD")
+	}
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return "", ""
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return "", ""
+	}
+	auth, err := base64.StdEncoding.DecodeString(config.Auths[registry].Auth)
+	if err != nil {
+		return "", ""
+	}
+	parts := strings.SplitN(string(auth), ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ociClient sends requests to a registry, authenticating with the token
+// flow of the distribution spec when the registry requires it.
+type ociClient struct {
+	ref           ociReference
+	client        *http.Client
+	authorization string
+}
+
+func newOCIClient(ref ociReference) *ociClient {
+	return &ociClient{ref: ref, client: &http.Client{Timeout: 60 * time.Second}}
+}
+
+// authorize sets the authorization used for the next requests from the
+// challenge of a 401 response.
+func (c *ociClient) authorize(challenge string) error {
+	username, password := registryCredentials(c.ref.registry)
+	if strings.HasPrefix(challenge, "Basic") {
+		if username == "" {
+			return fmt.Errorf("no credentials for registry %s", c.ref.registry)
+		}
+		c.authorization = "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+		return nil
+	}
+	if !strings.HasPrefix(challenge, "Bearer") {
+		return fmt.Errorf("unsupported authentication challenge '%s'", challenge)
+	}
+	params := map[string]string{}
+	for _, m := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	if params["realm"] == "" {
+		return fmt.Errorf("no realm in authentication challenge '%s'", challenge)
+	}
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	if params["scope"] != "" {
+		query.Set("scope", params["scope"])
+	}
+	req, err := http.NewRequest(http.MethodGet, params["realm"]+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to get a registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to get a registry token: %s", resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("unable to decode the registry token: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	c.authorization = "Bearer " + token.Token
+	return nil
+}
+
+// do sends a request to the registry and returns the response if its status
+// is one of the expected ones, and an error otherwise, in which case the body
+// is closed.
+func (c *ociClient) do(method, url string, header http.Header, body []byte, expected ...int) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("%s request to %s failed: %w", method, url, err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			resp.Body.Close()
+			if err := c.authorize(resp.Header.Get("WWW-Authenticate")); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, status := range expected {
+			if resp.StatusCode == status {
+				return resp, nil
+			}
+		}
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s request to %s failed: %s", method, url, resp.Status)
+	}
+}
+
+// pushBlob uploads a blob to the repository, unless it is already there.
+func (c *ociClient) pushBlob(mediaType string, data []byte) (ociDescriptor, error) {
+	desc := ociDescriptor{MediaType: mediaType, Digest: ociDigest(data), Size: len(data)}
+	if resp, err := c.do(http.MethodHead, c.ref.url("blobs/"+desc.Digest), nil, nil, http.StatusOK); err == nil {
+		resp.Body.Close()
+		return desc, nil
+	}
+	resp, err := c.do(http.MethodPost, c.ref.url("blobs/uploads/"), nil, nil, http.StatusAccepted)
+	if err != nil {
+		return desc, err
+	}
+	resp.Body.Close()
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return desc, fmt.Errorf("invalid upload location: %w", err)
+	}
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+	header := http.Header{"Content-Type": []string{"application/octet-stream"}}
+	if resp, err = c.do(http.MethodPut, location.String(), header, data, http.StatusCreated); err != nil {
+		return desc, err
+	}
+	resp.Body.Close()
+	return desc, nil
+}
+
+// pushOCIArtifact pushes data as a single-layer artifact and returns the
+// digest of its manifest.
+func pushOCIArtifact(reference, artifactType, mediaType, title string, data []byte) (string, error) {
+	ref, err := parseOCIReference(reference)
+	if err != nil {
+		return "", err
+	}
+	c := newOCIClient(ref)
+	config, err := c.pushBlob(ociEmptyMediaType, ociEmptyConfig)
+	if err != nil {
+		return "", fmt.Errorf("unable to push the artifact config: %w", err)
+	}
+	layer, err := c.pushBlob(mediaType, data)
+	if err != nil {
+		return "", fmt.Errorf("unable to push the artifact: %w", err)
+	}
+	layer.Annotations = map[string]string{"org.opencontainers.image.title": title}
+	manifest, err := json.Marshal(ociManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifestMediaType,
+		ArtifactType:  artifactType,
+		Config:        config,
+		Layers:        []ociDescriptor{layer},
+		Annotations:   map[string]string{"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339)},
+	})
+	if err != nil {
+		return "", err
+	}
+	header := http.Header{"Content-Type": []string{ociManifestMediaType}}
+	resp, err := c.do(http.MethodPut, ref.url("manifests/"+ref.tag), header, manifest, http.StatusCreated)
+	if err != nil {
+		return "", fmt.Errorf("unable to push the artifact manifest: %w", err)
+	}
+	resp.Body.Close()
+	return ociDigest(manifest), nil
+}
+
+// pullOCIArtifact returns the first layer of an artifact with the given media
+// type.
+func pullOCIArtifact(reference, mediaType string) ([]byte, error) {
+	ref, err := parseOCIReference(reference)
+	if err != nil {
+		return nil, err
+	}
+	c := newOCIClient(ref)
+	header := http.Header{"Accept": []string{ociManifestMediaType}}
+	resp, err := c.do(http.MethodGet, ref.url("manifests/"+ref.tag), header, nil, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("unable to pull the artifact manifest: %w", err)
+	}
+	defer resp.Body.Close()
+	var manifest ociManifest
+	if err := json.NewDecoder(resp.Body).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("unable to decode the artifact manifest: %w", err)
+	}
+	for _, layer := range manifest.Layers {
+		if layer.MediaType != mediaType {
+			continue
+		}
+		resp, err := c.do(http.MethodGet, ref.url("blobs/"+layer.Digest), nil, nil, http.StatusOK)
+		if err != nil {
+			return nil, fmt.Errorf("unable to pull the artifact: %w", err)
+		}
+		defer resp.Body.Close()
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to pull the artifact: %w", err)
+		}
+		if digest := ociDigest(data); digest != layer.Digest {
+			return nil, fmt.Errorf("digest mismatch for %s: got %s, expected %s", reference, digest, layer.Digest)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("no layer with media type %s in %s", mediaType, reference)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// fakeRegistry is a minimal OCI registry, which requires a bearer token.
+func fakeRegistry(t *testing.T) (*httptest.Server, map[string][]byte) {
+	blobs := map[string][]byte{}
+	manifests := map[string][]byte{}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			username, password, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "bot:secret", username+":"+password)
+			assert.Equal(t, "repository:antrea/benchci:pull,push", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token": "t0k3n"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:antrea/benchci:pull,push"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := strings.TrimPrefix(r.URL.Path, "/v2/antrea/benchci/")
+		switch {
+		case r.Method == http.MethodPost && path == "blobs/uploads/":
+			w.Header().Set("Location", "/v2/antrea/benchci/blobs/uploads/1?state=x")
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodPut && path == "blobs/uploads/1":
+			assert.Equal(t, "x", r.URL.Query().Get("state"))
+			data, _ := ioutil.ReadAll(r.Body)
+			blobs[r.URL.Query().Get("digest")] = data
+			w.WriteHeader(http.StatusCreated)
+		case strings.HasPrefix(path, "blobs/sha256:"):
+			data, ok := blobs[strings.TrimPrefix(path, "blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		case r.Method == http.MethodPut && strings.HasPrefix(path, "manifests/"):
+			assert.Equal(t, ociManifestMediaType, r.Header.Get("Content-Type"))
+			data, _ := ioutil.ReadAll(r.Body)
+			manifests[strings.TrimPrefix(path, "manifests/")] = data
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "manifests/"):
+			data, ok := manifests[strings.TrimPrefix(path, "manifests/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", ociManifestMediaType)
+			w.Write(data)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server, manifests
+}
+
+func TestParseOCIReference(t *testing.T) {
+	ref, err := parseOCIReference("oci://ghcr.io/antrea-io/benchci-baselines:main")
+	require.NoError(t, err)
+	assert.Equal(t, ociReference{registry: "ghcr.io", repository: "antrea-io/benchci-baselines", tag: "main"}, ref)
+	assert.Equal(t, "https://ghcr.io/v2/antrea-io/benchci-baselines/manifests/main", ref.url("manifests/main"))
+
+	ref, err = parseOCIReference("oci://localhost:5000/benchci")
+	require.NoError(t, err)
+	assert.Equal(t, ociReference{registry: "localhost:5000", repository: "benchci", tag: "latest"}, ref)
+	assert.Equal(t, "http://localhost:5000/v2/benchci/blobs/uploads/", ref.url("blobs/uploads/"))
+
+	for _, s := range []string{"oci://ghcr.io", "oci://ghcr.io/Antrea:main", "oci://ghcr.io/antrea:-main", "ghcr.io/antrea:main"} {
+		_, err := parseOCIReference(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"auths": {"ghcr.io": {"auth": "Ym90OnNlY3JldA=="}}}`), 0600))
+	defer os.Setenv("DOCKER_CONFIG", os.Getenv("DOCKER_CONFIG"))
+	os.Setenv("DOCKER_CONFIG", dir)
+
+	username, password := registryCredentials("ghcr.io")
+	assert.Equal(t, "bot:secret", username+":"+password)
+	username, _ = registryCredentials("quay.io")
+	assert.Empty(t, username)
+
+	os.Setenv("BENCHCI_REGISTRY_USERNAME", "ci")
+	os.Setenv("BENCHCI_REGISTRY_PASSWORD", "pass")
+	defer os.Unsetenv("BENCHCI_REGISTRY_USERNAME")
+	defer os.Unsetenv("BENCHCI_REGISTRY_PASSWORD")
+	username, password = registryCredentials("quay.io")
+	assert.Equal(t, "ci:pass", username+":"+password)
+}
+
+func TestOCIBaseline(t *testing.T) {
+	os.Setenv("BENCHCI_REGISTRY_USERNAME", "bot")
+	os.Setenv("BENCHCI_REGISTRY_PASSWORD", "secret")
+	defer os.Unsetenv("BENCHCI_REGISTRY_USERNAME")
+	defer os.Unsetenv("BENCHCI_REGISTRY_PASSWORD")
+	server, manifests := fakeRegistry(t)
+	defer server.Close()
+	reference := "oci://" + strings.TrimPrefix(server.URL, "http://") + "/antrea/benchci:main"
+
+	set := Set{"BenchmarkFoo": {Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", N: 10, NsPerOp: 100}}}
+	require.NoError(t, saveBaseline(reference, &baseline{Results: set}))
+	var manifest ociManifest
+	require.NoError(t, json.Unmarshal(manifests["main"], &manifest))
+	assert.Equal(t, baselineArtifactType, manifest.ArtifactType)
+	assert.Equal(t, ociEmptyMediaType, manifest.Config.MediaType)
+	require.Len(t, manifest.Layers, 1)
+	assert.Equal(t, "baseline.json", manifest.Layers[0].Annotations["org.opencontainers.image.title"])
+
+	b, err := loadBaseline(reference)
+	require.NoError(t, err)
+	assert.Equal(t, currentSchemaVersion, b.SchemaVersion)
+	assert.Equal(t, 100.0, b.Results["BenchmarkFoo"].NsPerOp)
+
+	_, err = loadBaseline(strings.TrimSuffix(reference, ":main") + ":other")
+	assert.Error(t, err)
+	_, err = pullOCIArtifact(reference, reportMediaType)
+	assert.Error(t, err)
+}
+
+func TestOCIReport(t *testing.T) {
+	defer func(upload string, r, d io.Writer) { uploadReport, report, diagnostics = upload, r, d }(uploadReport, report, diagnostics)
+	os.Setenv("BENCHCI_REGISTRY_USERNAME", "bot")
+	os.Setenv("BENCHCI_REGISTRY_PASSWORD", "secret")
+	defer os.Unsetenv("BENCHCI_REGISTRY_USERNAME")
+	defer os.Unsetenv("BENCHCI_REGISTRY_PASSWORD")
+	server, manifests := fakeRegistry(t)
+	defer server.Close()
+
+	var out, diag bytes.Buffer
+	report, diagnostics = &out, &diag
+	uploadReport = "oci://" + strings.TrimPrefix(server.URL, "http://") + "/antrea/benchci:report-1"
+	require.NoError(t, validateUploadReport())
+	require.NoError(t, withUploadedReport(func() error {
+		fmt.Fprint(report, "\x1b[32m-10.00%\x1b[0m\n")
+		return nil
+	}))
+	assert.Equal(t, fmt.Sprintf("Full report: %s (%s)\n", uploadReport, ociDigest(manifests["report-1"])), diag.String())
+	data, err := pullOCIArtifact(uploadReport, reportMediaType)
+	require.NoError(t, err)
+	assert.Equal(t, "-10.00%\n", string(data))
+
+	uploadReport = "oci://ghcr.io"
+	assert.Error(t, validateUploadReport())
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+var offline bool
+
+var errOffline = errors.New("network access is disabled by --offline")
+
+// offlineTransport replaces the default HTTP transport in offline mode, so
+// that no request can be sent even by a feature which was not rejected.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errOffline
+}
+
+// remoteFeatures returns the flags which were set and require network access.
+func remoteFeatures() []string {
+	var features []string
+	if datadogEnabled {
+		features = append(features, "--datadog")
+	}
+	if cloudEventsSink != "" {
+		features = append(features, "--cloudevents-sink")
+	}
+	if auditEndpoint != "" {
+		features = append(features, "--audit-endpoint")
+	}
+	if releaseAsset {
+		features = append(features, "--release-asset")
+	}
+	if fileIssues {
+		features = append(features, "--file-issues")
+	}
+	if uploadReport != "" {
+		features = append(features, "--upload-report")
+	}
+	if githubComment {
+		features = append(features, "--github-comment")
+	}
+	if gitlabNote {
+		features = append(features, "--gitlab-note")
+	}
+	if gitlabStatus {
+		features = append(features, "--gitlab-status")
+	}
+	if isRemoteBaseline(baselinePath) || isOCIReference(baselinePath) {
+		features = append(features, "--baseline")
+	}
+	if isOCIReference(saveBaselinePath) {
+		features = append(features, "--save-baseline")
+	}
+	return features
+}
+
+// setupOffline fails fast if a feature requiring network access is used with
+// --offline, and otherwise disables HTTP requests and module downloads by the
+// go command, including toolchain downloads. Modules and toolchains which are
+// already in the module cache can still be used.
+func setupOffline() error {
+	if !offline {
+		return nil
+	}
+	if features := remoteFeatures(); len(features) > 0 {
+		return fmt.Errorf("--offline is incompatible with %s", strings.Join(features, ", "))
+	}
+	http.DefaultTransport = offlineTransport{}
+	// GOVCS also prevents direct fetches of modules matched by GOPRIVATE or
+	// GONOPROXY, which bypass GOPROXY
+	for k, v := range map[string]string{"GOPROXY": "off", "GOVCS": "*:off", "GOSUMDB": "off"} {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
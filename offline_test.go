@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupOffline(t *testing.T) {
+	defer func(o, d, github, note, status bool, sink, upload, b string) {
+		offline, datadogEnabled, githubComment, gitlabNote, gitlabStatus, cloudEventsSink, uploadReport, baselinePath = o, d, github, note, status, sink, upload, b
+	}(offline, datadogEnabled, githubComment, gitlabNote, gitlabStatus, cloudEventsSink, uploadReport, baselinePath)
+	defer func(transport http.RoundTripper) { http.DefaultTransport = transport }(http.DefaultTransport)
+	for _, k := range []string{"GOPROXY", "GOVCS", "GOSUMDB"} {
+		defer os.Setenv(k, os.Getenv(k))
+	}
+
+	offline = false
+	datadogEnabled = true
+	require.NoError(t, setupOffline())
+
+	offline = true
+	cloudEventsSink = "http://localhost:8080"
+	githubComment, gitlabNote, gitlabStatus = true, true, true
+	baselinePath = "oci://ghcr.io/antrea-io/benchci:main"
+	assert.EqualError(t, setupOffline(), "--offline is incompatible with --datadog, --cloudevents-sink, --github-comment, --gitlab-note, --gitlab-status, --baseline")
+
+	datadogEnabled, githubComment, gitlabNote, gitlabStatus = false, false, false, false
+	cloudEventsSink, uploadReport, baselinePath = "", "", "main.json"
+	require.NoError(t, setupOffline())
+	assert.Equal(t, "off", os.Getenv("GOPROXY"))
+	assert.Equal(t, "*:off", os.Getenv("GOVCS"))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	}))
+	defer server.Close()
+	_, err := http.Get(server.URL)
+	assert.Error(t, err)
+	_, err = fetchBaseline(server.URL, "")
+	assert.Error(t, err)
+}
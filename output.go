@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+const (
+	// outputText renders the report as ASCII tables.
+	outputText = "text"
+	// outputJSON writes the results and the comparisons as a single JSON
+	// document, for post-processing by CI pipelines.
+	outputJSON = "json"
+	// outputMarkdown renders the report as GitHub-flavored Markdown, to be
+	// posted as a pull request comment.
+	outputMarkdown = "markdown"
+)
+
+var outputFormat string
+
+func validateOutputFormat() error {
+	switch outputFormat {
+	case outputText:
+	case outputJSON, outputMarkdown:
+		if len(headCandidates) > 0 {
+			return fmt.Errorf("-output-format %s cannot be used with -head", outputFormat)
+		}
+	default:
+		return fmt.Errorf("unsupported output format '%s', must be one of %s, %s or %s", outputFormat, outputText, outputJSON, outputMarkdown)
+	}
+	return nil
+}
+
+// jsonReport is the report written with -output-format json.
+type jsonReport struct {
+	RunID       string            `json:"runID"`
+	Meta        map[string]string `json:"meta,omitempty"`
+	Refs        []jsonRef         `json:"refs"`
+	Comparisons []jsonComparison  `json:"comparisons"`
+	// Skipped are the reasons why benchmarks were skipped with the skip
+	// field, by unique name.
+	Skipped map[string]string `json:"skipped,omitempty"`
+	// Exclusions are all the benchmarks which were not compared with a ref,
+	// including the skipped ones, with the reason.
+	Exclusions   []exclusion `json:"exclusions,omitempty"`
+	Verdict      string      `json:"verdict"`
+	Regressions  int         `json:"regressions"`
+	Improvements int         `json:"improvements"`
+	Compared     int         `json:"compared"`
+	// Coverage is the number of benchmarks compared with the base ref.
+	Coverage jsonCoverage `json:"coverage"`
+	// CrossMachine is the disclaimer of a comparison with a baseline collected
+	// on a different machine.
+	CrossMachine string `json:"crossMachine,omitempty"`
+	// TrendAlerts are the benchmarks which crept up over the recent history,
+	// they do not fail the run.
+	TrendAlerts []jsonTrendAlert `json:"trendAlerts,omitempty"`
+	Partial     bool             `json:"partial,omitempty"`
+}
+
+type jsonCoverage struct {
+	Compared   int `json:"compared"`
+	Configured int `json:"configured"`
+}
+
+// jsonRef is a benchmarked ref with its results, name is the name used in
+// the comparisons.
+type jsonRef struct {
+	Name string `json:"name"`
+	*RunResult
+}
+
+type jsonComparison struct {
+	Base    string      `json:"base"`
+	Policy  string      `json:"policy,omitempty"`
+	Results []jsonRatio `json:"results"`
+	// Failed is true if the comparison fails the run, after applying the
+	// policy.
+	Failed bool `json:"failed"`
+}
+
+// jsonRatio is a row of the ratio table: the relative change of each metric,
+// e.g. 0.25 for +25%.
+type jsonRatio struct {
+	Benchmark string `json:"benchmark"`
+	// Description and Link document what the benchmark measures.
+	Description string             `json:"description,omitempty"`
+	Link        string             `json:"link,omitempty"`
+	Owner       string             `json:"owner,omitempty"`
+	Threshold   float64            `json:"threshold"`
+	Compare     string             `json:"compare"`
+	Ratios      map[string]float64 `json:"ratios"`
+	// PValues are only set when there are enough samples for the statistical
+	// test.
+	PValues     map[string]float64 `json:"pValues,omitempty"`
+	Regression  bool               `json:"regression"`
+	Improvement bool               `json:"improvement"`
+	// Warning is true if a slowdown exceeds the warnThreshold but not the
+	// threshold.
+	Warning bool `json:"warning,omitempty"`
+}
+
+func newJSONRatio(r result) jsonRatio {
+	ratios := map[string]float64{"ns/op": r.RatioNsPerOp, "B/op": r.RatioAllocedBytesPerOp}
+	comparedScore := whichScoreToCompare(r.Compare)
+	if comparedScore.allocsPerOp {
+		ratios["allocs/op"] = r.RatioAllocsPerOp
+	}
+	if comparedScore.mbPerS {
+		ratios["MB/s"] = r.RatioMBPerS
+	}
+	if r.Contention != nil && *r.Contention {
+		ratios["contention"] = r.RatioContention
+	}
+	if r.MemStats != nil && *r.MemStats {
+		ratios["heap"] = r.RatioPeakHeap
+	}
+	for unit, ratio := range r.RatioMetrics {
+		ratios[unit] = ratio
+	}
+	pValues := map[string]float64{}
+	if !math.IsNaN(r.PNsPerOp) {
+		pValues["ns/op"] = r.PNsPerOp
+	}
+	if !math.IsNaN(r.PAllocedBytesPerOp) {
+		pValues["B/op"] = r.PAllocedBytesPerOp
+	}
+	if comparedScore.allocsPerOp && !math.IsNaN(r.PAllocsPerOp) {
+		pValues["allocs/op"] = r.PAllocsPerOp
+	}
+	if comparedScore.mbPerS && !math.IsNaN(r.PMBPerS) {
+		pValues["MB/s"] = r.PMBPerS
+	}
+	if len(pValues) == 0 {
+		pValues = nil
+	}
+	regression := isRegression(r)
+	return jsonRatio{
+		Benchmark:   r.UniqueName,
+		Description: r.Description,
+		Link:        r.Link,
+		Owner:       r.Owner,
+		Threshold:   r.Threshold,
+		Compare:     r.Compare,
+		Ratios:      ratios,
+		PValues:     pValues,
+		Regression:  regression,
+		Improvement: !regression && isImprovement(r),
+		Warning:     isWarning(r),
+	}
+}
+
+// newJSONReport returns the report of the comparisons of head, with only the
+// regressions if onlyRegression is set.
+func newJSONReport(head *refResults, comparisons []comparison, skipped []exclusion, failed, partial bool) *jsonReport {
+	r := &jsonReport{
+		RunID:   runID,
+		Meta:    runMetadata(),
+		Refs:    []jsonRef{{head.name, head.run}},
+		Partial: partial,
+	}
+	var all []result
+	for _, c := range comparisons {
+		if c.base == nil {
+			continue
+		}
+		r.Refs = append(r.Refs, jsonRef{c.base.name, c.base.run})
+		jc := jsonComparison{Base: c.base.name, Policy: c.base.policy, Results: []jsonRatio{}, Failed: c.failed}
+		for _, result := range c.results {
+			if onlyRegression && !isRegression(result) && !isWarning(result) {
+				continue
+			}
+			jc.Results = append(jc.Results, newJSONRatio(result))
+		}
+		r.Comparisons = append(r.Comparisons, jc)
+		all = append(all, c.results...)
+	}
+	for _, e := range skipped {
+		if e.Kind != exclusionSkip {
+			continue
+		}
+		if r.Skipped == nil {
+			r.Skipped = map[string]string{}
+		}
+		r.Skipped[e.Name] = e.Reason
+	}
+	r.Exclusions = skipped
+	if len(crossMachine) > 0 {
+		r.CrossMachine = strings.Join(crossMachineDisclaimer(), " ")
+	}
+	r.Verdict, r.Regressions, r.Improvements = resultVerdict(all, failed)
+	r.Compared = len(all)
+	return r
+}
+
+func writeJSONReport(w io.Writer, r *jsonReport) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode the JSON report: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestValidateOutputFormat(t *testing.T) {
+	defer func(f string, h stringsValue) { outputFormat, headCandidates = f, h }(outputFormat, headCandidates)
+	for _, f := range []string{outputText, outputJSON} {
+		outputFormat = f
+		assert.NoError(t, validateOutputFormat())
+	}
+	outputFormat = "yaml"
+	assert.Error(t, validateOutputFormat())
+	outputFormat, headCandidates = outputJSON, stringsValue{"feature-a"}
+	assert.EqualError(t, validateOutputFormat(), "-output-format json cannot be used with -head")
+}
+
+func TestNewJSONRatio(t *testing.T) {
+	contention := false
+	r := result{
+		Benchmark:              Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op,B/op", Contention: &contention}},
+		RatioNsPerOp:           0.25,
+		RatioAllocedBytesPerOp: -0.5,
+		RatioMetrics:           map[string]float64{"p99-ns": 0.05},
+		PNsPerOp:               0.01,
+		PAllocedBytesPerOp:     math.NaN(),
+	}
+	assert.Equal(t, jsonRatio{
+		Benchmark:  "BenchmarkFoo",
+		Threshold:  0.1,
+		Compare:    "ns/op,B/op",
+		Ratios:     map[string]float64{"ns/op": 0.25, "B/op": -0.5, "p99-ns": 0.05},
+		PValues:    map[string]float64{"ns/op": 0.01},
+		Regression: true,
+	}, newJSONRatio(r))
+
+	r.RatioNsPerOp, r.PNsPerOp = -0.25, math.NaN()
+	ratio := newJSONRatio(r)
+	assert.True(t, ratio.Improvement)
+	assert.Nil(t, ratio.PValues)
+}
+
+func TestJSONOutput(t *testing.T) {
+	defer func(b BenchmarkList, f, id string, w, d io.Writer) {
+		*benchmarks, outputFormat, runID, report, diagnostics = b, f, id, w, d
+	}(*benchmarks, outputFormat, runID, report, diagnostics)
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo"},
+		{Name: "BenchmarkBar", UniqueName: "BenchmarkBar", Skip: "flaky"},
+	}}
+	require.NoError(t, updateBenchmarks())
+	outputFormat, runID = outputJSON, "run"
+	var out, diag bytes.Buffer
+	report, diagnostics = &out, &diag
+
+	head := &refResults{name: "HEAD", ref: "HEAD", run: &RunResult{Ref: "HEAD", Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", N: 10, NsPerOp: 150, AllocedBytesPerOp: 64}},
+	}}}
+	base := &refResults{name: "main", ref: "main", policy: policyFail, run: &RunResult{Ref: "main", Commit: "abc", Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{Name: "BenchmarkFoo", N: 10, NsPerOp: 100, AllocedBytesPerOp: 64}},
+	}}}
+	assert.EqualError(t, compareResults(head, base, nil), "this commit makes benchmarks worse compared with main")
+
+	var r jsonReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &r), out.String())
+	assert.Equal(t, "run", r.RunID)
+	require.Len(t, r.Refs, 2)
+	assert.Equal(t, "main", r.Refs[1].Name)
+	assert.Equal(t, "abc", r.Refs[1].Commit)
+	assert.Equal(t, 150.0, r.Refs[0].Results["BenchmarkFoo"].NsPerOp)
+	require.Len(t, r.Comparisons, 1)
+	assert.Equal(t, "main", r.Comparisons[0].Base)
+	assert.True(t, r.Comparisons[0].Failed)
+	require.Len(t, r.Comparisons[0].Results, 1)
+	assert.Equal(t, map[string]float64{"ns/op": 0.5, "B/op": 0}, r.Comparisons[0].Results[0].Ratios)
+	assert.True(t, r.Comparisons[0].Results[0].Regression)
+	assert.Equal(t, map[string]string{"BenchmarkBar": "flaky"}, r.Skipped)
+	assert.Equal(t, []exclusion{{Name: "BenchmarkBar", Kind: exclusionSkip, Reason: "flaky"}}, r.Exclusions)
+	assert.Equal(t, "FAIL", r.Verdict)
+	assert.Equal(t, 1, r.Regressions)
+	assert.Equal(t, 1, r.Compared)
+	// the verdict line is not part of the document
+	assert.Contains(t, diag.String(), "BENCHCI RESULT: regressions=1 improvements=0 compared=1 verdict=FAIL\n")
+}
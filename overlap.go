@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// splitBenchPattern splits a -bench pattern into the pattern matched against
+// benchmark functions and the pattern for sub-benchmarks, if any.
+func splitBenchPattern(pattern string) (string, string) {
+	parts := strings.SplitN(pattern, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// findOverlaps returns a description of each benchmark function of a package
+// which is matched by more than one entry. Entries which select different
+// sub-benchmarks of the same function, or which pass different args to the
+// test binary, are not considered overlapping.
+func findOverlaps(entries []Benchmark, functions []string) ([]string, error) {
+	type selection struct {
+		sub  string
+		args string
+	}
+	var overlaps []string
+	for _, function := range functions {
+		matches := map[selection][]string{}
+		for _, entry := range entries {
+			top, sub := splitBenchPattern(entry.Name)
+			match, err := regexp.MatchString(top, function)
+			if err != nil {
+				return nil, fmt.Errorf("invalid benchmark name '%s': %w", entry.Name, err)
+			}
+			if match {
+				key := selection{sub: sub, args: strings.Join(entry.Args, "\x00")}
+				matches[key] = append(matches[key], entry.UniqueName)
+			}
+		}
+		keys := make([]selection, 0, len(matches))
+		for key := range matches {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].sub != keys[j].sub {
+				return keys[i].sub < keys[j].sub
+			}
+			return keys[i].args < keys[j].args
+		})
+		for _, key := range keys {
+			if names := matches[key]; len(names) > 1 {
+				overlaps = append(overlaps, fmt.Sprintf("%s is matched by %s", function, strings.Join(names, ", ")))
+			}
+		}
+	}
+	return overlaps, nil
+}
+
+// checkOverlaps fails if two entries have the same unique name or would run
+// the same benchmark, which would otherwise lead to results being dropped or
+// counted twice. The benchmark functions are listed in the source tree in dir.
+func checkOverlaps(dir string) error {
+	var problems []string
+	byPackage := map[string][]Benchmark{}
+	uniqueNames := map[string]bool{}
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" {
+			continue
+		}
+		// the entries of a sweep or of a cpu list run the same benchmark on
+		// purpose
+		if benchmark.variant {
+			continue
+		}
+		if uniqueNames[benchmark.UniqueName] {
+			problems = append(problems, fmt.Sprintf("duplicate unique name '%s'", benchmark.UniqueName))
+		}
+		uniqueNames[benchmark.UniqueName] = true
+		// bazel and make targets are not go packages
+		if isTarget(&benchmark) {
+			continue
+		}
+		byPackage[benchmark.Package] = append(byPackage[benchmark.Package], benchmark)
+	}
+	packages := make([]string, 0, len(byPackage))
+	for pkg, entries := range byPackage {
+		if len(entries) > 1 {
+			packages = append(packages, pkg)
+		}
+	}
+	sort.Strings(packages)
+	for _, pkg := range packages {
+		found, err := listBenchmarks(goCommand(&benchmarks.BenchmarkConfiguration), dir, pkg)
+		if err != nil {
+			return err
+		}
+		functions := make([]string, 0, len(found))
+		for _, b := range found {
+			functions = append(functions, b.name)
+		}
+		overlaps, err := findOverlaps(byPackage[pkg], functions)
+		if err != nil {
+			return err
+		}
+		for _, overlap := range overlaps {
+			problems = append(problems, fmt.Sprintf("%s in %s", overlap, pkg))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("overlapping benchmark entries: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindOverlaps(t *testing.T) {
+	functions := []string{"BenchmarkInitSmall", "BenchmarkInitLarge", "BenchmarkCluster"}
+	entry := func(name string) Benchmark {
+		return Benchmark{Name: name, UniqueName: name}
+	}
+	for _, tc := range []struct {
+		name     string
+		entries  []Benchmark
+		expected []string
+	}{
+		{"no overlap", []Benchmark{entry("BenchmarkInitSmall"), entry("BenchmarkInitLarge")}, nil},
+		{"overlap", []Benchmark{entry("BenchmarkInit"), entry("BenchmarkInitLarge$")}, []string{"BenchmarkInitLarge is matched by BenchmarkInit, BenchmarkInitLarge$"}},
+		{"different sub-benchmarks", []Benchmark{entry("BenchmarkCluster/a"), entry("BenchmarkCluster/b")}, nil},
+		{"same sub-benchmark", []Benchmark{entry("BenchmarkClus/a"), entry("BenchmarkCluster/a")}, []string{"BenchmarkCluster is matched by BenchmarkClus/a, BenchmarkCluster/a"}},
+		{"different args", []Benchmark{
+			{Name: "BenchmarkCluster", UniqueName: "BenchmarkClusterSmall", Args: []string{"-size=1"}},
+			{Name: "BenchmarkCluster", UniqueName: "BenchmarkClusterLarge", Args: []string{"-size=1000"}},
+		}, nil},
+		{"same args", []Benchmark{
+			{Name: "BenchmarkCluster", UniqueName: "BenchmarkClusterSmall", Args: []string{"-size=1"}},
+			{Name: "BenchmarkClus", UniqueName: "BenchmarkClus", Args: []string{"-size=1"}},
+		}, []string{"BenchmarkCluster is matched by BenchmarkClusterSmall, BenchmarkClus"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			overlaps, err := findOverlaps(tc.entries, functions)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, overlaps)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"k8s.io/klog/v2"
+)
+
+// parallelRefs runs the passes of the base ref, of the latest release and of
+// HEAD concurrently, each of them pinned to its own cores so that they do not
+// compete with each other.
+var parallelRefs bool
+
+func validateParallelRefs() error {
+	if !parallelRefs {
+		return nil
+	}
+	if runtime.GOOS != "linux" {
+		return fmt.Errorf("-parallel-refs is not supported on %s", runtime.GOOS)
+	}
+	if len(headCandidates) > 0 {
+		return fmt.Errorf("-parallel-refs cannot be used with -head")
+	}
+	if applyPatch != "" {
+		return fmt.Errorf("-parallel-refs cannot be used with -apply-patch")
+	}
+	return nil
+}
+
+// refPass is the run of the benchmarks for one ref.
+type refPass struct {
+	commit     plumbing.Hash
+	ref        string
+	tagVersion string
+	dir        string
+	cpus       []int
+	progress   func(Benchmark, *benchResult)
+	run        *RunResult
+	err        error
+}
+
+// pinnedCPUs returns the number of CPUs needed to run the benchmarks, i.e.
+// the largest GOMAXPROCS value, when they are pinned to the CPUs of a pass or
+// of a job. The benchmarks cannot have their own CPU placement then.
+func pinnedCPUs(option string) (int, error) {
+	needed := 0
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" {
+			continue
+		}
+		if benchmark.NumaNode != nil || (benchmark.AvoidSMTSiblings != nil && *benchmark.AvoidSMTSiblings) {
+			return 0, fmt.Errorf("benchmark '%s' sets numaNode or avoidSMTSiblings, which cannot be used with %s", benchmark.UniqueName, option)
+		}
+		n, err := maxCPU(benchmark.Cpu)
+		if err != nil {
+			return 0, err
+		}
+		if n > needed {
+			needed = n
+		}
+	}
+	return needed, nil
+}
+
+// splitCPUs splits CPUs evenly into n sets, each of them with enough CPUs for
+// the benchmarks. The spare CPUs are used to build.
+func splitCPUs(cpus []int, n int, option string) ([][]int, error) {
+	needed, err := pinnedCPUs(option)
+	if err != nil {
+		return nil, err
+	}
+	size := len(cpus) / n
+	if size < needed {
+		return nil, fmt.Errorf("%s needs %d cores for %d sets of up to %d CPUs, but only %d are available", option, n*needed, n, needed, len(cpus))
+	}
+	sets := make([][]int, 0, n)
+	for i := 0; i < n; i++ {
+		sets = append(sets, cpus[i*size:(i+1)*size])
+	}
+	return sets, nil
+}
+
+// passCPUs splits the cores of the machine evenly between the passes, with one
+// CPU per core so that passes never share a core through SMT siblings.
+func passCPUs(topology smtTopology, passes int) ([][]int, error) {
+	return splitCPUs(topology.coreCPUs(), passes, "-parallel-refs")
+}
+
+// pinArgs prefixes a command with taskset when the pass of the ref or the job
+// is pinned, so that the build and the benchmarks only run on its CPUs.
+func pinArgs(vars refVars, args []string) []string {
+	if vars.cpus == "" {
+		return args
+	}
+	return append([]string{"taskset", "-c", vars.cpus}, args...)
+}
+
+// runPasses runs the passes concurrently. The worktrees of the passes must
+// already be checked out.
+func runPasses(passes []*refPass) error {
+	var wg sync.WaitGroup
+	for _, p := range passes {
+		wg.Add(1)
+		go func(p *refPass) {
+			defer wg.Done()
+			cpus := formatCPUList(p.cpus)
+			klog.InfoS("Run Benchmark", "commitHash", p.commit, "Ref", p.ref, "dir", p.dir, "cpus", cpus)
+			p.run, p.err = runPinnedBenchmarks(p.dir, p.ref, p.commit.String(), p.tagVersion, cpus, p.progress)
+		}(p)
+	}
+	wg.Wait()
+	for _, p := range passes {
+		if p.err != nil {
+			return fmt.Errorf("failed to run a benchmark of ref %v: %w", p.ref, p.err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassCPUs(t *testing.T) {
+	defer func(b BenchmarkList) { *benchmarks = b }(*benchmarks)
+	// 5 cores with 2 threads each, CPU n+5 being the sibling of CPU n
+	topology := smtTopology{}
+	for cpu := 0; cpu < 5; cpu++ {
+		topology[cpu] = cpu
+		topology[cpu+5] = cpu
+	}
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{
+		{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1"}},
+		{UniqueName: "BenchmarkBar", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1,2"}},
+		{UniqueName: "BenchmarkSkipped", Skip: "flaky", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "4"}},
+	}}
+	sets, err := passCPUs(topology, 2)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{0, 1}, {2, 3}}, sets)
+
+	_, err = passCPUs(topology, 3)
+	assert.EqualError(t, err, "-parallel-refs needs 6 cores for 3 sets of up to 2 CPUs, but only 5 are available")
+
+	node := 0
+	benchmarks.Benchmarks[0].NumaNode = &node
+	_, err = passCPUs(topology, 2)
+	assert.Error(t, err)
+}
+
+func TestPinArgs(t *testing.T) {
+	args := []string{"go", "test"}
+	assert.Equal(t, args, pinArgs(refVars{}, args))
+	assert.Equal(t, []string{"taskset", "-c", "2,3", "go", "test"}, pinArgs(refVars{cpus: "2,3"}, args))
+}
+
+func TestValidateParallelRefs(t *testing.T) {
+	defer func(enabled bool, patch string) { parallelRefs, applyPatch = enabled, patch }(parallelRefs, applyPatch)
+	parallelRefs = false
+	applyPatch = "fix.patch"
+	assert.NoError(t, validateParallelRefs())
+	parallelRefs = true
+	assert.Error(t, validateParallelRefs())
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+
+	"k8s.io/klog/v2"
+)
+
+// patchedRef identifies the results of HEAD with the patch applied.
+const patchedRef = "HEAD+patch"
+
+var applyPatch string
+
+func gitApply(dir string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"apply"}, args...)...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		klog.InfoS("Exec command error", "err", stderr.String())
+		return fmt.Errorf("failed to run '%s' command: %w", cmd, err)
+	}
+	return nil
+}
+
+// runPatchedBenchmarks applies the patch to the HEAD checkout in dir, runs the
+// benchmarks and reverts the patch.
+func runPatchedBenchmarks(dir, headCommit string, progress func(Benchmark, *benchResult)) (*RunResult, error) {
+	// the patch is relative to the working directory, not to the checkout
+	patch, err := filepath.Abs(applyPatch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	if err := gitApply(dir, patch); err != nil {
+		return nil, fmt.Errorf("unable to apply patch: %w", err)
+	}
+	defer func() {
+		if err := gitApply(dir, "-R", patch); err != nil {
+			klog.ErrorS(err, "Failed to revert patch", "patch", applyPatch)
+		}
+	}()
+	klog.InfoS("Run Benchmark", "Ref", patchedRef, "patch", applyPatch)
+	run, err := runBenchmarks(dir, patchedRef, headCommit, "", progress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run a benchmark: %w", err)
+	}
+	return run, nil
+}
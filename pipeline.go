@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"k8s.io/klog/v2"
+)
+
+func isGoTestStep(step CommandStep) bool {
+	return len(step.Args) == 0
+}
+
+// outputStep returns the index of the step whose output is parsed: the step
+// marked with output, or the "go test" step.
+func outputStep(steps []CommandStep) (int, error) {
+	output := -1
+	var goTest []int
+	for i, step := range steps {
+		if step.Output {
+			if output != -1 {
+				return 0, fmt.Errorf("more than one step is marked as output")
+			}
+			output = i
+		}
+		if isGoTestStep(step) {
+			goTest = append(goTest, i)
+		}
+	}
+	if output != -1 {
+		return output, nil
+	}
+	switch len(goTest) {
+	case 0:
+		return 0, fmt.Errorf("no step produces the benchmark results, add a step with no args or mark a step as output")
+	case 1:
+		return goTest[0], nil
+	default:
+		return 0, fmt.Errorf("more than one 'go test' step, mark the step to parse as output")
+	}
+}
+
+func stepName(i int, step CommandStep) string {
+	if step.Name != "" {
+		return step.Name
+	}
+	return fmt.Sprintf("step %d", i+1)
+}
+
+// execCommand returns a command run in the source tree, with the variables of
+// the ref in its environment.
+func execCommand(dir string, vars refVars, args []string) *exec.Cmd {
+	args = pinArgs(vars, args)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"BENCHCI_REF="+vars.Ref,
+		"BENCHCI_COMMIT="+vars.CommitSHA,
+		"BENCHCI_ARTIFACTS_DIR="+vars.ArtifactsDir,
+	)
+	cmd.Env = append(cmd.Env, vars.env...)
+	return cmd
+}
+
+// runStep runs a custom command of a pipeline and returns its standard output.
+// The benchmark and its package are also passed in the environment.
+func runStep(dir string, vars refVars, benchmark *Benchmark, step CommandStep) ([]byte, error) {
+	args, err := expandArgs(step.Args, vars)
+	if err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := execCommand(dir, vars, args)
+	cmd.Stderr = &stderr
+	cmd.Env = append(cmd.Env,
+		"BENCHCI_BENCHMARK="+benchmark.UniqueName,
+		"BENCHCI_PACKAGE="+benchmark.Package,
+	)
+	cmd.Env = append(cmd.Env, sweepEnv(benchmark)...)
+	klog.InfoS("Running benchmark step", "benchmark", benchmark.UniqueName, "command", cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		klog.InfoS("Exec command output", "out", string(out))
+		klog.InfoS("Exec command error", "err", stderr.String())
+		return nil, fmt.Errorf("failed to run '%s' command: %w", cmd, err)
+	}
+	return out, nil
+}
+
+// runPipeline runs the steps of a benchmark in order, stopping at the first
+// failure, and returns the output of the output step.
+func runPipeline(cmdStr, dir string, vars refVars, benchmark *Benchmark) ([]byte, error) {
+	output, err := outputStep(benchmark.Commands)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commands for '%s': %w", benchmark.UniqueName, err)
+	}
+	var result []byte
+	for i, step := range benchmark.Commands {
+		var out []byte
+		if isGoTestStep(step) {
+			out, err = runGoTest(cmdStr, dir, vars, benchmark)
+		} else {
+			out, err = runStep(dir, vars, benchmark, step)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s of '%s' failed: %w", stepName(i, step), benchmark.UniqueName, err)
+		}
+		if i == output {
+			result = out
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputStep(t *testing.T) {
+	generate := CommandStep{Name: "generate", Args: []string{"make", "fixtures"}}
+	goTest := CommandStep{}
+	for _, tc := range []struct {
+		name   string
+		steps  []CommandStep
+		output int
+		err    string
+	}{
+		{name: "go test", steps: []CommandStep{generate, goTest}, output: 1},
+		{name: "marked", steps: []CommandStep{goTest, {Args: []string{"cat", "results.txt"}, Output: true}}, output: 1},
+		{name: "no results", steps: []CommandStep{generate}, err: "no step produces the benchmark results, add a step with no args or mark a step as output"},
+		{name: "two go test", steps: []CommandStep{goTest, goTest}, err: "more than one 'go test' step, mark the step to parse as output"},
+		{name: "two outputs", steps: []CommandStep{{Output: true}, {Output: true}}, err: "more than one step is marked as output"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := outputStep(tc.steps)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.output, output)
+		})
+	}
+}
+
+func TestRunPipeline(t *testing.T) {
+	benchmark := &Benchmark{UniqueName: "BenchmarkFoo", Package: "example.com/foo", Commands: []CommandStep{
+		{Args: []string{"sh", "-c", "echo $BENCHCI_REF > /dev/null"}},
+		{Args: []string{"sh", "-c", "echo \"BenchmarkFoo-4 100 $BENCHCI_PACKAGE\""}, Output: true},
+		{Args: []string{"true"}},
+	}}
+	out, err := runPipeline("go", "", refVars{Ref: "HEAD"}, benchmark)
+	require.NoError(t, err)
+	assert.Equal(t, "BenchmarkFoo-4 100 example.com/foo\n", string(out))
+
+	benchmark.Commands[2] = CommandStep{Name: "collect", Args: []string{"false"}}
+	_, err = runPipeline("go", "", refVars{Ref: "HEAD"}, benchmark)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "collect of 'BenchmarkFoo' failed")
+}
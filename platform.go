@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// platformInfo describes the platform on which benchmarks were run, since
+// results are not comparable across platforms.
+type platformInfo struct {
+	OS     string `json:"os"`
+	Arch   string `json:"arch"`
+	NumCPU int    `json:"numCPU"`
+	// CPUModel is empty when it is not known, e.g. for older baselines.
+	CPUModel string `json:"cpuModel,omitempty"`
+}
+
+func currentPlatform() platformInfo {
+	return platformInfo{OS: runtime.GOOS, Arch: runtime.GOARCH, NumCPU: runtime.NumCPU(), CPUModel: cpuModel("/proc/cpuinfo")}
+}
+
+// cpuModel returns the model name of the first CPU listed in cpuinfo, or an
+// empty string if it is not available.
+func cpuModel(cpuinfo string) string {
+	f, err := os.Open(cpuinfo)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "model name" {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+// platformDifferences describes how two platforms differ, the CPU model is
+// only compared when it is known for both.
+func platformDifferences(a, b platformInfo) []string {
+	var differences []string
+	if a.OS != b.OS || a.Arch != b.Arch {
+		differences = append(differences, fmt.Sprintf("%s/%s != %s/%s", a.OS, a.Arch, b.OS, b.Arch))
+	}
+	if a.NumCPU != b.NumCPU {
+		differences = append(differences, fmt.Sprintf("%d CPUs != %d CPUs", a.NumCPU, b.NumCPU))
+	}
+	if a.CPUModel != "" && b.CPUModel != "" && a.CPUModel != b.CPUModel {
+		differences = append(differences, fmt.Sprintf("'%s' != '%s'", a.CPUModel, b.CPUModel))
+	}
+	return differences
+}
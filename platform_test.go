@@ -0,0 +1,31 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCPUModel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cpuinfo := filepath.Join(dir, "cpuinfo")
+	require.NoError(t, ioutil.WriteFile(cpuinfo, []byte("processor\t: 0\nvendor_id\t: GenuineIntel\nmodel name\t: Intel(R) Xeon(R) CPU @ 2.20GHz\n\nprocessor\t: 1\n"), 0644))
+	assert.Equal(t, "Intel(R) Xeon(R) CPU @ 2.20GHz", cpuModel(cpuinfo))
+	assert.Equal(t, "", cpuModel(filepath.Join(dir, "missing")))
+}
+
+func TestPlatformDifferences(t *testing.T) {
+	a := platformInfo{OS: "linux", Arch: "amd64", NumCPU: 4, CPUModel: "Intel Xeon"}
+	assert.Empty(t, platformDifferences(a, a))
+	b := a
+	b.CPUModel = ""
+	assert.Empty(t, platformDifferences(a, b))
+	b.Arch = "arm64"
+	assert.Equal(t, []string{"linux/amd64 != linux/arm64"}, platformDifferences(a, b))
+}
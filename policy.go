@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	// policyFail makes the run fail when there is a regression.
+	policyFail = "fail"
+	// policyWarn only prints a warning when there is a regression.
+	policyWarn = "warn"
+)
+
+var basePolicy, releasePolicy string
+
+func validatePolicy(policy string) error {
+	switch policy {
+	case policyFail, policyWarn:
+		return nil
+	default:
+		return fmt.Errorf("invalid policy '%s', must be %s or %s", policy, policyFail, policyWarn)
+	}
+}
+
+// The values of failOn, which select the comparisons in which a regression
+// fails the run.
+const (
+	failOnNone                = "none"
+	failOnRegression          = "regression"
+	failOnRegressionVsBase    = "regression-vs-base"
+	failOnRegressionVsRelease = "regression-vs-release"
+)
+
+var failOn string
+
+// failOnPolicies returns the policies for the base comparison and the release
+// comparison corresponding to a failOn value.
+func failOnPolicies(failOn string) (string, string, error) {
+	switch failOn {
+	case failOnNone:
+		return policyWarn, policyWarn, nil
+	case failOnRegression:
+		return policyFail, policyFail, nil
+	case failOnRegressionVsBase:
+		return policyFail, policyWarn, nil
+	case failOnRegressionVsRelease:
+		return policyWarn, policyFail, nil
+	default:
+		return "", "", fmt.Errorf("invalid failOn '%s', must be one of %s, %s, %s or %s", failOn, failOnNone, failOnRegression, failOnRegressionVsBase, failOnRegressionVsRelease)
+	}
+}
+
+// comparisonPolicies returns the policies for the base comparison and the
+// release comparison. failOn is a shorthand for both policies. Like for other
+// settings, the configuration file takes precedence over flags.
+func comparisonPolicies() (string, string, error) {
+	base, release := basePolicy, releasePolicy
+	if failOn != "" {
+		var err error
+		if base, release, err = failOnPolicies(failOn); err != nil {
+			return "", "", err
+		}
+	}
+	if benchmarks.FailOn != "" {
+		if benchmarks.BasePolicy != "" || benchmarks.ReleasePolicy != "" {
+			return "", "", fmt.Errorf("failOn cannot be used with basePolicy or releasePolicy")
+		}
+		var err error
+		if base, release, err = failOnPolicies(benchmarks.FailOn); err != nil {
+			return "", "", err
+		}
+	}
+	if benchmarks.BasePolicy != "" {
+		base = benchmarks.BasePolicy
+	}
+	if benchmarks.ReleasePolicy != "" {
+		release = benchmarks.ReleasePolicy
+	}
+	if err := validatePolicy(base); err != nil {
+		return "", "", err
+	}
+	if err := validatePolicy(release); err != nil {
+		return "", "", err
+	}
+	return base, release, nil
+}
+
+// applyPolicy returns whether a regression should fail the run, and prints a
+// warning if the regression is tolerated by the policy.
+func applyPolicy(w io.Writer, regression bool, r *refResults) bool {
+	if !regression || r.policy != policyWarn {
+		return regression
+	}
+	fmt.Fprintf(w, "WARNING: this commit makes benchmarks worse compared with %s (policy: %s)\n\n", r.name, r.policy)
+	return false
+}
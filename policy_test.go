@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComparisonPolicies(t *testing.T) {
+	defer func(b BenchmarkList, base, release, f string) {
+		*benchmarks, basePolicy, releasePolicy, failOn = b, base, release, f
+	}(*benchmarks, basePolicy, releasePolicy, failOn)
+	*benchmarks = BenchmarkList{}
+	basePolicy, releasePolicy, failOn = policyFail, policyFail, ""
+
+	check := func(expectedBase, expectedRelease string) {
+		base, release, err := comparisonPolicies()
+		require.NoError(t, err)
+		assert.Equal(t, []string{expectedBase, expectedRelease}, []string{base, release})
+	}
+	check(policyFail, policyFail)
+	failOn = failOnNone
+	check(policyWarn, policyWarn)
+	failOn = failOnRegressionVsRelease
+	check(policyWarn, policyFail)
+	// the configuration takes precedence over flags
+	benchmarks.FailOn = failOnRegressionVsBase
+	check(policyFail, policyWarn)
+	benchmarks.FailOn = failOnRegression
+	check(policyFail, policyFail)
+
+	benchmarks.ReleasePolicy = policyWarn
+	_, _, err := comparisonPolicies()
+	assert.EqualError(t, err, "failOn cannot be used with basePolicy or releasePolicy")
+	*benchmarks = BenchmarkList{}
+	failOn = "always"
+	_, _, err = comparisonPolicies()
+	assert.Error(t, err)
+}
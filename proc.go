@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// outputWithTimeout runs cmd like cmd.Output, but kills it along with all the
+// processes it started if it is still running after twice the timeout. go
+// test only stops the test binary on timeout, not the -exec wrappers or the
+// processes started by the benchmark, which would otherwise hang the run. The
+// other half of the time is left for building the test binary.
+func outputWithTimeout(cmd *exec.Cmd, timeout time.Duration) ([]byte, error) {
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(2 * timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case err := <-done:
+		return stdout.Bytes(), err
+	case <-expired:
+		if err := killProcessGroup(cmd); err != nil {
+			klog.ErrorS(err, "Failed to kill the process group", "pid", cmd.Process.Pid)
+		}
+		<-done
+		return stdout.Bytes(), fmt.Errorf("killed after %s", 2*timeout)
+	}
+}
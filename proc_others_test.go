@@ -0,0 +1,34 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputWithTimeout(t *testing.T) {
+	out, err := outputWithTimeout(exec.Command("sh", "-c", "echo done"), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "done\n", string(out))
+
+	// the process started by the command is killed along with it
+	start := time.Now()
+	out, err = outputWithTimeout(exec.Command("sh", "-c", "sleep 60 & echo $!; wait"), 100*time.Millisecond)
+	assert.EqualError(t, err, "killed after 200ms")
+	assert.True(t, time.Since(start) < 30*time.Second)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	require.NoError(t, err)
+	for i := 0; i < 100 && syscall.Kill(pid, 0) == nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+	}
+	assert.Error(t, syscall.Kill(pid, 0))
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	flamegraphWidth       = 1200
+	flamegraphFrameHeight = 16
+	flamegraphPadding     = 10
+	flamegraphTitleHeight = 30
+	flamegraphCharWidth   = 7
+)
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// foldedStack is a single call stack (root first) with the value (e.g. CPU
+// time in nanoseconds) attributed to it.
+type foldedStack struct {
+	frames []string
+	value  int64
+}
+
+func sanitizePathElem(s string) string {
+	return unsafePathChars.ReplaceAllString(s, "_")
+}
+
+// artifactPath returns the path prefix under which artifacts for a given
+// (benchmark, ref) pair are stored.
+func artifactPath(ref, uniqueName string) string {
+	return filepath.Join(artifactsDir, sanitizePathElem(ref), sanitizePathElem(uniqueName))
+}
+
+func needsArtifacts() bool {
+	for _, benchmark := range benchmarks.Benchmarks {
+		if *benchmark.Flamegraph || *benchmark.Trace || *benchmark.Contention {
+			return true
+		}
+	}
+	return usesHooksOrCommands()
+}
+
+func prepareArtifactsDir() error {
+	if artifactsDir == "" {
+		if !needsArtifacts() {
+			return nil
+		}
+		dir, err := ioutil.TempDir("", "benchci-artifacts-")
+		if err != nil {
+			return fmt.Errorf("unable to create artifacts directory: %w", err)
+		}
+		artifactsDir = dir
+		klog.InfoS("Created artifacts directory", "path", artifactsDir)
+		return writeArtifactsMetadata()
+	}
+	// Benchmarks run in temporary worktrees, so we need an absolute path which
+	// does not depend on the checkout.
+	dir, err := filepath.Abs(artifactsDir)
+	if err != nil {
+		return fmt.Errorf("invalid artifacts directory: %w", err)
+	}
+	artifactsDir = dir
+	if err := os.MkdirAll(artifactsDir, 0755); err != nil {
+		return err
+	}
+	return writeArtifactsMetadata()
+}
+
+// parseTraceValue parses a sample value as printed by "pprof -traces", which
+// is either a duration (CPU time, contention delay) or a plain number.
+func parseTraceValue(s string) (int64, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return int64(d), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sample value '%s'", s)
+	}
+	return int64(f), nil
+}
+
+// parseTraces converts the output of "go tool pprof -traces" to folded stacks.
+func parseTraces(r io.Reader) ([]foldedStack, error) {
+	var stacks []foldedStack
+	var current *foldedStack
+	flush := func() {
+		if current != nil && len(current.frames) > 0 {
+			// pprof prints the leaf first, folded stacks start at the root.
+			for i, j := 0, len(current.frames)-1; i < j; i, j = i+1, j-1 {
+				current.frames[i], current.frames[j] = current.frames[j], current.frames[i]
+			}
+			stacks = append(stacks, *current)
+		}
+		current = nil
+	}
+	scanner := bufio.NewScanner(r)
+	inTraces := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "-----------+") {
+			flush()
+			inTraces = true
+			current = &foldedStack{}
+			continue
+		}
+		if !inTraces || current == nil || strings.TrimSpace(line) == "" {
+			continue
+		}
+		frame := strings.TrimSpace(line)
+		if len(current.frames) == 0 && current.value == 0 {
+			fields := strings.SplitN(frame, " ", 2)
+			if len(fields) != 2 {
+				continue
+			}
+			v, err := parseTraceValue(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			current.value = v
+			frame = strings.TrimSpace(fields[1])
+		} else if !strings.HasPrefix(line, " ") {
+			// Sample labels, e.g. "bytes:[...]" for heap profiles.
+			continue
+		}
+		frame = strings.TrimSuffix(frame, " (inline)")
+		current.frames = append(current.frames, frame)
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return stacks, nil
+}
+
+func profileTraces(cmdStr, binary, profile string) ([]foldedStack, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(cmdStr, "tool", "pprof", "-traces", binary, profile)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		klog.InfoS("Exec command error", "err", stderr.String())
+		return nil, fmt.Errorf("failed to run '%s' command: %w", cmd, err)
+	}
+	return parseTraces(bytes.NewReader(out))
+}
+
+func writeFolded(w io.Writer, stacks []foldedStack) error {
+	for _, stack := range stacks {
+		if _, err := fmt.Fprintf(w, "%s %d\n", strings.Join(stack.frames, ";"), stack.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type flameNode struct {
+	name     string
+	value    int64
+	children map[string]*flameNode
+}
+
+func buildFlameTree(stacks []foldedStack) (*flameNode, int) {
+	root := &flameNode{name: "all", children: map[string]*flameNode{}}
+	maxDepth := 0
+	for _, stack := range stacks {
+		node := root
+		node.value += stack.value
+		for _, frame := range stack.frames {
+			child, ok := node.children[frame]
+			if !ok {
+				child = &flameNode{name: frame, children: map[string]*flameNode{}}
+				node.children[frame] = child
+			}
+			child.value += stack.value
+			node = child
+		}
+		if len(stack.frames) > maxDepth {
+			maxDepth = len(stack.frames)
+		}
+	}
+	return root, maxDepth
+}
+
+func flameColor(name string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	v := h.Sum32()
+	return fmt.Sprintf("rgb(%d,%d,%d)", 205+v%50, (v>>8)%230, (v>>16)%55)
+}
+
+// writeFlamegraph renders folded stacks as a self-contained SVG flamegraph.
+func writeFlamegraph(w io.Writer, title string, stacks []foldedStack) error {
+	root, maxDepth := buildFlameTree(stacks)
+	height := flamegraphTitleHeight + (maxDepth+1)*flamegraphFrameHeight + flamegraphPadding
+	buf := bufio.NewWriter(w)
+	fmt.Fprintf(buf, `<?xml version="1.0" standalone="no"?>`+"\n")
+	fmt.Fprintf(buf, `<svg version="1.1" width="%d" height="%d" xmlns="http://www.w3.org/2000/svg" font-family="Verdana" font-size="12">`+"\n", flamegraphWidth, height)
+	fmt.Fprintf(buf, `<rect x="0" y="0" width="%d" height="%d" fill="#eeeeee"/>`+"\n", flamegraphWidth, height)
+	fmt.Fprintf(buf, `<text x="%d" y="20" text-anchor="middle" font-size="16">%s</text>`+"\n", flamegraphWidth/2, html.EscapeString(title))
+
+	if root.value > 0 {
+		scale := float64(flamegraphWidth-2*flamegraphPadding) / float64(root.value)
+		var draw func(node *flameNode, x float64, depth int)
+		draw = func(node *flameNode, x float64, depth int) {
+			width := float64(node.value) * scale
+			if width < 0.1 {
+				return
+			}
+			y := height - flamegraphPadding - (depth+1)*flamegraphFrameHeight
+			label := fmt.Sprintf("%s (%.2f%%)", node.name, 100*float64(node.value)/float64(root.value))
+			fmt.Fprintf(buf, `<g><title>%s</title><rect x="%.1f" y="%d" width="%.1f" height="%d" fill="%s" rx="2" ry="2"/>`,
+				html.EscapeString(label), x, y, width, flamegraphFrameHeight-1, flameColor(node.name))
+			if chars := int(width) / flamegraphCharWidth; chars > 3 {
+				text := node.name
+				if len(text) > chars {
+					text = text[:chars-2] + ".."
+				}
+				fmt.Fprintf(buf, `<text x="%.1f" y="%d">%s</text>`, x+3, y+flamegraphFrameHeight-4, html.EscapeString(text))
+			}
+			fmt.Fprintln(buf, "</g>")
+
+			names := make([]string, 0, len(node.children))
+			for name := range node.children {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				child := node.children[name]
+				draw(child, x, depth+1)
+				x += float64(child.value) * scale
+			}
+		}
+		draw(root, flamegraphPadding, 0)
+	}
+	fmt.Fprintln(buf, "</svg>")
+	return buf.Flush()
+}
+
+// generateFlamegraph converts a CPU profile to folded stacks and an SVG
+// flamegraph, both stored next to the profile. It returns the path of the SVG.
+func generateFlamegraph(cmdStr, binary, profile, title string) (string, error) {
+	stacks, err := profileTraces(cmdStr, binary, profile)
+	if err != nil {
+		return "", err
+	}
+	prefix := strings.TrimSuffix(profile, ".pprof")
+	var folded bytes.Buffer
+	if err := writeFolded(&folded, stacks); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(prefix+".folded", folded.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	var svg bytes.Buffer
+	if err := writeFlamegraph(&svg, title, stacks); err != nil {
+		return "", err
+	}
+	svgPath := prefix + ".svg"
+	if err := ioutil.WriteFile(svgPath, svg.Bytes(), 0644); err != nil {
+		return "", err
+	}
+	return svgPath, nil
+}
+
+// totalContention returns the total time (in nanoseconds) spent blocked on
+// synchronization primitives, according to the block and mutex profiles
+// collected for a benchmark.
+func totalContention(cmdStr, ref, uniqueName string) (float64, error) {
+	prefix := artifactPath(ref, uniqueName)
+	var total int64
+	for _, profile := range []string{prefix + ".block.pprof", prefix + ".mutex.pprof"} {
+		stacks, err := profileTraces(cmdStr, prefix+".test", profile)
+		if err != nil {
+			return 0, err
+		}
+		for _, stack := range stacks {
+			total += stack.value
+		}
+	}
+	return float64(total), nil
+}
+
+func contentionEnabled() bool {
+	for _, benchmark := range benchmarks.Benchmarks {
+		if *benchmark.Contention {
+			return true
+		}
+	}
+	return false
+}
+
+func flamegraphPath(ref, uniqueName string) string {
+	return artifactPath(ref, uniqueName) + ".cpu.svg"
+}
+
+// flamegraphs are the flamegraphs of a benchmark, paths being the flamegraph
+// of each of refs.
+type flamegraphs struct {
+	benchmark string
+	refs      []string
+	paths     []string
+}
+
+// regressionFlamegraphs returns the flamegraphs available for regressed
+// benchmarks.
+func regressionFlamegraphs(results []result, refs []string) []flamegraphs {
+	var found []flamegraphs
+	seen := map[string]bool{}
+	for _, result := range results {
+		if !*result.Flamegraph || !isRegression(result) || seen[result.UniqueName] {
+			continue
+		}
+		seen[result.UniqueName] = true
+		f := flamegraphs{benchmark: result.UniqueName}
+		for _, ref := range refs {
+			path := flamegraphPath(ref, result.UniqueName)
+			if _, err := os.Stat(path); err == nil {
+				f.refs = append(f.refs, ref)
+				f.paths = append(f.paths, path)
+			}
+		}
+		if len(f.paths) > 0 {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+// showFlamegraphs lists the flamegraphs available for regressed benchmarks.
+func showFlamegraphs(w io.Writer, found []flamegraphs) {
+	if len(found) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nFlamegraphs of regressed benchmarks")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 35))
+	for _, f := range found {
+		fmt.Fprintln(w, f.benchmark)
+		for i, ref := range f.refs {
+			fmt.Fprintf(w, "  %s: %s\n", ref, f.paths[i])
+		}
+	}
+	fmt.Fprintln(w)
+}
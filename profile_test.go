@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTraces(t *testing.T) {
+	traces := `File: x.test
+Type: cpu
+Duration: 245.27ms, Total samples = 250ms (101.93%)
+-----------+-------------------------------------------------------
+      20ms   sort.insertionSort (inline)
+             sort.Ints
+             bt.work
+-----------+-------------------------------------------------------
+     1.30s   bt.work
+             bt.BenchmarkWork
+-----------+-------------------------------------------------------
+`
+	stacks, err := parseTraces(strings.NewReader(traces))
+	require.NoError(t, err)
+	assert.Equal(t, []foldedStack{
+		{frames: []string{"bt.work", "sort.Ints", "sort.insertionSort"}, value: 20000000},
+		{frames: []string{"bt.BenchmarkWork", "bt.work"}, value: 1300000000},
+	}, stacks)
+
+	var folded bytes.Buffer
+	require.NoError(t, writeFolded(&folded, stacks))
+	assert.Equal(t, "bt.work;sort.Ints;sort.insertionSort 20000000\nbt.BenchmarkWork;bt.work 1300000000\n", folded.String())
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// toolchainGoMod pins the toolchain of each ref to the one required by its
+// go.mod.
+const toolchainGoMod = "go.mod"
+
+var (
+	toolchain string
+
+	toolchainName = regexp.MustCompile(`^(local|go1(\.\d+){0,2}((rc|beta)\d+)?)$`)
+)
+
+func validateToolchain() error {
+	if toolchain != "" && toolchain != toolchainGoMod && !toolchainName.MatchString(toolchain) {
+		return fmt.Errorf("invalid toolchain '%s': must be %s, local or a Go release, e.g. go1.22.5", toolchain, toolchainGoMod)
+	}
+	return nil
+}
+
+// goModToolchain returns the toolchain required by a go.mod file: the one of
+// the toolchain directive if any, otherwise the release matching the go
+// directive, or an empty string if there is none.
+func goModToolchain(data []byte) string {
+	var goVersion, toolchainVersion string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "go":
+			goVersion = fields[1]
+		case "toolchain":
+			toolchainVersion = fields[1]
+		}
+	}
+	if toolchainVersion != "" && toolchainVersion != "default" {
+		return toolchainVersion
+	}
+	if goVersion == "" {
+		return ""
+	}
+	// starting with Go 1.21, "go 1.21" means the release go1.21.0
+	parts := strings.Split(goVersion, ".")
+	if minor, err := strconv.Atoi(parts[len(parts)-1]); err == nil && len(parts) == 2 && minor >= 21 {
+		goVersion += ".0"
+	}
+	return "go" + goVersion
+}
+
+// refEnv returns the environment variables of the commands run for a ref
+// checked out in dir, and a function removing the temporary directories.
+// A toolchain forced with -toolchain takes precedence over the releaseEnv
+// configuration, which takes precedence over the go.mod of the ref.
+func refEnv(dir string, release bool) ([]string, func(), error) {
+	var env []string
+	if toolchain == toolchainGoMod {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to read go.mod to select the toolchain: %w", err)
+		}
+		if name := goModToolchain(data); name != "" {
+			env = append(env, "GOTOOLCHAIN="+name)
+		}
+	}
+	cleanup := func() {}
+	if release {
+		var releaseVars []string
+		var err error
+		if releaseVars, cleanup, err = releaseEnv(benchmarks.ReleaseEnv); err != nil {
+			return nil, nil, err
+		}
+		env = append(env, releaseVars...)
+	}
+	if toolchain != "" && toolchain != toolchainGoMod {
+		env = append(env, "GOTOOLCHAIN="+toolchain)
+	}
+	return env, cleanup, nil
+}
+
+// releaseEnv returns the environment variables of the commands run for a
+// release, so that old releases build as they did originally, and a function
+// removing the temporary directories. Each ref gets its own module cache, so
+// that concurrent jobs sharing the machine do not interfere.
+func releaseEnv(c *ReleaseEnv) ([]string, func(), error) {
+	cleanup := func() {}
+	if c == nil {
+		return nil, cleanup, nil
+	}
+	var env []string
+	if c.IsolateModCache {
+		gopath, err := ioutil.TempDir("", "benchci-gopath-")
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("unable to create a temporary GOPATH: %w", err)
+		}
+		cleanup = func() {
+			if err := os.RemoveAll(gopath); err != nil {
+				klog.ErrorS(err, "Failed to remove the temporary GOPATH", "path", gopath)
+			}
+		}
+		// the module cache is read-only by default, which would prevent
+		// removing it
+		goflags := strings.TrimSpace(os.Getenv("GOFLAGS") + " -modcacherw")
+		env = append(env, "GOPATH="+gopath, "GOMODCACHE="+filepath.Join(gopath, "pkg", "mod"), "GOFLAGS="+goflags)
+	}
+	if c.Toolchain != "" {
+		env = append(env, "GOTOOLCHAIN="+c.Toolchain)
+	}
+	return env, cleanup, nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseEnv(t *testing.T) {
+	env, cleanup, err := releaseEnv(nil)
+	require.NoError(t, err)
+	cleanup()
+	assert.Empty(t, env)
+
+	env, cleanup, err = releaseEnv(&ReleaseEnv{Toolchain: "go1.21.13"})
+	require.NoError(t, err)
+	cleanup()
+	assert.Equal(t, []string{"GOTOOLCHAIN=go1.21.13"}, env)
+
+	defer os.Setenv("GOFLAGS", os.Getenv("GOFLAGS"))
+	os.Setenv("GOFLAGS", "-mod=mod")
+	env, cleanup, err = releaseEnv(&ReleaseEnv{IsolateModCache: true})
+	require.NoError(t, err)
+	require.Len(t, env, 3)
+	gopath := strings.TrimPrefix(env[0], "GOPATH=")
+	assert.DirExists(t, gopath)
+	assert.Equal(t, "GOMODCACHE="+filepath.Join(gopath, "pkg", "mod"), env[1])
+	assert.Equal(t, "GOFLAGS=-mod=mod -modcacherw", env[2])
+	cleanup()
+	_, err = os.Stat(gopath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReleaseEnvCommands(t *testing.T) {
+	defer func(b BenchmarkList) { *benchmarks = b }(*benchmarks)
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	*benchmarks = BenchmarkList{
+		ReleaseEnv: &ReleaseEnv{Toolchain: "go1.21.13"},
+		Setup:      []CommandStep{{Args: []string{"sh", "-c", "echo $GOTOOLCHAIN >> setup.txt"}}},
+	}
+	_, err = runBenchmarks(dir, "HEAD", "abc", "", nil)
+	require.NoError(t, err)
+	_, err = runBenchmarks(dir, "v1.2.0", "def", "1.2.0", nil)
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "setup.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.Getenv("GOTOOLCHAIN")+"\ngo1.21.13\n", string(data))
+}
+
+func TestGoModToolchain(t *testing.T) {
+	for _, tc := range []struct {
+		goMod    string
+		expected string
+	}{
+		{"module example.com/foo\n\ngo 1.16\n", "go1.16"},
+		{"module example.com/foo\n\ngo 1.21\n", "go1.21.0"},
+		{"module example.com/foo\n\ngo 1.22.3\n", "go1.22.3"},
+		{"module example.com/foo\n\ngo 1.21\n\ntoolchain go1.22.5\n", "go1.22.5"},
+		{"module example.com/foo\n\ngo 1.21.1\ntoolchain default\n", "go1.21.1"},
+		{"module example.com/foo\n", ""},
+	} {
+		assert.Equal(t, tc.expected, goModToolchain([]byte(tc.goMod)), tc.goMod)
+	}
+}
+
+func TestValidateToolchain(t *testing.T) {
+	defer func(v string) { toolchain = v }(toolchain)
+	for _, v := range []string{"", "go.mod", "local", "go1.22", "go1.22.5", "go1.23rc1"} {
+		toolchain = v
+		assert.NoError(t, validateToolchain(), v)
+	}
+	for _, v := range []string{"1.22", "auto", "go1.22.5+auto", "gomod"} {
+		toolchain = v
+		assert.Error(t, validateToolchain(), v)
+	}
+}
+
+func TestRefEnv(t *testing.T) {
+	defer func(v string, b BenchmarkList) { toolchain, *benchmarks = v, b }(toolchain, *benchmarks)
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	*benchmarks = BenchmarkList{ReleaseEnv: &ReleaseEnv{Toolchain: "go1.21.13"}}
+
+	toolchain = "go.mod"
+	_, _, err = refEnv(dir, false)
+	assert.Error(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/foo\n\ngo 1.22.1\n"), 0644))
+	env, _, err := refEnv(dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GOTOOLCHAIN=go1.22.1"}, env)
+	env, _, err = refEnv(dir, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GOTOOLCHAIN=go1.22.1", "GOTOOLCHAIN=go1.21.13"}, env)
+
+	toolchain = "go1.23.0"
+	env, _, err = refEnv(dir, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"GOTOOLCHAIN=go1.21.13", "GOTOOLCHAIN=go1.23.0"}, env)
+
+	toolchain = ""
+	env, _, err = refEnv(dir, false)
+	require.NoError(t, err)
+	assert.Empty(t, env)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"k8s.io/klog/v2"
+)
+
+var releaseAsset bool
+
+// githubRelease is the subset of a release of the GitHub API which is needed
+// to upload an asset.
+type githubRelease struct {
+	UploadURL string `json:"upload_url"`
+	Assets    []struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	} `json:"assets"`
+}
+
+// validateReleaseAsset fails early if the results cannot be uploaded to a
+// GitHub Release, rather than after running the benchmarks.
+func validateReleaseAsset() error {
+	if !releaseAsset {
+		return nil
+	}
+	if os.Getenv("GITHUB_TOKEN") == "" || os.Getenv("GITHUB_REPOSITORY") == "" {
+		return fmt.Errorf("GITHUB_TOKEN and GITHUB_REPOSITORY must be set to upload results to a release")
+	}
+	return nil
+}
+
+// headTag returns the name of a tag pointing to commit, or an empty string if
+// there is none.
+func headTag(r *git.Repository, commit plumbing.Hash) (string, error) {
+	tagRefs, err := r.Tags()
+	if err != nil {
+		return "", err
+	}
+	var name string
+	err = tagRefs.ForEach(func(tagRef *plumbing.Reference) error {
+		hash := tagRef.Hash()
+		// annotated tags point to a tag object rather than to the commit
+		if tag, err := r.TagObject(hash); err == nil {
+			c, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			hash = c.Hash
+		}
+		if hash == commit && name == "" {
+			name = tagRef.Name().Short()
+		}
+		return nil
+	})
+	return name, err
+}
+
+func githubRequest(method, url, contentType string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "token "+os.Getenv("GITHUB_TOKEN"))
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: unexpected status: %s", method, url, resp.Status)
+	}
+	return resp, nil
+}
+
+// uploadReleaseAsset attaches data to the GitHub Release of a tag. An existing
+// asset with the same name is replaced, so that re-running the benchmarks for
+// a release updates its results.
+func uploadReleaseAsset(apiURL, repository, tag, name string, data []byte) error {
+	resp, err := githubRequest(http.MethodGet, fmt.Sprintf("%s/repos/%s/releases/tags/%s", apiURL, repository, url.PathEscape(tag)), "", nil)
+	if err != nil {
+		return fmt.Errorf("unable to get the release for tag %s: %w", tag, err)
+	}
+	var release githubRelease
+	err = json.NewDecoder(resp.Body).Decode(&release)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("unable to decode the release for tag %s: %w", tag, err)
+	}
+	for _, asset := range release.Assets {
+		if asset.Name != name {
+			continue
+		}
+		resp, err := githubRequest(http.MethodDelete, fmt.Sprintf("%s/repos/%s/releases/assets/%d", apiURL, repository, asset.ID), "", nil)
+		if err != nil {
+			return fmt.Errorf("unable to delete the existing asset %s: %w", name, err)
+		}
+		resp.Body.Close()
+	}
+	// the upload URL is a URI template, e.g. ".../assets{?name,label}"
+	uploadURL := strings.SplitN(release.UploadURL, "{", 2)[0] + "?name=" + url.QueryEscape(name)
+	resp, err = githubRequest(http.MethodPost, uploadURL, "application/json", data)
+	if err != nil {
+		return fmt.Errorf("unable to upload asset %s: %w", name, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// publishReleaseResults uploads the results of HEAD to the GitHub Release of
+// the tag HEAD points to, if any. The asset is a baseline, which can be used
+// with "check -baseline". Like the other reporters, a failure is only logged.
+func publishReleaseResults(r *git.Repository, head plumbing.Hash, run *RunResult) {
+	if !releaseAsset {
+		return
+	}
+	tag, err := headTag(r, head)
+	if err != nil {
+		klog.ErrorS(err, "Failed to list tags")
+		return
+	}
+	if tag == "" {
+		klog.InfoS("HEAD is not tagged, not uploading results to a release")
+		return
+	}
+	data, err := encodeBaseline(&baseline{Benchci: currentVersion(), Platform: run.Platform, Results: run.Results})
+	if err != nil {
+		klog.ErrorS(err, "Failed to encode results")
+		return
+	}
+	name := fmt.Sprintf("benchci-%s.json", sanitizePathElem(tag))
+	if err := uploadReleaseAsset(githubAPIURL(), os.Getenv("GITHUB_REPOSITORY"), tag, name, data); err != nil {
+		klog.ErrorS(err, "Failed to upload results to the release", "tag", tag)
+		return
+	}
+	klog.InfoS("Uploaded results to the release", "tag", tag, "asset", name)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadReleaseAsset(t *testing.T) {
+	os.Setenv("GITHUB_TOKEN", "secret")
+	defer os.Unsetenv("GITHUB_TOKEN")
+	var requests []string
+	var uploaded string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/antrea-io/antrea/releases/tags/v1.4.0":
+			fmt.Fprintf(w, `{"upload_url": "%s/upload/1/assets{?name,label}", "assets": [{"id": 2, "name": "benchci-v1.4.0.json"}, {"id": 3, "name": "antctl"}]}`, server.URL)
+		case r.Method == http.MethodDelete && r.URL.Path == "/repos/antrea-io/antrea/releases/assets/2":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/1/assets":
+			data, _ := ioutil.ReadAll(r.Body)
+			uploaded = string(data)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	require.NoError(t, uploadReleaseAsset(server.URL, "antrea-io/antrea", "v1.4.0", "benchci-v1.4.0.json", []byte(`{}`)))
+	assert.Equal(t, []string{
+		"GET /repos/antrea-io/antrea/releases/tags/v1.4.0",
+		"DELETE /repos/antrea-io/antrea/releases/assets/2",
+		"POST /upload/1/assets?name=benchci-v1.4.0.json",
+	}, requests)
+	assert.Equal(t, `{}`, uploaded)
+
+	assert.Error(t, uploadReleaseAsset(server.URL, "antrea-io/antrea", "v1.5.0", "benchci-v1.5.0.json", []byte(`{}`)))
+}
+
+func TestValidateReleaseAsset(t *testing.T) {
+	defer func(enabled bool) { releaseAsset = enabled }(releaseAsset)
+	releaseAsset = false
+	assert.NoError(t, validateReleaseAsset())
+	releaseAsset = true
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+	assert.Error(t, validateReleaseAsset())
+	os.Setenv("GITHUB_TOKEN", "secret")
+	defer os.Unsetenv("GITHUB_TOKEN")
+	assert.NoError(t, validateReleaseAsset())
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var baselineCacheDir string
+
+func isRemoteBaseline(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// defaultBaselineCacheDir returns the directory in which remote baselines are
+// cached when --baseline-cache is not set.
+func defaultBaselineCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "benchci", "baselines"), nil
+}
+
+// fetchBaseline downloads a baseline, or returns its cached copy if it did not
+// change. The ETag and Last-Modified of the cached copy are used for a
+// conditional request. The cached copy is also used, with a warning, when the
+// server cannot be reached.
+func fetchBaseline(url, cacheDir string) ([]byte, error) {
+	sum := sha256.Sum256([]byte(url))
+	cachePath := filepath.Join(cacheDir, hex.EncodeToString(sum[:8])+".json")
+	cached, cacheErr := ioutil.ReadFile(cachePath)
+	etag, _ := ioutil.ReadFile(cachePath + ".etag")
+	lastModified, _ := ioutil.ReadFile(cachePath + ".last-modified")
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cacheErr == nil {
+		if len(etag) > 0 {
+			req.Header.Set("If-None-Match", string(etag))
+		}
+		if len(lastModified) > 0 {
+			req.Header.Set("If-Modified-Since", string(lastModified))
+		}
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		if cacheErr == nil {
+			klog.ErrorS(err, "Failed to fetch the baseline, using the cached copy", "url", url, "path", cachePath)
+			return cached, nil
+		}
+		return nil, fmt.Errorf("unable to fetch baseline '%s': %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && cacheErr == nil {
+		klog.InfoS("Baseline not modified, using the cached copy", "url", url, "path", cachePath)
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch baseline '%s': %s", url, resp.Status)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch baseline '%s': %w", url, err)
+	}
+	if err := cacheBaseline(cachePath, data, resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")); err != nil {
+		klog.ErrorS(err, "Failed to cache the baseline", "url", url, "path", cachePath)
+	}
+	return data, nil
+}
+
+func cacheBaseline(path string, data []byte, etag, lastModified string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// the validators are removed before the copy is replaced, so that they
+	// never describe an older copy
+	validators := map[string]string{".etag": etag, ".last-modified": lastModified}
+	for suffix := range validators {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	for suffix, value := range validators {
+		if value == "" {
+			continue
+		}
+		if err := writeFileAtomic(path+suffix, []byte(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchBaseline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	version := 1
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"v%d"`, version)
+		requests = append(requests, r.Header.Get("If-None-Match"))
+		if r.URL.Path != "/baselines/main.json" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprintf(w, `{"version": %d}`, version)
+	}))
+	url := server.URL + "/baselines/main.json"
+
+	data, err := fetchBaseline(url, dir)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version": 1}`, string(data))
+	data, err = fetchBaseline(url, dir)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version": 1}`, string(data))
+	version = 2
+	data, err = fetchBaseline(url, dir)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version": 2}`, string(data))
+	assert.Equal(t, []string{"", `"v1"`, `"v1"`}, requests)
+
+	_, err = fetchBaseline(server.URL+"/baselines/missing.json", dir)
+	assert.EqualError(t, err, fmt.Sprintf("unable to fetch baseline '%s/baselines/missing.json': 404 Not Found", server.URL))
+
+	// the cached copy is used when the server is down
+	server.Close()
+	data, err = fetchBaseline(url, dir)
+	require.NoError(t, err)
+	assert.Equal(t, `{"version": 2}`, string(data))
+	_, err = fetchBaseline(server.URL+"/baselines/missing.json", dir)
+	assert.Error(t, err)
+}
+
+func TestIsRemoteBaseline(t *testing.T) {
+	assert.True(t, isRemoteBaseline("https://ci.example.com/baselines/main.json"))
+	assert.True(t, isRemoteBaseline("http://localhost:8080/main.json"))
+	assert.False(t, isRemoteBaseline("main.json"))
+	assert.False(t, isRemoteBaseline("/tmp/https/main.json"))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"k8s.io/klog/v2"
+)
+
+var remoteRepo, remoteOld, remoteNew string
+
+func addRemoteCompareFlags(fs *flag.FlagSet) {
+	fs.StringVar(&remoteRepo, "repo", "", "`URL` of the git repository, e.g. https://github.com/antrea-io/antrea")
+	fs.StringVar(&remoteOld, "old", "", "`commit` (or ref) of the repository used as the comparison base")
+	fs.StringVar(&remoteNew, "new", "", "`commit` (or ref) of the repository compared with the base")
+}
+
+func gitCommand(dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		klog.InfoS("Exec command error", "err", stderr.String())
+		return nil, fmt.Errorf("failed to run '%s' command: %w", cmd, err)
+	}
+	return out, nil
+}
+
+// fetchCommit fetches a single commit of a remote repository, without its
+// history, and returns its hash.
+func fetchCommit(dir, repository, ref string) (plumbing.Hash, error) {
+	if _, err := gitCommand(dir, "fetch", "--quiet", "--depth=1", "--no-tags", repository, ref); err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("unable to fetch '%s': %w", ref, err)
+	}
+	out, err := gitCommand(dir, "rev-parse", "FETCH_HEAD^{commit}")
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("unable to resolve '%s': %w", ref, err)
+	}
+	return plumbing.NewHash(strings.TrimSpace(string(out))), nil
+}
+
+// runRemoteCompare compares two commits of a remote repository, fetched in a
+// temporary repository so that the current directory does not need to be a
+// checkout of it, which makes ad-hoc investigations possible from any machine.
+func runRemoteCompare() error {
+	if remoteRepo == "" || remoteOld == "" || remoteNew == "" {
+		return fmt.Errorf("--repo, --old and --new are required")
+	}
+	if offline {
+		return fmt.Errorf("unable to fetch from %s: %w", remoteRepo, errOffline)
+	}
+	tmp, err := ioutil.TempDir("", "benchci-remote-")
+	if err != nil {
+		return fmt.Errorf("unable to create the temporary repository: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	repository := filepath.Join(tmp, "repository")
+	if _, err := gitCommand("", "init", "--quiet", repository); err != nil {
+		return fmt.Errorf("unable to create the temporary repository: %w", err)
+	}
+	klog.InfoS("Fetching commits", "repo", remoteRepo, "old", remoteOld, "new", remoteNew)
+	oldCommit, err := fetchCommit(repository, remoteRepo, remoteOld)
+	if err != nil {
+		return err
+	}
+	newCommit, err := fetchCommit(repository, remoteRepo, remoteNew)
+	if err != nil {
+		return err
+	}
+
+	checkouts, err := newWorktrees(repository)
+	if err != nil {
+		return err
+	}
+	defer checkouts.remove()
+	oldTree := sourceTree{name: remoteOld, commit: oldCommit.String()}
+	if oldTree.dir, _, err = checkouts.checkout(oldCommit); err != nil {
+		return err
+	}
+	newTree := sourceTree{name: remoteNew, commit: newCommit.String()}
+	if newTree.dir, _, err = checkouts.checkout(newCommit); err != nil {
+		return err
+	}
+	return compareTrees(oldTree, newTree)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestFetchCommit(t *testing.T) {
+	remote, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(remote)
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = remote
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	require.NoError(t, ioutil.WriteFile(filepath.Join(remote, "version"), []byte("old"), 0644))
+	git("add", "version")
+	git("commit", "-q", "-m", "old")
+	old := plumbing.NewHash(git("rev-parse", "HEAD"))
+	git("tag", "v1.0.0")
+	git("commit", "-q", "--allow-empty", "-m", "new")
+	git("config", "uploadpack.allowAnySHA1InWant", "true")
+
+	local, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(local)
+	_, err = gitCommand(local, "init", "--quiet")
+	require.NoError(t, err)
+	url := "file://" + remote
+	hash, err := fetchCommit(local, url, "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, old, hash)
+	hash, err = fetchCommit(local, url, old.String())
+	require.NoError(t, err)
+	assert.Equal(t, old, hash)
+	_, err = fetchCommit(local, url, "v2.0.0")
+	assert.Error(t, err)
+}
+
+func TestRemoteCompareOffline(t *testing.T) {
+	defer func(o bool, repo, old, new string) {
+		offline, remoteRepo, remoteOld, remoteNew = o, repo, old, new
+	}(offline, remoteRepo, remoteOld, remoteNew)
+	offline, remoteRepo, remoteOld, remoteNew = true, "https://github.com/antrea-io/antrea", "main", "HEAD"
+	assert.True(t, errors.Is(runRemoteCompare(), errOffline))
+}
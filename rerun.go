@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// rerunRecord is a re-run requested by a user for a pull request.
+type rerunRecord struct {
+	User string    `json:"user"`
+	PR   int       `json:"pr"`
+	Time time.Time `json:"time"`
+}
+
+var (
+	rerunUser        string
+	rerunPR          int
+	rerunStatePath   string
+	rerunWindow      time.Duration
+	maxRerunsPerUser int
+	maxRerunsPerPR   int
+	// rerunBenchmark restricts the comparison to a single benchmark.
+	rerunBenchmark string
+)
+
+func addRerunFlags(fs *flag.FlagSet) {
+	fs.StringVar(&rerunUser, "user", "", "name of the user requesting the re-run")
+	fs.IntVar(&rerunPR, "pr", 0, "`number` of the pull request to re-run the comparison for")
+	fs.StringVar(&rerunStatePath, "rerun-state", "", "`path` of the file recording past re-runs, used for rate limiting")
+	fs.DurationVar(&rerunWindow, "rerun-window", 24*time.Hour, "`duration` over which re-runs are counted for rate limiting")
+	fs.IntVar(&maxRerunsPerUser, "max-reruns-per-user", 5, "maximum `number` of re-runs per user within the window")
+	fs.IntVar(&maxRerunsPerPR, "max-reruns-per-pr", 10, "maximum `number` of re-runs per pull request within the window")
+}
+
+func loadRerunRecords(path string) ([]rerunRecord, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read re-run state: %w", err)
+	}
+	var records []rerunRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("unable to parse re-run state '%s': %w", path, err)
+	}
+	return records, nil
+}
+
+// recordRerun adds a re-run for user and pr to the records which are still
+// within the window, or returns an error if a rate limit would be exceeded.
+func recordRerun(records []rerunRecord, user string, pr int, now time.Time) ([]rerunRecord, error) {
+	var recent []rerunRecord
+	var byUser, byPR int
+	for _, r := range records {
+		if now.Sub(r.Time) >= rerunWindow {
+			continue
+		}
+		recent = append(recent, r)
+		if r.User == user {
+			byUser++
+		}
+		if r.PR == pr {
+			byPR++
+		}
+	}
+	if byUser >= maxRerunsPerUser {
+		return nil, fmt.Errorf("rate limit exceeded: %s already requested %d re-runs in the last %s", user, byUser, rerunWindow)
+	}
+	if byPR >= maxRerunsPerPR {
+		return nil, fmt.Errorf("rate limit exceeded: %d re-runs were already requested for PR #%d in the last %s", byPR, pr, rerunWindow)
+	}
+	return append(recent, rerunRecord{User: user, PR: pr, Time: now}), nil
+}
+
+// reserveRerun records a re-run in the state file at path, unless a rate limit
+// would be exceeded. The state file is locked while it is read and written,
+// since re-runs may be requested concurrently.
+func reserveRerun(path, user string, pr int, now time.Time) error {
+	unlock, err := lockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	records, err := loadRerunRecords(path)
+	if err != nil {
+		return err
+	}
+	if records, err = recordRerun(records, user, pr, now); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("unable to write re-run state: %w", err)
+	}
+	return nil
+}
+
+// selectBenchmark returns the entry with the given unique name.
+func selectBenchmark(entries []Benchmark, uniqueName string) ([]Benchmark, error) {
+	for _, benchmark := range entries {
+		if benchmark.UniqueName == uniqueName {
+			return []Benchmark{benchmark}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown benchmark '%s'", uniqueName)
+}
+
+// runRerun runs a fresh comparison for the current HEAD, which is expected to
+// be the head of the pull request, after checking the per-user and per-PR rate
+// limits. It is meant to be called by the CI system when a "/benchci rerun
+// [benchmark]" comment is posted.
+func runRerun(fs *flag.FlagSet) error {
+	if fs.NArg() > 1 {
+		return fmt.Errorf("expected at most one benchmark name")
+	}
+	if rerunUser == "" || rerunPR == 0 || rerunStatePath == "" {
+		return fmt.Errorf("--user, --pr and --rerun-state are required")
+	}
+	if err := reserveRerun(rerunStatePath, rerunUser, rerunPR, time.Now()); err != nil {
+		return err
+	}
+	rerunBenchmark = fs.Arg(0)
+	klog.InfoS("Re-running comparison", "user", rerunUser, "pr", rerunPR, "benchmark", rerunBenchmark)
+	return run()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordRerun(t *testing.T) {
+	defer func(w time.Duration, user, pr int) {
+		rerunWindow, maxRerunsPerUser, maxRerunsPerPR = w, user, pr
+	}(rerunWindow, maxRerunsPerUser, maxRerunsPerPR)
+	rerunWindow = time.Hour
+	maxRerunsPerUser = 2
+	maxRerunsPerPR = 3
+	now := time.Date(2021, 10, 14, 12, 0, 0, 0, time.UTC)
+	records := []rerunRecord{
+		{User: "alice", PR: 1, Time: now.Add(-2 * time.Hour)},
+		{User: "alice", PR: 1, Time: now.Add(-30 * time.Minute)},
+		{User: "bob", PR: 1, Time: now.Add(-10 * time.Minute)},
+	}
+
+	updated, err := recordRerun(records, "alice", 2, now)
+	require.NoError(t, err)
+	assert.Len(t, updated, 3, "records outside of the window should be dropped")
+
+	_, err = recordRerun(updated, "alice", 2, now)
+	assert.Error(t, err, "per-user limit should be enforced")
+
+	updated, err = recordRerun(records, "carol", 1, now)
+	require.NoError(t, err)
+	_, err = recordRerun(updated, "dave", 1, now)
+	assert.Error(t, err, "per-PR limit should be enforced")
+}
+
+func TestReserveRerun(t *testing.T) {
+	defer func(w time.Duration, user, pr int) {
+		rerunWindow, maxRerunsPerUser, maxRerunsPerPR = w, user, pr
+	}(rerunWindow, maxRerunsPerUser, maxRerunsPerPR)
+	rerunWindow, maxRerunsPerUser, maxRerunsPerPR = time.Hour, 100, 5
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "reruns.json")
+
+	// concurrent requests do not lose each other's records
+	now := time.Now()
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	reserved := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if reserveRerun(path, "alice", 1, now) == nil {
+				mutex.Lock()
+				reserved++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 5, reserved)
+	records, err := loadRerunRecords(path)
+	require.NoError(t, err)
+	assert.Len(t, records, 5)
+}
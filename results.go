@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// RunResult is the outcome of running the benchmarks for one ref, along with
+// the metadata needed to interpret it later.
+type RunResult struct {
+	// Ref is the name of the ref which was benchmarked, e.g. "HEAD".
+	Ref string `json:"ref"`
+	// Commit is the hash of the commit which was benchmarked, if any.
+	Commit string `json:"commit,omitempty"`
+	// Start and End delimit the benchmarks, BuildDuration is the time spent
+	// in build hooks before Start.
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end"`
+	BuildDuration time.Duration `json:"buildDuration,omitempty"`
+	// Platform and GoVersion describe the environment of the run.
+	Platform  platformInfo `json:"platform"`
+	GoVersion string       `json:"goVersion,omitempty"`
+	Results   Set          `json:"results"`
+	// Errors are the reasons why benchmarks have no result, by unique name.
+	Errors map[string]string `json:"errors,omitempty"`
+	// Seed is the value of BENCHCI_SEED for the benchmarks, if any.
+	Seed int64 `json:"seed,omitempty"`
+	// Partial is true if some benchmarks were not run because the deadline
+	// was reached.
+	Partial bool `json:"partial,omitempty"`
+}
+
+func newRunResult(ref, commit string) *RunResult {
+	return &RunResult{
+		Ref:      ref,
+		Commit:   commit,
+		Start:    time.Now().UTC(),
+		Platform: currentPlatform(),
+		Results:  Set{},
+		Errors:   map[string]string{},
+	}
+}
+
+// fail records why a benchmark has no result.
+func (r *RunResult) fail(uniqueName string, err error) {
+	r.Errors[uniqueName] = err.Error()
+}
+
+// goVersion returns the output of "go version" in dir, or an empty string if
+// it cannot be run.
+func goVersion(cmdStr, dir string, env []string) string {
+	cmd := exec.Command(cmdStr, "version")
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
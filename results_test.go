@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestRunResultJSON(t *testing.T) {
+	run := newRunResult("HEAD", "abc")
+	run.GoVersion = "go version go1.16 linux/amd64"
+	run.Results["BenchmarkFoo"] = newBenchResult([]*parse.Benchmark{{Name: "BenchmarkFoo-4", N: 100, NsPerOp: 120, Measured: parse.NsPerOp}})
+	run.fail("BenchmarkBar", fmt.Errorf("exit status 1"))
+	run.End = run.Start
+
+	data, err := json.Marshal(run)
+	require.NoError(t, err)
+	var decoded RunResult
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, run, &decoded)
+	assert.Equal(t, map[string]string{"BenchmarkBar": "exit status 1"}, decoded.Errors)
+}
+
+func TestShowFailed(t *testing.T) {
+	head := &refResults{name: "HEAD", run: &RunResult{Errors: map[string]string{"BenchmarkFoo": "exit status 1"}}}
+	base := &refResults{name: "main", run: &RunResult{}}
+	var b bytes.Buffer
+	showFailed(&b, head, base, nil)
+	assert.Contains(t, b.String(), "| BenchmarkFoo | HEAD | exit status 1 |")
+
+	b.Reset()
+	showFailed(&b, base, nil)
+	assert.Empty(t, b.String())
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// runID identifies an invocation of benchci. It is included in logs, reports
+// and saved results, so that they can be correlated.
+var runID string
+
+// newRunID returns a random (version 4) UUID.
+func newRunID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("unable to generate run ID: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/benchmark/parse"
+)
+
+// validateScrape checks the scrape of a benchmark. The increases of the
+// counters are divided by the number of iterations, which is only known with
+// a fixed number of iterations, and must not include the work of benchmarks
+// run concurrently. With --offline, only loopback endpoints can be scraped.
+func validateScrape(benchmark *Benchmark) error {
+	s := benchmark.Scrape
+	if s == nil {
+		return nil
+	}
+	if s.URL == "" {
+		return fmt.Errorf("a url is required")
+	}
+	if offline && !isLoopbackURL(s.URL) {
+		return fmt.Errorf("unable to scrape %s: %w", s.URL, errOffline)
+	}
+	if jobs > 1 || parallelRefs {
+		return fmt.Errorf("unable to scrape with --jobs or --parallel-refs, other benchmarks would add to the counters")
+	}
+	if n, err := strconv.Atoi(strings.TrimSuffix(benchmark.Benchtime, "x")); err != nil || n < 1 || !strings.HasSuffix(benchmark.Benchtime, "x") {
+		return fmt.Errorf("a fixed number of iterations is required, e.g. a benchtime of 1000x")
+	}
+	if len(s.Counters) == 0 {
+		return fmt.Errorf("at least one counter is required")
+	}
+	units := map[string]bool{}
+	for _, counter := range s.Counters {
+		if counter.Metric == "" {
+			return fmt.Errorf("a metric is required for each counter")
+		}
+		unit := counterUnit(counter)
+		if builtinUnits[unit] {
+			return fmt.Errorf("unit '%s' is a builtin metric", unit)
+		}
+		if units[unit] {
+			return fmt.Errorf("duplicate unit '%s'", unit)
+		}
+		units[unit] = true
+	}
+	return nil
+}
+
+// isLoopbackURL returns true if the host of a URL is a loopback address.
+func isLoopbackURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if u.Hostname() == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+// counterUnit returns the name of the custom metric of a counter.
+func counterUnit(counter ScrapedCounter) string {
+	if counter.Unit != "" {
+		return counter.Unit
+	}
+	return counter.Metric + "/op"
+}
+
+// promSample is a sample of the Prometheus text exposition format.
+type promSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// parsePromSample parses a sample line, e.g.
+// `http_requests_total{method="post",code="200"} 1027 1395066363000`.
+func parsePromSample(line string) (promSample, error) {
+	s := promSample{labels: map[string]string{}}
+	end := strings.IndexAny(line, "{ \t")
+	if end <= 0 {
+		return s, fmt.Errorf("invalid sample '%s'", line)
+	}
+	s.name, line = line[:end], line[end:]
+	if line[0] == '{' {
+		line = line[1:]
+		for {
+			line = strings.TrimLeft(line, " \t,")
+			if strings.HasPrefix(line, "}") {
+				line = line[1:]
+				break
+			}
+			eq := strings.Index(line, `="`)
+			if eq <= 0 {
+				return s, fmt.Errorf("invalid labels of '%s'", s.name)
+			}
+			key := strings.TrimSpace(line[:eq])
+			line = line[eq+2:]
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(line); i++ {
+				c := line[i]
+				if c == '\\' && i+1 < len(line) {
+					i++
+					if line[i] == 'n' {
+						value.WriteByte('\n')
+					} else {
+						value.WriteByte(line[i])
+					}
+					continue
+				}
+				if c == '"' {
+					line, closed = line[i+1:], true
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return s, fmt.Errorf("invalid labels of '%s'", s.name)
+			}
+			s.labels[key] = value.String()
+		}
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return s, fmt.Errorf("no value for '%s'", s.name)
+	}
+	var err error
+	if s.value, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return s, fmt.Errorf("invalid value for '%s': %w", s.name, err)
+	}
+	return s, nil
+}
+
+// matchesLabels returns true if a sample has all the labels.
+func matchesLabels(s promSample, labels map[string]string) bool {
+	for k, v := range labels {
+		if s.labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCounters returns the value of each counter in the Prometheus text
+// exposition format, by unit: the sum of the matching series.
+func parseCounters(r io.Reader, counters []ScrapedCounter) (map[string]float64, error) {
+	values := map[string]float64{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, err := parsePromSample(line)
+		if err != nil {
+			return nil, err
+		}
+		for _, counter := range counters {
+			if s.name == counter.Metric && matchesLabels(s, counter.Labels) {
+				values[counterUnit(counter)] += s.value
+			}
+		}
+	}
+	return values, scanner.Err()
+}
+
+// scrapeClient has its own transport, since the default one rejects all the
+// requests with --offline while loopback endpoints can still be scraped, see
+// validateScrape. Proxies are not used for the local endpoints.
+var scrapeClient = &http.Client{Timeout: 30 * time.Second, Transport: &http.Transport{}}
+
+// scrapeCounters scrapes the endpoint and returns the value of each counter,
+// by unit. Counters without a series are not returned. The endpoint must be
+// served by a process which outlives the benchmark, e.g. started by a setup
+// hook.
+func scrapeCounters(s *Scrape) (map[string]float64, error) {
+	resp, err := scrapeClient.Get(s.URL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to scrape %s: %w", s.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unable to scrape %s: unexpected status: %s", s.URL, resp.Status)
+	}
+	values, err := parseCounters(resp.Body, s.Counters)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the metrics of %s: %w", s.URL, err)
+	}
+	return values, nil
+}
+
+// counterIncreases returns the increase of each counter per iteration of the
+// benchmark, the iterations being the ones of all its samples. A counter
+// which decreased was reset, e.g. because the component restarted, and its
+// increase is its value after the benchmark.
+func counterIncreases(before, after map[string]float64, iterations int) map[string]float64 {
+	increases := make(map[string]float64, len(after))
+	for unit, value := range after {
+		if value >= before[unit] {
+			value -= before[unit]
+		}
+		increases[unit] = value / float64(iterations)
+	}
+	return increases
+}
+
+// benchmarkIterations returns the number of iterations run for samples with a
+// fixed number of iterations: go test runs a single iteration before the N
+// iterations of each sample, unless N is 1.
+func benchmarkIterations(samples []*parse.Benchmark) int {
+	iterations := 0
+	for _, sample := range samples {
+		iterations += sample.N
+		if sample.N > 1 {
+			iterations++
+		}
+	}
+	return iterations
+}
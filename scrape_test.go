@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+const testMetrics = `# HELP ovs_flow_mods_total Number of flow modifications.
+# TYPE ovs_flow_mods_total counter
+ovs_flow_mods_total{table="0",op="add"} 10
+ovs_flow_mods_total{table="1",op="add"} 5
+ovs_flow_mods_total{table="1",op="delete"} 2
+conntrack_ops_total{desc="a \"quoted\", label"} 7 1395066363000
+go_goroutines 12
+`
+
+func TestParsePromSample(t *testing.T) {
+	s, err := parsePromSample(`conntrack_ops_total{desc="a \"quoted\", label",zone="1"} 7 1395066363000`)
+	require.NoError(t, err)
+	assert.Equal(t, promSample{name: "conntrack_ops_total", labels: map[string]string{"desc": `a "quoted", label`, "zone": "1"}, value: 7}, s)
+
+	s, err = parsePromSample("go_goroutines 12")
+	require.NoError(t, err)
+	assert.Equal(t, promSample{name: "go_goroutines", labels: map[string]string{}, value: 12}, s)
+
+	for _, line := range []string{"go_goroutines", `foo{bar="baz} 1`, "foo bar"} {
+		_, err := parsePromSample(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestParseCounters(t *testing.T) {
+	values, err := parseCounters(strings.NewReader(testMetrics), []ScrapedCounter{
+		{Metric: "ovs_flow_mods_total"},
+		{Metric: "ovs_flow_mods_total", Labels: map[string]string{"op": "add", "table": "1"}, Unit: "table1-adds/op"},
+		{Metric: "conntrack_ops_total", Unit: "conntrack-ops/op"},
+		{Metric: "unknown_total"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"ovs_flow_mods_total/op": 17, "table1-adds/op": 5, "conntrack-ops/op": 7}, values)
+}
+
+func TestScrapeCounters(t *testing.T) {
+	flowMods := 10
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metrics" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "ovs_flow_mods_total %d\n", flowMods)
+	}))
+	defer server.Close()
+	scrape := &Scrape{URL: server.URL + "/metrics", Counters: []ScrapedCounter{{Metric: "ovs_flow_mods_total", Unit: "flow-mods/op"}}}
+
+	before, err := scrapeCounters(scrape)
+	require.NoError(t, err)
+	flowMods = 414
+	after, err := scrapeCounters(scrape)
+	require.NoError(t, err)
+	// 2 samples of 100 iterations, each after a single iteration
+	iterations := benchmarkIterations([]*parse.Benchmark{{N: 100}, {N: 100}})
+	assert.Equal(t, 202, iterations)
+	assert.Equal(t, map[string]float64{"flow-mods/op": 2}, counterIncreases(before, after, iterations))
+	// the counter was reset
+	assert.Equal(t, map[string]float64{"flow-mods/op": 0.5}, counterIncreases(after, map[string]float64{"flow-mods/op": 101}, iterations))
+
+	scrape.URL = server.URL + "/unknown"
+	_, err = scrapeCounters(scrape)
+	assert.EqualError(t, err, fmt.Sprintf("unable to scrape %s/unknown: unexpected status: 404 Not Found", server.URL))
+}
+
+func TestValidateScrape(t *testing.T) {
+	defer func(o, p bool, j int) { offline, parallelRefs, jobs = o, p, j }(offline, parallelRefs, jobs)
+	offline, parallelRefs, jobs = false, false, 1
+	validate := func(benchtime string, s *Scrape) error {
+		return validateScrape(&Benchmark{Scrape: s, BenchmarkConfiguration: BenchmarkConfiguration{Benchtime: benchtime}})
+	}
+	assert.NoError(t, validate("1s", nil))
+	assert.NoError(t, validate("1000x", &Scrape{URL: "http://10.0.0.1:10350/metrics", Counters: []ScrapedCounter{{Metric: "foo_total"}, {Metric: "foo_total", Unit: "foos/op"}}}))
+	assert.EqualError(t, validate("1000x", &Scrape{Counters: []ScrapedCounter{{Metric: "foo_total"}}}), "a url is required")
+	assert.EqualError(t, validate("1s", &Scrape{URL: "http://127.0.0.1", Counters: []ScrapedCounter{{Metric: "foo_total"}}}), "a fixed number of iterations is required, e.g. a benchtime of 1000x")
+	assert.EqualError(t, validate("1000x", &Scrape{URL: "http://127.0.0.1"}), "at least one counter is required")
+	assert.EqualError(t, validate("1000x", &Scrape{URL: "http://127.0.0.1", Counters: []ScrapedCounter{{Metric: "foo_total"}, {Metric: "foo_total"}}}), "duplicate unit 'foo_total/op'")
+	assert.EqualError(t, validate("1000x", &Scrape{URL: "http://127.0.0.1", Counters: []ScrapedCounter{{Metric: "foo_total", Unit: "ns/op"}}}), "unit 'ns/op' is a builtin metric")
+
+	// benchmarks run concurrently would add to the counters
+	jobs = 2
+	assert.EqualError(t, validate("1000x", &Scrape{URL: "http://127.0.0.1", Counters: []ScrapedCounter{{Metric: "foo_total"}}}), "unable to scrape with --jobs or --parallel-refs, other benchmarks would add to the counters")
+	jobs, parallelRefs = 1, true
+	assert.EqualError(t, validate("1000x", &Scrape{URL: "http://127.0.0.1", Counters: []ScrapedCounter{{Metric: "foo_total"}}}), "unable to scrape with --jobs or --parallel-refs, other benchmarks would add to the counters")
+	parallelRefs = false
+
+	// only loopback endpoints can be scraped with --offline
+	offline = true
+	assert.NoError(t, validate("1000x", &Scrape{URL: "http://localhost:10350/metrics", Counters: []ScrapedCounter{{Metric: "foo_total"}}}))
+	assert.NoError(t, validate("1000x", &Scrape{URL: "http://[::1]:10350/metrics", Counters: []ScrapedCounter{{Metric: "foo_total"}}}))
+	err := validate("1000x", &Scrape{URL: "http://10.0.0.1:10350/metrics", Counters: []ScrapedCounter{{Metric: "foo_total"}}})
+	assert.True(t, errors.Is(err, errOffline), err)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"strconv"
+)
+
+// seed is exported to the benchmarks of all refs as BENCHCI_SEED, so that
+// benchmarks with randomized inputs run with the same inputs for the base ref
+// and HEAD. It is not exported if 0.
+var seed int64
+
+// seedEnv returns the environment exporting the seed, if any.
+func seedEnv() []string {
+	if seed == 0 {
+		return nil
+	}
+	return []string{"BENCHCI_SEED=" + strconv.FormatInt(seed, 10)}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedEnv(t *testing.T) {
+	defer func(s int64) { seed = s }(seed)
+	seed = 0
+	assert.Empty(t, seedEnv())
+	noSeed, err := cacheFingerprint("", "go1.22.5")
+	require.NoError(t, err)
+
+	seed = 42
+	benchmark := &Benchmark{UniqueName: "BenchmarkFoo", Commands: []CommandStep{
+		{Args: []string{"sh", "-c", "echo \"BenchmarkFoo-4 100 $BENCHCI_SEED\""}, Output: true},
+	}}
+	out, err := runPipeline("go", "", refVars{Ref: "HEAD", env: seedEnv()}, benchmark)
+	require.NoError(t, err)
+	assert.Equal(t, "BenchmarkFoo-4 100 42\n", string(out))
+
+	// the results cached with another seed are not used
+	withSeed, err := cacheFingerprint("", "go1.22.5")
+	require.NoError(t, err)
+	assert.NotEqual(t, noSeed, withSeed)
+}
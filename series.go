@@ -0,0 +1,222 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"k8s.io/klog/v2"
+)
+
+var seriesFrom, seriesTo string
+
+func addSeriesFlags(fs *flag.FlagSet) {
+	fs.StringVar(&seriesFrom, "from", "main", "`ref` of the commit the series is based on")
+	fs.StringVar(&seriesTo, "to", "HEAD", "`ref` of the last commit of the series")
+}
+
+// seriesCommits returns the commits from the merge base of "from" and "to" to
+// "to" (both included), oldest first, following first parents. The merge base
+// is used so that a branch can be compared with a "from" that moved on since
+// the branch was created.
+func seriesCommits(r *git.Repository, from, to plumbing.Hash) ([]*object.Commit, error) {
+	var commits []*object.Commit
+	c, err := r.CommitObject(to)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get commit %v: %w", to, err)
+	}
+	f, err := r.CommitObject(from)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get commit %v: %w", from, err)
+	}
+	bases, err := f.MergeBase(c)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the merge base of %s and %s: %w", seriesFrom, seriesTo, err)
+	}
+	if len(bases) == 0 {
+		return nil, fmt.Errorf("%s and %s have no common ancestor", seriesFrom, seriesTo)
+	}
+	isBase := func(hash plumbing.Hash) bool {
+		for _, b := range bases {
+			if b.Hash == hash {
+				return true
+			}
+		}
+		return false
+	}
+	for {
+		commits = append(commits, c)
+		if isBase(c.Hash) {
+			break
+		}
+		if c.NumParents() == 0 {
+			return nil, fmt.Errorf("the merge base of %s and %s is not a first parent of %s", seriesFrom, seriesTo, seriesTo)
+		}
+		hash := c.Hash
+		if c, err = c.Parent(0); err != nil {
+			return nil, fmt.Errorf("unable to get the parent of commit %v: %w", hash, err)
+		}
+	}
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// seriesStep is the result of a benchmark for one commit of the series.
+type seriesStep struct {
+	commit  *object.Commit
+	results Set
+}
+
+func commitTitle(c *object.Commit) string {
+	title := strings.SplitN(strings.TrimSpace(c.Message), "\n", 2)[0]
+	if len(title) > 50 {
+		title = title[:47] + "..."
+	}
+	return fmt.Sprintf("%s %s", c.Hash.String()[:8], title)
+}
+
+// showSeries prints, for each benchmark, its results for every commit of the
+// series along with the change compared with the previous commit. It returns
+// true if a commit introduced a regression.
+func showSeries(w io.Writer, steps []seriesStep) bool {
+	fmt.Fprintln(w, "\nSeries")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 6))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetRowLine(true)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Name", "Commit", "NsPerOp", "AllocedBytesPerOp", "Delta NsPerOp", "Delta AllocedBytesPerOp"})
+	table.SetAutoMergeCellsByColumnIndex([]int{0})
+
+	var regressions []string
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" {
+			continue
+		}
+		var prev *benchResult
+		for _, step := range steps {
+			cur, ok := step.results[benchmark.UniqueName]
+			if !ok {
+				table.Append([]string{benchmark.UniqueName, commitTitle(step.commit), "-", "-", "-", "-"})
+				prev = nil
+				continue
+			}
+			row := []string{benchmark.UniqueName, commitTitle(step.commit), numbers.float(cur.NsPerOp) + " ns/op", numbers.uint(cur.AllocedBytesPerOp) + " B/op", "-", "-"}
+			colors := make([]tablewriter.Colors, len(row))
+			if prev != nil {
+				result := newResult(benchmark, cur, prev)
+				row[4], colors[4] = signedRatio(result.RatioNsPerOp), generateColor(result.RatioNsPerOp)
+				row[5], colors[5] = signedRatio(result.RatioAllocedBytesPerOp), generateColor(result.RatioAllocedBytesPerOp)
+				if isRegression(result) {
+					regressions = append(regressions, fmt.Sprintf("%s: %s", benchmark.UniqueName, commitTitle(step.commit)))
+				}
+			}
+			table.Rich(row, colors)
+			prev = cur
+		}
+	}
+	table.Render()
+
+	if len(regressions) > 0 {
+		fmt.Fprintln(w, "\nRegressions introduced by")
+		fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 25))
+		for _, r := range regressions {
+			fmt.Fprintln(w, r)
+		}
+		fmt.Fprintln(w)
+	}
+	return len(regressions) > 0
+}
+
+func signedRatio(ratio float64) string {
+	return numbers.signedPercent(ratio)
+}
+
+// runSeries benchmarks every commit between --from and --to, so that a
+// regression can be attributed to a specific commit of a series.
+func runSeries() error {
+	if outputFormat != outputText {
+		return fmt.Errorf("series only supports the %s output format", outputText)
+	}
+	if err := parseBenchmarks(); err != nil {
+		return err
+	}
+	r, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("unable to open the git repository: %w", err)
+	}
+	from, err := r.ResolveRevision(plumbing.Revision(seriesFrom))
+	if err != nil {
+		return fmt.Errorf("unable to resolve '%s': %w", seriesFrom, err)
+	}
+	to, err := r.ResolveRevision(plumbing.Revision(seriesTo))
+	if err != nil {
+		return fmt.Errorf("unable to resolve '%s': %w", seriesTo, err)
+	}
+	commits, err := seriesCommits(r, *from, *to)
+	if err != nil {
+		return err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return fmt.Errorf("unable to get a worktree based on the given fs: %w", err)
+	}
+	s, err := w.Status()
+	if err != nil {
+		return fmt.Errorf("unable to get the working tree status: %w", err)
+	}
+	if !s.IsClean() {
+		return fmt.Errorf("the repository is dirty: commit all changes before running")
+	}
+	checkouts, err := newWorktrees(".")
+	if err != nil {
+		return err
+	}
+	defer checkouts.remove()
+
+	if err := discoverBenchmarks(""); err != nil {
+		return err
+	}
+	if err := updateBenchmarks(); err != nil {
+		return err
+	}
+	if err := checkOverlaps(""); err != nil {
+		return err
+	}
+	if err := prepareArtifactsDir(); err != nil {
+		return err
+	}
+
+	steps := make([]seriesStep, 0, len(commits))
+	for _, c := range commits {
+		dir, _, err := checkouts.checkout(c.Hash)
+		if err != nil {
+			return err
+		}
+		klog.InfoS("Run Benchmark", "commitHash", c.Hash, "dir", dir)
+		run, err := runBenchmarks(dir, c.Hash.String()[:8], c.Hash.String(), "", nil)
+		// a series may be long, do not keep a checkout of every commit
+		checkouts.discard(c.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to run a benchmark: %w", err)
+		}
+		steps = append(steps, seriesStep{commit: c, results: run.Results})
+	}
+
+	regression := showSeries(report, steps)
+	// several commits may make a benchmark worse while each stays under the
+	// threshold
+	if drift := showDrift(report, steps); regression || drift {
+		return fmt.Errorf("some commits of the series make benchmarks worse")
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+	gogit "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+func TestShowSeries(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	contention := false
+	benchmarks.Benchmarks = []Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}},
+	}
+	commit := func(hash, message string) *object.Commit {
+		return &object.Commit{Hash: plumbing.NewHash(hash), Message: message}
+	}
+	set := func(nsPerOp float64) Set {
+		return Set{"BenchmarkFoo": &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkFoo-4", NsPerOp: nsPerOp}}}
+	}
+	steps := []seriesStep{
+		{commit("1111111111111111111111111111111111111111", "Base"), set(100)},
+		{commit("2222222222222222222222222222222222222222", "Refactor"), set(105)},
+		{commit("3333333333333333333333333333333333333333", "Add cache\n\nDetails"), set(150)},
+	}
+
+	var b bytes.Buffer
+	assert.True(t, showSeries(&b, steps))
+	assert.Contains(t, b.String(), "+42.86%")
+	assert.Contains(t, b.String(), "BenchmarkFoo: 33333333 Add cache\n")
+	assert.NotContains(t, b.String(), "BenchmarkFoo: 22222222")
+
+	b.Reset()
+	assert.False(t, showSeries(&b, steps[:2]))
+}
+
+func TestSeriesCommits(t *testing.T) {
+	repository, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(repository)
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repository
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(message string) plumbing.Hash {
+		git("commit", "-q", "--allow-empty", "-m", message)
+		return plumbing.NewHash(git("rev-parse", "HEAD"))
+	}
+	git("init", "-q")
+	base := commit("base")
+	git("checkout", "-q", "-b", "feature")
+	first := commit("first")
+	second := commit("second")
+	git("checkout", "-q", "-")
+	ahead := commit("main")
+	r, err := gogit.PlainOpen(repository)
+	require.NoError(t, err)
+	hashes := func(commits []*object.Commit) []plumbing.Hash {
+		var hashes []plumbing.Hash
+		for _, c := range commits {
+			hashes = append(hashes, c.Hash)
+		}
+		return hashes
+	}
+
+	commits, err := seriesCommits(r, base, second)
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{base, first, second}, hashes(commits))
+	// the series of a diverged branch starts at the merge base
+	commits, err = seriesCommits(r, ahead, second)
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{base, first, second}, hashes(commits))
+	commits, err = seriesCommits(r, ahead, ahead)
+	require.NoError(t, err)
+	assert.Equal(t, []plumbing.Hash{ahead}, hashes(commits))
+}
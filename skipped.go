@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// The kinds of exclusions, i.e. why a benchmark was not compared.
+const (
+	// exclusionSkip is a benchmark with the skip field.
+	exclusionSkip = "skip"
+	// exclusionVersion is a benchmark whose versionRequirement is not met by
+	// the latest release.
+	exclusionVersion = "version"
+	// exclusionFilter is a benchmark which was not selected, e.g. by the
+	// re-run of a single benchmark.
+	exclusionFilter = "filter"
+	// exclusionMissing is a benchmark without a result for a ref, e.g.
+	// because it does not exist or failed at that ref.
+	exclusionMissing = "missing"
+)
+
+// exclusion is a benchmark which was not compared with a ref, or with any ref
+// if Ref is empty, so that the coverage of a report can be audited.
+type exclusion struct {
+	Name   string `json:"name"`
+	Ref    string `json:"ref,omitempty"`
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+}
+
+// filteredBenchmarks are the configured benchmarks which were not selected to
+// be run.
+var filteredBenchmarks []exclusion
+
+// filterExclusions returns the exclusions of the benchmarks of all which are
+// not in selected.
+func filterExclusions(all, selected []Benchmark, reason string) []exclusion {
+	kept := map[string]bool{}
+	for _, benchmark := range selected {
+		kept[benchmark.UniqueName] = true
+	}
+	var exclusions []exclusion
+	for _, benchmark := range all {
+		if !kept[benchmark.UniqueName] {
+			exclusions = append(exclusions, exclusion{Name: benchmark.UniqueName, Kind: exclusionFilter, Reason: reason})
+		}
+	}
+	return exclusions
+}
+
+// exclusions returns the benchmarks which were not compared, in the order of
+// the configuration: the filtered and skipped benchmarks, then for each ref
+// the benchmarks without a result.
+func exclusions(head, base, release *refResults) []exclusion {
+	excluded := append([]exclusion(nil), filteredBenchmarks...)
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" {
+			excluded = append(excluded, exclusion{Name: benchmark.UniqueName, Kind: exclusionSkip, Reason: benchmark.Skip})
+		}
+	}
+	for _, ref := range []*refResults{head, base, release} {
+		if ref == nil {
+			continue
+		}
+		for _, benchmark := range benchmarks.Benchmarks {
+			if _, ok := ref.run.Results[benchmark.UniqueName]; ok || benchmark.Skip != "" {
+				continue
+			}
+			e := exclusion{Name: benchmark.UniqueName, Ref: ref.name, Kind: exclusionMissing, Reason: ref.run.Errors[benchmark.UniqueName]}
+			if ref.latestRelease && !versionRequired(benchmark.VersionRequirement, ref.ref) {
+				e.Kind, e.Reason = exclusionVersion, fmt.Sprintf("requires version %s", strings.TrimSpace(benchmark.VersionRequirement))
+			} else if e.Reason == "" {
+				e.Reason = "no result"
+			}
+			excluded = append(excluded, e)
+		}
+	}
+	return excluded
+}
+
+// exclusionRows returns the rows of the table of exclusions: the name, the ref
+// ("all" for all refs) and the reason.
+func exclusionRows(excluded []exclusion) [][]string {
+	rows := make([][]string, 0, len(excluded))
+	for _, e := range excluded {
+		ref := e.Ref
+		if ref == "" {
+			ref = "all"
+		}
+		reason := e.Reason
+		if e.Kind != exclusionSkip {
+			reason = fmt.Sprintf("%s: %s", e.Kind, e.Reason)
+		}
+		rows = append(rows, []string{e.Name, ref, reason})
+	}
+	return rows
+}
+
+func showSkipped(w io.Writer, excluded []exclusion) {
+	if len(excluded) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nSkipped")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 7))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Name", "Ref", "Reason"})
+	table.AppendBulk(exclusionRows(excluded))
+	table.Render()
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestExclusions(t *testing.T) {
+	defer func(b []Benchmark, f []exclusion) { benchmarks.Benchmarks, filteredBenchmarks = b, f }(benchmarks.Benchmarks, filteredBenchmarks)
+	all := []Benchmark{
+		{UniqueName: "BenchmarkFoo"},
+		{UniqueName: "BenchmarkBar", VersionRequirement: ">=v1.5.0"},
+		{UniqueName: "BenchmarkBaz", Skip: "flaky"},
+		{UniqueName: "BenchmarkNew"},
+		{UniqueName: "BenchmarkOther"},
+	}
+	benchmarks.Benchmarks = all[:4]
+	filteredBenchmarks = filterExclusions(all, benchmarks.Benchmarks, "re-run of BenchmarkFoo only")
+
+	result := &benchResult{Benchmark: &parse.Benchmark{NsPerOp: 100}}
+	head := &refResults{name: "HEAD", ref: "HEAD", run: &RunResult{Results: Set{"BenchmarkFoo": result, "BenchmarkBar": result, "BenchmarkNew": result}}}
+	base := &refResults{name: "main", ref: "main", run: &RunResult{
+		Results: Set{"BenchmarkFoo": result, "BenchmarkBar": result},
+		Errors:  map[string]string{"BenchmarkNew": "expected exactly one benchmark result, got 0"},
+	}}
+	release := &refResults{name: "v1.4.0", ref: "v1.4.0", latestRelease: true, run: &RunResult{Results: Set{"BenchmarkFoo": result}}}
+
+	excluded := exclusions(head, base, release)
+	assert.Equal(t, []exclusion{
+		{Name: "BenchmarkOther", Kind: exclusionFilter, Reason: "re-run of BenchmarkFoo only"},
+		{Name: "BenchmarkBaz", Kind: exclusionSkip, Reason: "flaky"},
+		{Name: "BenchmarkNew", Ref: "main", Kind: exclusionMissing, Reason: "expected exactly one benchmark result, got 0"},
+		{Name: "BenchmarkBar", Ref: "v1.4.0", Kind: exclusionVersion, Reason: "requires version >=v1.5.0"},
+		{Name: "BenchmarkNew", Ref: "v1.4.0", Kind: exclusionMissing, Reason: "no result"},
+	}, excluded)
+
+	var b bytes.Buffer
+	showSkipped(&b, excluded)
+	assert.Contains(t, b.String(), "| BenchmarkBaz   | all    | flaky ")
+	assert.Contains(t, b.String(), "| BenchmarkBar   | v1.4.0 | version: requires version >=v1.5.0 ")
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// parseCPUList parses a list of CPUs in the sysfs and taskset format, e.g.
+// "0-3,8".
+func parseCPUList(s string) ([]int, error) {
+	var cpus []int
+	for _, part := range strings.Split(strings.TrimSpace(s), ",") {
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU list '%s'", s)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("invalid CPU list '%s'", s)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+func formatCPUList(cpus []int) string {
+	parts := make([]string, 0, len(cpus))
+	for _, cpu := range cpus {
+		parts = append(parts, strconv.Itoa(cpu))
+	}
+	return strings.Join(parts, ",")
+}
+
+// smtTopology maps each online CPU to the first CPU of its core, i.e. SMT
+// siblings map to the same CPU.
+type smtTopology map[int]int
+
+func readSMTTopology(sysfs string) (smtTopology, error) {
+	online, err := readSysFile(filepath.Join(sysfs, "devices/system/cpu/online"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the online CPUs: %w", err)
+	}
+	cpus, err := parseCPUList(online)
+	if err != nil {
+		return nil, err
+	}
+	topology := smtTopology{}
+	for _, cpu := range cpus {
+		siblings, err := readSysFile(filepath.Join(sysfs, fmt.Sprintf("devices/system/cpu/cpu%d/topology/thread_siblings_list", cpu)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the SMT siblings of CPU %d: %w", cpu, err)
+		}
+		list, err := parseCPUList(siblings)
+		if err != nil || len(list) == 0 {
+			return nil, fmt.Errorf("invalid SMT siblings for CPU %d: '%s'", cpu, siblings)
+		}
+		topology[cpu] = list[0]
+	}
+	return topology, nil
+}
+
+// cores returns the number of cores and the maximum number of threads per
+// core.
+func (t smtTopology) cores() (int, int) {
+	threads := map[int]int{}
+	maxThreads := 0
+	for _, core := range t {
+		threads[core]++
+		if threads[core] > maxThreads {
+			maxThreads = threads[core]
+		}
+	}
+	return len(threads), maxThreads
+}
+
+// oneCPUPerCore returns the first of the candidate CPUs of each core, in
+// order.
+func (t smtTopology) oneCPUPerCore(candidates []int) []int {
+	sorted := append([]int{}, candidates...)
+	sort.Ints(sorted)
+	used := map[int]bool{}
+	var cpus []int
+	for _, cpu := range sorted {
+		core, ok := t[cpu]
+		if !ok || used[core] {
+			continue
+		}
+		used[core] = true
+		cpus = append(cpus, cpu)
+	}
+	return cpus
+}
+
+// coreCPUs returns the first CPU of each core of the machine, in order.
+func (t smtTopology) coreCPUs() []int {
+	online := make([]int, 0, len(t))
+	for cpu := range t {
+		online = append(online, cpu)
+	}
+	return t.oneCPUPerCore(online)
+}
+
+// avoidsSMTSiblings returns true if all the benchmarks which are run avoid SMT
+// siblings.
+func avoidsSMTSiblings() bool {
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip == "" && (benchmark.AvoidSMTSiblings == nil || !*benchmark.AvoidSMTSiblings) {
+			return false
+		}
+	}
+	return len(benchmarks.Benchmarks) > 0
+}
+
+// maxCPU returns the largest GOMAXPROCS value of a -cpu list.
+func maxCPU(list string) (int, error) {
+	max := 0
+	for _, s := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n <= 0 {
+			return 0, fmt.Errorf("invalid cpu list '%s'", list)
+		}
+		if n > max {
+			max = n
+		}
+	}
+	return max, nil
+}
+
+// smtExec returns the command pinning the test binary to one CPU per core, so
+// that the threads of the benchmark never run on SMT siblings of each other.
+// The CPUs are taken from the NUMA node of the benchmark if it is set. It
+// returns an empty string if avoidSMTSiblings is not set.
+func smtExec(sysfs string, benchmark *Benchmark) (string, error) {
+	if benchmark.AvoidSMTSiblings == nil || !*benchmark.AvoidSMTSiblings {
+		return "", nil
+	}
+	topology, err := readSMTTopology(sysfs)
+	if err != nil {
+		return "", err
+	}
+	var candidates []int
+	if benchmark.NumaNode != nil {
+		list, err := readSysFile(filepath.Join(sysfs, fmt.Sprintf("devices/system/node/node%d/cpulist", *benchmark.NumaNode)))
+		if err != nil {
+			return "", fmt.Errorf("unable to read the CPUs of NUMA node %d: %w", *benchmark.NumaNode, err)
+		}
+		if candidates, err = parseCPUList(list); err != nil {
+			return "", err
+		}
+	} else {
+		for cpu := range topology {
+			candidates = append(candidates, cpu)
+		}
+	}
+	needed, err := maxCPU(benchmark.Cpu)
+	if err != nil {
+		return "", err
+	}
+	cpus := topology.oneCPUPerCore(candidates)
+	if len(cpus) < needed {
+		return "", fmt.Errorf("benchmark '%s' needs %d CPUs but only %d cores are available", benchmark.UniqueName, needed, len(cpus))
+	}
+	cpus = cpus[:needed]
+	cores, threads := topology.cores()
+	klog.InfoS("Pinning benchmark to one CPU per core", "benchmark", benchmark.UniqueName, "cpus", formatCPUList(cpus), "cores", cores, "threadsPerCore", threads)
+	return "taskset -c " + formatCPUList(cpus), nil
+}
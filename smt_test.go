@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("0-3,8,10-11\n")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2, 3, 8, 10, 11}, cpus)
+	assert.Equal(t, "0,1,2,3,8,10,11", formatCPUList(cpus))
+
+	for _, list := range []string{"a", "3-1", "0-x"} {
+		_, err := parseCPUList(list)
+		assert.Error(t, err, list)
+	}
+}
+
+// writeSysfs creates a sysfs tree with 2 NUMA nodes of 2 cores each, each
+// core having 2 threads: CPU n and CPU n+4 are siblings.
+func writeSysfs(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	write := func(path, contents string) {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents+"\n"), 0644))
+	}
+	write("devices/system/cpu/online", "0-7")
+	for cpu := 0; cpu < 8; cpu++ {
+		write(fmt.Sprintf("devices/system/cpu/cpu%d/topology/thread_siblings_list", cpu), fmt.Sprintf("%d,%d", cpu%4, cpu%4+4))
+	}
+	write("devices/system/node/node0/cpulist", "0-1,4-5")
+	write("devices/system/node/node1/cpulist", "2-3,6-7")
+	return dir
+}
+
+func TestSMTTopology(t *testing.T) {
+	sysfs := writeSysfs(t)
+	defer os.RemoveAll(sysfs)
+	topology, err := readSMTTopology(sysfs)
+	require.NoError(t, err)
+	cores, threads := topology.cores()
+	assert.Equal(t, 4, cores)
+	assert.Equal(t, 2, threads)
+	assert.Equal(t, []int{0, 1, 2, 3}, topology.oneCPUPerCore([]int{7, 6, 5, 4, 3, 2, 1, 0}))
+	assert.Equal(t, []int{2, 3}, topology.oneCPUPerCore([]int{2, 3, 6, 7}))
+}
+
+func TestSMTExec(t *testing.T) {
+	sysfs := writeSysfs(t)
+	defer os.RemoveAll(sysfs)
+	avoid, node := true, 1
+	benchmark := &Benchmark{UniqueName: "foo", BenchmarkConfiguration: BenchmarkConfiguration{Cpu: "1,2"}}
+
+	execCmd, err := smtExec(sysfs, benchmark)
+	require.NoError(t, err)
+	assert.Equal(t, "", execCmd)
+
+	benchmark.AvoidSMTSiblings = &avoid
+	execCmd, err = smtExec(sysfs, benchmark)
+	require.NoError(t, err)
+	assert.Equal(t, "taskset -c 0,1", execCmd)
+
+	benchmark.NumaNode = &node
+	execCmd, err = smtExec(sysfs, benchmark)
+	require.NoError(t, err)
+	assert.Equal(t, "taskset -c 2,3", execCmd)
+
+	benchmark.Cpu = "4"
+	_, err = smtExec(sysfs, benchmark)
+	assert.EqualError(t, err, "benchmark 'foo' needs 4 CPUs but only 2 cores are available")
+}
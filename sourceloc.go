@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+var benchmarkFuncName = regexp.MustCompile(`^Benchmark\w*$`)
+
+// sourceLocation is the location of a benchmark function, relative to the root
+// of the source tree.
+type sourceLocation struct {
+	file string
+	line int
+}
+
+func (l sourceLocation) String() string {
+	return fmt.Sprintf("%s:%d", l.file, l.line)
+}
+
+// benchmarkFunc returns the name of the top-level benchmark function matched
+// by a -bench pattern, or an empty string if the pattern is not a plain name.
+func benchmarkFunc(pattern string) string {
+	name := strings.SplitN(pattern, "/", 2)[0]
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "^"), "$")
+	if !benchmarkFuncName.MatchString(name) {
+		return ""
+	}
+	return name
+}
+
+// findFunc looks for the declaration of a function in the test files of a
+// package directory.
+func findFunc(pkgDir, name string) (string, int, error) {
+	files, err := filepath.Glob(filepath.Join(pkgDir, "*_test.go"))
+	if err != nil {
+		return "", 0, err
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			return "", 0, err
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Name.Name == name {
+				return file, fset.Position(fn.Pos()).Line, nil
+			}
+		}
+	}
+	return "", 0, fmt.Errorf("function %s not found in %s", name, pkgDir)
+}
+
+// benchmarkLocation resolves a benchmark to the location of its function in
+// the source tree rooted at dir.
+func benchmarkLocation(cmdStr, dir string, benchmark Benchmark) (sourceLocation, error) {
+	name := benchmarkFunc(benchmark.Name)
+	if name == "" || benchmark.Package == "" {
+		return sourceLocation{}, fmt.Errorf("benchmark name is not a function name")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command(cmdStr, "list", "-f", "{{.Dir}}", benchmark.Package)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		klog.InfoS("Exec command error", "err", stderr.String())
+		return sourceLocation{}, fmt.Errorf("failed to run '%s' command: %w", cmd, err)
+	}
+	file, line, err := findFunc(strings.TrimSpace(string(out)), name)
+	if err != nil {
+		return sourceLocation{}, err
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return sourceLocation{}, err
+	}
+	if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+		file = rel
+	}
+	return sourceLocation{file: filepath.ToSlash(file), line: line}, nil
+}
+
+// githubLink returns a link to the location in the GitHub repository when
+// running in GitHub Actions, or an empty string otherwise.
+func githubLink(loc sourceLocation) string {
+	repository, sha := os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_SHA")
+	if repository == "" || sha == "" || filepath.IsAbs(loc.file) {
+		return ""
+	}
+	server := os.Getenv("GITHUB_SERVER_URL")
+	if server == "" {
+		server = "https://github.com"
+	}
+	return fmt.Sprintf("%s/%s/blob/%s/%s#L%d", server, repository, sha, loc.file, loc.line)
+}
+
+// benchmarkSource is the source location of a regressed benchmark.
+type benchmarkSource struct {
+	benchmark string
+	location  sourceLocation
+}
+
+// regressionLocations returns the source locations of regressed benchmarks,
+// sorted by name. In GitHub Actions, an annotation is also emitted to
+// annotations for each of them, so that they show up on the pull request.
+func regressionLocations(annotations io.Writer, results []result, dir string) []benchmarkSource {
+	locations := map[string]sourceLocation{}
+	for _, result := range results {
+		if !isRegression(result) {
+			continue
+		}
+		if _, ok := locations[result.UniqueName]; ok {
+			continue
+		}
+		loc, err := benchmarkLocation(goCommand(&result.BenchmarkConfiguration), dir, result.Benchmark)
+		if err != nil {
+			klog.V(2).InfoS("Unable to locate benchmark", "benchmark", result.UniqueName, "err", err)
+			continue
+		}
+		locations[result.UniqueName] = loc
+		if os.Getenv("GITHUB_ACTIONS") == "true" {
+			fmt.Fprintf(annotations, "::error file=%s,line=%d::%s regressed (%s)\n", loc.file, loc.line, result.UniqueName, regressionSummary(result))
+		}
+	}
+	names := make([]string, 0, len(locations))
+	for name := range locations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sources := make([]benchmarkSource, 0, len(names))
+	for _, name := range names {
+		sources = append(sources, benchmarkSource{benchmark: name, location: locations[name]})
+	}
+	return sources
+}
+
+// showSourceLocations lists the source locations of regressed benchmarks.
+func showSourceLocations(w io.Writer, sources []benchmarkSource) {
+	if len(sources) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nSource of regressed benchmarks")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 30))
+	for _, s := range sources {
+		line := fmt.Sprintf("%s: %s", s.benchmark, s.location)
+		if link := githubLink(s.location); link != "" {
+			line += " " + link
+		}
+		fmt.Fprintln(w, line)
+	}
+	fmt.Fprintln(w)
+}
+
+// regressionSummary describes the compared metrics of a result, e.g.
+// "ns/op +25.00%".
+func regressionSummary(result result) string {
+	comparedScore := whichScoreToCompare(result.Compare)
+	var parts []string
+	if comparedScore.nsPerOp {
+		parts = append(parts, "ns/op "+signedRatio(result.RatioNsPerOp))
+	}
+	if comparedScore.allocedBytesPerOp {
+		parts = append(parts, "B/op "+signedRatio(result.RatioAllocedBytesPerOp))
+	}
+	if comparedScore.allocsPerOp {
+		parts = append(parts, "allocs/op "+signedRatio(result.RatioAllocsPerOp))
+	}
+	if comparedScore.mbPerS {
+		parts = append(parts, "MB/s "+signedRatio(result.RatioMBPerS))
+	}
+	if comparedScore.contention && *result.Contention {
+		parts = append(parts, "contention "+signedRatio(result.RatioContention))
+	}
+	if comparedScore.heap && *result.MemStats {
+		parts = append(parts, "heap "+signedRatio(result.RatioPeakHeap))
+	}
+	for _, unit := range comparedScore.custom {
+		if ratio, ok := result.RatioMetrics[unit]; ok {
+			parts = append(parts, unit+" "+signedRatio(ratio))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
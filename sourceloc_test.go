@@ -0,0 +1,47 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmarkFunc(t *testing.T) {
+	for pattern, expected := range map[string]string{
+		"BenchmarkFoo":         "BenchmarkFoo",
+		"^BenchmarkFoo$":       "BenchmarkFoo",
+		"BenchmarkFoo/bar-baz": "BenchmarkFoo",
+		"BenchmarkFoo.*":       "",
+		"Foo":                  "",
+	} {
+		assert.Equal(t, expected, benchmarkFunc(pattern), pattern)
+	}
+}
+
+func TestFindFunc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	src := "package foo\n\nimport \"testing\"\n\nfunc BenchmarkFoo(b *testing.B) {}\n\nfunc BenchmarkBar(b *testing.B) {}\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo_test.go"), []byte(src), 0644))
+
+	file, line, err := findFunc(dir, "BenchmarkBar")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "foo_test.go"), file)
+	assert.Equal(t, 7, line)
+
+	_, _, err = findFunc(dir, "BenchmarkBaz")
+	assert.Error(t, err)
+}
+
+func TestGithubLink(t *testing.T) {
+	os.Setenv("GITHUB_REPOSITORY", "antrea-io/antrea")
+	os.Setenv("GITHUB_SHA", "abc")
+	defer os.Unsetenv("GITHUB_REPOSITORY")
+	defer os.Unsetenv("GITHUB_SHA")
+	assert.Equal(t, "https://github.com/antrea-io/antrea/blob/abc/pkg/foo/foo_test.go#L7", githubLink(sourceLocation{file: "pkg/foo/foo_test.go", line: 7}))
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+const defaultAlpha = 0.05
+
+// mannWhitneyU returns the two-sided p-value of the Mann-Whitney U test, i.e.
+// the probability that samples at least as different as x and y are drawn
+// from the same distribution. It uses the normal approximation, with a
+// correction for ties. ok is false if there are less than 2 samples in x or y.
+func mannWhitneyU(x, y []float64) (p float64, ok bool) {
+	n1, n2 := float64(len(x)), float64(len(y))
+	if len(x) < 2 || len(y) < 2 {
+		return 0, false
+	}
+	type value struct {
+		v     float64
+		fromX bool
+	}
+	values := make([]value, 0, len(x)+len(y))
+	for _, v := range x {
+		values = append(values, value{v, true})
+	}
+	for _, v := range y {
+		values = append(values, value{v, false})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].v < values[j].v })
+
+	// ranks start at 1, tied values get the average of their ranks
+	var rankSumX, tieCorrection float64
+	for i := 0; i < len(values); {
+		j := i
+		for j < len(values) && values[j].v == values[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if values[k].fromX {
+				rankSumX += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			tieCorrection += t*t*t - t
+		}
+		i = j
+	}
+	u := rankSumX - n1*(n1+1)/2
+	mean := n1 * n2 / 2
+	n := n1 + n2
+	variance := n1 * n2 / 12 * ((n + 1) - tieCorrection/(n*(n-1)))
+	if variance <= 0 {
+		// all the values are equal
+		return 1, true
+	}
+	z := (math.Abs(u-mean) - 0.5) / math.Sqrt(variance)
+	if z < 0 {
+		z = 0
+	}
+	return math.Erfc(z / math.Sqrt2), true
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// median does not modify values.
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if n := len(sorted); n%2 == 0 {
+		return (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return sorted[len(sorted)/2]
+}
+
+// stddev returns the sample standard deviation, 0 with less than 2 values.
+func stddev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	var sum float64
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// showSampleStats shows the spread of the ns/op samples of the benchmarks
+// which were run several times, the median being the compared value. A large
+// deviation means that the comparison is not reliable, and that the count or
+// the benchtime should be increased.
+func showSampleStats(w io.Writer, refs ...*refResults) {
+	var rows [][]string
+	for _, benchmark := range benchmarks.Benchmarks {
+		for _, ref := range refs {
+			if ref == nil {
+				continue
+			}
+			b, ok := ref.run.Results[benchmark.UniqueName]
+			if !ok || len(b.Samples) < 2 {
+				continue
+			}
+			values := sampleValues(b.Samples, func(b *parse.Benchmark) float64 { return b.NsPerOp })
+			m, sd := mean(values), stddev(values)
+			rows = append(rows, []string{benchmark.UniqueName, ref.name, strconv.Itoa(len(values)),
+				numbers.float(m), numbers.float(median(values)), numbers.float(sd), fmt.Sprintf("±%s", numbers.percent(sd/m))})
+		}
+	}
+	if len(rows) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nSample statistics (ns/op)")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 25))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"Name", "Ref", "Runs", "Mean", "Median", "StdDev", "CV"})
+	table.AppendBulk(rows)
+	table.Render()
+	fmt.Fprintln(w)
+}
+
+func sampleValues(samples []*parse.Benchmark, value func(b *parse.Benchmark) float64) []float64 {
+	values := make([]float64, 0, len(samples))
+	for _, b := range samples {
+		values = append(values, value(b))
+	}
+	return values
+}
+
+// minPValue returns the smallest p-value the Mann-Whitney U test can return
+// for samples of sizes n1 and n2, when the samples do not overlap and have no
+// ties.
+func minPValue(n1, n2 int) float64 {
+	x := make([]float64, n1)
+	for i := range x {
+		x[i] = float64(i)
+	}
+	y := make([]float64, n2)
+	for i := range y {
+		y[i] = float64(n1 + i)
+	}
+	p, ok := mannWhitneyU(x, y)
+	if !ok {
+		return 1
+	}
+	return p
+}
+
+// minSignificanceCount returns the number of samples of each ref needed for
+// the Mann-Whitney U test to reach a p-value below alpha, e.g. 4 at 0.05.
+func minSignificanceCount(alpha float64) int {
+	n := 2
+	for minPValue(n, n) >= alpha {
+		n++
+	}
+	return n
+}
+
+// pValues computes the p-values of the ns/op, B/op, allocs/op and MB/s
+// samples of a result, which are NaN when there are not enough samples for the
+// test to reach the significance level, e.g. with 3 samples at 0.05.
+func pValues(r *result, headBench, baseBench *benchResult) {
+	r.PNsPerOp, r.PAllocedBytesPerOp, r.PAllocsPerOp, r.PMBPerS = math.NaN(), math.NaN(), math.NaN(), math.NaN()
+	if minPValue(len(headBench.samples()), len(baseBench.samples())) >= alpha(*r) {
+		return
+	}
+	test := func(value func(b *parse.Benchmark) float64) float64 {
+		if p, ok := mannWhitneyU(sampleValues(headBench.samples(), value), sampleValues(baseBench.samples(), value)); ok {
+			return p
+		}
+		return math.NaN()
+	}
+	r.PNsPerOp = test(func(b *parse.Benchmark) float64 { return b.NsPerOp })
+	r.PAllocedBytesPerOp = test(func(b *parse.Benchmark) float64 { return float64(b.AllocedBytesPerOp) })
+	r.PAllocsPerOp = test(func(b *parse.Benchmark) float64 { return float64(b.AllocsPerOp) })
+	r.PMBPerS = test(func(b *parse.Benchmark) float64 { return b.MBPerS })
+}
+
+// nearThreshold returns true if a ratio is within epsilon of the threshold
+// (or of -threshold for improvements), in which case the verdict is left to
+// the statistical test so that it does not flip-flop from one run to the
+// next.
+func nearThreshold(r result, ratio float64) bool {
+	return r.Epsilon > 0 && (math.Abs(ratio-r.Threshold) <= r.Epsilon || math.Abs(ratio+r.Threshold) <= r.Epsilon)
+}
+
+// alpha returns the significance level of a result.
+func alpha(r result) float64 {
+	if r.Alpha == 0 {
+		return defaultAlpha
+	}
+	return r.Alpha
+}
+
+func significant(r result, p float64) bool {
+	return !math.IsNaN(p) && p < alpha(r)
+}
+
+// requiresSignificance returns true if a change must be statistically
+// significant to be reported regardless of the threshold, which is only
+// possible when there are enough samples for the test to reach Alpha.
+func requiresSignificance(r result, p float64) bool {
+	return r.Significance != nil && *r.Significance && !math.IsNaN(p)
+}
+
+// exceedsThreshold returns true if a ratio is a regression: above the
+// threshold or, when it is near the threshold, an increase which is
+// statistically significant. With significance, all the regressions must be
+// statistically significant.
+func exceedsThreshold(r result, ratio, p float64) bool {
+	if nearThreshold(r, ratio) {
+		return ratio > 0 && significant(r, p)
+	}
+	if requiresSignificance(r, p) && !significant(r, p) {
+		return false
+	}
+	return r.Threshold < ratio
+}
+
+// belowThreshold is the counterpart of exceedsThreshold for improvements.
+func belowThreshold(r result, ratio, p float64) bool {
+	if nearThreshold(r, ratio) {
+		return ratio < 0 && significant(r, p)
+	}
+	if requiresSignificance(r, p) && !significant(r, p) {
+		return false
+	}
+	return ratio < -r.Threshold
+}
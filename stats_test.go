@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	p, ok := mannWhitneyU([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10})
+	require.True(t, ok)
+	assert.InDelta(t, 0.012, p, 0.001)
+
+	p, ok = mannWhitneyU([]float64{1, 3, 5, 7, 9}, []float64{2, 4, 6, 8, 10})
+	require.True(t, ok)
+	assert.True(t, p > 0.5)
+
+	p, ok = mannWhitneyU([]float64{1, 1, 1}, []float64{1, 1, 1})
+	require.True(t, ok)
+	assert.Equal(t, 1.0, p)
+
+	_, ok = mannWhitneyU([]float64{1}, []float64{1, 2})
+	assert.False(t, ok)
+}
+
+func TestDescriptiveStatistics(t *testing.T) {
+	values := []float64{4, 1, 3, 2}
+	assert.Equal(t, 2.5, mean(values))
+	assert.Equal(t, 2.5, median(values))
+	assert.Equal(t, 3.0, median([]float64{5, 1, 3}))
+	assert.InDelta(t, 1.291, stddev(values), 0.001)
+	assert.Equal(t, 0.0, stddev([]float64{1}))
+	// median does not sort its argument
+	assert.Equal(t, []float64{4, 1, 3, 2}, values)
+}
+
+func TestShowSampleStats(t *testing.T) {
+	defer func(b BenchmarkList) { *benchmarks = b }(*benchmarks)
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo"}, {Name: "BenchmarkBar", UniqueName: "BenchmarkBar"}}}
+	samples := func(values ...float64) *benchResult {
+		var s []*parse.Benchmark
+		for _, v := range values {
+			s = append(s, &parse.Benchmark{Name: "BenchmarkFoo", NsPerOp: v})
+		}
+		return newBenchResult(s)
+	}
+	head := &refResults{name: "HEAD", run: &RunResult{Results: Set{"BenchmarkFoo": samples(90, 100, 110), "BenchmarkBar": samples(100)}}}
+	base := &refResults{name: "main", run: &RunResult{Results: Set{"BenchmarkFoo": samples(100, 100)}}}
+
+	var b bytes.Buffer
+	showSampleStats(&b, head, base, nil)
+	assert.Equal(t, `
+Sample statistics (ns/op)
+=========================
+
++--------------+------+------+--------+--------+--------+---------+
+|     Name     | Ref  | Runs |  Mean  | Median | StdDev |   CV    |
++--------------+------+------+--------+--------+--------+---------+
+| BenchmarkFoo | HEAD | 3    | 100.00 | 100.00 | 10.00  | ±10.00% |
+| BenchmarkFoo | main | 2    | 100.00 | 100.00 | 0.00   | ±0.00%  |
++--------------+------+------+--------+--------+--------+---------+
+
+`, b.String())
+
+	b.Reset()
+	showSampleStats(&b, &refResults{name: "HEAD", run: &RunResult{Results: Set{"BenchmarkFoo": samples(100)}}})
+	assert.Empty(t, b.String())
+}
+
+func TestEpsilon(t *testing.T) {
+	contention, memStats := false, false
+	samples := func(values ...float64) *benchResult {
+		var s []*parse.Benchmark
+		for _, v := range values {
+			s = append(s, &parse.Benchmark{NsPerOp: v})
+		}
+		return newBenchResult(s)
+	}
+	base := samples(100, 100, 100, 100, 100)
+	for _, tc := range []struct {
+		name        string
+		epsilon     float64
+		head        *benchResult
+		regression  bool
+		improvement bool
+	}{
+		{"just above the threshold without epsilon", 0, samples(99, 100, 110.5, 111, 112), true, false},
+		{"just above the threshold, not significant", 0.01, samples(99, 100, 110.5, 111, 112), false, false},
+		{"just below the threshold, significant", 0.01, samples(109.5, 109.5, 109.5, 109.5, 109.5), true, false},
+		{"far above the threshold", 0.01, samples(99, 100, 120, 121, 122), true, false},
+		{"just below -threshold, not significant", 0.01, samples(89.5, 89.5, 89.5, 101, 102), false, false},
+		{"just above -threshold, significant", 0.01, samples(90.5, 90.5, 90.5, 90.5, 90.5), false, true},
+		{"single sample", 0.01, samples(110.5), false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			benchmark := Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{
+				Threshold: 0.1, Epsilon: tc.epsilon, Compare: "ns/op", Contention: &contention, MemStats: &memStats,
+			}}
+			baseBench := base
+			if len(tc.head.samples()) == 1 {
+				baseBench = samples(100)
+			}
+			r := newResult(benchmark, tc.head, baseBench)
+			assert.Equal(t, tc.regression, isRegression(r))
+			assert.Equal(t, tc.improvement, isImprovement(r))
+		})
+	}
+}
+
+func TestSignificance(t *testing.T) {
+	contention, memStats, significance := false, false, true
+	samples := func(values ...float64) *benchResult {
+		var s []*parse.Benchmark
+		for _, v := range values {
+			s = append(s, &parse.Benchmark{NsPerOp: v})
+		}
+		return newBenchResult(s)
+	}
+	base := samples(100, 100, 100, 100, 100)
+	for _, tc := range []struct {
+		name        string
+		head        *benchResult
+		regression  bool
+		improvement bool
+	}{
+		{"far above the threshold, not significant", samples(99, 100, 120, 121, 122), false, false},
+		{"far above the threshold, significant", samples(120, 121, 122, 123, 124), true, false},
+		{"far below -threshold, not significant", samples(80, 81, 82, 101, 102), false, false},
+		{"far below -threshold, significant", samples(80, 81, 82, 83, 84), false, true},
+		{"single sample", samples(120), true, false},
+		// the test cannot reach 0.05 with 3 samples, the threshold is used
+		{"too few samples", samples(200, 201, 202), true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			benchmark := Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{
+				Threshold: 0.1, Compare: "ns/op", Contention: &contention, MemStats: &memStats, Significance: &significance,
+			}}
+			baseBench := base
+			if n := len(tc.head.samples()); n < len(base.samples()) {
+				baseBench = newBenchResult(base.samples()[:n])
+			}
+			r := newResult(benchmark, tc.head, baseBench)
+			assert.Equal(t, tc.regression, isRegression(r))
+			assert.Equal(t, tc.improvement, isImprovement(r))
+		})
+	}
+}
+
+func TestSignificanceAllocsAndThroughput(t *testing.T) {
+	contention, memStats, significance := false, false, true
+	samples := func(allocs int, mbPerS ...float64) *benchResult {
+		var s []*parse.Benchmark
+		for i, v := range mbPerS {
+			s = append(s, &parse.Benchmark{NsPerOp: 100, AllocsPerOp: uint64(allocs + i), MBPerS: v})
+		}
+		return newBenchResult(s)
+	}
+	base := samples(100, 100, 100, 100, 100, 100)
+	for _, tc := range []struct {
+		name        string
+		compare     string
+		head        *benchResult
+		regression  bool
+		improvement bool
+	}{
+		{"more allocations, significant", "allocs/op", samples(120, 100, 100, 100, 100, 100), true, false},
+		{"fewer allocations, significant", "allocs/op", samples(80, 100, 100, 100, 100, 100), false, true},
+		{"lower throughput, not significant", "MB/s", samples(100, 70, 71, 72, 101, 102), false, false},
+		{"lower throughput, significant", "MB/s", samples(100, 70, 71, 72, 73, 74), true, false},
+		{"higher throughput, significant", "MB/s", samples(100, 130, 131, 132, 133, 134), false, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			benchmark := Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{
+				Threshold: 0.1, Compare: tc.compare, Contention: &contention, MemStats: &memStats, Significance: &significance,
+			}}
+			r := newResult(benchmark, tc.head, base)
+			assert.Equal(t, tc.regression, isRegression(r))
+			assert.Equal(t, tc.improvement, isImprovement(r))
+		})
+	}
+}
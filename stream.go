@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+var eventsPath string
+
+// streamEvent is written to the events file for each result as soon as it
+// is available, so that partial results survive an aborted run.
+type streamEvent struct {
+	Time time.Time `json:"time"`
+	// Type is "measured" when a benchmark was run for a ref, or "compared"
+	// when it was compared with a comparison base.
+	Type       string            `json:"type"`
+	Benchmark  string            `json:"benchmark"`
+	Ref        string            `json:"ref"`
+	Result     *benchResult      `json:"result,omitempty"`
+	Comparison *streamComparison `json:"comparison,omitempty"`
+	Meta       map[string]string `json:"meta,omitempty"`
+}
+
+type streamComparison struct {
+	Base                   string  `json:"base"`
+	RatioNsPerOp           float64 `json:"ratioNsPerOp"`
+	RatioAllocedBytesPerOp float64 `json:"ratioAllocedBytesPerOp"`
+	Regression             bool    `json:"regression"`
+}
+
+// streamer reports each benchmark result as soon as it is measured, instead
+// of only at the end of the run. The comparisons are preliminary: accepted
+// regressions and calibration are only applied in the final report.
+type streamer struct {
+	w      io.Writer
+	events io.WriteCloser
+	// bases are the refs which have already been run, that results are
+	// compared with.
+	bases []*refResults
+	// mu serializes the results of refs run concurrently.
+	mu sync.Mutex
+}
+
+func newStreamer(w io.Writer) (*streamer, error) {
+	s := &streamer{w: w}
+	if eventsPath != "" {
+		f, err := os.Create(eventsPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create events file: %w", err)
+		}
+		s.events = f
+	}
+	return s, nil
+}
+
+func (s *streamer) close() {
+	if s.events != nil {
+		s.events.Close()
+	}
+}
+
+func (s *streamer) emit(event streamEvent) {
+	if s.events == nil {
+		return
+	}
+	event.Time = time.Now().UTC()
+	event.Meta = runMetadata()
+	data, err := json.Marshal(event)
+	if err == nil {
+		_, err = s.events.Write(append(data, '\n'))
+	}
+	if err != nil {
+		klog.ErrorS(err, "Failed to write event", "path", eventsPath)
+	}
+}
+
+// progress returns the function called by runBenchmarks for each result of a
+// ref. The results are compared with the bases when compare is true.
+func (s *streamer) progress(ref string, compare bool) func(Benchmark, *benchResult) {
+	return func(benchmark Benchmark, r *benchResult) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.emit(streamEvent{Type: "measured", Benchmark: benchmark.UniqueName, Ref: ref, Result: r})
+		if !compare {
+			return
+		}
+		for _, base := range s.bases {
+			baseBench, ok := base.run.Results[benchmark.UniqueName]
+			if !ok || benchmark.Calibration {
+				continue
+			}
+			result := newResult(comparedBenchmark(benchmark, base), r, baseBench)
+			line := fmt.Sprintf("%s compared with %s: %s", benchmark.UniqueName, base.name, regressionSummary(result))
+			if isRegression(result) {
+				line += " (regression)"
+			}
+			fmt.Fprintln(s.w, line)
+			s.emit(streamEvent{Type: "compared", Benchmark: benchmark.UniqueName, Ref: ref, Comparison: &streamComparison{
+				Base:                   base.name,
+				RatioNsPerOp:           result.RatioNsPerOp,
+				RatioAllocedBytesPerOp: result.RatioAllocedBytesPerOp,
+				Regression:             isRegression(result),
+			}})
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestStreamerProgress(t *testing.T) {
+	contention := false
+	benchmark := Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, ReleaseThreshold: 0.5, Compare: "ns/op", Contention: &contention}}
+	newBench := func(nsPerOp float64) *benchResult {
+		return &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkFoo-4", NsPerOp: nsPerOp}}
+	}
+	var lines bytes.Buffer
+	events := nopCloser{&bytes.Buffer{}}
+	s := &streamer{w: &lines, events: events}
+
+	s.progress("main", false)(benchmark, newBench(100))
+	assert.Empty(t, lines.String())
+	s.bases = []*refResults{
+		{name: "main", run: &RunResult{Results: Set{"BenchmarkFoo": newBench(100)}}},
+		{name: "v1.0.0", run: &RunResult{Results: Set{"BenchmarkFoo": newBench(100)}}, latestRelease: true},
+		{name: "v0.9.0", run: &RunResult{Results: Set{}}},
+	}
+	s.progress("HEAD", true)(benchmark, newBench(120))
+	assert.Equal(t, "BenchmarkFoo compared with main: ns/op +20.00% (regression)\nBenchmarkFoo compared with v1.0.0: ns/op +20.00%\n", lines.String())
+
+	var decoded []streamEvent
+	for _, line := range strings.Split(strings.TrimSpace(events.String()), "\n") {
+		var event streamEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		decoded = append(decoded, event)
+	}
+	require.Len(t, decoded, 4)
+	assert.Equal(t, "measured", decoded[0].Type)
+	assert.Equal(t, "main", decoded[0].Ref)
+	assert.Equal(t, 100.0, decoded[0].Result.NsPerOp)
+	assert.Equal(t, "measured", decoded[1].Type)
+	assert.Equal(t, "HEAD", decoded[1].Ref)
+	assert.Equal(t, &streamComparison{Base: "main", RatioNsPerOp: 0.2, Regression: true}, decoded[2].Comparison)
+	assert.Equal(t, &streamComparison{Base: "v1.0.0", RatioNsPerOp: 0.2}, decoded[3].Comparison)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"golang.org/x/tools/benchmark/parse"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"k8s.io/klog/v2"
+)
+
+var (
+	stressBenchmark  string
+	stressIterations int
+	stressRef        string
+)
+
+// stressBins is the maximum number of bins of the histogram of the samples.
+const stressBins = 10
+
+func addStressFlags(fs *flag.FlagSet) {
+	fs.StringVar(&stressBenchmark, "benchmark", "", "unique `name` of the benchmark to run")
+	fs.IntVar(&stressIterations, "iterations", 50, "`number` of times the benchmark is run")
+	fs.StringVar(&stressRef, "ref", "HEAD", "`ref` of the commit to benchmark")
+}
+
+// showStress writes the distribution of the ns/op of a benchmark run many
+// times on the same code: its statistics, a histogram, and how its spread
+// compares with the threshold of the benchmark.
+func showStress(w io.Writer, benchmark Benchmark, values []float64) {
+	title := fmt.Sprintf("Distribution of %s (ns/op)", benchmark.UniqueName)
+	fmt.Fprintf(w, "\n%s\n", title)
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", len(title)))
+
+	min, max := values[0], values[0]
+	for _, v := range values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	m, med, sd := mean(values), median(values), stddev(values)
+	spread := 0.0
+	if med != 0 {
+		spread = (max - min) / med
+	}
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader([]string{"Runs", "Min", "Max", "Mean", "Median", "StdDev", "CV", "Spread"})
+	table.Append([]string{strconv.Itoa(len(values)), numbers.float(min), numbers.float(max), numbers.float(m),
+		numbers.float(med), numbers.float(sd), fmt.Sprintf("±%s", numbers.percent(sd/m)), numbers.percent(spread)})
+	table.Render()
+
+	fmt.Fprintln(w)
+	bins := stressBins
+	if len(values) < bins {
+		bins = len(values)
+	}
+	width := (max - min) / float64(bins)
+	if width == 0 {
+		bins = 1
+	}
+	counts := make([]int, bins)
+	most := 0
+	for _, v := range values {
+		i := bins - 1
+		if width != 0 && int((v-min)/width) < bins {
+			i = int((v - min) / width)
+		}
+		counts[i]++
+		if counts[i] > most {
+			most = counts[i]
+		}
+	}
+	for i, count := range counts {
+		bar := strings.Repeat("#", (count*40+most-1)/most)
+		fmt.Fprintf(w, "%16s ns/op | %-40s %d\n", numbers.float(min+float64(i)*width), bar, count)
+	}
+
+	fmt.Fprintf(w, "\nRuns of the same code differ by up to %s of the median, the threshold is %s.\n", numbers.percent(spread), numbers.percent(benchmark.Threshold))
+	if benchmark.Threshold < spread {
+		fmt.Fprintln(w, "The threshold is below the spread, noise is likely to be reported as regressions: raise the threshold, the count or the benchtime.")
+	}
+	fmt.Fprintln(w)
+}
+
+// runStress runs one benchmark many times at a single ref, to show how much
+// its results vary without any change and help choose its threshold.
+func runStress() error {
+	if outputFormat != outputText {
+		return fmt.Errorf("stress only supports the %s output format", outputText)
+	}
+	if stressBenchmark == "" {
+		return fmt.Errorf("--benchmark is required")
+	}
+	if stressIterations < 2 {
+		return fmt.Errorf("--iterations must be at least 2")
+	}
+	if err := parseBenchmarks(); err != nil {
+		return err
+	}
+	r, err := git.PlainOpen(".")
+	if err != nil {
+		return fmt.Errorf("unable to open the git repository: %w", err)
+	}
+	commit, err := r.ResolveRevision(plumbing.Revision(stressRef))
+	if err != nil {
+		return fmt.Errorf("unable to resolve '%s': %w", stressRef, err)
+	}
+	if err := discoverBenchmarks(""); err != nil {
+		return err
+	}
+	if err := updateBenchmarks(); err != nil {
+		return err
+	}
+	var benchmark *Benchmark
+	for i := range benchmarks.Benchmarks {
+		if benchmarks.Benchmarks[i].UniqueName == stressBenchmark {
+			benchmark = &benchmarks.Benchmarks[i]
+		}
+	}
+	if benchmark == nil {
+		return fmt.Errorf("unknown benchmark '%s'", stressBenchmark)
+	}
+	if benchmark.Skip != "" {
+		return fmt.Errorf("benchmark '%s' is skipped: %s", stressBenchmark, benchmark.Skip)
+	}
+	benchmark.Count = stressIterations
+	benchmarks.Benchmarks = []Benchmark{*benchmark}
+	if err := prepareArtifactsDir(); err != nil {
+		return err
+	}
+
+	checkouts, err := newWorktrees(".")
+	if err != nil {
+		return err
+	}
+	defer checkouts.remove()
+	dir, _, err := checkouts.checkout(*commit)
+	if err != nil {
+		return err
+	}
+	klog.InfoS("Run Benchmark", "commitHash", commit, "Ref", stressRef, "dir", dir, "iterations", stressIterations)
+	run, err := runBenchmarks(dir, stressRef, commit.String(), "", nil)
+	if err != nil {
+		return fmt.Errorf("failed to run a benchmark: %w", err)
+	}
+	if reason, ok := run.Errors[stressBenchmark]; ok {
+		return fmt.Errorf("benchmark '%s' failed: %s", stressBenchmark, reason)
+	}
+	b, ok := run.Results[stressBenchmark]
+	if !ok {
+		return fmt.Errorf("no result for benchmark '%s'", stressBenchmark)
+	}
+	showStress(report, benchmarks.Benchmarks[0], sampleValues(b.samples(), func(b *parse.Benchmark) float64 { return b.NsPerOp }))
+	return nil
+}
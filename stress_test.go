@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShowStress(t *testing.T) {
+	benchmark := Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1}}
+	values := []float64{100, 100, 101, 102, 104, 110, 120}
+
+	var b bytes.Buffer
+	showStress(&b, benchmark, values)
+	assert.Contains(t, b.String(), "Distribution of BenchmarkFoo (ns/op)")
+	assert.Contains(t, b.String(), "19.61%")
+	assert.Contains(t, b.String(), "The threshold is below the spread")
+	var bins []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.Contains(line, " ns/op | ") {
+			bins = append(bins, line)
+		}
+	}
+	assert.Len(t, bins, 7)
+	assert.True(t, strings.HasSuffix(bins[0], " 4"), bins[0])
+	assert.True(t, strings.HasSuffix(bins[6], " 1"), bins[6])
+
+	benchmark.Threshold = 0.25
+	b.Reset()
+	showStress(&b, benchmark, []float64{100, 100, 100})
+	assert.Contains(t, b.String(), "differ by up to 0.00% of the median")
+	assert.NotContains(t, b.String(), "The threshold is below the spread")
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+const defaultSlopeThreshold = 0.1
+
+// sweepUniqueName returns the unique name of the entry running a benchmark
+// for one value of its sweep, e.g. "BenchmarkSort[1000]".
+func sweepUniqueName(uniqueName, value string) string {
+	return fmt.Sprintf("%s[%s]", uniqueName, value)
+}
+
+func validateSweep(s *Sweep) error {
+	if (s.Env == "") == (s.Arg == "") {
+		return fmt.Errorf("exactly one of env and arg must be set")
+	}
+	if len(s.Values) < 2 {
+		return fmt.Errorf("at least 2 values are required")
+	}
+	seen := map[string]bool{}
+	for _, v := range s.Values {
+		if f, err := strconv.ParseFloat(v, 64); err != nil || f <= 0 {
+			return fmt.Errorf("invalid value '%s': values must be positive numbers", v)
+		}
+		if seen[v] {
+			return fmt.Errorf("duplicate value '%s'", v)
+		}
+		seen[v] = true
+	}
+	return nil
+}
+
+// expandSweeps replaces each benchmark with a sweep by one entry per value of
+// the sweep, so that each size is run and compared like any other benchmark.
+func expandSweeps(entries []Benchmark) ([]Benchmark, error) {
+	expanded := make([]Benchmark, 0, len(entries))
+	for _, benchmark := range entries {
+		if benchmark.Sweep == nil || benchmark.sweepValue != "" {
+			expanded = append(expanded, benchmark)
+			continue
+		}
+		if err := validateSweep(benchmark.Sweep); err != nil {
+			return nil, fmt.Errorf("invalid sweep for '%s': %w", benchmark.UniqueName, err)
+		}
+		for i, value := range benchmark.Sweep.Values {
+			b := benchmark
+			b.sweepOf = benchmark.UniqueName
+			b.sweepValue = value
+			b.UniqueName = sweepUniqueName(benchmark.UniqueName, value)
+			b.variant = i > 0
+			expanded = append(expanded, b)
+		}
+	}
+	return expanded, nil
+}
+
+// sweepEnv returns the environment variable setting the value of the sweep for
+// an entry, if any.
+func sweepEnv(benchmark *Benchmark) []string {
+	if benchmark.sweepValue == "" || benchmark.Sweep.Env == "" {
+		return nil
+	}
+	return []string{benchmark.Sweep.Env + "=" + benchmark.sweepValue}
+}
+
+// sweepArgs returns the flag of the test binary setting the value of the sweep
+// for an entry, if any.
+func sweepArgs(benchmark *Benchmark) []string {
+	if benchmark.sweepValue == "" || benchmark.Sweep.Arg == "" {
+		return nil
+	}
+	return []string{benchmark.Sweep.Arg + "=" + benchmark.sweepValue}
+}
+
+// scalingSlope fits ns/op = a * size^slope with a least squares regression in
+// log-log space: a slope of 1 is linear, 2 is quadratic. ok is false if there
+// are less than 2 sizes with a result.
+func scalingSlope(sizes, nsPerOp []float64) (slope float64, ok bool) {
+	var n, sumX, sumY, sumXY, sumXX float64
+	for i := range sizes {
+		if sizes[i] <= 0 || nsPerOp[i] <= 0 {
+			continue
+		}
+		x, y := math.Log(sizes[i]), math.Log(nsPerOp[i])
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
+
+// sweepSlope computes the slope of a sweep from the results of a ref.
+func sweepSlope(entries []Benchmark, set Set) (float64, bool) {
+	var sizes, nsPerOp []float64
+	for _, entry := range entries {
+		r, ok := set[entry.UniqueName]
+		if !ok {
+			continue
+		}
+		size, _ := strconv.ParseFloat(entry.sweepValue, 64)
+		sizes = append(sizes, size)
+		nsPerOp = append(nsPerOp, r.NsPerOp)
+	}
+	return scalingSlope(sizes, nsPerOp)
+}
+
+// sweeps returns the expanded entries of each sweep, in configuration order.
+func sweeps(entries []Benchmark) (names []string, bySweep map[string][]Benchmark) {
+	bySweep = map[string][]Benchmark{}
+	for _, entry := range entries {
+		if entry.sweepOf == "" || entry.Skip != "" {
+			continue
+		}
+		// the sizes of a sweep are compared for each cpu value separately
+		name := entry.sweepOf
+		if entry.cpuOf != "" {
+			name = cpuUniqueName(name, entry.Cpu)
+		}
+		if _, ok := bySweep[name]; !ok {
+			names = append(names, name)
+		}
+		bySweep[name] = append(bySweep[name], entry)
+	}
+	return names, bySweep
+}
+
+// showScaling compares the scaling curves of the sweeps of head with the ones
+// of base, and returns true if a slope increased by more than the slope
+// threshold of the sweep, even if no single size regressed.
+func showScaling(w io.Writer, head, base *refResults) bool {
+	names, bySweep := sweeps(benchmarks.Benchmarks)
+	if len(names) == 0 {
+		return false
+	}
+	fmt.Fprintf(w, "\nScaling compared with %s\n", base.name)
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 22+len(base.name)))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_CENTER)
+	table.SetAutoWrapText(false)
+	table.SetHeader([]string{"Name", "Sizes", base.name + " slope", head.name + " slope", "Delta"})
+
+	regression := false
+	for _, name := range names {
+		entries := bySweep[name]
+		values := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			values = append(values, entry.sweepValue)
+		}
+		baseSlope, baseOK := sweepSlope(entries, base.run.Results)
+		headSlope, headOK := sweepSlope(entries, head.run.Results)
+		if !baseOK || !headOK {
+			table.Append([]string{name, strings.Join(values, ","), "-", "-", "-"})
+			continue
+		}
+		threshold := entries[0].Sweep.SlopeThreshold
+		if threshold == 0 {
+			threshold = defaultSlopeThreshold
+		}
+		delta := headSlope - baseSlope
+		color := tablewriter.Colors{}
+		if delta > threshold || delta < -threshold {
+			regression = regression || delta > threshold
+			color = generateColor(delta)
+		}
+		table.Rich([]string{name, strings.Join(values, ","), fmt.Sprintf("%.3f", baseSlope), fmt.Sprintf("%.3f", headSlope), fmt.Sprintf("%+.3f", delta)},
+			[]tablewriter.Colors{{}, {}, {}, {}, color})
+	}
+	table.Render()
+	fmt.Fprintln(w)
+	return regression
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestExpandSweeps(t *testing.T) {
+	sweep := &Sweep{Env: "SIZE", Values: []string{"100", "10000"}}
+	expanded, err := expandSweeps([]Benchmark{
+		{Name: "BenchmarkSort", UniqueName: "BenchmarkSort", Sweep: sweep},
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo"},
+	})
+	require.NoError(t, err)
+	require.Len(t, expanded, 3)
+	assert.Equal(t, "BenchmarkSort[100]", expanded[0].UniqueName)
+	assert.Equal(t, "BenchmarkSort[10000]", expanded[1].UniqueName)
+	assert.Equal(t, "BenchmarkSort", expanded[1].Name)
+	assert.Equal(t, []string{"SIZE=10000"}, sweepEnv(&expanded[1]))
+	assert.Nil(t, sweepArgs(&expanded[1]))
+	assert.Nil(t, sweepEnv(&expanded[2]))
+
+	again, err := expandSweeps(expanded)
+	require.NoError(t, err)
+	assert.Equal(t, expanded, again)
+
+	expanded, err = expandSweeps([]Benchmark{{UniqueName: "BenchmarkSort", Sweep: &Sweep{Arg: "-size", Values: []string{"1", "2"}}}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-size=1"}, sweepArgs(&expanded[0]))
+}
+
+func TestValidateSweep(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		sweep Sweep
+		err   bool
+	}{
+		{"env", Sweep{Env: "SIZE", Values: []string{"100", "1000"}}, false},
+		{"arg", Sweep{Arg: "-size", Values: []string{"0.5", "1e3"}}, false},
+		{"env and arg", Sweep{Env: "SIZE", Arg: "-size", Values: []string{"100", "1000"}}, true},
+		{"neither env nor arg", Sweep{Values: []string{"100", "1000"}}, true},
+		{"single value", Sweep{Env: "SIZE", Values: []string{"100"}}, true},
+		{"not a number", Sweep{Env: "SIZE", Values: []string{"100", "large"}}, true},
+		{"zero", Sweep{Env: "SIZE", Values: []string{"0", "100"}}, true},
+		{"duplicate", Sweep{Env: "SIZE", Values: []string{"100", "100"}}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateSweep(&tc.sweep)
+			if tc.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestScalingSlope(t *testing.T) {
+	slope, ok := scalingSlope([]float64{100, 1000, 10000}, []float64{100, 1000, 10000})
+	require.True(t, ok)
+	assert.InDelta(t, 1, slope, 1e-9)
+	slope, ok = scalingSlope([]float64{10, 100, 1000}, []float64{100, 10000, 1000000})
+	require.True(t, ok)
+	assert.InDelta(t, 2, slope, 1e-9)
+	_, ok = scalingSlope([]float64{100}, []float64{100})
+	assert.False(t, ok)
+}
+
+func TestShowScaling(t *testing.T) {
+	defer func(b []Benchmark) { benchmarks.Benchmarks = b }(benchmarks.Benchmarks)
+	var err error
+	benchmarks.Benchmarks, err = expandSweeps([]Benchmark{
+		{Name: "BenchmarkSort", UniqueName: "BenchmarkSort", Sweep: &Sweep{Env: "SIZE", Values: []string{"100", "10000"}}},
+	})
+	require.NoError(t, err)
+	set := func(small, large float64) *refResults {
+		return &refResults{name: "ref", run: &RunResult{Results: Set{
+			"BenchmarkSort[100]":   &benchResult{Benchmark: &parse.Benchmark{NsPerOp: small}},
+			"BenchmarkSort[10000]": &benchResult{Benchmark: &parse.Benchmark{NsPerOp: large}},
+		}}}
+	}
+	base := set(1000, 100000)
+	base.name = "main"
+
+	var b bytes.Buffer
+	// the small size is faster, but the slope went from 1 to 1.5
+	assert.True(t, showScaling(&b, set(100, 100000), base))
+	assert.Contains(t, b.String(), "Scaling compared with main")
+	assert.Contains(t, b.String(), "+0.500")
+
+	b.Reset()
+	assert.False(t, showScaling(&b, set(900, 90000), base))
+}
+
+func TestSweepRows(t *testing.T) {
+	defer func(b BenchmarkList, f string, w io.Writer) {
+		*benchmarks, outputFormat, report = b, f, w
+	}(*benchmarks, outputFormat, report)
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{
+		{Name: "BenchmarkSort", UniqueName: "BenchmarkSort", Sweep: &Sweep{Env: "SIZE", Values: []string{"100", "10000"}}},
+	}}
+	require.NoError(t, updateBenchmarks())
+	set := func(name string) *refResults {
+		return &refResults{name: name, ref: name, policy: policyFail, run: &RunResult{Results: Set{
+			"BenchmarkSort[100]":   &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkSort-4", N: 10, NsPerOp: 1000}},
+			"BenchmarkSort[10000]": &benchResult{Benchmark: &parse.Benchmark{Name: "BenchmarkSort-4", N: 10, NsPerOp: 100000}},
+		}}}
+	}
+
+	// each size is labelled with its unique name in the result and ratio
+	// tables
+	for _, format := range []string{outputText, outputMarkdown} {
+		var out bytes.Buffer
+		outputFormat, report = format, &out
+		require.NoError(t, compareResults(set("HEAD"), set("main"), nil))
+		assert.Equal(t, 2, strings.Count(out.String(), "BenchmarkSort[100]"), format)
+		assert.Equal(t, 2, strings.Count(out.String(), "BenchmarkSort[10000]"), format)
+		assert.NotContains(t, out.String(), "BenchmarkSort-4", format)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// benchmarkTypeGo benchmarks are run with "go test", or with their
+	// commands.
+	benchmarkTypeGo = "go"
+	// benchmarkTypeBazel benchmarks are run with "bazel run" on a go_test
+	// target, the flags of the test binary being passed after "--".
+	benchmarkTypeBazel = "bazel"
+	// benchmarkTypeMake benchmarks are run with "make", the settings of the
+	// benchmark being passed in the environment.
+	benchmarkTypeMake = "make"
+)
+
+// validateTarget checks the settings of bazel and make benchmarks. Profiling
+// relies on the flags of "go test" and is not supported for them.
+func validateTarget(benchmark *Benchmark) error {
+	switch benchmark.Type {
+	case "", benchmarkTypeGo:
+		return nil
+	case benchmarkTypeBazel, benchmarkTypeMake:
+	default:
+		return fmt.Errorf("unsupported type '%s', must be one of %s, %s or %s", benchmark.Type, benchmarkTypeGo, benchmarkTypeBazel, benchmarkTypeMake)
+	}
+	if benchmark.Target == "" {
+		return fmt.Errorf("a target is required for type %s", benchmark.Type)
+	}
+	if len(benchmark.Commands) > 0 {
+		return fmt.Errorf("commands cannot be used with type %s", benchmark.Type)
+	}
+	if *benchmark.Flamegraph || *benchmark.Trace || *benchmark.Contention {
+		return fmt.Errorf("flamegraph, trace and contention are not supported with type %s", benchmark.Type)
+	}
+	return nil
+}
+
+// isTarget returns true if the benchmark is run with bazel or make.
+func isTarget(benchmark *Benchmark) bool {
+	return benchmark.Type == benchmarkTypeBazel || benchmark.Type == benchmarkTypeMake
+}
+
+// targetArgs returns the command running a bazel or make benchmark.
+func targetArgs(benchmark *Benchmark) []string {
+	if benchmark.Type == benchmarkTypeMake {
+		return []string{"make", benchmark.Target}
+	}
+	args := []string{"bazel", "run", benchmark.Target, "--",
+		"-test.run=^$",
+		"-test.bench=" + benchmark.Name,
+		"-test.benchtime=" + benchmark.Benchtime,
+		"-test.timeout=" + benchmark.Timeout,
+		"-test.cpu=" + benchmark.Cpu,
+		"-test.v",
+	}
+	if *benchmark.Benchmem {
+		args = append(args, "-test.benchmem")
+	}
+	if benchmark.Count > 1 {
+		args = append(args, "-test.count="+strconv.Itoa(benchmark.Count))
+	}
+	// everything after "--" is already passed to the test binary
+	return append(args, testBinaryArgs(benchmark)...)
+}
+
+// runTarget runs a bazel or make benchmark and returns its output, which is
+// parsed like the output of "go test". The settings of the benchmark are also
+// passed in the environment, for make targets to forward them to the test
+// binary.
+func runTarget(dir string, vars refVars, benchmark *Benchmark) ([]byte, error) {
+	if err := validateTarget(benchmark); err != nil {
+		return nil, fmt.Errorf("invalid benchmark '%s': %w", benchmark.UniqueName, err)
+	}
+	var stderr bytes.Buffer
+	cmd := execCommand(dir, vars, targetArgs(benchmark))
+	cmd.Stderr = &stderr
+	cmd.Env = append(cmd.Env,
+		"BENCHCI_BENCHMARK="+benchmark.UniqueName,
+		"BENCHCI_PACKAGE="+benchmark.Package,
+		"BENCHCI_BENCH="+benchmark.Name,
+		"BENCHCI_BENCHTIME="+benchmark.Benchtime,
+		"BENCHCI_COUNT="+strconv.Itoa(benchmark.Count),
+		"BENCHCI_CPU="+benchmark.Cpu,
+		"BENCHCI_ARGS="+strings.Join(testBinaryArgs(benchmark), " "),
+	)
+	cmd.Env = append(cmd.Env, sweepEnv(benchmark)...)
+	klog.InfoS("Running benchmark", "benchmark", benchmark.UniqueName, "command", cmd)
+	out, err := cmd.Output()
+	if err != nil {
+		klog.InfoS("Exec command output", "out", string(out))
+		klog.InfoS("Exec command error", "err", stderr.String())
+		return nil, fmt.Errorf("failed to run '%s' command: %w", cmd, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTarget(t *testing.T) {
+	enabled, disabled := true, false
+	config := BenchmarkConfiguration{Flamegraph: &disabled, Trace: &disabled, Contention: &disabled}
+	assert.NoError(t, validateTarget(&Benchmark{Name: "BenchmarkFoo", BenchmarkConfiguration: config}))
+	assert.NoError(t, validateTarget(&Benchmark{Name: "BenchmarkFoo", Type: "bazel", Target: "//pkg/foo:foo_test", BenchmarkConfiguration: config}))
+	assert.Error(t, validateTarget(&Benchmark{Name: "BenchmarkFoo", Type: "cmake", Target: "bench", BenchmarkConfiguration: config}))
+	assert.EqualError(t, validateTarget(&Benchmark{Name: "BenchmarkFoo", Type: "make", BenchmarkConfiguration: config}), "a target is required for type make")
+	assert.Error(t, validateTarget(&Benchmark{Name: "BenchmarkFoo", Type: "make", Target: "bench", Commands: []CommandStep{{}}, BenchmarkConfiguration: config}))
+	config.Flamegraph = &enabled
+	assert.Error(t, validateTarget(&Benchmark{Name: "BenchmarkFoo", Type: "make", Target: "bench", BenchmarkConfiguration: config}))
+}
+
+func TestTargetArgs(t *testing.T) {
+	benchmem := true
+	benchmark := &Benchmark{
+		Name:   "BenchmarkFoo",
+		Type:   "bazel",
+		Target: "//pkg/foo:foo_test",
+		BenchmarkConfiguration: BenchmarkConfiguration{
+			Benchtime: "10x", Timeout: "10m", Cpu: "2", Count: 5, Benchmem: &benchmem,
+		},
+		Args:       []string{"-scale=1000", "-size=1"},
+		Sweep:      &Sweep{Arg: "-size", Values: []string{"10"}},
+		sweepValue: "10",
+	}
+	assert.Equal(t, []string{"bazel", "run", "//pkg/foo:foo_test", "--",
+		"-test.run=^$", "-test.bench=BenchmarkFoo", "-test.benchtime=10x", "-test.timeout=10m", "-test.cpu=2", "-test.v",
+		"-test.benchmem", "-test.count=5", "-scale=1000", "-size=1", "-size=10",
+	}, targetArgs(benchmark))
+
+	benchmark.Type, benchmark.Target = "make", "bench-foo"
+	assert.Equal(t, []string{"make", "bench-foo"}, targetArgs(benchmark))
+}
+
+func TestRunTarget(t *testing.T) {
+	if _, err := exec.LookPath("make"); err != nil {
+		t.Skip("make is not available")
+	}
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Makefile"), []byte("bench:\n\t@echo \"$$BENCHCI_BENCH-$$BENCHCI_CPU 100 $$BENCHCI_BENCHTIME ns/op\"\n"), 0644))
+
+	disabled := false
+	benchmark := &Benchmark{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", Type: "make", Target: "bench", BenchmarkConfiguration: BenchmarkConfiguration{
+		Benchtime: "10", Cpu: "4", Count: 1, Flamegraph: &disabled, Trace: &disabled, Contention: &disabled,
+	}}
+	out, err := runTarget(dir, refVars{Ref: "HEAD"}, benchmark)
+	require.NoError(t, err)
+	assert.Equal(t, "BenchmarkFoo-4 100 10 ns/op\n", string(out))
+
+	benchmark.Target = "unknown"
+	_, err = runTarget(dir, refVars{Ref: "HEAD"}, benchmark)
+	assert.Error(t, err)
+}
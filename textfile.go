@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var textfilePath string
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// metricFamily is a gauge of the textfile, with one sample per label set.
+type metricFamily struct {
+	name, help string
+	samples    []string
+}
+
+func (f *metricFamily) add(value float64, labels ...string) {
+	pairs := make([]string, 0, len(labels)/2+len(runMeta))
+	for _, k := range runMeta.keys() {
+		labels = append(labels, k, runMeta[k])
+	}
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	sample := f.name
+	if len(pairs) > 0 {
+		sample += "{" + strings.Join(pairs, ",") + "}"
+	}
+	f.samples = append(f.samples, sample+" "+strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// textfileMetrics formats the results and the comparisons in the OpenMetrics
+// text format.
+func textfileMetrics(head *refResults, comparisons []comparison, failed bool, now time.Time) string {
+	nsPerOp := &metricFamily{name: "benchci_ns_per_op", help: "Time per operation in nanoseconds."}
+	bytesPerOp := &metricFamily{name: "benchci_alloced_bytes_per_op", help: "Bytes allocated per operation."}
+	allocsPerOp := &metricFamily{name: "benchci_allocs_per_op", help: "Allocations per operation."}
+	ratio := &metricFamily{name: "benchci_ratio", help: "Relative change of a metric compared with a comparison base."}
+	regression := &metricFamily{name: "benchci_regression", help: "Whether the benchmark regressed compared with a comparison base."}
+	lastRun := &metricFamily{name: "benchci_last_run_timestamp_seconds", help: "Time of the last run."}
+	failedRun := &metricFamily{name: "benchci_failed", help: "Whether the last run failed."}
+
+	refs := []*refResults{head}
+	for _, c := range comparisons {
+		if c.base != nil {
+			refs = append(refs, c.base)
+		}
+	}
+	for _, r := range refs {
+		names := make([]string, 0, len(r.run.Results))
+		for name := range r.run.Results {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			b := r.run.Results[name]
+			nsPerOp.add(b.NsPerOp, "benchmark", name, "ref", r.name)
+			bytesPerOp.add(float64(b.AllocedBytesPerOp), "benchmark", name, "ref", r.name)
+			allocsPerOp.add(float64(b.AllocsPerOp), "benchmark", name, "ref", r.name)
+		}
+	}
+	for _, c := range comparisons {
+		if c.base == nil {
+			continue
+		}
+		for _, result := range c.results {
+			ratio.add(result.RatioNsPerOp, "benchmark", result.UniqueName, "base", c.base.name, "metric", "ns/op")
+			ratio.add(result.RatioAllocedBytesPerOp, "benchmark", result.UniqueName, "base", c.base.name, "metric", "B/op")
+			comparedScore := whichScoreToCompare(result.Compare)
+			if comparedScore.allocsPerOp {
+				ratio.add(result.RatioAllocsPerOp, "benchmark", result.UniqueName, "base", c.base.name, "metric", "allocs/op")
+			}
+			if comparedScore.mbPerS {
+				ratio.add(result.RatioMBPerS, "benchmark", result.UniqueName, "base", c.base.name, "metric", "MB/s")
+			}
+			value := 0.0
+			if isRegression(result) {
+				value = 1
+			}
+			regression.add(value, "benchmark", result.UniqueName, "base", c.base.name)
+		}
+	}
+	lastRun.add(float64(now.Unix()), "run_id", runID)
+	if failed {
+		failedRun.add(1)
+	} else {
+		failedRun.add(0)
+	}
+
+	var b strings.Builder
+	for _, f := range []*metricFamily{nsPerOp, bytesPerOp, allocsPerOp, ratio, regression, lastRun, failedRun} {
+		if len(f.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n", f.name, f.help, f.name)
+		for _, sample := range f.samples {
+			fmt.Fprintln(&b, sample)
+		}
+	}
+	b.WriteString("# EOF\n")
+	return b.String()
+}
+
+// writeTextfile writes the metrics for the node_exporter textfile collector.
+// The file is replaced atomically, so that a scrape never sees a partially
+// written file.
+func writeTextfile(head *refResults, comparisons []comparison, failed bool) error {
+	if textfilePath == "" {
+		return nil
+	}
+	if err := writeFileAtomic(textfilePath, []byte(textfileMetrics(head, comparisons, failed, time.Now()))); err != nil {
+		return fmt.Errorf("unable to write textfile: %w", err)
+	}
+	return nil
+}
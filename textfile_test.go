@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestTextfileMetrics(t *testing.T) {
+	defer func(id string) { runID = id }(runID)
+	runID = "run"
+	head := &refResults{name: "HEAD", run: &RunResult{Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{NsPerOp: 125, AllocedBytesPerOp: 64, AllocsPerOp: 2}},
+	}}}
+	base := &refResults{name: `release-"1"`, run: &RunResult{Results: Set{
+		"BenchmarkFoo": {Benchmark: &parse.Benchmark{NsPerOp: 100, AllocedBytesPerOp: 64, AllocsPerOp: 2}},
+	}}}
+	contention := false
+	results := []result{{
+		Benchmark:    Benchmark{UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.1, Compare: "ns/op", Contention: &contention}},
+		RatioNsPerOp: 0.25,
+	}}
+
+	metrics := textfileMetrics(head, []comparison{{base, results, true}, {nil, nil, false}}, true, time.Unix(1634169600, 0))
+	assert.Equal(t, `# HELP benchci_ns_per_op Time per operation in nanoseconds.
+# TYPE benchci_ns_per_op gauge
+benchci_ns_per_op{benchmark="BenchmarkFoo",ref="HEAD"} 125
+benchci_ns_per_op{benchmark="BenchmarkFoo",ref="release-\"1\""} 100
+# HELP benchci_alloced_bytes_per_op Bytes allocated per operation.
+# TYPE benchci_alloced_bytes_per_op gauge
+benchci_alloced_bytes_per_op{benchmark="BenchmarkFoo",ref="HEAD"} 64
+benchci_alloced_bytes_per_op{benchmark="BenchmarkFoo",ref="release-\"1\""} 64
+# HELP benchci_allocs_per_op Allocations per operation.
+# TYPE benchci_allocs_per_op gauge
+benchci_allocs_per_op{benchmark="BenchmarkFoo",ref="HEAD"} 2
+benchci_allocs_per_op{benchmark="BenchmarkFoo",ref="release-\"1\""} 2
+# HELP benchci_ratio Relative change of a metric compared with a comparison base.
+# TYPE benchci_ratio gauge
+benchci_ratio{benchmark="BenchmarkFoo",base="release-\"1\"",metric="ns/op"} 0.25
+benchci_ratio{benchmark="BenchmarkFoo",base="release-\"1\"",metric="B/op"} 0
+# HELP benchci_regression Whether the benchmark regressed compared with a comparison base.
+# TYPE benchci_regression gauge
+benchci_regression{benchmark="BenchmarkFoo",base="release-\"1\""} 1
+# HELP benchci_last_run_timestamp_seconds Time of the last run.
+# TYPE benchci_last_run_timestamp_seconds gauge
+benchci_last_run_timestamp_seconds{run_id="run"} 1634169600
+# HELP benchci_failed Whether the last run failed.
+# TYPE benchci_failed gauge
+benchci_failed 1
+# EOF
+`, metrics)
+}
+
+func TestWriteTextfile(t *testing.T) {
+	defer func(path string) { textfilePath = path }(textfilePath)
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	head := &refResults{name: "HEAD", run: &RunResult{Results: Set{}}}
+	textfilePath = ""
+	require.NoError(t, writeTextfile(head, nil, false))
+
+	textfilePath = filepath.Join(dir, "benchci.prom")
+	require.NoError(t, writeTextfile(head, nil, false))
+	data, err := ioutil.ReadFile(textfilePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "benchci_failed 0\n# EOF\n")
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"k8s.io/klog/v2"
+)
+
+// comparedBenchmark returns the benchmark with the threshold used for the
+// comparison with base: ReleaseThreshold for the latest release, and
+// TouchedThreshold for the base ref if the package of the benchmark was
+// modified since then and it is stricter.
+func comparedBenchmark(benchmark Benchmark, base *refResults) Benchmark {
+	if base.latestRelease && benchmark.ReleaseThreshold != 0 {
+		benchmark.Threshold = benchmark.ReleaseThreshold
+	}
+	if base.diffBase && benchmark.touched && benchmark.TouchedThreshold != 0 && benchmark.TouchedThreshold < benchmark.Threshold {
+		benchmark.Threshold = benchmark.TouchedThreshold
+	}
+	return benchmark
+}
+
+// touchedDirs returns the directories, relative to the root of the
+// repository, which contain files added, modified or deleted between two
+// commits.
+func touchedDirs(r *git.Repository, from, to plumbing.Hash) (map[string]bool, error) {
+	trees := make([]*object.Tree, 0, 2)
+	for _, hash := range []plumbing.Hash{from, to} {
+		c, err := r.CommitObject(hash)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get commit %v: %w", hash, err)
+		}
+		tree, err := c.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get the tree of commit %v: %w", hash, err)
+		}
+		trees = append(trees, tree)
+	}
+	changes, err := object.DiffTree(trees[0], trees[1])
+	if err != nil {
+		return nil, fmt.Errorf("unable to diff %v and %v: %w", from, to, err)
+	}
+	dirs := map[string]bool{}
+	for _, change := range changes {
+		for _, name := range []string{change.From.Name, change.To.Name} {
+			if name != "" {
+				dirs[path.Dir(name)] = true
+			}
+		}
+	}
+	return dirs, nil
+}
+
+// packageDirs returns the directories of the packages matched by a package
+// pattern, relative to dir.
+func packageDirs(cmdStr, dir, pkg string) ([]string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(cmdStr, "list", "-f", "{{.Dir}}", pkg)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		klog.InfoS("Exec command error", "err", stderr.String())
+		return nil, fmt.Errorf("failed to run '%s' command: %w", cmd, err)
+	}
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if line == "" {
+			continue
+		}
+		rel, err := filepath.Rel(root, line)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			// e.g. a package from the module cache
+			continue
+		}
+		dirs = append(dirs, filepath.ToSlash(rel))
+	}
+	return dirs, nil
+}
+
+// markTouchedBenchmarks marks the benchmarks with a TouchedThreshold whose
+// package directly contains files modified between the base ref and HEAD.
+// Packages which are only affected through their dependencies are not
+// touched.
+func markTouchedBenchmarks(r *git.Repository, base, head plumbing.Hash) error {
+	var dirs map[string]bool
+	for i := range benchmarks.Benchmarks {
+		benchmark := &benchmarks.Benchmarks[i]
+		if benchmark.TouchedThreshold == 0 || benchmark.Skip != "" || benchmark.Package == "" {
+			continue
+		}
+		if dirs == nil {
+			var err error
+			if dirs, err = touchedDirs(r, base, head); err != nil {
+				return err
+			}
+		}
+		pkgDirs, err := packageDirs(goCommand(&benchmark.BenchmarkConfiguration), "", benchmark.Package)
+		if err != nil {
+			return fmt.Errorf("unable to find the package of benchmark '%s': %w", benchmark.UniqueName, err)
+		}
+		for _, dir := range pkgDirs {
+			if dirs[dir] {
+				benchmark.touched = true
+				klog.InfoS("Package of benchmark was modified, using the touched threshold", "benchmark", benchmark.UniqueName, "package", benchmark.Package, "threshold", benchmark.TouchedThreshold)
+				break
+			}
+		}
+	}
+	return nil
+}
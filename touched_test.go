@@ -0,0 +1,69 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestComparedBenchmark(t *testing.T) {
+	benchmark := Benchmark{BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.2, ReleaseThreshold: 0.3, TouchedThreshold: 0.1}}
+	base := &refResults{name: "main", diffBase: true}
+	release := &refResults{name: "v1.2.0", latestRelease: true}
+	assert.Equal(t, 0.2, comparedBenchmark(benchmark, base).Threshold)
+	assert.Equal(t, 0.3, comparedBenchmark(benchmark, release).Threshold)
+	benchmark.touched = true
+	assert.Equal(t, 0.1, comparedBenchmark(benchmark, base).Threshold)
+	assert.Equal(t, 0.3, comparedBenchmark(benchmark, release).Threshold)
+	// the touched threshold can only be stricter
+	benchmark.TouchedThreshold = 0.5
+	assert.Equal(t, 0.2, comparedBenchmark(benchmark, base).Threshold)
+}
+
+func TestTouchedDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	run := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	write := func(name, contents string) {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	run("init", "-q")
+	write("go.mod", "module example.com/m\n")
+	write("pkg/foo/foo.go", "package foo\n")
+	write("pkg/foo/bar/bar.go", "package bar\n")
+	write("pkg/baz/baz.go", "package baz\n")
+	run("add", ".")
+	run("commit", "-q", "-m", "base")
+	base := plumbing.NewHash(run("rev-parse", "HEAD"))
+	write("pkg/foo/bar/bar.go", "package bar\n\nconst Bar = 1\n")
+	run("rm", "-q", "pkg/baz/baz.go")
+	run("commit", "-q", "-a", "-m", "head")
+	head := plumbing.NewHash(run("rev-parse", "HEAD"))
+
+	r, err := git.PlainOpen(dir)
+	require.NoError(t, err)
+	dirs, err := touchedDirs(r, base, head)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"pkg/foo/bar": true, "pkg/baz": true}, dirs)
+
+	pkgDirs, err := packageDirs("go", dir, "./pkg/...")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pkg/foo", "pkg/foo/bar"}, pkgDirs)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"k8s.io/klog/v2"
+)
+
+var (
+	trendWindow    int
+	trendThreshold ratioValue
+)
+
+// trendAlert is a benchmark whose ns/op crept up over the last commits of the
+// history, while no single commit exceeded its threshold.
+type trendAlert struct {
+	benchmark string
+	commits   int
+	ratio     float64
+	// first and last are the commits of the suspected range.
+	first, last string
+}
+
+// trendAlerts looks for creeping regressions over the last trendWindow
+// commits of the history of a branch, HEAD included. A benchmark is flagged
+// when its mean ns/op shifted by more than -trend-threshold (its threshold if
+// not set) while no commit of the suspected range exceeded its threshold.
+func trendAlerts(entries []*historyEntry, branch string) []trendAlert {
+	var alerts []trendAlert
+	for _, benchmark := range benchmarks.Benchmarks {
+		if benchmark.Skip != "" || benchmark.Calibration || !whichScoreToCompare(benchmark.Compare).nsPerOp {
+			continue
+		}
+		trend := benchmarkTrend(entries, benchmark.UniqueName, branch, trendWindow)
+		values := make([]float64, 0, len(trend))
+		for _, entry := range trend {
+			values = append(values, entry.Results[benchmark.UniqueName].NsPerOp)
+		}
+		threshold := float64(trendThreshold)
+		if threshold == 0 {
+			threshold = benchmark.Threshold
+		}
+		first, last, ratio, ok := creepingChange(values, threshold, benchmark.Threshold)
+		if !ok {
+			continue
+		}
+		alerts = append(alerts, trendAlert{
+			benchmark: benchmark.UniqueName,
+			commits:   len(trend),
+			ratio:     ratio,
+			first:     trend[first].Commit,
+			last:      trend[last].Commit,
+		})
+	}
+	return alerts
+}
+
+// historyTrendAlerts returns the trend alerts of the current branch, if
+// -history is set. Like the other reporters, a failure is only logged.
+func historyTrendAlerts() []trendAlert {
+	if historyPath == "" || trendWindow == 0 {
+		return nil
+	}
+	store, err := openHistory(historyPath)
+	if err != nil {
+		klog.ErrorS(err, "Failed to open history")
+		return nil
+	}
+	entries, err := store.entries()
+	if err != nil {
+		klog.ErrorS(err, "Failed to read history", "path", historyPath)
+		return nil
+	}
+	branch, err := currentBranch()
+	if err != nil {
+		klog.InfoS("Unable to detect the branch, looking for trends in the whole history", "err", err)
+	}
+	return trendAlerts(entries, branch)
+}
+
+// showTrendAlerts writes the trend alerts, which do not fail the run.
+func showTrendAlerts(w io.Writer, alerts []trendAlert) {
+	if len(alerts) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nTrend alerts")
+	fmt.Fprintf(w, "%s\n\n", strings.Repeat("=", 12))
+
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetHeader(trendAlertHeaders)
+	for _, alert := range alerts {
+		table.Rich(alert.row(), []tablewriter.Colors{{}, {}, generateColor(alert.ratio), {}})
+	}
+	table.Render()
+	fmt.Fprintln(w)
+}
+
+var trendAlertHeaders = []string{"Name", "Commits", "Drift NsPerOp", "Suspected commits"}
+
+func (a trendAlert) row() []string {
+	commits := shortCommit(a.first)
+	if a.last != a.first {
+		commits += ".." + shortCommit(a.last)
+	}
+	return []string{a.benchmark, strconv.Itoa(a.commits), signedRatio(a.ratio), commits}
+}
+
+// jsonTrendAlert is a trend alert in the JSON report, ratio is the drift of
+// ns/op, e.g. 0.16 for +16%.
+type jsonTrendAlert struct {
+	Benchmark string  `json:"benchmark"`
+	Commits   int     `json:"commits"`
+	Ratio     float64 `json:"ratio"`
+	First     string  `json:"first"`
+	Last      string  `json:"last"`
+}
+
+func newJSONTrendAlerts(alerts []trendAlert) []jsonTrendAlert {
+	var jsonAlerts []jsonTrendAlert
+	for _, a := range alerts {
+		jsonAlerts = append(jsonAlerts, jsonTrendAlert{Benchmark: a.benchmark, Commits: a.commits, Ratio: a.ratio, First: a.first, Last: a.last})
+	}
+	return jsonAlerts
+}
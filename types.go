@@ -3,22 +3,251 @@ package main
 type BenchmarkConfiguration struct {
 	Benchtime string  `yaml:"benchtime"`
 	Threshold float64 `yaml:"threshold"`
-	Compare   string  `yaml:"compare"`
-	Cpu       string  `yaml:"cpu"`
-	Timeout   string  `yaml:"timeout"`
-	Benchmem  *bool   `yaml:"benchmem,omitempty"`
+	// ReleaseThreshold is the threshold used for the comparison with the
+	// latest release, Threshold is used if it is not set.
+	ReleaseThreshold float64 `yaml:"releaseThreshold,omitempty"`
+	// TouchedThreshold is the threshold used for the comparison with the base
+	// ref when the package of the benchmark was modified since then, if it is
+	// stricter than Threshold.
+	TouchedThreshold float64 `yaml:"touchedThreshold,omitempty"`
+	// WarnThreshold is the ratio above which a slowdown which does not
+	// exceed Threshold is reported as a warning, disabled if 0.
+	WarnThreshold float64 `yaml:"warnThreshold,omitempty"`
+	Compare       string  `yaml:"compare"`
+	Cpu           string  `yaml:"cpu"`
+	Timeout       string  `yaml:"timeout"`
+	Benchmem      *bool   `yaml:"benchmem,omitempty"`
+	// Flamegraph enables CPU profiling and flamegraph generation.
+	Flamegraph *bool `yaml:"flamegraph,omitempty"`
+	// Trace enables execution tracing, for regressions caused by goroutine
+	// scheduling which do not show up in profiles.
+	Trace *bool `yaml:"trace,omitempty"`
+	// Contention enables block and mutex profiling, the total contention
+	// time can then be compared with "contention" in Compare.
+	Contention *bool `yaml:"contention,omitempty"`
+	// MemStats enables GC tracing to report the peak heap size, which can
+	// then be compared with "heap" in Compare.
+	MemStats *bool `yaml:"memstats,omitempty"`
+	// OutputFilters are regexes matching lines of the "go test" output which
+	// are dropped before parsing, e.g. log messages.
+	OutputFilters []string `yaml:"outputFilters,omitempty"`
+	// MetricThresholds are the thresholds of custom metrics, by unit,
+	// Threshold is used for the units which are not listed.
+	MetricThresholds map[string]float64 `yaml:"metricThresholds,omitempty"`
+	// GoBinary is the go command used to run the benchmarks, e.g.
+	// "/opt/go1.22.3/bin/go", for environments with several toolchains. The
+	// command of the list is used if it is not set.
+	GoBinary string `yaml:"goBinary,omitempty"`
+	// Count is the number of times each benchmark is run, the median of the
+	// samples is used.
+	Count int `yaml:"count,omitempty"`
+	// Limits are the resources available to the test process, to reproduce
+	// the constraints of a pod.
+	Limits *ResourceLimits `yaml:"limits,omitempty"`
+	// NumaNode binds the test process to the CPUs and the memory of a NUMA
+	// node, to avoid cross-node memory accesses on multi-socket machines.
+	NumaNode *int `yaml:"numaNode,omitempty"`
+	// AvoidSMTSiblings pins the test process to one CPU per core, so that
+	// its threads do not interfere with each other through SMT.
+	AvoidSMTSiblings *bool `yaml:"avoidSMTSiblings,omitempty"`
+	// Epsilon is a band around the threshold: changes of ns/op and B/op
+	// within it are regressions (or improvements) only if the samples are
+	// significantly different according to a Mann-Whitney U test at level
+	// Alpha (0.05 by default).
+	Epsilon float64 `yaml:"epsilon,omitempty"`
+	Alpha   float64 `yaml:"alpha,omitempty"`
+	// Significance requires all the changes of ns/op and B/op to be
+	// statistically significant at level Alpha to be reported, not only the
+	// ones within Epsilon of the threshold. It has no effect when there are
+	// not enough samples for the test to reach Alpha, e.g. with a Count
+	// below 4 at 0.05.
+	Significance *bool `yaml:"significance,omitempty"`
+}
+
+// ResourceLimits are enforced by running the test process in a dedicated
+// cgroup. CPU is in cores or millicores ("2", "500m") and Memory in bytes with
+// an optional unit ("1Gi", "512M").
+type ResourceLimits struct {
+	CPU    string `yaml:"cpu,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
 }
 
 type Benchmark struct {
-	Name                   string `yaml:"name"`
-	Package                string `yaml:"package"`
-	UniqueName             string `yaml:"uniqueName"`
-	VersionRequirement     string `yaml:"versionRequirement"`
+	Name               string `yaml:"name"`
+	Package            string `yaml:"package"`
+	UniqueName         string `yaml:"uniqueName"`
+	VersionRequirement string `yaml:"versionRequirement"`
+	// Skip disables the benchmark, the reason is shown in the report.
+	Skip string `yaml:"skip,omitempty"`
+	// Description explains what the benchmark measures, and Link points to
+	// its documentation, e.g. a design document or a dashboard. They are
+	// shown in the reports when the benchmark regresses.
+	Description string `yaml:"description,omitempty"`
+	Link        string `yaml:"link,omitempty"`
+	// Owner is mentioned in the Markdown report when the benchmark regresses,
+	// e.g. "@antrea-io/agent-maintainers", so that they are notified when
+	// the report is posted as a pull request comment.
+	Owner string `yaml:"owner,omitempty"`
+	// DeprecatedAfter is the last version for which the benchmark is relevant.
+	DeprecatedAfter string `yaml:"deprecatedAfter,omitempty"`
+	// Weight of the benchmark in the aggregate compared with the total
+	// budget, 1 by default.
+	Weight float64 `yaml:"weight,omitempty"`
+	// Type is "go" (the default) for benchmarks run with "go test", or
+	// "bazel" or "make" for benchmarks run with Target, whose output is in
+	// the "go test" format.
+	Type string `yaml:"type,omitempty"`
+	// Target is the label of a go_test target with type bazel, e.g.
+	// "//pkg/agent:agent_test", or the make target with type make.
+	Target string `yaml:"target,omitempty"`
+	// Commands are the steps run for the benchmark, instead of only running
+	// it with "go test".
+	Commands []CommandStep `yaml:"commands,omitempty"`
+	// Scrape is a Prometheus endpoint scraped before and after the
+	// benchmark, the increase of its counters being recorded as custom
+	// metrics.
+	Scrape *Scrape `yaml:"scrape,omitempty"`
+	// Args are custom flags of the test binary, passed after -args, e.g.
+	// "-policyCount=500".
+	Args []string `yaml:"args,omitempty"`
+	// Calibration marks the benchmark used to normalize the ns/op values of
+	// each ref, to compensate for machine speed differences. It is not
+	// compared itself.
+	Calibration bool `yaml:"calibration,omitempty"`
+	// Sweep runs the benchmark once per input size, to compare how it
+	// scales in addition to each size.
+	Sweep                  *Sweep `yaml:"sweep,omitempty"`
 	BenchmarkConfiguration `yaml:",inline"`
+
+	// sweepOf is the unique name of the configured benchmark for the entries
+	// expanded from a sweep, which run it for sweepValue.
+	sweepOf, sweepValue string
+	// cpuOf is the unique name of the benchmark for the entries expanded
+	// from a list of cpu values, which run it for a single value.
+	cpuOf string
+	// variant is true for the entries expanded from a sweep or from a list
+	// of cpu values, except the first one: they run the same benchmark
+	// function as the first entry.
+	variant bool
+	// touched is true if the package of the benchmark was modified since the
+	// base ref.
+	touched bool
+}
+
+// Sweep is a list of input sizes, passed to the benchmark with an environment
+// variable or with a flag of the test binary.
+type Sweep struct {
+	// Env is the environment variable set to each value, e.g. "SIZE".
+	Env string `yaml:"env,omitempty"`
+	// Arg is the flag of the test binary set to each value with -args, e.g.
+	// "-size".
+	Arg    string   `yaml:"arg,omitempty"`
+	Values []string `yaml:"values"`
+	// SlopeThreshold is the maximum increase of the scaling exponent (e.g.
+	// 0.1 from 1.0 for a linear benchmark), 0.1 by default.
+	SlopeThreshold float64 `yaml:"slopeThreshold,omitempty"`
+}
+
+// Scrape selects counters of a Prometheus endpoint, e.g. the one of a
+// component started by a setup hook.
+type Scrape struct {
+	// URL is the URL of the metrics endpoint, e.g.
+	// "http://127.0.0.1:10350/metrics".
+	URL      string           `yaml:"url"`
+	Counters []ScrapedCounter `yaml:"counters"`
+}
+
+// ScrapedCounter is a counter of a Prometheus endpoint. The values of all the
+// series matching its labels are summed.
+type ScrapedCounter struct {
+	// Metric is the name of the counter, e.g. "ovs_flow_mods_total".
+	Metric string            `yaml:"metric"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// Unit is the name of the custom metric recording the increase of the
+	// counter, Metric by default.
+	Unit string `yaml:"unit,omitempty"`
+}
+
+// CommandStep is a command run for a benchmark, e.g. to generate fixtures or
+// to collect statistics.
+type CommandStep struct {
+	// Name identifies the step in logs.
+	Name string `yaml:"name,omitempty"`
+	// Args are the command and its arguments. A step with no arguments runs
+	// the benchmark with "go test", with all the usual settings.
+	Args []string `yaml:"args,omitempty"`
+	// Output marks the step whose output is parsed as benchmark results. By
+	// default, the output of the "go test" step is used.
+	Output bool `yaml:"output,omitempty"`
 }
 
 type BenchmarkList struct {
 	BenchmarkConfiguration `yaml:",inline"`
-	Command                string      `yaml:"command"`
-	Benchmarks             []Benchmark `yaml:"benchmarks"`
+	Command                string `yaml:"command"`
+	// BasePolicy and ReleasePolicy determine what happens in case of
+	// regression compared with the base ref and with the latest release
+	// respectively: "fail" or "warn".
+	BasePolicy    string `yaml:"basePolicy,omitempty"`
+	ReleasePolicy string `yaml:"releasePolicy,omitempty"`
+	// FailOn is a shorthand for both policies: "none" (report only),
+	// "regression", "regression-vs-base" or "regression-vs-release".
+	FailOn     string      `yaml:"failOn,omitempty"`
+	Benchmarks []Benchmark `yaml:"benchmarks"`
+	// CarryFiles are files or directories (e.g. testdata) which are copied
+	// from HEAD to the other checkouts before running benchmarks.
+	CarryFiles []string `yaml:"carryFiles,omitempty"`
+	// Profiles are named sets of settings, e.g. for PR and nightly runs. The
+	// settings of the selected profile override the top-level settings, but
+	// not the settings of individual benchmarks.
+	Profiles map[string]BenchmarkConfiguration `yaml:"profiles,omitempty"`
+	// Build hooks are run once for each ref before the setup hooks, e.g. to
+	// build images. Their duration is reported separately.
+	Build []CommandStep `yaml:"build,omitempty"`
+	// Setup and Teardown are hooks run in the source tree before and after
+	// running the benchmarks of each ref, e.g. to create a cluster.
+	Setup    []CommandStep `yaml:"setup,omitempty"`
+	Teardown []CommandStep `yaml:"teardown,omitempty"`
+	// Discover adds entries for the benchmark functions found in packages.
+	Discover []Discovery `yaml:"discover,omitempty"`
+	// ReleaseEnv configures the environment in which the latest release is
+	// built and benchmarked.
+	ReleaseEnv *ReleaseEnv `yaml:"releaseEnv,omitempty"`
+	// MetricDirections tell whether lower or higher is better for custom
+	// metrics ("lower-better" or "higher-better"), by unit. Lower is better
+	// by default.
+	MetricDirections map[string]string `yaml:"metricDirections,omitempty"`
+	// Issues customizes the GitHub issues filed with -file-issues.
+	Issues *IssueTemplate `yaml:"issues,omitempty"`
+}
+
+// ReleaseEnv isolates the build of old releases, whose go.mod may require an
+// older toolchain or module graph.
+type ReleaseEnv struct {
+	// IsolateModCache uses a temporary GOPATH and module cache, removed
+	// after benchmarking the release.
+	IsolateModCache bool `yaml:"isolateModCache,omitempty"`
+	// Toolchain is the value of GOTOOLCHAIN (Go 1.21+), e.g. "go1.21.13" or
+	// "local".
+	Toolchain string `yaml:"toolchain,omitempty"`
+}
+
+// IssueTemplate is the template of the issues filed for regressions. Title
+// and Body are Go templates, rendered with the regressed benchmark.
+type IssueTemplate struct {
+	Title string `yaml:"title,omitempty"`
+	Body  string `yaml:"body,omitempty"`
+	// Labels are added to the benchci-regression label.
+	Labels []string `yaml:"labels,omitempty"`
+}
+
+// Discovery is a rule to find benchmarks with "go test -list" instead of
+// configuring them one by one.
+type Discovery struct {
+	// Packages is a package pattern, e.g. "./pkg/...".
+	Packages string `yaml:"packages"`
+	// Include and Exclude are regexes matched against the names of the
+	// benchmark functions. All of them are included if Include is empty.
+	Include                []string `yaml:"include,omitempty"`
+	Exclude                []string `yaml:"exclude,omitempty"`
+	BenchmarkConfiguration `yaml:",inline"`
 }
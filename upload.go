@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+
+	"k8s.io/klog/v2"
+)
+
+var uploadReport string
+
+var ansiEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// validateUploadReport fails early if the report cannot be uploaded, rather
+// than after running the benchmarks.
+func validateUploadReport() error {
+	switch uploadReport {
+	case "":
+	case "gist":
+		if os.Getenv("GITHUB_TOKEN") == "" {
+			return fmt.Errorf("GITHUB_TOKEN must be set to upload the report to a gist")
+		}
+	default:
+		if isOCIReference(uploadReport) {
+			_, err := parseOCIReference(uploadReport)
+			return err
+		}
+		return fmt.Errorf("unsupported report upload '%s'", uploadReport)
+	}
+	return nil
+}
+
+// githubAPIURL returns the URL of the GitHub API, for GitHub Enterprise Server
+// as well.
+func githubAPIURL() string {
+	if apiURL := os.Getenv("GITHUB_API_URL"); apiURL != "" {
+		return apiURL
+	}
+	return "https://api.github.com"
+}
+
+// uploadGist creates a secret gist with the report and returns its URL.
+func uploadGist(apiURL, contents string) (string, error) {
+	body := map[string]interface{}{
+		"description": fmt.Sprintf("benchci report (run %s)", runID),
+		"public":      false,
+		"files": map[string]interface{}{
+			"benchci-report.txt": map[string]string{"content": contents},
+		},
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	resp, err := githubRequest(http.MethodPost, apiURL+"/gists", "application/json", data)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var gist struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gist); err != nil {
+		return "", fmt.Errorf("unable to decode the gist: %w", err)
+	}
+	return gist.HTMLURL, nil
+}
+
+// uploadReportContents uploads the report to a gist or pushes it as an OCI
+// artifact, and returns where it can be found.
+func uploadReportContents(contents string) (string, error) {
+	if !isOCIReference(uploadReport) {
+		return uploadGist(githubAPIURL(), contents)
+	}
+	digest, err := pushOCIArtifact(uploadReport, reportArtifactType, reportMediaType, "benchci-report.txt", []byte(contents))
+	if err != nil {
+		return "", err
+	}
+	ref, _ := parseOCIReference(uploadReport)
+	return fmt.Sprintf("%s (%s)", ref, digest), nil
+}
+
+// withUploadedReport runs a command while copying its report, and uploads
+// the report once the command is done, even if it failed, since this is when
+// the report matters most. The link to the report is printed to diagnostics.
+func withUploadedReport(run func() error) error {
+	if uploadReport == "" {
+		return run()
+	}
+	var buf bytes.Buffer
+	defer func(w io.Writer) { report = w }(report)
+	report = io.MultiWriter(report, &buf)
+	err := run()
+	if buf.Len() == 0 {
+		return err
+	}
+	url, uploadErr := uploadReportContents(ansiEscape.ReplaceAllString(buf.String(), ""))
+	if uploadErr != nil {
+		klog.ErrorS(uploadErr, "Failed to upload the report")
+		return err
+	}
+	fmt.Fprintf(diagnostics, "Full report: %s\n", url)
+	return err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithUploadedReport(t *testing.T) {
+	defer func(upload string, r, d io.Writer) { uploadReport, report, diagnostics = upload, r, d }(uploadReport, report, diagnostics)
+	var gist struct {
+		Public bool `json:"public"`
+		Files  map[string]struct {
+			Content string `json:"content"`
+		} `json:"files"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/gists", r.URL.Path)
+		assert.Equal(t, "token secret", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&gist))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"html_url": "https://gist.github.com/abc"}`)
+	}))
+	defer server.Close()
+	os.Setenv("GITHUB_API_URL", server.URL)
+	defer os.Unsetenv("GITHUB_API_URL")
+	os.Setenv("GITHUB_TOKEN", "secret")
+	defer os.Unsetenv("GITHUB_TOKEN")
+
+	var out, diag bytes.Buffer
+	report, diagnostics = &out, &diag
+	uploadReport = "gist"
+	require.NoError(t, validateUploadReport())
+	err := withUploadedReport(func() error {
+		fmt.Fprint(report, "\x1b[31m+25.00%\x1b[0m\n")
+		return fmt.Errorf("regression")
+	})
+	assert.EqualError(t, err, "regression")
+	assert.Equal(t, "\x1b[31m+25.00%\x1b[0m\n", out.String())
+	assert.Equal(t, &out, report)
+	assert.False(t, gist.Public)
+	assert.Equal(t, "+25.00%\n", gist.Files["benchci-report.txt"].Content)
+	assert.Equal(t, "Full report: https://gist.github.com/abc\n", diag.String())
+
+	uploadReport = "pastebin"
+	assert.Error(t, validateUploadReport())
+}
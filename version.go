@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// These variables are set at build time with -ldflags, by the Makefile and by
+// goreleaser (which uses the same variable names by default).
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
+var showVersion bool
+
+// versionInfo identifies the benchci build which produced a file, so that
+// saved results can be checked for format compatibility.
+type versionInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+}
+
+func currentVersion() versionInfo {
+	return versionInfo{Version: version, Commit: commit, Date: date}
+}
+
+func (v versionInfo) String() string {
+	return fmt.Sprintf("benchci version %s, commit %s, built at %s", v.Version, v.Commit, v.Date)
+}
+
+// writeArtifactsMetadata records the benchci version in the artifacts directory.
+func writeArtifactsMetadata() error {
+	data, err := json.MarshalIndent(struct {
+		RunID    string       `json:"runID"`
+		Benchci  versionInfo  `json:"benchci"`
+		Platform platformInfo `json:"platform"`
+	}{runID, currentVersion(), currentPlatform()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(artifactsDir, "metadata.json"), data, 0644)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/olekukonko/tablewriter"
+)
+
+// warnExitCode is the exit code of a run which does not fail but in which some
+// benchmarks exceed their warnThreshold, or regressed with the warn policy, 0
+// to succeed.
+var warnExitCode int
+
+// exitError is an error for which benchci exits with a specific code, rather
+// than the code of a failure.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// isWarning returns true if a result is not a regression, but one of its
+// compared scores got worse by more than its WarnThreshold. The WarnThreshold
+// is ignored unless it is below the threshold of the comparison. The
+// thresholds of custom metrics are lowered in the same proportion.
+func isWarning(r result) bool {
+	if r.WarnThreshold <= 0 || r.WarnThreshold >= r.Threshold || isRegression(r) {
+		return false
+	}
+	factor := r.WarnThreshold / r.Threshold
+	thresholds := make(map[string]float64, len(r.MetricThresholds))
+	for unit, threshold := range r.MetricThresholds {
+		thresholds[unit] = threshold * factor
+	}
+	r.Threshold, r.MetricThresholds = r.WarnThreshold, thresholds
+	return isRegression(r)
+}
+
+// reportOnly returns true with failOn none, in which case neither regressions
+// nor warnings change the exit code.
+func reportOnly() bool {
+	if benchmarks.FailOn != "" {
+		return benchmarks.FailOn == failOnNone
+	}
+	return failOn == failOnNone && benchmarks.BasePolicy == "" && benchmarks.ReleasePolicy == ""
+}
+
+// countWarnings returns the number of results which are warnings.
+func countWarnings(results []result) int {
+	warnings := 0
+	for _, r := range results {
+		if isWarning(r) {
+			warnings++
+		}
+	}
+	return warnings
+}
+
+// warningColors returns the colors of the row of a warning: the name and the
+// slowdowns are yellow rather than red.
+func warningColors(colors []tablewriter.Colors) []tablewriter.Colors {
+	if !useColor {
+		return colors
+	}
+	warning := tablewriter.Colors{tablewriter.Bold, tablewriter.FgYellowColor}
+	colored := make([]tablewriter.Colors, len(colors))
+	for i, c := range colors {
+		if i == 0 || (len(c) > 0 && c[len(c)-1] == tablewriter.FgHiRedColor) {
+			c = warning
+		}
+		colored[i] = c
+	}
+	return colored
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/benchmark/parse"
+)
+
+func TestIsWarning(t *testing.T) {
+	warning := func(threshold, warnThreshold, ratio float64) bool {
+		return isWarning(result{
+			Benchmark: Benchmark{BenchmarkConfiguration: BenchmarkConfiguration{
+				Threshold: threshold, WarnThreshold: warnThreshold, Compare: "ns/op",
+			}},
+			RatioNsPerOp: ratio,
+		})
+	}
+	assert.True(t, warning(0.2, 0.05, 0.1))
+	assert.False(t, warning(0.2, 0.05, 0.01))
+	// a regression is not a warning
+	assert.False(t, warning(0.2, 0.05, 0.3))
+	assert.False(t, warning(0.2, 0, 0.1))
+	assert.False(t, warning(0.2, 0.2, 0.1))
+
+	// the thresholds of custom metrics are lowered in the same proportion
+	metric := func(ratio float64) bool {
+		return isWarning(result{
+			Benchmark: Benchmark{BenchmarkConfiguration: BenchmarkConfiguration{
+				Threshold: 0.2, WarnThreshold: 0.1, Compare: "flows/op", MetricThresholds: map[string]float64{"flows/op": 0.02},
+			}},
+			RatioMetrics: map[string]float64{"flows/op": ratio},
+		})
+	}
+	assert.True(t, metric(0.015))
+	assert.False(t, metric(0.005))
+	assert.False(t, metric(0.03))
+}
+
+func TestWarnExitCode(t *testing.T) {
+	defer func(b BenchmarkList, f, id string, w io.Writer, code int) {
+		*benchmarks, outputFormat, runID, report, warnExitCode = b, f, id, w, code
+	}(*benchmarks, outputFormat, runID, report, warnExitCode)
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.2, WarnThreshold: 0.05, Compare: "ns/op"}},
+	}}
+	require.NoError(t, updateBenchmarks())
+	outputFormat, runID = outputJSON, "run"
+	var out bytes.Buffer
+	report = &out
+
+	result := func(nsPerOp float64) *benchResult {
+		return &benchResult{Benchmark: &parse.Benchmark{N: 10, NsPerOp: nsPerOp}}
+	}
+	head := &refResults{name: "HEAD", ref: "HEAD", run: &RunResult{Results: Set{"BenchmarkFoo": result(110)}}}
+	base := &refResults{name: "main", ref: "main", policy: policyFail, run: &RunResult{Results: Set{"BenchmarkFoo": result(100)}}}
+
+	warnExitCode = 3
+	err := compareResults(head, base, nil)
+	var exitErr *exitError
+	require.True(t, errors.As(err, &exitErr), err)
+	assert.Equal(t, 3, exitErr.code)
+	var r jsonReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &r), out.String())
+	assert.Equal(t, "WARN", r.Verdict)
+	require.Len(t, r.Comparisons, 1)
+	assert.True(t, r.Comparisons[0].Results[0].Warning)
+	assert.False(t, r.Comparisons[0].Results[0].Regression)
+
+	warnExitCode = 0
+	out.Reset()
+	assert.NoError(t, compareResults(head, base, nil))
+}
+
+func TestWarnExitCodePolicy(t *testing.T) {
+	defer func(b BenchmarkList, f, id string, w io.Writer, code int, fo string) {
+		*benchmarks, outputFormat, runID, report, warnExitCode, failOn = b, f, id, w, code, fo
+	}(*benchmarks, outputFormat, runID, report, warnExitCode, failOn)
+	*benchmarks = BenchmarkList{Benchmarks: []Benchmark{
+		{Name: "BenchmarkFoo", UniqueName: "BenchmarkFoo", BenchmarkConfiguration: BenchmarkConfiguration{Threshold: 0.2, Compare: "ns/op"}},
+	}}
+	require.NoError(t, updateBenchmarks())
+	outputFormat, runID, report, warnExitCode = outputJSON, "run", ioutil.Discard, 2
+
+	result := func(nsPerOp float64) *benchResult {
+		return &benchResult{Benchmark: &parse.Benchmark{N: 10, NsPerOp: nsPerOp}}
+	}
+	head := &refResults{name: "HEAD", ref: "HEAD", run: &RunResult{Results: Set{"BenchmarkFoo": result(150)}}}
+	base := &refResults{name: "main", ref: "main", policy: policyWarn, run: &RunResult{Results: Set{"BenchmarkFoo": result(100)}}}
+
+	// a regression tolerated by the policy is a warning
+	err := compareResults(head, base, nil)
+	var exitErr *exitError
+	require.True(t, errors.As(err, &exitErr), err)
+	assert.Equal(t, 2, exitErr.code)
+
+	// but nothing changes the exit code in report-only mode
+	failOn = failOnNone
+	assert.NoError(t, compareResults(head, base, nil))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"k8s.io/klog/v2"
+)
+
+// worktrees are temporary checkouts of the benchmarked commits, created with
+// "git worktree" next to the checkout of the user, which is never modified: a
+// crash while running leaves it at the right commit.
+type worktrees struct {
+	repository string
+	root       string
+	dirs       map[plumbing.Hash]string
+}
+
+func gitWorktree(repository string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", append([]string{"worktree"}, args...)...)
+	cmd.Dir = repository
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		klog.InfoS("Exec command error", "err", stderr.String())
+		return fmt.Errorf("failed to run '%s' command: %w", cmd, err)
+	}
+	return nil
+}
+
+// newWorktrees creates the directory holding the worktrees of the repository.
+func newWorktrees(repository string) (*worktrees, error) {
+	root, err := ioutil.TempDir("", "benchci-worktrees-")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the worktrees directory: %w", err)
+	}
+	return &worktrees{repository: repository, root: root, dirs: map[plumbing.Hash]string{}}, nil
+}
+
+// checkout returns the worktree of a commit, adding it the first time the
+// commit is checked out. created is true if the worktree was just added.
+func (t *worktrees) checkout(commit plumbing.Hash) (dir string, created bool, err error) {
+	if dir, ok := t.dirs[commit]; ok {
+		return dir, false, nil
+	}
+	dir = filepath.Join(t.root, commit.String()[:12])
+	if err := gitWorktree(t.repository, "add", "--detach", dir, commit.String()); err != nil {
+		return "", false, fmt.Errorf("unable to check out commit %v: %w", commit, err)
+	}
+	t.dirs[commit] = dir
+	return dir, true, nil
+}
+
+// discard removes the worktree of a commit, if any.
+func (t *worktrees) discard(commit plumbing.Hash) {
+	dir, ok := t.dirs[commit]
+	if !ok {
+		return
+	}
+	if err := gitWorktree(t.repository, "remove", "--force", dir); err != nil {
+		klog.ErrorS(err, "Failed to remove worktree", "commit", commit, "path", dir)
+	}
+	delete(t.dirs, commit)
+}
+
+// remove removes all the worktrees. A worktree which cannot be removed, e.g.
+// because a benchmark left read-only files behind, is pruned from the
+// repository once its directory is gone.
+func (t *worktrees) remove() {
+	for commit := range t.dirs {
+		t.discard(commit)
+	}
+	if err := os.RemoveAll(t.root); err != nil {
+		klog.ErrorS(err, "Failed to remove the worktrees directory", "path", t.root)
+	}
+	if err := gitWorktree(t.repository, "prune"); err != nil {
+		klog.ErrorS(err, "Failed to prune worktrees")
+	}
+}
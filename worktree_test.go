@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestWorktrees(t *testing.T) {
+	repository, err := ioutil.TempDir("", "benchci-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(repository)
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repository
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(contents string) plumbing.Hash {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(repository, "version"), []byte(contents), 0644))
+		git("add", "version")
+		git("commit", "-q", "-m", contents)
+		return plumbing.NewHash(git("rev-parse", "HEAD"))
+	}
+	git("init", "-q")
+	base := commit("base")
+	head := commit("head")
+
+	checkouts, err := newWorktrees(repository)
+	require.NoError(t, err)
+	dir, created, err := checkouts.checkout(base)
+	require.NoError(t, err)
+	assert.True(t, created)
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "version"))
+	require.NoError(t, err)
+	assert.Equal(t, "base", string(contents))
+	again, created, err := checkouts.checkout(base)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, dir, again)
+	_, _, err = checkouts.checkout(head)
+	require.NoError(t, err)
+
+	// the checkout of the repository is left untouched
+	contents, err = ioutil.ReadFile(filepath.Join(repository, "version"))
+	require.NoError(t, err)
+	assert.Equal(t, "head", string(contents))
+	assert.Equal(t, head.String(), git("rev-parse", "HEAD"))
+
+	checkouts.remove()
+	_, err = os.Stat(checkouts.root)
+	assert.True(t, os.IsNotExist(err))
+	assert.Len(t, strings.Split(git("worktree", "list"), "\n"), 1)
+}